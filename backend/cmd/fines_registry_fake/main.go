@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"alem-auto/internal/fines"
+)
+
+// Локальная заглушка реестра штрафов для разработки.
+// Для одного и того же номера всегда возвращает одинаковый набор штрафов.
+//
+//	go run ./cmd/fines_registry_fake -addr :8090
+//	FINES_REGISTRY_URL=http://localhost:8090 go run cmd/server/main.go
+
+type violation struct {
+	article     string
+	description string
+	amount      float64
+}
+
+var violations = []violation{
+	{"592 ч.1", "Превышение скорости на 10-20 км/ч", 19660},
+	{"592 ч.2", "Превышение скорости на 20-40 км/ч", 39320},
+	{"597 ч.1", "Нарушение правил остановки и стоянки", 19660},
+	{"599 ч.1", "Проезд на запрещающий сигнал светофора", 39320},
+	{"611 ч.1", "Непристегнутый ремень безопасности", 11796},
+	{"612 ч.1", "Использование телефона за рулем", 39320},
+}
+
+func main() {
+	addr := flag.String("addr", ":8090", "Listen address")
+	apiKey := flag.String("api-key", "", "Require this bearer token if set")
+	flag.Parse()
+
+	http.HandleFunc("/fines", func(w http.ResponseWriter, r *http.Request) {
+		if *apiKey != "" && r.Header.Get("Authorization") != "Bearer "+*apiKey {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		plate := strings.ToUpper(strings.ReplaceAll(r.URL.Query().Get("plate"), " ", ""))
+		if plate == "" && r.URL.Query().Get("iin") == "" {
+			http.Error(w, "plate or iin is required", http.StatusBadRequest)
+			return
+		}
+		list := generate(plate, time.Now())
+		log.Printf("GET /fines plate=%q -> %d fines", plate, len(list))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"fines": list})
+	})
+
+	log.Printf("Fake fines registry listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// generate returns 0-3 fines derived from the plate. The oldest one is reported as paid.
+func generate(plate string, now time.Time) []fines.RegistryFine {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(plate))
+	seed := h.Sum32()
+
+	count := int(seed % 4)
+	list := make([]fines.RegistryFine, 0, count)
+	for i := 0; i < count; i++ {
+		v := violations[(int(seed>>4)+i*7)%len(violations)]
+		daysAgo := int((seed>>(8+i*3))%40) + i*15
+		issuedAt := now.AddDate(0, 0, -daysAgo)
+		f := fines.RegistryFine{
+			ExternalID:   fmt.Sprintf("FAKE-%08X-%d", seed, i),
+			LicensePlate: plate,
+			Amount:       v.amount,
+			Currency:     "KZT",
+			Article:      v.article,
+			Description:  v.description,
+			IssuedAt:     issuedAt.Format("2006-01-02"),
+			Status:       "unpaid",
+		}
+		if i == count-1 && count > 1 {
+			paidAt := issuedAt.AddDate(0, 0, 3)
+			f.Status = "paid"
+			f.PaidAt = &paidAt
+		}
+		list = append(list, f)
+	}
+	return list
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/payments"
+)
+
+// Локальный симулятор платежной страницы для fake-провайдера.
+// Открывает checkout_url из payment intent и отправляет подписанный вебхук на сервер.
+//
+//	PAYMENTS_PROVIDER=fake PAYMENTS_WEBHOOK_SECRET=dev go run cmd/server/main.go
+//	go run ./cmd/payments_simulator -secret dev
+
+var checkoutPage = template.Must(template.New("checkout").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Оплата</title></head>
+<body style="font-family: sans-serif; max-width: 420px; margin: 40px auto">
+<h2>Тестовая оплата</h2>
+<p>Платеж: <code>{{.ID}}</code></p>
+<p>Сумма: <b>{{.Amount}} {{.Currency}}</b></p>
+<p>
+<a href="/complete/{{.ID}}?result=succeeded&amount={{.Amount}}">Оплатить</a> ·
+<a href="/complete/{{.ID}}?result=failed&amount={{.Amount}}">Отказ банка</a> ·
+<a href="/complete/{{.ID}}?result=cancelled&amount={{.Amount}}">Отмена</a>
+</p>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+</body></html>`))
+
+type pageData struct {
+	ID       string
+	Amount   string
+	Currency string
+	Message  string
+}
+
+func main() {
+	addr := flag.String("addr", ":8091", "Listen address")
+	webhookURL := flag.String("webhook-url", "http://localhost:8080/api/v1/payments/webhooks/fake", "Server webhook endpoint")
+	secret := flag.String("secret", "", "Webhook signing secret (PAYMENTS_WEBHOOK_SECRET of the server)")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Fatal("Webhook secret is required. Use --secret=<PAYMENTS_WEBHOOK_SECRET>")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	http.HandleFunc("/checkout/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/checkout/")
+		_ = checkoutPage.Execute(w, pageData{
+			ID:       id,
+			Amount:   r.URL.Query().Get("amount"),
+			Currency: r.URL.Query().Get("currency"),
+		})
+	})
+
+	http.HandleFunc("/complete/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/complete/")
+		amount, _ := strconv.ParseFloat(r.URL.Query().Get("amount"), 64)
+		event := payments.WebhookEvent{
+			EventID:           "evt_" + uuid.New().String(),
+			ProviderPaymentID: id,
+			Amount:            amount,
+			OccurredAt:        time.Now(),
+		}
+		switch r.URL.Query().Get("result") {
+		case "succeeded":
+			event.Type = payments.EventPaymentSucceeded
+		case "failed":
+			event.Type = payments.EventPaymentFailed
+			event.FailureReason = "declined by issuer"
+		default:
+			event.Type = payments.EventPaymentCancelled
+		}
+
+		status, err := sendWebhook(client, *webhookURL, *secret, &event)
+		msg := fmt.Sprintf("Webhook %s отправлен, ответ сервера: %d", event.Type, status)
+		if err != nil {
+			msg = fmt.Sprintf("Ошибка отправки вебхука: %v", err)
+		}
+		log.Printf("%s: %s", id, msg)
+		_ = checkoutPage.Execute(w, pageData{ID: id, Amount: r.URL.Query().Get("amount"), Message: msg})
+	})
+
+	log.Printf("Payments simulator listening on %s, webhooks -> %s", *addr, *webhookURL)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func sendWebhook(client *http.Client, url, secret string, event *payments.WebhookEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payments.FakeSignatureHeader, payments.Sign(secret, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
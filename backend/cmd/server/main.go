@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // service center timezones on images without zoneinfo
 
 	"alem-auto/config"
 	"alem-auto/internal/agent"
@@ -17,10 +18,15 @@ import (
 	"alem-auto/internal/catalog"
 	"alem-auto/internal/booking"
 	"alem-auto/internal/database"
+	"alem-auto/internal/directory"
 	"alem-auto/internal/fines"
 	"alem-auto/internal/inspection"
 	"alem-auto/internal/knowledge"
 	"alem-auto/internal/media"
+	"alem-auto/internal/notify"
+	"alem-auto/internal/payments"
+	"alem-auto/internal/reviews"
+	"alem-auto/internal/scheduler"
 	"alem-auto/internal/servicebook"
 	"alem-auto/internal/vehicle"
 	"alem-auto/internal/warehouse"
@@ -66,9 +72,13 @@ func main() {
 	var mediaService *media.Service
 	var authService *auth.Service
 	var finesService *fines.Service
+	var finesSyncer *fines.Syncer
 	var bookingService *booking.Service
 	var warehouseService *warehouse.Service
+	var paymentsService *payments.Service
 	var servicebookService *servicebook.Service
+	var reviewsService *reviews.Service
+	var directoryService *directory.Service
 
 	if db != nil {
 		catalogRepo := catalog.NewRepository(db)
@@ -87,13 +97,49 @@ func main() {
 		authService = auth.NewService(authRepo, cfg.Auth)
 
 		finesRepo := fines.NewRepository(db)
-		finesService = fines.NewService(finesRepo)
+		finesService = fines.NewService(finesRepo, cfg.Fines, mediaService)
+		if cfg.Fines.RegistryURL != "" {
+			provider := fines.NewHTTPProvider(cfg.Fines.RegistryName, cfg.Fines.RegistryURL, cfg.Fines.RegistryAPIKey, cfg.Fines.RegistryTimeout)
+			finesSyncer = fines.NewSyncer(finesService, provider)
+		}
 
 		bookingRepo := booking.NewRepository(db)
-		bookingService = booking.NewService(bookingRepo, vehicleService, inspectionService)
+		bookingService = booking.NewService(bookingRepo, vehicleService, inspectionService, cfg.Booking)
+		var notifier notify.Channel = notify.LogChannel{}
+		if cfg.Notify.WebhookURL != "" {
+			notifier = notify.NewWebhookChannel(cfg.Notify.WebhookURL, cfg.Notify.WebhookAPIKey, cfg.Notify.Timeout)
+		}
+		bookingService.SetNotifier(notifier)
+
+		directoryRepo := directory.NewRepository(db)
+		directoryService = directory.NewService(directoryRepo, inspectionService, bookingService)
+
+		reviewsRepo := reviews.NewRepository(db)
+		reviewsService = reviews.NewService(reviewsRepo, inspectionService, vehicleService, mediaService)
 
 		warehouseRepo := warehouse.NewRepository(db)
-		warehouseService = warehouse.NewService(warehouseRepo)
+		warehouseService = warehouse.NewService(warehouseRepo, cfg.Warehouse)
+		warehouseService.SetNotifier(notifier)
+		bookingService.SetPartsReservations(warehouseService)
+		switch cfg.Warehouse.CostingMethod {
+		case warehouse.CostingFIFO, warehouse.CostingAverage:
+		default:
+			log.Printf("Warning: Unknown warehouse costing method %q (using fifo)", cfg.Warehouse.CostingMethod)
+		}
+
+		switch cfg.Payments.Provider {
+		case "":
+		case "fake":
+			if cfg.Payments.WebhookSecret == "" {
+				log.Printf("Warning: PAYMENTS_WEBHOOK_SECRET is empty, payment webhooks will be rejected")
+			}
+			provider := payments.NewFakeProvider(cfg.Payments.WebhookSecret, cfg.Payments.CheckoutBaseURL)
+			paymentsService = payments.NewService(payments.NewRepository(db), provider)
+			paymentsService.RegisterPayable(payments.PayableFine, payments.NewFinePayable(finesService))
+			paymentsService.RegisterPayable(payments.PayableBooking, payments.NewBookingPayable(bookingService))
+		default:
+			log.Printf("Warning: Unknown payments provider %q (payments disabled)", cfg.Payments.Provider)
+		}
 	}
 
 	// Agent сервис (работает без БД, но без сохранения)
@@ -104,6 +150,13 @@ func main() {
 		agentRepo, err = agent.NewRepository(gormDB)
 		if err != nil {
 			log.Printf("Warning: Failed to init agent repository: %v", err)
+		} else {
+			if finesService != nil {
+				finesService.SetExpenseRecorder(agent.NewFineExpenses(agentRepo))
+			}
+			if bookingService != nil {
+				bookingService.SetServiceRecorder(agent.NewBookingRecords(agentRepo))
+			}
 		}
 
 		knowledgeRepo, err = knowledge.NewRepository(gormDB)
@@ -131,12 +184,59 @@ func main() {
 		knowledgeService = knowledge.NewService(knowledgeRepo, geminiClient)
 	}
 
-	agentService := agent.NewChatService(agentRepo, geminiClient, knowledgeService)
+	agentService := agent.NewChatService(agentRepo, geminiClient, knowledgeService, finesService)
 
 	if db != nil {
 		servicebookService = servicebook.NewService(vehicleService, inspectionService, agentRepo)
 	}
 
+	// Фоновые задачи
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if db != nil {
+		// Задачи записи выполняются на одной реплике за раз (advisory lock в Postgres)
+		jobs := scheduler.New(db)
+		jobs.Add("fines-overdue", cfg.Fines.OverdueCheckInterval, func(ctx context.Context) error {
+			n, err := finesService.MarkOverdue(ctx)
+			if n > 0 {
+				log.Printf("fines: marked %d fines as overdue", n)
+			}
+			return err
+		})
+		if finesSyncer != nil {
+			jobs.Add("fines-sync", cfg.Fines.SyncInterval, finesSyncer.SyncAll)
+		}
+		jobs.Add("booking-reminders", cfg.Booking.JobsInterval, func(ctx context.Context) error {
+			n, err := bookingService.SendReminders(ctx)
+			if n > 0 {
+				log.Printf("booking: sent %d reminders", n)
+			}
+			return err
+		})
+		jobs.Add("booking-no-shows", cfg.Booking.JobsInterval, func(ctx context.Context) error {
+			n, err := bookingService.MarkNoShows(ctx)
+			if n > 0 {
+				log.Printf("booking: marked %d bookings as no-show", n)
+			}
+			return err
+		})
+		jobs.Add("booking-waitlist", cfg.Booking.JobsInterval, func(ctx context.Context) error {
+			n, err := bookingService.ExpireWaitlist(ctx)
+			if n > 0 {
+				log.Printf("booking: %d waitlist offers expired", n)
+			}
+			return err
+		})
+		jobs.Add("warehouse-reservations", cfg.Warehouse.JobsInterval, func(ctx context.Context) error {
+			n, err := warehouseService.ExpireReservations(ctx)
+			if n > 0 {
+				log.Printf("warehouse: %d reservations expired", n)
+			}
+			return err
+		})
+		go jobs.Run(jobsCtx)
+	}
+
 	// Настраиваем роутинг
 	router := api.SetupRoutes(
 		authService,
@@ -145,9 +245,13 @@ func main() {
 		inspectionService,
 		mediaService,
 		finesService,
+		finesSyncer,
 		bookingService,
 		warehouseService,
+		paymentsService,
 		servicebookService,
+		reviewsService,
+		directoryService,
 		cfg.Mock.CarsJSONPath,
 		agentService,
 	)
@@ -174,6 +278,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"alem-auto/config"
+	"alem-auto/internal/database"
+	"alem-auto/internal/spreadsheet"
+	"alem-auto/internal/warehouse"
+)
+
+func main() {
+	var (
+		filePath string
+		format   string
+		mapping  string
+		location string
+		dryRun   bool
+		setStock bool
+	)
+	flag.StringVar(&filePath, "file", "", "Path to a CSV or XLSX file")
+	flag.StringVar(&format, "format", "", "File format: csv or xlsx (default: by extension)")
+	flag.StringVar(&mapping, "map", "", "Column mapping, e.g. sku=Артикул,name=Наименование")
+	flag.StringVar(&location, "location", "main", "Location code for opening stock")
+	flag.BoolVar(&dryRun, "dry-run", false, "Only validate and report what would change")
+	flag.BoolVar(&setStock, "set-stock", false, "Set stock at the location to the quantity column")
+	flag.Parse()
+
+	if filePath == "" {
+		log.Fatal("File path is required. Use --file=items.xlsx")
+	}
+	if format == "" {
+		format = spreadsheet.FormatFromName(filePath)
+	}
+	opts := warehouse.ImportOptions{DryRun: dryRun, SetStock: setStock}
+	if mapping != "" {
+		opts.Mapping = map[string]string{}
+		for _, pair := range strings.Split(mapping, ",") {
+			field, column, ok := strings.Cut(pair, "=")
+			if !ok {
+				log.Fatalf("Invalid mapping %q, use field=Column", pair)
+			}
+			opts.Mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+		}
+	}
+
+	// Читаем таблицу
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatalf("Failed to open file: %v", err)
+	}
+	table, err := spreadsheet.Read(f, format)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to read file: %v", err)
+	}
+
+	// Загружаем конфигурацию
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Подключаемся к БД
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	service := warehouse.NewService(warehouse.NewRepository(db), cfg.Warehouse)
+	ctx := context.Background()
+
+	if setStock {
+		loc, err := service.GetLocationByCode(ctx, location)
+		if err != nil {
+			log.Fatalf("Failed to find location: %v", err)
+		}
+		if loc == nil {
+			log.Fatalf("Location not found: %s", location)
+		}
+		opts.LocationID = &loc.ID
+	}
+
+	log.Printf("Starting import of %d rows from %s...", len(table)-1, filePath)
+	res, err := service.ImportItems(ctx, table, opts)
+	if res != nil {
+		for _, e := range res.Errors {
+			log.Printf("Row %d %s: %s", e.Row, e.Column, e.Message)
+		}
+		log.Printf("Rows: %d, created: %d, updated: %d, unchanged: %d, stock adjusted: %d",
+			res.Rows, res.Created, res.Updated, res.Unchanged, res.StockAdjusted)
+	}
+	if errors.Is(err, warehouse.ErrImportInvalid) {
+		log.Fatalf("Import rejected: %d row errors", len(res.Errors))
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	if dryRun {
+		log.Println("Dry run, nothing was saved")
+		return
+	}
+	log.Println("Import completed successfully!")
+}
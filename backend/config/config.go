@@ -3,18 +3,24 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	S3       S3Config
-	Auth     AuthConfig
-	Mock     MockConfig
-	AI       AIConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	S3        S3Config
+	Auth      AuthConfig
+	Mock      MockConfig
+	AI        AIConfig
+	Fines     FinesConfig
+	Payments  PaymentsConfig
+	Booking   BookingConfig
+	Notify    NotifyConfig
+	Warehouse WarehouseConfig
 }
 
 type ServerConfig struct {
@@ -59,6 +65,52 @@ type AIConfig struct {
 	GeminiModel  string
 }
 
+// FinesConfig задает сроки оплаты штрафов и скидку за раннюю оплату
+type FinesConfig struct {
+	PaymentTermDays      int     // срок оплаты со дня вынесения
+	DiscountDays         int     // окно оплаты со скидкой
+	DiscountPercent      float64 // размер скидки в процентах
+	OverdueCheckInterval time.Duration
+
+	// Внешний реестр штрафов; синхронизация выключена, если RegistryURL пуст
+	RegistryName    string
+	RegistryURL     string
+	RegistryAPIKey  string
+	RegistryTimeout time.Duration
+	SyncInterval    time.Duration
+}
+
+// PaymentsConfig задает платежный шлюз; платежи выключены, если Provider пуст
+type PaymentsConfig struct {
+	Provider        string // fake
+	WebhookSecret   string
+	CheckoutBaseURL string // адрес локального симулятора для fake
+}
+
+// BookingConfig задает правила записи в сервисы
+type BookingConfig struct {
+	CancelCutoff  time.Duration   // владелец не может отменить или перенести запись позже, чем за это время до начала
+	ReminderLeads []time.Duration // за сколько до начала напоминать владельцу
+	NoShowGrace   time.Duration   // через сколько после начала запись без приема машины становится неявкой; 0 — выключено
+	JobsInterval  time.Duration   // период фоновых задач записи
+	WaitlistHold  time.Duration   // сколько освободившееся время держится за человеком из листа ожидания
+}
+
+// NotifyConfig задает канал уведомлений; без WebhookURL уведомления пишутся в лог
+type NotifyConfig struct {
+	WebhookURL    string
+	WebhookAPIKey string
+	Timeout       time.Duration
+}
+
+// WarehouseConfig задает учет склада
+type WarehouseConfig struct {
+	CostingMethod  string        // fifo или average — как считается себестоимость списания
+	ReservationTTL time.Duration // сколько держится резерв без явного срока
+	JobsInterval   time.Duration // период фоновых задач склада
+	LotPicking     string        // fefo или fifo — из каких партий списывается товар
+}
+
 func Load() (*Config, error) {
 	// Загружаем .env файл если он существует (не критично если его нет)
 	_ = godotenv.Load()
@@ -100,6 +152,40 @@ func Load() (*Config, error) {
 			GeminiAPIKey: getEnv("GEMINI_API_KEY", ""),
 			GeminiModel:  getEnv("GEMINI_MODEL", "gemini-1.5-flash"),
 		},
+		Fines: FinesConfig{
+			PaymentTermDays:      parseInt(getEnv("FINES_PAYMENT_TERM_DAYS", "30")),
+			DiscountDays:         parseInt(getEnv("FINES_DISCOUNT_DAYS", "7")),
+			DiscountPercent:      parseFloat(getEnv("FINES_DISCOUNT_PERCENT", "50")),
+			OverdueCheckInterval: parseDuration(getEnv("FINES_OVERDUE_CHECK_INTERVAL", "1h")),
+			RegistryName:         getEnv("FINES_REGISTRY_NAME", "registry"),
+			RegistryURL:          getEnv("FINES_REGISTRY_URL", ""),
+			RegistryAPIKey:       getEnv("FINES_REGISTRY_API_KEY", ""),
+			RegistryTimeout:      parseDuration(getEnv("FINES_REGISTRY_TIMEOUT", "15s")),
+			SyncInterval:         parseDuration(getEnv("FINES_SYNC_INTERVAL", "6h")),
+		},
+		Payments: PaymentsConfig{
+			Provider:        getEnv("PAYMENTS_PROVIDER", ""),
+			WebhookSecret:   getEnv("PAYMENTS_WEBHOOK_SECRET", ""),
+			CheckoutBaseURL: getEnv("PAYMENTS_CHECKOUT_BASE_URL", "http://localhost:8091"),
+		},
+		Booking: BookingConfig{
+			CancelCutoff:  parseDuration(getEnv("BOOKING_CANCEL_CUTOFF", "2h")),
+			ReminderLeads: parseDurations(getEnv("BOOKING_REMINDERS", "24h,2h")),
+			NoShowGrace:   parseDuration(getEnv("BOOKING_NO_SHOW_GRACE", "30m")),
+			JobsInterval:  parseDuration(getEnv("BOOKING_JOBS_INTERVAL", "1m")),
+			WaitlistHold:  parseDuration(getEnv("BOOKING_WAITLIST_HOLD", "30m")),
+		},
+		Notify: NotifyConfig{
+			WebhookURL:    getEnv("NOTIFY_WEBHOOK_URL", ""),
+			WebhookAPIKey: getEnv("NOTIFY_WEBHOOK_API_KEY", ""),
+			Timeout:       parseDuration(getEnv("NOTIFY_TIMEOUT", "10s")),
+		},
+		Warehouse: WarehouseConfig{
+			CostingMethod:  getEnv("WAREHOUSE_COSTING_METHOD", "fifo"),
+			ReservationTTL: parseDuration(getEnv("WAREHOUSE_RESERVATION_TTL", "72h")),
+			JobsInterval:   parseDuration(getEnv("WAREHOUSE_JOBS_INTERVAL", "5m")),
+			LotPicking:     getEnv("WAREHOUSE_LOT_PICKING", "fefo"),
+		},
 	}
 
 	// Валидация обязательных полей
@@ -131,6 +217,14 @@ func parseInt(s string) int {
 	return val
 }
 
+func parseFloat(s string) float64 {
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
 func parseBool(s string) bool {
 	val, err := strconv.ParseBool(s)
 	if err != nil {
@@ -146,3 +240,14 @@ func parseDuration(s string) time.Duration {
 	}
 	return d
 }
+
+// parseDurations разбирает список вида "24h,2h"; некорректные значения пропускаются
+func parseDurations(s string) []time.Duration {
+	var list []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		if d, err := time.ParseDuration(strings.TrimSpace(part)); err == nil && d > 0 {
+			list = append(list, d)
+		}
+	}
+	return list
+}
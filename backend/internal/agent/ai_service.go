@@ -47,6 +47,7 @@ Your name is "AutoExpert". You are polite, professional, and concise.
 ### DATA HANDLING
 -   If the user provides information about an expense (e.g., "Поменял масло за 20000"), ALWAYS try to call the 'add_service_record' function.
 -   If details are missing (e.g., amount), ask the user for them politely.
+-   If the user asks about their own fines (how much they paid, what is unpaid, which violations cost the most), call the 'get_fines_summary' function and answer from its result.
 
 ### ПРАВИЛА РАБОТЫ С КОНТЕКСТОМ
 1. Тебе будет передан контекст (выдержки из законов). ИСПОЛЬЗУЙ ЕГО в первую очередь.
@@ -99,6 +100,27 @@ Your name is "AutoExpert". You are polite, professional, and concise.
 						Required: []string{"category", "amount", "description", "date"},
 					},
 				},
+				{
+					Name:        "get_fines_summary",
+					Description: "Get the user's fines totals: paid vs outstanding, by status, vehicle, article and month, and the most frequent violations. Every total is per currency.",
+					Parameters: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"vehicle_id": {
+								Type:        genai.TypeString,
+								Description: "Optional UUID of a vehicle to limit the summary to.",
+							},
+							"from": {
+								Type:        genai.TypeString,
+								Description: "Optional start of the period (issue date), YYYY-MM-DD.",
+							},
+							"to": {
+								Type:        genai.TypeString,
+								Description: "Optional end of the period (issue date), YYYY-MM-DD.",
+							},
+						},
+					},
+				},
 			},
 		},
 	}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/booking"
+)
+
+// BookingRecords records completed bookings as service records with category service.
+type BookingRecords struct {
+	repo *Repository
+}
+
+func NewBookingRecords(repo *Repository) *BookingRecords {
+	return &BookingRecords{repo: repo}
+}
+
+// BookingCompleted creates the service book entry of a completed booking or updates the existing one.
+func (e *BookingRecords) BookingCompleted(ctx context.Context, b *booking.Booking, centerName string) (uuid.UUID, error) {
+	record, err := e.repo.GetServiceRecordByBookingID(ctx, b.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if record == nil {
+		bookingID := b.ID
+		record = &ServiceRecord{ID: uuid.New(), UserID: b.UserID, BookingID: &bookingID}
+	}
+	vehicleID := b.VehicleID
+	record.VehicleID = &vehicleID
+	record.Date = b.ServiceRecordDate()
+	record.Category = CategoryService
+	record.Amount = b.ServiceRecordAmount()
+	record.Description = b.ServiceRecordTitle(centerName)
+	if err := e.repo.SaveServiceRecord(ctx, record); err != nil {
+		return uuid.Nil, err
+	}
+	return record.ID, nil
+}
@@ -2,9 +2,12 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"alem-auto/internal/fines"
 	"alem-auto/internal/knowledge"
 
 	"github.com/google/generative-ai-go/genai"
@@ -15,10 +18,11 @@ type ChatService struct {
 	repo      *Repository
 	gemini    *GeminiClient
 	knowledge *knowledge.Service
+	fines     *fines.Service
 }
 
-func NewChatService(repo *Repository, gemini *GeminiClient, knowledgeService *knowledge.Service) *ChatService {
-	return &ChatService{repo: repo, gemini: gemini, knowledge: knowledgeService}
+func NewChatService(repo *Repository, gemini *GeminiClient, knowledgeService *knowledge.Service, finesService *fines.Service) *ChatService {
+	return &ChatService{repo: repo, gemini: gemini, knowledge: knowledgeService, fines: finesService}
 }
 
 func (s *ChatService) ProcessUserMessage(
@@ -76,22 +80,29 @@ func (s *ChatService) handleResponse(
 	for _, part := range resp.Candidates[0].Content.Parts {
 		switch typed := part.(type) {
 		case genai.FunctionCall:
-			if typed.Name == "add_service_record" {
-				result, err := s.handleAddServiceRecord(ctx, userID, typed.Args)
-				if err != nil {
-					return "", err
-				}
-
-				followUp, err := session.SendMessage(ctx, genai.FunctionResponse{
-					Name:     "add_service_record",
-					Response: result,
-				})
-				if err != nil {
-					return "", fmt.Errorf("failed to send function response: %w", err)
-				}
-
-				return extractText(followUp)
+			var result map[string]interface{}
+			var err error
+			switch typed.Name {
+			case "add_service_record":
+				result, err = s.handleAddServiceRecord(ctx, userID, typed.Args)
+			case "get_fines_summary":
+				result, err = s.handleGetFinesSummary(ctx, userID, typed.Args)
+			default:
+				continue
 			}
+			if err != nil {
+				return "", err
+			}
+
+			followUp, err := session.SendMessage(ctx, genai.FunctionResponse{
+				Name:     typed.Name,
+				Response: result,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to send function response: %w", err)
+			}
+
+			return extractText(followUp)
 		case genai.Text:
 			return string(typed), nil
 		}
@@ -146,6 +157,47 @@ func (s *ChatService) handleAddServiceRecord(
 	return map[string]interface{}{"status": "success"}, nil
 }
 
+func (s *ChatService) handleGetFinesSummary(
+	ctx context.Context,
+	userID string,
+	args map[string]interface{},
+) (map[string]interface{}, error) {
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user_id: %w", err)
+	}
+	if s.fines == nil {
+		return map[string]interface{}{"status": "skipped", "reason": "fines not available"}, nil
+	}
+
+	var filter fines.SummaryFilter
+	if vid, err := uuid.Parse(strings.TrimSpace(toString(args["vehicle_id"]))); err == nil {
+		filter.VehicleID = &vid
+	}
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(toString(args["from"]))); err == nil {
+		filter.From = &t
+	}
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(toString(args["to"]))); err == nil {
+		filter.To = &t
+	}
+
+	summary, err := s.fines.Summary(ctx, parsedUserID, filter)
+	if err != nil {
+		return map[string]interface{}{"status": "error", "reason": err.Error()}, nil
+	}
+	// genai expects a plain map, so go through JSON to reuse the API field names.
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fines summary: %w", err)
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to encode fines summary: %w", err)
+	}
+	result["status"] = "success"
+	return result, nil
+}
+
 func extractText(resp *genai.GenerateContentResponse) (string, error) {
 	if resp == nil || len(resp.Candidates) == 0 {
 		return "", fmt.Errorf("empty response from gemini")
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/fines"
+)
+
+// FineExpenses records paid fines as service records with category fine.
+type FineExpenses struct {
+	repo *Repository
+}
+
+func NewFineExpenses(repo *Repository) *FineExpenses {
+	return &FineExpenses{repo: repo}
+}
+
+// FinePaid creates the expense entry of a paid fine or updates the existing one.
+func (e *FineExpenses) FinePaid(ctx context.Context, f *fines.Fine) (uuid.UUID, error) {
+	record, err := e.repo.GetServiceRecordByFineID(ctx, f.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if record == nil {
+		fineID := f.ID
+		record = &ServiceRecord{ID: uuid.New(), UserID: f.UserID, FineID: &fineID}
+	}
+	record.VehicleID = f.VehicleID
+	record.Date = f.ExpenseDate()
+	record.Category = CategoryFine
+	record.Amount = f.ExpenseAmount()
+	record.Description = f.ExpenseTitle()
+	if err := e.repo.SaveServiceRecord(ctx, record); err != nil {
+		return uuid.Nil, err
+	}
+	return record.ID, nil
+}
+
+// FineUnpaid deletes the expense entry of a fine.
+func (e *FineExpenses) FineUnpaid(ctx context.Context, fineID uuid.UUID) error {
+	return e.repo.DeleteServiceRecordsByFineID(ctx, fineID)
+}
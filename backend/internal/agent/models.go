@@ -53,6 +53,8 @@ type ServiceRecord struct {
 	Category    Category   `json:"category" gorm:"type:varchar(16)"`
 	Amount      float64    `json:"amount"`
 	Description string     `json:"description"`
+	FineID      *uuid.UUID `json:"fine_id,omitempty" gorm:"type:uuid"`    // set for entries created from paid fines; the unique index is created by migrations
+	BookingID   *uuid.UUID `json:"booking_id,omitempty" gorm:"type:uuid"` // set for entries created from completed bookings; the unique index is created by migrations
 	CreatedAt   time.Time  `json:"created_at"`
 }
 
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -44,3 +45,37 @@ func (r *Repository) GetServiceRecordsByVehicleID(ctx context.Context, vehicleID
 	}
 	return records, nil
 }
+
+// GetServiceRecordByFineID returns the expense entry created for a fine, or nil.
+func (r *Repository) GetServiceRecordByFineID(ctx context.Context, fineID uuid.UUID) (*ServiceRecord, error) {
+	var record ServiceRecord
+	err := r.db.WithContext(ctx).Where("fine_id = ?", fineID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetServiceRecordByBookingID returns the service book entry created for a booking, or nil.
+func (r *Repository) GetServiceRecordByBookingID(ctx context.Context, bookingID uuid.UUID) (*ServiceRecord, error) {
+	var record ServiceRecord
+	err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *Repository) SaveServiceRecord(ctx context.Context, record *ServiceRecord) error {
+	return r.db.WithContext(ctx).Save(record).Error
+}
+
+func (r *Repository) DeleteServiceRecordsByFineID(ctx context.Context, fineID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("fine_id = ?", fineID).Delete(&ServiceRecord{}).Error
+}
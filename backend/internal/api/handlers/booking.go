@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +34,10 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return
 	}
 	b, err := h.service.Create(c.Request.Context(), userID.(uuid.UUID), &req)
+	if errors.Is(err, booking.ErrSlotUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -45,6 +52,17 @@ func (h *BookingHandler) ListBookings(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
+	filter := bookingsFilter(c)
+	list, err := h.service.List(c.Request.Context(), userID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// bookingsFilter reads list filters from the query string; invalid values are ignored.
+func bookingsFilter(c *gin.Context) booking.ListBookingsFilter {
 	filter := booking.ListBookingsFilter{Limit: 50, Offset: 0}
 	if v := c.Query("service_center_id"); v != "" {
 		if id, err := uuid.Parse(v); err == nil {
@@ -56,6 +74,11 @@ func (h *BookingHandler) ListBookings(c *gin.Context) {
 			filter.VehicleID = &id
 		}
 	}
+	if v := c.Query("mechanic_user_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.MechanicUserID = &id
+		}
+	}
 	if v := c.Query("status"); v != "" {
 		filter.Status = &v
 	}
@@ -79,17 +102,12 @@ func (h *BookingHandler) ListBookings(c *gin.Context) {
 			filter.Offset = o
 		}
 	}
-	list, err := h.service.List(c.Request.Context(), userID.(uuid.UUID), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, list)
+	return filter
 }
 
-// GetBooking returns a single booking by ID (only if owned by current user).
+// GetBooking returns a single booking by ID (to its owner or the service center staff).
 func (h *BookingHandler) GetBooking(c *gin.Context) {
-	userID, ok := auth.GetUserID(c)
+	userID, role, ok := currentUser(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
@@ -100,7 +118,7 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	b, err := h.service.GetByID(c.Request.Context(), id, userID.(uuid.UUID))
+	b, err := h.service.GetByID(c.Request.Context(), id, userID, role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -114,7 +132,7 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 
 // UpdateBooking updates a booking (e.g. status, notes).
 func (h *BookingHandler) UpdateBooking(c *gin.Context) {
-	userID, ok := auth.GetUserID(c)
+	userID, role, ok := currentUser(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
@@ -130,7 +148,7 @@ func (h *BookingHandler) UpdateBooking(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	b, err := h.service.Update(c.Request.Context(), id, userID.(uuid.UUID), &req)
+	b, err := h.service.Update(c.Request.Context(), id, userID, role, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -156,8 +174,672 @@ func (h *BookingHandler) DeleteBooking(c *gin.Context) {
 		return
 	}
 	if err := h.service.Delete(c.Request.Context(), id, userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// CancelBooking cancels a booking on behalf of its owner or the service center.
+func (h *BookingHandler) CancelBooking(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	h.transition(c, &booking.TransitionRequest{Status: booking.StatusCancelled, Reason: req.Reason})
+}
+
+// TransitionBooking moves a booking to another status (confirm, check in, complete, no-show).
+func (h *BookingHandler) TransitionBooking(c *gin.Context) {
+	var req booking.TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.transition(c, &req)
+}
+
+func (h *BookingHandler) transition(c *gin.Context, req *booking.TransitionRequest) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	b, err := h.service.Transition(c.Request.Context(), id, userID, role, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// RescheduleBooking moves a booking to another free time.
+func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b, err := h.service.Reschedule(c.Request.Context(), id, userID, role, &req)
+	if errors.Is(err, booking.ErrSlotUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// GetBookingHistory returns status changes and reschedules of a booking.
+func (h *BookingHandler) GetBookingHistory(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	events, err := h.service.History(c.Request.Context(), id, userID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if events == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// CompleteBooking finishes the work on a booking with the prices actually charged.
+func (h *BookingHandler) CompleteBooking(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.CompleteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	b, err := h.service.Complete(c.Request.Context(), id, userID, role, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// CheckInBooking receives the car of a booking and opens an inspection for the visit.
+func (h *BookingHandler) CheckInBooking(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.CheckInRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	res, err := h.service.CheckIn(c.Request.Context(), id, userID, role, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if res == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ListCenterBookings returns bookings at a service center for its staff.
+func (h *BookingHandler) ListCenterBookings(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListForCenter(c.Request.Context(), userID, role, centerID, bookingsFilter(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetAgenda returns the bookings of a service center for a day (?date=YYYY-MM-DD, today by default).
+func (h *BookingHandler) GetAgenda(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	agenda, err := h.service.Agenda(c.Request.Context(), userID, role, centerID, c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agenda)
+}
+
+// calendarFeedResponse is a feed with the URL to subscribe to in a calendar app.
+type calendarFeedResponse struct {
+	*booking.CalendarFeed
+	URL string `json:"url"`
+}
+
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s/api/v1/calendar/%s.ics", scheme, c.Request.Host, token)
+}
+
+// CreateCalendarFeed issues a secret feed URL of the user's (or a service center's) bookings.
+func (h *BookingHandler) CreateCalendarFeed(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req booking.CreateCalendarFeedRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	f, err := h.service.CreateCalendarFeed(c.Request.Context(), userID, role, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, calendarFeedResponse{CalendarFeed: f, URL: feedURL(c, f.Token)})
+}
+
+// ListCalendarFeeds returns the active feeds of the current user.
+func (h *BookingHandler) ListCalendarFeeds(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	feeds, err := h.service.ListCalendarFeeds(c.Request.Context(), userID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	list := make([]calendarFeedResponse, len(feeds))
+	for i, f := range feeds {
+		list[i] = calendarFeedResponse{CalendarFeed: f, URL: feedURL(c, f.Token)}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RevokeCalendarFeed disables a feed URL.
+func (h *BookingHandler) RevokeCalendarFeed(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	found, err := h.service.RevokeCalendarFeed(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetCalendarFeed serves a feed by its secret token (public: the token is the credential).
+func (h *BookingHandler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	data, err := h.service.CalendarFeed(c.Request.Context(), token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// DownloadBookingICS returns a booking as an .ics file.
+func (h *BookingHandler) DownloadBookingICS(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	data, err := h.service.BookingICS(c.Request.Context(), id, userID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="booking-%s.ics"`, id))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// currentUser returns the ID and role of the authenticated user.
+func currentUser(c *gin.Context) (uuid.UUID, string, bool) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return uuid.Nil, "", false
+	}
+	role, _ := auth.GetUserRole(c)
+	roleStr, _ := role.(string)
+	return userID.(uuid.UUID), roleStr, true
+}
+
+// GetSchedule returns working hours, holidays and capacity of a service center.
+func (h *BookingHandler) GetSchedule(c *gin.Context) {
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	cs, err := h.service.GetSchedule(c.Request.Context(), centerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cs == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+// UpdateSchedule changes capacity settings and weekly hours (center admins only).
+func (h *BookingHandler) UpdateSchedule(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cs, err := h.service.UpdateSchedule(c.Request.Context(), userID, role, centerID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+// AddHoliday closes a service center for bookings on a date (center admins only).
+func (h *BookingHandler) AddHoliday(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.AddHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hol, err := h.service.AddHoliday(c.Request.Context(), userID, role, centerID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, hol)
+}
+
+// DeleteHoliday reopens a service center on a date (center admins only).
+func (h *BookingHandler) DeleteHoliday(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeleteHoliday(c.Request.Context(), userID, role, centerID, c.Param("date")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListCenterServices returns services of a service center with their durations.
+func (h *BookingHandler) ListCenterServices(c *gin.Context) {
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	filter := booking.CenterServicesFilter{IncludeInactive: c.Query("include_inactive") == "true"}
+	if v := c.Query("category"); v != "" {
+		filter.Category = &v
+	}
+	if v := c.Query("component_code"); v != "" {
+		filter.ComponentCode = &v
+	}
+	if v := c.Query("vehicle_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vehicle_id"})
+			return
+		}
+		filter.VehicleID = &id
+	}
+	list, err := h.service.ListCenterServices(c.Request.Context(), centerID, filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateCenterService adds a service to a service center (center admins only).
+func (h *BookingHandler) CreateCenterService(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req booking.CreateCenterServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	svc, err := h.service.CreateCenterService(c.Request.Context(), userID, role, centerID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, svc)
+}
+
+// UpdateCenterService changes a service of a service center (center admins only).
+func (h *BookingHandler) UpdateCenterService(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	serviceID, err := uuid.Parse(c.Param("service_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service_id"})
+		return
+	}
+	var req booking.UpdateCenterServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	svc, err := h.service.UpdateCenterService(c.Request.Context(), userID, role, centerID, serviceID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if svc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, svc)
+}
+
+// GetAvailability returns free booking slots of a service center for a date range.
+// Query: from, to (YYYY-MM-DD), service_ids (comma-separated, optional).
+func (h *BookingHandler) GetAvailability(c *gin.Context) {
+	centerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	from := c.Query("from")
+	if from == "" {
+		from = time.Now().Format("2006-01-02")
+	}
+	to := c.DefaultQuery("to", from)
+	var serviceIDs []uuid.UUID
+	if v := c.Query("service_ids"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service_ids"})
+				return
+			}
+			serviceIDs = append(serviceIDs, id)
+		}
+	}
+	slots, err := h.service.Availability(c.Request.Context(), centerID, from, to, serviceIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, slots)
+}
+
+// JoinWaitlist puts the user in the queue for a fully booked center and date range.
+func (h *BookingHandler) JoinWaitlist(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req booking.JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e, err := h.service.JoinWaitlist(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// ListWaitlist returns the user's waitlist entries with the slots currently held for them.
+func (h *BookingHandler) ListWaitlist(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	list, err := h.service.ListWaitlist(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// LeaveWaitlist cancels a waitlist entry of the user.
+func (h *BookingHandler) LeaveWaitlist(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	found, err := h.service.LeaveWaitlist(c.Request.Context(), id, userID)
+	if !found && err == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// AcceptWaitlistOffer books the slot held for the user.
+func (h *BookingHandler) AcceptWaitlistOffer(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	b, err := h.service.AcceptOffer(c.Request.Context(), id, userID)
+	if errors.Is(err, booking.ErrSlotUnavailable) || errors.Is(err, booking.ErrOfferUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// DeclineWaitlistOffer gives the held slot to the next user in the queue.
+func (h *BookingHandler) DeclineWaitlistOffer(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	found, err := h.service.DeclineOffer(c.Request.Context(), id, userID)
+	if errors.Is(err, booking.ErrOfferUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
 	c.JSON(http.StatusNoContent, nil)
 }
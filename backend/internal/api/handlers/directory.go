@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"alem-auto/internal/directory"
+)
+
+type DirectoryHandler struct {
+	service *directory.Service
+}
+
+func NewDirectoryHandler(service *directory.Service) *DirectoryHandler {
+	return &DirectoryHandler{service: service}
+}
+
+// SearchServiceCenters lists service centers, optionally around ?lat&lon within ?radius_km.
+func (h *DirectoryHandler) SearchServiceCenters(c *gin.Context) {
+	filter := directory.SearchFilter{Sort: c.Query("sort")}
+	var err error
+	if filter.Lat, err = queryFloat(c, "lat"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lat"})
+		return
+	}
+	if filter.Lon, err = queryFloat(c, "lon"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lon"})
+		return
+	}
+	radius, err := queryFloat(c, "radius_km")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid radius_km"})
+		return
+	}
+	if radius != nil {
+		filter.RadiusKm = *radius
+	}
+	if v := c.Query("make_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid make_id"})
+			return
+		}
+		filter.MakeID = &id
+	}
+	if v := c.Query("city"); v != "" {
+		filter.City = &v
+	}
+	if v := c.Query("category"); v != "" {
+		filter.Category = &v
+	}
+	if v := c.Query("q"); v != "" {
+		filter.Query = &v
+	}
+	filter.Limit, _ = parseInt(c.DefaultQuery("limit", "20"))
+	filter.Offset, _ = parseInt(c.DefaultQuery("offset", "0"))
+
+	list, err := h.service.Search(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// queryFloat parses an optional float query parameter.
+func queryFloat(c *gin.Context, key string) (*float64, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GetServiceCenter returns the public page of a service center.
+func (h *DirectoryHandler) GetServiceCenter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	p, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// UpdateServiceCenter changes the address, location, contacts and specialization of a center.
+func (h *DirectoryHandler) UpdateServiceCenter(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req directory.UpdateCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.Update(c.Request.Context(), userID, role, id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,10 +13,11 @@ import (
 
 type FinesHandler struct {
 	service *fines.Service
+	syncer  *fines.Syncer // nil when no registry is configured
 }
 
-func NewFinesHandler(service *fines.Service) *FinesHandler {
-	return &FinesHandler{service: service}
+func NewFinesHandler(service *fines.Service, syncer *fines.Syncer) *FinesHandler {
+	return &FinesHandler{service: service, syncer: syncer}
 }
 
 // CreateFine creates a new fine for the current user.
@@ -75,6 +77,44 @@ func (h *FinesHandler) ListFines(c *gin.Context) {
 	c.JSON(http.StatusOK, list)
 }
 
+// GetSummary returns fines totals by status, vehicle, article and month for the current user.
+func (h *FinesHandler) GetSummary(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var filter fines.SummaryFilter
+	if v := c.Query("vehicle_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vehicle_id"})
+			return
+		}
+		filter.VehicleID = &id
+	}
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return
+			}
+			*dst = &t
+		}
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+	sum, err := h.service.Summary(c.Request.Context(), userID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sum)
+}
+
 // GetFine returns a single fine by ID (only if owned by current user).
 func (h *FinesHandler) GetFine(c *gin.Context) {
 	userID, ok := auth.GetUserID(c)
@@ -150,6 +190,184 @@ func (h *FinesHandler) DeleteFine(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// SyncFines pulls fines from the external registry for the current user's vehicles.
+func (h *FinesHandler) SyncFines(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if h.syncer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "fines registry sync is not configured"})
+		return
+	}
+	run, err := h.syncer.SyncUser(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// GetSyncStatus returns the registry sync status and history for the current user.
+func (h *FinesHandler) GetSyncStatus(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	if h.syncer == nil {
+		c.JSON(http.StatusOK, fines.SyncStatusResponse{Enabled: false, Runs: []*fines.SyncRun{}})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "20"))
+	resp, err := h.syncer.Status(c.Request.Context(), userID.(uuid.UUID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SubmitDispute opens a dispute for a fine of the current user.
+func (h *FinesHandler) SubmitDispute(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req fines.SubmitDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := h.service.SubmitDispute(c.Request.Context(), id, userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+// GetDispute returns the latest dispute of a fine with its history and evidence.
+func (h *FinesHandler) GetDispute(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	d, err := h.service.GetDispute(c.Request.Context(), id, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if d == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// AddDisputeEvidence attaches an uploaded media asset to an open dispute.
+func (h *FinesHandler) AddDisputeEvidence(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req fines.AddEvidenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := h.service.AddDisputeEvidence(c.Request.Context(), id, userID.(uuid.UUID), req.AssetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// GetComplaintLetter returns a prefilled complaint letter for the fine's dispute as plain text.
+func (h *FinesHandler) GetComplaintLetter(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	letter, err := h.service.ComplaintLetter(c.Request.Context(), id, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="complaint-%s.txt"`, id))
+	c.String(http.StatusOK, letter)
+}
+
+// ListDisputes returns disputes awaiting or past review (admin/platform only).
+func (h *FinesHandler) ListDisputes(c *gin.Context) {
+	var status *string
+	if v := c.Query("status"); v != "" {
+		status = &v
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	list, err := h.service.ListDisputes(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []*fines.Dispute{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ReviewDispute records a reviewer's decision on a dispute (admin/platform only).
+func (h *FinesHandler) ReviewDispute(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("dispute_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dispute_id"})
+		return
+	}
+	var req fines.ReviewDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := h.service.ReviewDispute(c.Request.Context(), id, userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
 func parseInt(s string) (int, error) {
 	var n int
 	_, err := fmt.Sscanf(s, "%d", &n)
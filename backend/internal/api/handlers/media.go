@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"alem-auto/internal/auth"
 	"alem-auto/internal/media"
 )
 
@@ -34,7 +35,13 @@ func (h *MediaHandler) PrepareUpload(c *gin.Context) {
 		return
 	}
 
-	response, err := h.mediaService.PrepareUpload(c.Request.Context(), &req)
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	response, err := h.mediaService.PrepareUpload(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"alem-auto/internal/auth"
+	"alem-auto/internal/payments"
+)
+
+const maxWebhookBodyBytes = 1 << 20
+
+type PaymentsHandler struct {
+	service *payments.Service
+}
+
+func NewPaymentsHandler(service *payments.Service) *PaymentsHandler {
+	return &PaymentsHandler{service: service}
+}
+
+// CreateIntent starts a payment for a fine, booking or order of the current user.
+func (h *PaymentsHandler) CreateIntent(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req payments.CreateIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.CreateIntent(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// ListIntents returns payments of the current user with optional filters.
+func (h *PaymentsHandler) ListIntents(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	filter := payments.ListIntentsFilter{Limit: 50}
+	if v := c.Query("payable_type"); v != "" {
+		filter.PayableType = &v
+	}
+	if v := c.Query("payable_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.PayableID = &id
+		}
+	}
+	if v := c.Query("status"); v != "" {
+		filter.Status = &v
+	}
+	if l, err := parseInt(c.DefaultQuery("limit", "50")); err == nil && l > 0 {
+		filter.Limit = l
+	}
+	if o, err := parseInt(c.DefaultQuery("offset", "0")); err == nil && o >= 0 {
+		filter.Offset = o
+	}
+	list, err := h.service.ListIntents(c.Request.Context(), userID.(uuid.UUID), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetIntent returns a payment of the current user.
+func (h *PaymentsHandler) GetIntent(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	p, err := h.service.GetIntent(c.Request.Context(), id, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	refunds, err := h.service.ListRefunds(c.Request.Context(), p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if refunds == nil {
+		refunds = []*payments.Refund{}
+	}
+	c.JSON(http.StatusOK, gin.H{"payment": p, "refunds": refunds})
+}
+
+// RefundIntent refunds a succeeded payment fully or partially (admin/platform only).
+func (h *PaymentsHandler) RefundIntent(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req payments.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.Refund(c.Request.Context(), id, userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Webhook receives signed notifications from the payment provider (public).
+func (h *PaymentsHandler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	err = h.service.HandleWebhook(c.Request.Context(), c.Param("provider"), c.Request.Header, body)
+	if errors.Is(err, payments.ErrUnknownProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, payments.ErrInvalidSignature) || errors.Is(err, payments.ErrInvalidPayload) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// Providers retry on 5xx, so a temporary failure does not lose the event.
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
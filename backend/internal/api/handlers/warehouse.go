@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"alem-auto/internal/spreadsheet"
 	"alem-auto/internal/warehouse"
 )
 
@@ -16,16 +23,23 @@ func NewWarehouseHandler(service *warehouse.Service) *WarehouseHandler {
 	return &WarehouseHandler{service: service}
 }
 
-// ListItems returns warehouse items (paginated).
+// ListItems returns warehouse items (paginated). ?q= searches SKU, name, description, part
+// numbers and barcodes with typo tolerance, best matches first; ?category= narrows the list.
 func (h *WarehouseHandler) ListItems(c *gin.Context) {
 	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
 	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
 	if limit <= 0 {
 		limit = 50
 	}
-	list, err := h.service.ListItems(c.Request.Context(), limit, offset)
+	filter := warehouse.ItemsFilter{
+		Query:    c.Query("q"),
+		Category: c.Query("category"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	list, err := h.service.ListItems(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, list)
@@ -91,28 +105,36 @@ func (h *WarehouseHandler) UpdateItem(c *gin.Context) {
 	c.JSON(http.StatusOK, item)
 }
 
-// GetStock returns stock for an item.
+// GetStock returns on-hand, reserved and available stock for an item at the locations visible
+// to the user.
 func (h *WarehouseHandler) GetStock(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	stock, err := h.service.GetStock(c.Request.Context(), id)
+	stock, err := h.service.GetStock(c.Request.Context(), userID, role, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if stock == nil {
-		c.JSON(http.StatusOK, gin.H{"item_id": id, "quantity": 0})
-		return
-	}
 	c.JSON(http.StatusOK, stock)
 }
 
-// AdjustStock adjusts stock (in/out/adjust).
+// AdjustStock adjusts stock (in/out/adjust) at a location. A retried request with the same
+// Idempotency-Key header is applied only once.
 func (h *WarehouseHandler) AdjustStock(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -124,31 +146,1219 @@ func (h *WarehouseHandler) AdjustStock(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	stock, err := h.service.AdjustStock(c.Request.Context(), id, &req)
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = &key
+	}
+	stock, replayed, err := h.service.AdjustStock(c.Request.Context(), userID, role, id, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		stockError(c, err)
 		return
 	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
 	c.JSON(http.StatusOK, stock)
 }
 
-// ListMovements returns stock movements (optional filter by item_id).
+// stockError maps errors of stock changes to responses.
+func stockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, warehouse.ErrLocationAccess):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, warehouse.ErrInsufficientStock), errors.Is(err, warehouse.ErrIdempotencyKeyReused),
+		errors.Is(err, warehouse.ErrReservationClosed), errors.Is(err, warehouse.ErrStocktakeClosed),
+		errors.Is(err, warehouse.ErrStocktakeInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// ListMovements returns stock movements (optional filter by item_id and location_id).
 func (h *WarehouseHandler) ListMovements(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
 	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
 	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
 	if limit <= 0 {
 		limit = 50
 	}
-	var itemID *uuid.UUID
+	filter := warehouse.MovementsFilter{Limit: limit, Offset: offset}
 	if v := c.Query("item_id"); v != "" {
 		if id, err := uuid.Parse(v); err == nil {
-			itemID = &id
+			filter.ItemID = &id
+		}
+	}
+	if v := c.Query("location_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.LocationID = &id
+		}
+	}
+	list, err := h.service.ListMovements(c.Request.Context(), userID, role, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListLocations returns the warehouse locations visible to the user.
+func (h *WarehouseHandler) ListLocations(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	list, err := h.service.ListLocations(c.Request.Context(), userID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateLocation creates a warehouse location (admin only).
+func (h *WarehouseHandler) CreateLocation(c *gin.Context) {
+	var req warehouse.CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	l, err := h.service.CreateLocation(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, l)
+}
+
+// UpdateLocation renames, reassigns or deactivates a location (admin only).
+func (h *WarehouseHandler) UpdateLocation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	l, err := h.service.UpdateLocation(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if l == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, l)
+}
+
+// CreateTransfer moves stock of an item between two locations. A retried request with the
+// same Idempotency-Key header is applied only once.
+func (h *WarehouseHandler) CreateTransfer(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req warehouse.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = &key
+	}
+	t, replayed, err := h.service.Transfer(c.Request.Context(), userID, role, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, t)
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// LowStock returns items below their minimum quantity (optional filter by location_id).
+func (h *WarehouseHandler) LowStock(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var locationID *uuid.UUID
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		locationID = &id
+	}
+	list, err := h.service.LowStock(c.Request.Context(), userID, role, locationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListSuppliers returns suppliers (?active=true for active ones only).
+func (h *WarehouseHandler) ListSuppliers(c *gin.Context) {
+	list, err := h.service.ListSuppliers(c.Request.Context(), c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateSupplier creates a supplier (admin only).
+func (h *WarehouseHandler) CreateSupplier(c *gin.Context) {
+	var req warehouse.CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sup, err := h.service.CreateSupplier(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, sup)
+}
+
+// GetSupplier returns a supplier by ID.
+func (h *WarehouseHandler) GetSupplier(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	sup, err := h.service.GetSupplier(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sup == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, sup)
+}
+
+// UpdateSupplier updates contacts, lead time or the active flag of a supplier (admin only).
+func (h *WarehouseHandler) UpdateSupplier(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sup, err := h.service.UpdateSupplier(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if sup == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, sup)
+}
+
+// ListSupplierItems returns the items a supplier sells with SKUs and prices.
+func (h *WarehouseHandler) ListSupplierItems(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListSupplierItems(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListItemSuppliers returns the suppliers of an item, the preferred one first.
+func (h *WarehouseHandler) ListItemSuppliers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListItemSuppliers(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// SetSupplierItem sets the SKU, price and minimum order of an item at a supplier (admin only).
+func (h *WarehouseHandler) SetSupplierItem(c *gin.Context) {
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item id"})
+		return
+	}
+	var req warehouse.SupplierItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	si, err := h.service.SetSupplierItem(c.Request.Context(), supplierID, itemID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, si)
+}
+
+// DeleteSupplierItem removes an item from a supplier's offer (admin only).
+func (h *WarehouseHandler) DeleteSupplierItem(c *gin.Context) {
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item id"})
+		return
+	}
+	deleted, err := h.service.DeleteSupplierItem(c.Request.Context(), supplierID, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListPurchaseOrders returns purchase orders (optional filter by status and supplier_id).
+func (h *WarehouseHandler) ListPurchaseOrders(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	filter := warehouse.PurchaseOrdersFilter{Status: c.Query("status"), Limit: limit, Offset: offset}
+	if v := c.Query("supplier_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.SupplierID = &id
 		}
 	}
-	list, err := h.service.ListMovements(c.Request.Context(), itemID, limit, offset)
+	list, err := h.service.ListPurchaseOrders(c.Request.Context(), userID, role, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, list)
 }
+
+// CreatePurchaseOrder creates a draft purchase order (admin only).
+func (h *WarehouseHandler) CreatePurchaseOrder(c *gin.Context) {
+	userID, _, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req warehouse.CreatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	po, err := h.service.CreatePurchaseOrder(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, po)
+}
+
+// GetPurchaseOrder returns a purchase order with its lines.
+func (h *WarehouseHandler) GetPurchaseOrder(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	po, err := h.service.GetPurchaseOrder(c.Request.Context(), userID, role, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if po == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, po)
+}
+
+// SendPurchaseOrder marks a draft purchase order as sent to the supplier (admin only).
+func (h *WarehouseHandler) SendPurchaseOrder(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	po, err := h.service.SendPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if po == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, po)
+}
+
+// CancelPurchaseOrder cancels a purchase order nothing was received against (admin only).
+func (h *WarehouseHandler) CancelPurchaseOrder(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	po, err := h.service.CancelPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if po == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, po)
+}
+
+// ReceivePurchaseOrder books goods delivered against a purchase order into stock. A retried
+// request with the same Idempotency-Key header is applied only once.
+func (h *WarehouseHandler) ReceivePurchaseOrder(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.ReceivePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = &key
+	}
+	po, replayed, err := h.service.ReceivePurchaseOrder(c.Request.Context(), userID, role, id, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if po == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, po)
+}
+
+// Valuation returns what the stock was worth at the end of a day (?as_of=YYYY-MM-DD, default
+// now; optional location_id).
+func (h *WarehouseHandler) Valuation(c *gin.Context) {
+	var asOf *time.Time
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid as_of, use YYYY-MM-DD"})
+			return
+		}
+		asOf = &t
+	}
+	var locationID *uuid.UUID
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		locationID = &id
+	}
+	v, err := h.service.Valuation(c.Request.Context(), asOf, locationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// Margins returns revenue, cost and margin of parts sold per work order reference
+// (?from, to as YYYY-MM-DD; optional reference and location_id).
+func (h *WarehouseHandler) Margins(c *gin.Context) {
+	var filter warehouse.MarginFilter
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", use YYYY-MM-DD"})
+				return
+			}
+			*dst = &t
+		}
+	}
+	if v := c.Query("reference"); v != "" {
+		filter.Reference = &v
+	}
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		filter.LocationID = &id
+	}
+	list, err := h.service.Margins(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListReservations returns stock reservations (optional filter by item_id, reference_type,
+// reference_id and status).
+func (h *WarehouseHandler) ListReservations(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	filter := warehouse.ReservationsFilter{
+		ReferenceType: c.Query("reference_type"),
+		ReferenceID:   c.Query("reference_id"),
+		Status:        c.Query("status"),
+		Limit:         limit,
+		Offset:        offset,
+	}
+	if v := c.Query("item_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.ItemID = &id
+		}
+	}
+	list, err := h.service.ListReservations(c.Request.Context(), userID, role, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateReservation reserves stock for a booking or work order.
+func (h *WarehouseHandler) CreateReservation(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req warehouse.CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.service.Reserve(c.Request.Context(), userID, role, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// CompleteReservation issues reserved stock as an "out" movement when the job is done.
+func (h *WarehouseHandler) CompleteReservation(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.CompleteReservationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	res, err := h.service.CompleteReservation(c.Request.Context(), userID, role, id, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if res == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ReleaseReservation gives reserved stock back when the job is cancelled.
+func (h *WarehouseHandler) ReleaseReservation(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	res, err := h.service.ReleaseReservation(c.Request.Context(), userID, role, id)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if res == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ImportItems creates or updates items by SKU from an uploaded CSV or XLSX file. Form fields:
+// file, optional format, mapping (JSON object of field to column header), dry_run, set_stock
+// and location_id. Row errors reject the whole import with 422.
+func (h *WarehouseHandler) ImportItems(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = spreadsheet.FormatFromName(header.Filename)
+	}
+	var opts warehouse.ImportOptions
+	if v := c.PostForm("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping, use a JSON object of field to column"})
+			return
+		}
+	}
+	for param, dst := range map[string]*bool{"dry_run": &opts.DryRun, "set_stock": &opts.SetStock} {
+		if v := c.PostForm(param); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+				return
+			}
+			*dst = b
+		}
+	}
+	if v := c.PostForm("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		opts.LocationID = &id
+	}
+
+	table, err := spreadsheet.Read(file, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.service.ImportItems(c.Request.Context(), table, opts)
+	if errors.Is(err, warehouse.ErrImportInvalid) {
+		c.JSON(http.StatusUnprocessableEntity, res)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ExportItems downloads all items with their stock (?format=csv|xlsx, default csv; optional
+// location_id). The file can be imported back.
+func (h *WarehouseHandler) ExportItems(c *gin.Context) {
+	format := c.DefaultQuery("format", spreadsheet.FormatCSV)
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use csv or xlsx"})
+		return
+	}
+	var locationID *uuid.UUID
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		locationID = &id
+	}
+	table, err := h.service.ExportItems(c.Request.Context(), locationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var buf bytes.Buffer
+	if err := spreadsheet.Write(&buf, format, table); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="warehouse-items-%s.%s"`, time.Now().Format("20060102"), format))
+	c.Data(http.StatusOK, spreadsheet.ContentType(format), buf.Bytes())
+}
+
+// ListStocktakes returns stocktakes without their lines (optional filter by status and
+// location_id).
+func (h *WarehouseHandler) ListStocktakes(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	filter := warehouse.StocktakesFilter{Status: c.Query("status"), Limit: limit, Offset: offset}
+	if v := c.Query("location_id"); v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			filter.LocationID = &id
+		}
+	}
+	list, err := h.service.ListStocktakes(c.Request.Context(), userID, role, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// StartStocktake opens a count of a location or of one item category at it.
+func (h *WarehouseHandler) StartStocktake(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	var req warehouse.StartStocktakeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	st, err := h.service.StartStocktake(c.Request.Context(), userID, role, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, st)
+}
+
+// GetStocktake returns a stocktake with its lines; ?variances=true returns only the lines whose
+// count differs from the expected quantity.
+func (h *WarehouseHandler) GetStocktake(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	st, err := h.service.GetStocktake(c.Request.Context(), userID, role, id, c.Query("variances") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if st == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// RecordStocktakeCount records a count of an item, by item_id or by a scanned code.
+func (h *WarehouseHandler) RecordStocktakeCount(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.StocktakeCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	line, err := h.service.RecordCount(c.Request.Context(), userID, role, id, &req)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if line == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, line)
+}
+
+// ListStocktakeCounts returns the count entries of a stocktake with who made them (optional
+// filter by item_id).
+func (h *WarehouseHandler) ListStocktakeCounts(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var itemID *uuid.UUID
+	if v := c.Query("item_id"); v != "" {
+		if iid, err := uuid.Parse(v); err == nil {
+			itemID = &iid
+		}
+	}
+	list, err := h.service.ListStocktakeCounts(c.Request.Context(), userID, role, id, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// PostStocktake books the counted variances as adjust movements (admin only).
+func (h *WarehouseHandler) PostStocktake(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	st, err := h.service.PostStocktake(c.Request.Context(), userID, role, id)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if st == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// CancelStocktake closes a stocktake without changing stock (admin only).
+func (h *WarehouseHandler) CancelStocktake(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	st, err := h.service.CancelStocktake(c.Request.Context(), userID, role, id)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if st == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// LookupCode returns the item a scanned barcode or SKU belongs to, with its stock.
+func (h *WarehouseHandler) LookupCode(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	res, err := h.service.LookupCode(c.Request.Context(), userID, role, c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if res == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no item with this code"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ListItemBarcodes returns the barcodes of an item.
+func (h *WarehouseHandler) ListItemBarcodes(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListBarcodes(c.Request.Context(), itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddItemBarcode assigns an EAN-13, EAN-8, UPC-A or internal barcode to an item (admin only).
+func (h *WarehouseHandler) AddItemBarcode(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.AddBarcodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b, err := h.service.AddBarcode(c.Request.Context(), itemID, &req)
+	if errors.Is(err, warehouse.ErrBarcodeInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// DeleteItemBarcode removes a barcode from an item (admin only).
+func (h *WarehouseHandler) DeleteItemBarcode(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	barcodeID, err := uuid.Parse(c.Param("barcodeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid barcode id"})
+		return
+	}
+	deleted, err := h.service.DeleteBarcode(c.Request.Context(), itemID, barcodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListItemPartNumbers returns the OEM and aftermarket numbers of an item.
+func (h *WarehouseHandler) ListItemPartNumbers(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListPartNumbers(c.Request.Context(), itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddItemPartNumber adds an OEM or aftermarket number to an item (admin only).
+func (h *WarehouseHandler) AddItemPartNumber(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.AddPartNumberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pn, err := h.service.AddPartNumber(c.Request.Context(), itemID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pn)
+}
+
+// DeleteItemPartNumber removes a part number from an item (admin only).
+func (h *WarehouseHandler) DeleteItemPartNumber(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	partNumberID, err := uuid.Parse(c.Param("partNumberId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid part number id"})
+		return
+	}
+	deleted, err := h.service.DeletePartNumber(c.Request.Context(), itemID, partNumberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListItemLots returns the lots of an item with stock left, soonest expiring first
+// (?location_id, all=true to include used up lots).
+func (h *WarehouseHandler) ListItemLots(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	filter := warehouse.LotsFilter{IncludeEmpty: c.Query("all") == "true", Limit: limit, Offset: offset}
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		filter.LocationID = &id
+	}
+	list, err := h.service.ListLots(c.Request.Context(), userID, role, itemID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ExpiringLots reports lots with stock left that expire within ?days (default 30), expired
+// ones included (?location_id).
+func (h *WarehouseHandler) ExpiringLots(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	days, err := parseInt(c.DefaultQuery("days", "30"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+		return
+	}
+	limit, _ := parseInt(c.DefaultQuery("limit", "50"))
+	offset, _ := parseInt(c.DefaultQuery("offset", "0"))
+	filter := warehouse.LotsFilter{Limit: limit, Offset: offset}
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		filter.LocationID = &id
+	}
+	list, err := h.service.ExpiringLots(c.Request.Context(), userID, role, days, filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// TraceLot shows where a lot went for a recall: its movements at every location and the
+// vehicles it was fitted to (?item_id, lot_number; admin only).
+func (h *WarehouseHandler) TraceLot(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	itemID, err := uuid.Parse(c.Query("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item_id"})
+		return
+	}
+	trace, err := h.service.TraceLot(c.Request.Context(), userID, role, itemID, c.Query("lot_number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trace)
+}
+
+// ListItemFitments returns the vehicles and components an item fits.
+func (h *WarehouseHandler) ListItemFitments(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.ListFitments(c.Request.Context(), itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddItemFitment maps an item to a component on a platform, generation or model (admin only).
+func (h *WarehouseHandler) AddItemFitment(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req warehouse.AddFitmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f, err := h.service.AddFitment(c.Request.Context(), itemID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// DeleteItemFitment removes a fitment from an item (admin only).
+func (h *WarehouseHandler) DeleteItemFitment(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	fitmentID, err := uuid.Parse(c.Param("fitmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fitment id"})
+		return
+	}
+	deleted, err := h.service.DeleteFitment(c.Request.Context(), itemID, fitmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// VehicleParts returns the items in stock that fit a component of a vehicle, with their OEM
+// and aftermarket numbers (?component as a catalog component id or code, optional location_id).
+func (h *WarehouseHandler) VehicleParts(c *gin.Context) {
+	userID, role, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	vehicleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var locationID *uuid.UUID
+	if v := c.Query("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid location_id"})
+			return
+		}
+		locationID = &id
+	}
+	res, err := h.service.VehicleParts(c.Request.Context(), userID, role, vehicleID, c.Query("component"), locationID)
+	if err != nil {
+		stockError(c, err)
+		return
+	}
+	if res == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "vehicle not found"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
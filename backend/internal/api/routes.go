@@ -6,9 +6,12 @@ import (
 	"alem-auto/internal/auth"
 	"alem-auto/internal/booking"
 	"alem-auto/internal/catalog"
+	"alem-auto/internal/directory"
 	"alem-auto/internal/fines"
 	"alem-auto/internal/inspection"
 	"alem-auto/internal/media"
+	"alem-auto/internal/payments"
+	"alem-auto/internal/reviews"
 	"alem-auto/internal/servicebook"
 	"alem-auto/internal/vehicle"
 	"alem-auto/internal/warehouse"
@@ -23,9 +26,13 @@ func SetupRoutes(
 	inspectionService *inspection.Service,
 	mediaService *media.Service,
 	finesService *fines.Service,
+	finesSyncer *fines.Syncer,
 	bookingService *booking.Service,
 	warehouseService *warehouse.Service,
+	paymentsService *payments.Service,
 	servicebookService *servicebook.Service,
+	reviewsService *reviews.Service,
+	directoryService *directory.Service,
 	mockCarsPath string,
 	agentService *agent.ChatService,
 ) *gin.Engine {
@@ -74,6 +81,26 @@ func SetupRoutes(
 			catalogGroup.GET("/components", catalogHandler.GetComponents)
 		}
 
+		// Payment provider webhooks (public, verified by signature)
+		if paymentsService != nil {
+			paymentsHandler := handlers.NewPaymentsHandler(paymentsService)
+			v1.POST("/payments/webhooks/:provider", paymentsHandler.Webhook)
+		}
+
+		// Calendar feeds (public, the secret token in the URL is the credential)
+		if bookingService != nil {
+			calendarHandler := handlers.NewBookingHandler(bookingService)
+			v1.GET("/calendar/:token", calendarHandler.GetCalendarFeed)
+		}
+
+		// Service center directory (public)
+		var directoryHandler *handlers.DirectoryHandler
+		if directoryService != nil {
+			directoryHandler = handlers.NewDirectoryHandler(directoryService)
+			v1.GET("/service-centers", directoryHandler.SearchServiceCenters)
+			v1.GET("/service-centers/:id", directoryHandler.GetServiceCenter)
+		}
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(auth.AuthMiddleware(authService))
@@ -107,14 +134,23 @@ func SetupRoutes(
 
 			// Fines routes (only when DB available)
 			if finesService != nil {
-				finesHandler := handlers.NewFinesHandler(finesService)
+				finesHandler := handlers.NewFinesHandler(finesService, finesSyncer)
 				finesGroup := protected.Group("/fines")
 				{
 					finesGroup.POST("", finesHandler.CreateFine)
 					finesGroup.GET("", finesHandler.ListFines)
+					finesGroup.GET("/summary", finesHandler.GetSummary)
+					finesGroup.GET("/sync", finesHandler.GetSyncStatus)
+					finesGroup.POST("/sync", finesHandler.SyncFines)
+					finesGroup.GET("/disputes", auth.RequireRole("admin", "platform"), finesHandler.ListDisputes)
+					finesGroup.PATCH("/disputes/:dispute_id", auth.RequireRole("admin", "platform"), finesHandler.ReviewDispute)
 					finesGroup.GET("/:id", finesHandler.GetFine)
 					finesGroup.PUT("/:id", finesHandler.UpdateFine)
 					finesGroup.DELETE("/:id", finesHandler.DeleteFine)
+					finesGroup.POST("/:id/dispute", finesHandler.SubmitDispute)
+					finesGroup.GET("/:id/dispute", finesHandler.GetDispute)
+					finesGroup.POST("/:id/dispute/evidence", finesHandler.AddDisputeEvidence)
+					finesGroup.GET("/:id/dispute/letter", finesHandler.GetComplaintLetter)
 				}
 			}
 
@@ -128,7 +164,66 @@ func SetupRoutes(
 					bookingsGroup.GET("/:id", bookingHandler.GetBooking)
 					bookingsGroup.PATCH("/:id", bookingHandler.UpdateBooking)
 					bookingsGroup.DELETE("/:id", bookingHandler.DeleteBooking)
+					bookingsGroup.POST("/:id/cancel", bookingHandler.CancelBooking)
+					bookingsGroup.POST("/:id/transition", bookingHandler.TransitionBooking)
+					bookingsGroup.POST("/:id/reschedule", bookingHandler.RescheduleBooking)
+					bookingsGroup.POST("/:id/check-in", bookingHandler.CheckInBooking)
+					bookingsGroup.POST("/:id/complete", bookingHandler.CompleteBooking)
+					bookingsGroup.GET("/:id/history", bookingHandler.GetBookingHistory)
+					bookingsGroup.GET("/:id/ics", bookingHandler.DownloadBookingICS)
 				}
+
+				feedsGroup := protected.Group("/calendar-feeds")
+				{
+					feedsGroup.POST("", bookingHandler.CreateCalendarFeed)
+					feedsGroup.GET("", bookingHandler.ListCalendarFeeds)
+					feedsGroup.DELETE("/:id", bookingHandler.RevokeCalendarFeed)
+				}
+
+				waitlistGroup := protected.Group("/waitlist")
+				{
+					waitlistGroup.POST("", bookingHandler.JoinWaitlist)
+					waitlistGroup.GET("", bookingHandler.ListWaitlist)
+					waitlistGroup.DELETE("/:id", bookingHandler.LeaveWaitlist)
+					waitlistGroup.POST("/offers/:id/accept", bookingHandler.AcceptWaitlistOffer)
+					waitlistGroup.POST("/offers/:id/decline", bookingHandler.DeclineWaitlistOffer)
+				}
+
+				centersGroup := protected.Group("/service-centers")
+				{
+					centersGroup.GET("/:id/schedule", bookingHandler.GetSchedule)
+					centersGroup.PATCH("/:id/schedule", bookingHandler.UpdateSchedule)
+					centersGroup.POST("/:id/holidays", bookingHandler.AddHoliday)
+					centersGroup.DELETE("/:id/holidays/:date", bookingHandler.DeleteHoliday)
+					centersGroup.GET("/:id/services", bookingHandler.ListCenterServices)
+					centersGroup.POST("/:id/services", bookingHandler.CreateCenterService)
+					centersGroup.PATCH("/:id/services/:service_id", bookingHandler.UpdateCenterService)
+					centersGroup.GET("/:id/availability", bookingHandler.GetAvailability)
+					centersGroup.GET("/:id/bookings", bookingHandler.ListCenterBookings)
+					centersGroup.GET("/:id/agenda", bookingHandler.GetAgenda)
+				}
+			}
+
+			// Review routes (only when DB available)
+			if reviewsService != nil {
+				reviewsHandler := handlers.NewReviewsHandler(reviewsService)
+				reviewsGroup := protected.Group("/reviews")
+				{
+					reviewsGroup.POST("", reviewsHandler.CreateReview)
+					reviewsGroup.GET("/my", reviewsHandler.ListMyReviews)
+					reviewsGroup.GET("/flagged", auth.RequireRole("admin", "platform"), reviewsHandler.ListFlaggedReviews)
+					reviewsGroup.GET("/:id", reviewsHandler.GetReview)
+					reviewsGroup.PATCH("/:id", reviewsHandler.UpdateReview)
+					reviewsGroup.DELETE("/:id", reviewsHandler.DeleteReview)
+					reviewsGroup.POST("/:id/reply", reviewsHandler.ReplyReview)
+					reviewsGroup.POST("/:id/flag", reviewsHandler.FlagReview)
+					reviewsGroup.PATCH("/:id/moderation", auth.RequireRole("admin", "platform"), reviewsHandler.ModerateReview)
+				}
+				protected.GET("/service-centers/:id/reviews", reviewsHandler.ListCenterReviews)
+			}
+
+			if directoryHandler != nil {
+				protected.PATCH("/service-centers/:id", directoryHandler.UpdateServiceCenter)
 			}
 
 			// Warehouse routes (admin/mechanic only, only when DB available)
@@ -144,6 +239,66 @@ func SetupRoutes(
 					warehouseGroup.GET("/items/:id/stock", warehouseHandler.GetStock)
 					warehouseGroup.POST("/items/:id/stock", warehouseHandler.AdjustStock)
 					warehouseGroup.GET("/movements", warehouseHandler.ListMovements)
+					warehouseGroup.GET("/locations", warehouseHandler.ListLocations)
+					warehouseGroup.POST("/locations", auth.RequireRole("admin"), warehouseHandler.CreateLocation)
+					warehouseGroup.PATCH("/locations/:id", auth.RequireRole("admin"), warehouseHandler.UpdateLocation)
+					warehouseGroup.POST("/transfers", warehouseHandler.CreateTransfer)
+					warehouseGroup.GET("/low-stock", warehouseHandler.LowStock)
+					warehouseGroup.GET("/items/:id/suppliers", warehouseHandler.ListItemSuppliers)
+					warehouseGroup.GET("/items/:id/barcodes", warehouseHandler.ListItemBarcodes)
+					warehouseGroup.POST("/items/:id/barcodes", auth.RequireRole("admin"), warehouseHandler.AddItemBarcode)
+					warehouseGroup.DELETE("/items/:id/barcodes/:barcodeId", auth.RequireRole("admin"), warehouseHandler.DeleteItemBarcode)
+					warehouseGroup.GET("/items/:id/part-numbers", warehouseHandler.ListItemPartNumbers)
+					warehouseGroup.POST("/items/:id/part-numbers", auth.RequireRole("admin"), warehouseHandler.AddItemPartNumber)
+					warehouseGroup.DELETE("/items/:id/part-numbers/:partNumberId", auth.RequireRole("admin"), warehouseHandler.DeleteItemPartNumber)
+					warehouseGroup.GET("/items/:id/fitments", warehouseHandler.ListItemFitments)
+					warehouseGroup.POST("/items/:id/fitments", auth.RequireRole("admin"), warehouseHandler.AddItemFitment)
+					warehouseGroup.DELETE("/items/:id/fitments/:fitmentId", auth.RequireRole("admin"), warehouseHandler.DeleteItemFitment)
+					warehouseGroup.GET("/codes/:code", warehouseHandler.LookupCode)
+					warehouseGroup.GET("/vehicles/:id/parts", warehouseHandler.VehicleParts)
+					warehouseGroup.GET("/items/:id/lots", warehouseHandler.ListItemLots)
+					warehouseGroup.GET("/lots/expiring", warehouseHandler.ExpiringLots)
+					warehouseGroup.GET("/lots/trace", auth.RequireRole("admin"), warehouseHandler.TraceLot)
+					warehouseGroup.GET("/suppliers", warehouseHandler.ListSuppliers)
+					warehouseGroup.POST("/suppliers", auth.RequireRole("admin"), warehouseHandler.CreateSupplier)
+					warehouseGroup.GET("/suppliers/:id", warehouseHandler.GetSupplier)
+					warehouseGroup.PATCH("/suppliers/:id", auth.RequireRole("admin"), warehouseHandler.UpdateSupplier)
+					warehouseGroup.GET("/suppliers/:id/items", warehouseHandler.ListSupplierItems)
+					warehouseGroup.PUT("/suppliers/:id/items/:itemId", auth.RequireRole("admin"), warehouseHandler.SetSupplierItem)
+					warehouseGroup.DELETE("/suppliers/:id/items/:itemId", auth.RequireRole("admin"), warehouseHandler.DeleteSupplierItem)
+					warehouseGroup.GET("/purchase-orders", warehouseHandler.ListPurchaseOrders)
+					warehouseGroup.POST("/purchase-orders", auth.RequireRole("admin"), warehouseHandler.CreatePurchaseOrder)
+					warehouseGroup.GET("/purchase-orders/:id", warehouseHandler.GetPurchaseOrder)
+					warehouseGroup.POST("/purchase-orders/:id/send", auth.RequireRole("admin"), warehouseHandler.SendPurchaseOrder)
+					warehouseGroup.POST("/purchase-orders/:id/cancel", auth.RequireRole("admin"), warehouseHandler.CancelPurchaseOrder)
+					warehouseGroup.POST("/purchase-orders/:id/receive", warehouseHandler.ReceivePurchaseOrder)
+					warehouseGroup.GET("/valuation", auth.RequireRole("admin"), warehouseHandler.Valuation)
+					warehouseGroup.GET("/reports/margin", auth.RequireRole("admin"), warehouseHandler.Margins)
+					warehouseGroup.GET("/reservations", warehouseHandler.ListReservations)
+					warehouseGroup.POST("/reservations", warehouseHandler.CreateReservation)
+					warehouseGroup.POST("/reservations/:id/complete", warehouseHandler.CompleteReservation)
+					warehouseGroup.POST("/reservations/:id/release", warehouseHandler.ReleaseReservation)
+					warehouseGroup.POST("/import", auth.RequireRole("admin"), warehouseHandler.ImportItems)
+					warehouseGroup.GET("/export", auth.RequireRole("admin"), warehouseHandler.ExportItems)
+					warehouseGroup.GET("/stocktakes", warehouseHandler.ListStocktakes)
+					warehouseGroup.POST("/stocktakes", auth.RequireRole("admin"), warehouseHandler.StartStocktake)
+					warehouseGroup.GET("/stocktakes/:id", warehouseHandler.GetStocktake)
+					warehouseGroup.POST("/stocktakes/:id/counts", warehouseHandler.RecordStocktakeCount)
+					warehouseGroup.GET("/stocktakes/:id/counts", warehouseHandler.ListStocktakeCounts)
+					warehouseGroup.POST("/stocktakes/:id/post", auth.RequireRole("admin"), warehouseHandler.PostStocktake)
+					warehouseGroup.POST("/stocktakes/:id/cancel", auth.RequireRole("admin"), warehouseHandler.CancelStocktake)
+				}
+			}
+
+			// Payments routes (only when a provider is configured)
+			if paymentsService != nil {
+				paymentsHandler := handlers.NewPaymentsHandler(paymentsService)
+				paymentsGroup := protected.Group("/payments")
+				{
+					paymentsGroup.POST("/intents", paymentsHandler.CreateIntent)
+					paymentsGroup.GET("/intents", paymentsHandler.ListIntents)
+					paymentsGroup.GET("/intents/:id", paymentsHandler.GetIntent)
+					paymentsGroup.POST("/intents/:id/refund", auth.RequireRole("admin", "platform"), paymentsHandler.RefundIntent)
 				}
 			}
 
@@ -14,6 +14,7 @@ type User struct {
 	PasswordHash string    `json:"-"` // не возвращаем в JSON
 	Name         *string   `json:"name,omitempty"`
 	Role         string    `json:"role"` // owner, mechanic, admin, platform
+	IIN          *string   `json:"iin,omitempty"` // ИИН, нужен для загрузки штрафов из реестра
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -29,6 +30,7 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 	Name     string `json:"name" binding:"required"`
 	Role     string `json:"role"` // по умолчанию owner
+	IIN      string `json:"iin" binding:"omitempty,len=12,numeric"`
 }
 
 // TokenResponse представляет ответ с токеном
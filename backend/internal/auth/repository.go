@@ -19,11 +19,11 @@ func NewRepository(db *database.DB) *Repository {
 
 func (r *Repository) CreateUser(ctx context.Context, u *User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, name, role)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (id, email, password_hash, name, role, iin)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, u.ID, u.Email, u.PasswordHash, u.Name, u.Role)
+	_, err := r.db.ExecContext(ctx, query, u.ID, u.Email, u.PasswordHash, u.Name, u.Role, u.IIN)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -33,14 +33,14 @@ func (r *Repository) CreateUser(ctx context.Context, u *User) error {
 
 func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at
+		SELECT id, email, password_hash, name, role, iin, created_at
 		FROM users
 		WHERE id = $1
 	`
 
 	u := &User{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt,
+		&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.IIN, &u.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -54,14 +54,14 @@ func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*User, erro
 
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at
+		SELECT id, email, password_hash, name, role, iin, created_at
 		FROM users
 		WHERE email = $1
 	`
 
 	u := &User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt,
+		&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.IIN, &u.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -76,11 +76,11 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, e
 func (r *Repository) UpdateUser(ctx context.Context, u *User) error {
 	query := `
 		UPDATE users
-		SET email = $2, name = $3, role = $4
+		SET email = $2, name = $3, role = $4, iin = $5
 		WHERE id = $1
 	`
 
-	result, err := r.db.ExecContext(ctx, query, u.ID, u.Email, u.Name, u.Role)
+	result, err := r.db.ExecContext(ctx, query, u.ID, u.Email, u.Name, u.Role, u.IIN)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
@@ -65,6 +65,9 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*User, er
 		Name:         &req.Name,
 		Role:         role,
 	}
+	if req.IIN != "" {
+		user.IIN = &req.IIN
+	}
 
 	err = s.repo.CreateUser(ctx, user)
 	if err != nil {
@@ -0,0 +1,169 @@
+package booking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// feedHistory keeps recent past bookings in feeds so cancellations still reach calendars.
+	feedHistory   = 30 * 24 * time.Hour
+	feedMaxEvents = 500
+)
+
+// CreateCalendarFeed issues a new feed token for the user's bookings or, with a center,
+// for the bookings of a center the user works at. The previous feed of the same scope stops working.
+func (s *Service) CreateCalendarFeed(ctx context.Context, userID uuid.UUID, role string, req *CreateCalendarFeedRequest) (*CalendarFeed, error) {
+	if req.ServiceCenterID != nil {
+		if err := s.canViewCenter(ctx, userID, role, *req.ServiceCenterID); err != nil {
+			return nil, err
+		}
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	f := &CalendarFeed{
+		ID:              uuid.New(),
+		Token:           hex.EncodeToString(raw),
+		UserID:          userID,
+		ServiceCenterID: req.ServiceCenterID,
+	}
+	if err := s.repo.ReplaceCalendarFeed(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *Service) ListCalendarFeeds(ctx context.Context, userID uuid.UUID) ([]*CalendarFeed, error) {
+	return s.repo.ListCalendarFeeds(ctx, userID)
+}
+
+// RevokeCalendarFeed disables a feed of the user; false means it was not found.
+func (s *Service) RevokeCalendarFeed(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	return s.repo.RevokeCalendarFeed(ctx, id, userID)
+}
+
+// CalendarFeed renders the feed with the given token, or returns nil if the token is unknown,
+// revoked, or its owner no longer works at the center.
+func (s *Service) CalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	f, err := s.repo.GetCalendarFeedByToken(ctx, token)
+	if err != nil || f == nil {
+		return nil, err
+	}
+	since := time.Now().Add(-feedHistory)
+	if f.ServiceCenterID == nil {
+		entries, err := s.repo.ListCalendarEntries(ctx, "user_id", f.UserID, since, feedMaxEvents)
+		if err != nil {
+			return nil, err
+		}
+		return s.renderEntries(ctx, "Записи в сервис", entries, false)
+	}
+
+	role, err := s.repo.GetUserRole(ctx, f.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if s.canViewCenter(ctx, f.UserID, role, *f.ServiceCenterID) != nil {
+		return nil, nil
+	}
+	entries, err := s.repo.ListCalendarEntries(ctx, "service_center_id", *f.ServiceCenterID, since, feedMaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.repo.GetCenterName(ctx, *f.ServiceCenterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderEntries(ctx, "Записи: "+name, entries, true)
+}
+
+// BookingICS renders a single booking visible to the user as an .ics file, nil if not found.
+func (s *Service) BookingICS(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string) ([]byte, error) {
+	e, err := s.repo.GetCalendarEntry(ctx, id)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	actor, err := s.actorFor(ctx, e.Booking, userID, role)
+	if err != nil || actor == "" {
+		return nil, err
+	}
+	return s.renderEntries(ctx, "Запись в сервис", []*calendarEntry{e}, actor == ActorCenter)
+}
+
+// renderEntries builds the calendar; forCenter switches summaries from the owner's view
+// (which service center) to the staff's view (which car).
+func (s *Service) renderEntries(ctx context.Context, name string, entries []*calendarEntry, forCenter bool) ([]byte, error) {
+	bookings := make([]*Booking, len(entries))
+	for i, e := range entries {
+		bookings[i] = e.Booking
+	}
+	if err := s.withServices(ctx, bookings...); err != nil {
+		return nil, err
+	}
+	events := make([]calendarEvent, 0, len(entries))
+	for _, e := range entries {
+		events = append(events, e.event(forCenter))
+	}
+	return renderCalendar(name, events), nil
+}
+
+func (e *calendarEntry) event(forCenter bool) calendarEvent {
+	names := make([]string, len(e.Services))
+	for i, l := range e.Services {
+		names[i] = l.Name
+	}
+
+	var summary string
+	if forCenter {
+		car := "Автомобиль"
+		if e.LicensePlate != nil && *e.LicensePlate != "" {
+			car = *e.LicensePlate
+		}
+		summary = car
+		if len(names) > 0 {
+			summary += ": " + strings.Join(names, ", ")
+		}
+	} else {
+		summary = "Сервис: " + e.CenterName
+	}
+
+	var desc []string
+	if len(names) > 0 {
+		desc = append(desc, "Услуги: "+strings.Join(names, ", "))
+	}
+	if e.QuotedPriceMax > 0 {
+		if e.QuotedPriceMin == e.QuotedPriceMax {
+			desc = append(desc, fmt.Sprintf("Стоимость: %.0f", e.QuotedPriceMin))
+		} else {
+			desc = append(desc, fmt.Sprintf("Стоимость: %.0f–%.0f", e.QuotedPriceMin, e.QuotedPriceMax))
+		}
+	}
+	if e.Notes != nil && *e.Notes != "" {
+		desc = append(desc, "Комментарий: "+*e.Notes)
+	}
+
+	cs := &CenterSchedule{Timezone: e.CenterTimezone}
+	ev := calendarEvent{
+		UID:         e.ID.String() + "@alem-auto",
+		Sequence:    e.Sequence,
+		Stamp:       e.UpdatedAt,
+		Start:       e.ScheduledAt,
+		End:         e.EndsAt,
+		Location:    cs.location(),
+		Summary:     summary,
+		Description: strings.Join(desc, "\n"),
+		Place:       e.CenterName,
+		Status:      icalStatus(e.Status),
+	}
+	if e.CenterAddress != nil && *e.CenterAddress != "" {
+		ev.Place += ", " + *e.CenterAddress
+	}
+	return ev
+}
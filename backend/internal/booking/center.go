@@ -0,0 +1,369 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/vehicle"
+)
+
+// ErrSlotUnavailable is returned when all bays are taken for the requested time.
+var ErrSlotUnavailable = errors.New("selected time is no longer available")
+
+const maxAvailabilityDays = 31
+
+// GetSchedule returns booking settings, hours and upcoming holidays of a center.
+func (s *Service) GetSchedule(ctx context.Context, centerID uuid.UUID) (*CenterSchedule, error) {
+	return s.repo.GetCenterSchedule(ctx, centerID)
+}
+
+// canManageCenter reports whether the user may change a center's schedule and services:
+// platform users and admins of that center.
+func (s *Service) canManageCenter(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID) error {
+	if role == "platform" {
+		return nil
+	}
+	centerRole, err := s.repo.CenterRole(ctx, userID, centerID)
+	if err != nil {
+		return err
+	}
+	if centerRole != "admin" {
+		return fmt.Errorf("only service center admins can manage the schedule")
+	}
+	return nil
+}
+
+// UpdateSchedule changes booking settings and, if given, the weekly hours of a center.
+func (s *Service) UpdateSchedule(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, req *UpdateScheduleRequest) (*CenterSchedule, error) {
+	if err := s.canManageCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, centerID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %s", *req.Timezone)
+		}
+		cs.Timezone = *req.Timezone
+	}
+	if req.Bays != nil {
+		if *req.Bays <= 0 {
+			return nil, fmt.Errorf("bays must be positive")
+		}
+		cs.Bays = *req.Bays
+	}
+	if req.SlotMinutes != nil {
+		if *req.SlotMinutes < 5 || *req.SlotMinutes > 240 {
+			return nil, fmt.Errorf("slot_minutes must be between 5 and 240")
+		}
+		cs.SlotMinutes = *req.SlotMinutes
+	}
+	if req.DefaultDurationMinutes != nil {
+		if *req.DefaultDurationMinutes <= 0 {
+			return nil, fmt.Errorf("default_duration_minutes must be positive")
+		}
+		cs.DefaultDurationMinutes = *req.DefaultDurationMinutes
+	}
+	if req.Hours != nil {
+		seen := map[int]bool{}
+		for _, h := range req.Hours {
+			if seen[h.Weekday] {
+				return nil, fmt.Errorf("duplicate hours for weekday %d", h.Weekday)
+			}
+			seen[h.Weekday] = true
+			opens, err1 := time.Parse("15:04", h.OpensAt)
+			closes, err2 := time.Parse("15:04", h.ClosesAt)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid hours for weekday %d, use HH:MM", h.Weekday)
+			}
+			if !closes.After(opens) {
+				return nil, fmt.Errorf("closes_at must be after opens_at for weekday %d", h.Weekday)
+			}
+		}
+		cs.Hours = req.Hours
+	}
+	if err := s.repo.UpdateCenterSchedule(ctx, cs, req.Hours != nil); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCenterSchedule(ctx, centerID)
+}
+
+// AddHoliday closes a center for bookings on a date.
+func (s *Service) AddHoliday(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, req *AddHolidayRequest) (*Holiday, error) {
+	if err := s.canManageCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		return nil, fmt.Errorf("invalid date, use YYYY-MM-DD")
+	}
+	h := &Holiday{ID: uuid.New(), ServiceCenterID: centerID, Date: req.Date}
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		h.Reason = &reason
+	}
+	if err := s.repo.CreateHoliday(ctx, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *Service) DeleteHoliday(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, date string) error {
+	if err := s.canManageCenter(ctx, userID, role, centerID); err != nil {
+		return err
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("invalid date, use YYYY-MM-DD")
+	}
+	return s.repo.DeleteHoliday(ctx, centerID, date)
+}
+
+// ListCenterServices returns the price list of a center, optionally only the services
+// applicable to a vehicle.
+func (s *Service) ListCenterServices(ctx context.Context, centerID uuid.UUID, filter CenterServicesFilter) ([]*CenterService, error) {
+	services, err := s.repo.ListCenterServices(ctx, centerID, !filter.IncludeInactive, nil)
+	if err != nil {
+		return nil, err
+	}
+	var platformID *uuid.UUID
+	if filter.VehicleID != nil {
+		veh, err := s.vehicleService.GetVehicleByID(ctx, *filter.VehicleID)
+		if err != nil || veh == nil {
+			return nil, fmt.Errorf("vehicle not found")
+		}
+		platformID = veh.VehiclePlatformID
+	}
+	list := []*CenterService{}
+	for _, svc := range services {
+		if filter.Category != nil && (svc.Category == nil || !strings.EqualFold(*svc.Category, *filter.Category)) {
+			continue
+		}
+		if filter.ComponentCode != nil && !svc.hasComponent(*filter.ComponentCode) {
+			continue
+		}
+		if filter.VehicleID != nil && !svc.appliesTo(platformID) {
+			continue
+		}
+		list = append(list, svc)
+	}
+	return list, nil
+}
+
+func (s *Service) CreateCenterService(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, req *CreateCenterServiceRequest) (*CenterService, error) {
+	if err := s.canManageCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	cs := &CenterService{
+		ID:              uuid.New(),
+		ServiceCenterID: centerID,
+		Name:            strings.TrimSpace(req.Name),
+		DurationMinutes: req.DurationMinutes,
+		PriceMin:        req.PriceMin,
+		PriceMax:        req.PriceMin,
+		Currency:        strings.ToUpper(strings.TrimSpace(req.Currency)),
+		PlatformIDs:     req.PlatformIDs,
+		ComponentCodes:  req.ComponentCodes,
+		IsActive:        true,
+	}
+	if cs.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if v := strings.TrimSpace(req.Category); v != "" {
+		cs.Category = &v
+	}
+	if v := strings.TrimSpace(req.Description); v != "" {
+		cs.Description = &v
+	}
+	if req.PriceMax != nil {
+		cs.PriceMax = *req.PriceMax
+	}
+	if err := checkPriceRange(cs.PriceMin, cs.PriceMax); err != nil {
+		return nil, err
+	}
+	if cs.Currency == "" {
+		cs.Currency = "KZT"
+	}
+	if cs.PlatformIDs == nil {
+		cs.PlatformIDs = []uuid.UUID{}
+	}
+	if cs.ComponentCodes == nil {
+		cs.ComponentCodes = []string{}
+	}
+	if err := s.repo.CreateCenterService(ctx, cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (s *Service) UpdateCenterService(ctx context.Context, userID uuid.UUID, role string, centerID, serviceID uuid.UUID, req *UpdateCenterServiceRequest) (*CenterService, error) {
+	if err := s.canManageCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterServiceByID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil || cs.ServiceCenterID != centerID {
+		return nil, nil
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		cs.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Category != nil {
+		cs.Category = nil
+		if v := strings.TrimSpace(*req.Category); v != "" {
+			cs.Category = &v
+		}
+	}
+	if req.Description != nil {
+		cs.Description = nil
+		if v := strings.TrimSpace(*req.Description); v != "" {
+			cs.Description = &v
+		}
+	}
+	if req.DurationMinutes != nil {
+		if *req.DurationMinutes <= 0 {
+			return nil, fmt.Errorf("duration_minutes must be positive")
+		}
+		cs.DurationMinutes = *req.DurationMinutes
+	}
+	if req.PriceMin != nil {
+		cs.PriceMin = *req.PriceMin
+		if req.PriceMax == nil && cs.PriceMax < cs.PriceMin {
+			cs.PriceMax = cs.PriceMin
+		}
+	}
+	if req.PriceMax != nil {
+		cs.PriceMax = *req.PriceMax
+	}
+	if err := checkPriceRange(cs.PriceMin, cs.PriceMax); err != nil {
+		return nil, err
+	}
+	if req.Currency != nil {
+		if v := strings.ToUpper(strings.TrimSpace(*req.Currency)); v != "" {
+			cs.Currency = v
+		}
+	}
+	if req.PlatformIDs != nil {
+		cs.PlatformIDs = req.PlatformIDs
+	}
+	if req.ComponentCodes != nil {
+		cs.ComponentCodes = req.ComponentCodes
+	}
+	if req.IsActive != nil {
+		cs.IsActive = *req.IsActive
+	}
+	if err := s.repo.UpdateCenterService(ctx, cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// selectServices snapshots the selected active services of a center for a booking. If veh
+// is given, every service must apply to its platform.
+func (s *Service) selectServices(ctx context.Context, centerID, bookingID uuid.UUID, serviceIDs []uuid.UUID, veh *vehicle.Vehicle) ([]*BookingService, error) {
+	if len(serviceIDs) == 0 {
+		return []*BookingService{}, nil
+	}
+	services, err := s.repo.ListCenterServices(ctx, centerID, true, serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	if veh != nil {
+		for _, svc := range services {
+			if !svc.appliesTo(veh.VehiclePlatformID) {
+				return nil, fmt.Errorf("service %s is not available for this vehicle", svc.Name)
+			}
+		}
+	}
+	return newBookingServices(bookingID, services, serviceIDs)
+}
+
+// Availability returns free start times between the dates from and to (YYYY-MM-DD, inclusive,
+// in the center's timezone) for a booking of the selected services.
+func (s *Service) Availability(ctx context.Context, centerID uuid.UUID, from, to string, serviceIDs []uuid.UUID) ([]Slot, error) {
+	cs, err := s.repo.GetCenterSchedule(ctx, centerID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+	loc := cs.location()
+	fromDay, err := time.ParseInLocation("2006-01-02", from, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from, use YYYY-MM-DD")
+	}
+	toDay, err := time.ParseInLocation("2006-01-02", to, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to, use YYYY-MM-DD")
+	}
+	if toDay.Before(fromDay) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if toDay.Sub(fromDay) > maxAvailabilityDays*24*time.Hour {
+		return nil, fmt.Errorf("date range must not exceed %d days", maxAvailabilityDays)
+	}
+	lines, err := s.selectServices(ctx, centerID, uuid.Nil, serviceIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	duration := bookingDuration(cs, lines)
+	busy, err := s.repo.ListBusyIntervals(ctx, s.repo.db, centerID, fromDay, toDay.AddDate(0, 0, 1), nil)
+	if err != nil {
+		return nil, err
+	}
+	return cs.freeSlots(busy, fromDay, toDay, duration, time.Now()), nil
+}
+
+// reserve creates b after checking working hours and, under a center lock, free capacity,
+// so concurrent requests cannot overbook the same time.
+func (s *Service) reserve(ctx context.Context, cs *CenterSchedule, b *Booking) error {
+	if err := cs.checkWorkingTime(b.ScheduledAt, b.EndsAt); err != nil {
+		return err
+	}
+	return s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockCenter(ctx, tx, b.ServiceCenterID); err != nil {
+			return err
+		}
+		return s.reserveTx(ctx, tx, cs, b, nil)
+	})
+}
+
+// reserveTx creates b if the center has capacity left; the caller holds the center lock.
+// holdID is the waitlist offer whose held slot becomes the booking.
+func (s *Service) reserveTx(ctx context.Context, tx *sql.Tx, cs *CenterSchedule, b *Booking, holdID *uuid.UUID) error {
+	busy, err := s.repo.ListBusyIntervals(ctx, tx, b.ServiceCenterID, b.ScheduledAt, b.EndsAt, holdID)
+	if err != nil {
+		return err
+	}
+	if maxConcurrent(busy, b.ScheduledAt, b.EndsAt) >= cs.capacity() {
+		return ErrSlotUnavailable
+	}
+	if err := s.repo.Create(ctx, tx, b); err != nil {
+		return err
+	}
+	if err := s.repo.CreateServices(ctx, tx, b.Services); err != nil {
+		return err
+	}
+	scheduledAt := b.ScheduledAt
+	return s.repo.CreateEvent(ctx, tx, &BookingEvent{
+		ID:             uuid.New(),
+		BookingID:      b.ID,
+		ToStatus:       b.Status,
+		NewScheduledAt: &scheduledAt,
+		ActorUserID:    &b.UserID,
+		ActorRole:      ActorOwner,
+	})
+}
@@ -0,0 +1,173 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/inspection"
+)
+
+// canViewCenter reports whether the user may see bookings of a center:
+// platform users and any staff member of that center.
+func (s *Service) canViewCenter(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID) error {
+	if role == "platform" {
+		return nil
+	}
+	centerRole, err := s.repo.CenterRole(ctx, userID, centerID)
+	if err != nil {
+		return err
+	}
+	if centerRole == "" {
+		return fmt.Errorf("only service center staff can view its bookings")
+	}
+	return nil
+}
+
+// ListForCenter returns bookings at a service center for its staff.
+func (s *Service) ListForCenter(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, filter ListBookingsFilter) ([]*Booking, error) {
+	if err := s.canViewCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	list, err := s.repo.ListByCenter(ctx, centerID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withServices(ctx, list...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Agenda returns the bookings of a center for a local date (YYYY-MM-DD), today if empty.
+func (s *Service) Agenda(ctx context.Context, userID uuid.UUID, role string, centerID uuid.UUID, date string) (*Agenda, error) {
+	if err := s.canViewCenter(ctx, userID, role, centerID); err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, centerID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+	loc := cs.location()
+	var day time.Time
+	if date == "" {
+		now := time.Now().In(loc)
+		day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	} else if day, err = time.ParseInLocation("2006-01-02", date, loc); err != nil {
+		return nil, fmt.Errorf("invalid date, use YYYY-MM-DD")
+	}
+	items, err := s.repo.ListAgenda(ctx, centerID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	bookings := make([]*Booking, len(items))
+	for i, item := range items {
+		bookings[i] = item.Booking
+	}
+	if err := s.withServices(ctx, bookings...); err != nil {
+		return nil, err
+	}
+	return &Agenda{
+		ServiceCenterID: centerID,
+		Date:            day.Format("2006-01-02"),
+		Timezone:        cs.Timezone,
+		Capacity:        cs.capacity(),
+		Items:           items,
+	}, nil
+}
+
+// CheckIn receives the car of a booking: it opens an inspection for the vehicle at the center,
+// assigned to the mechanic and with the current odometer, and moves the booking to checked_in.
+// A nil result means the booking was not found for this user.
+func (s *Service) CheckIn(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string, req *CheckInRequest) (*CheckInResult, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	actor, err := s.actorFor(ctx, b, userID, role)
+	if err != nil || actor == "" {
+		return nil, err
+	}
+	if actor != ActorCenter {
+		return nil, fmt.Errorf("only the service center can check in a booking")
+	}
+	if !canTransition(actor, b.Status, StatusCheckedIn) {
+		return nil, fmt.Errorf("booking cannot be checked in from status %s", b.Status)
+	}
+
+	mechanicID := userID
+	if req.MechanicUserID != nil && *req.MechanicUserID != userID {
+		centerRole, err := s.repo.CenterRole(ctx, *req.MechanicUserID, b.ServiceCenterID)
+		if err != nil {
+			return nil, err
+		}
+		if centerRole == "" {
+			return nil, fmt.Errorf("mechanic does not work at this service center")
+		}
+		mechanicID = *req.MechanicUserID
+	}
+
+	insp := &inspection.Inspection{
+		ID:              uuid.New(),
+		VehicleID:       b.VehicleID,
+		ServiceCenterID: b.ServiceCenterID,
+		CreatedByUserID: mechanicID,
+		OdometerKm:      req.OdometerKm,
+		Notes:           b.Notes,
+	}
+	if insp.OdometerKm == nil {
+		veh, err := s.vehicleService.GetVehicleByID(ctx, b.VehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if veh != nil && veh.OdometerKm > 0 {
+			insp.OdometerKm = &veh.OdometerKm
+		}
+	}
+	if notes := strings.TrimSpace(req.Notes); notes != "" {
+		insp.Notes = &notes
+	}
+	if err := s.inspectionService.CreateInspection(ctx, insp); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		b, err = s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("booking not found")
+		}
+		if b.InspectionID != nil {
+			return fmt.Errorf("booking is already checked in")
+		}
+		b.MechanicUserID = &mechanicID
+		b.InspectionID = &insp.ID
+		return s.applyTransition(ctx, tx, b, ActorCenter, &userID, StatusCheckedIn, "")
+	})
+	if err != nil {
+		// The inspection was created outside the transaction; don't leave it orphaned.
+		_ = s.inspectionService.DeleteInspection(ctx, insp.ID)
+		return nil, err
+	}
+
+	created, err := s.inspectionService.GetInspectionByID(ctx, insp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withServices(ctx, b); err != nil {
+		return nil, err
+	}
+	return &CheckInResult{Booking: b, Inspection: created}, nil
+}
@@ -0,0 +1,121 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ServiceRecorder keeps a service book entry for each completed booking.
+// BookingCompleted must be idempotent: it is repeated on retries.
+type ServiceRecorder interface {
+	// BookingCompleted creates or updates the entry of a completed booking and returns its ID.
+	BookingCompleted(ctx context.Context, b *Booking, centerName string) (uuid.UUID, error)
+}
+
+// SetServiceRecorder enables service book entries for completed bookings.
+func (s *Service) SetServiceRecorder(r ServiceRecorder) {
+	s.records = r
+}
+
+// withServices loads the selected services of bookings and fills their totals.
+func (s *Service) withServices(ctx context.Context, bookings ...*Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(bookings))
+	for i, b := range bookings {
+		ids[i] = b.ID
+	}
+	lines, err := s.repo.ListServices(ctx, s.repo.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookings {
+		b.Services = lines[b.ID]
+		b.setTotals()
+	}
+	return nil
+}
+
+// Complete finishes the work on a booking with the prices actually charged and records
+// the visit in the service book of the vehicle. A nil booking means it was not found for this user.
+func (s *Service) Complete(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string, req *CompleteRequest) (*Booking, error) {
+	var b *Booking
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		b, err = s.repo.LockByID(ctx, tx, id)
+		if err != nil || b == nil {
+			return err
+		}
+		actor, err := s.actorFor(ctx, b, userID, role)
+		if err != nil {
+			return err
+		}
+		if actor == "" {
+			b = nil
+			return nil
+		}
+		return s.completeTx(ctx, tx, b, actor, userID, req.Prices, req.Reason)
+	})
+	if err != nil || b == nil {
+		return nil, err
+	}
+	s.settleParts(ctx, b)
+	if err := s.syncServiceRecord(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// completeTx sets the actual prices of b's services and moves it to completed.
+func (s *Service) completeTx(ctx context.Context, tx *sql.Tx, b *Booking, actor string, userID uuid.UUID, prices []ActualPrice, reason string) error {
+	if actor != ActorCenter {
+		return fmt.Errorf("only the service center can complete a booking")
+	}
+	lines, err := s.repo.ListServices(ctx, tx, []uuid.UUID{b.ID})
+	if err != nil {
+		return err
+	}
+	b.Services = lines[b.ID]
+	if err := applyActualPrices(b.Services, prices); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateActualPrices(ctx, tx, b.Services); err != nil {
+		return err
+	}
+	if err := s.applyTransition(ctx, tx, b, actor, &userID, StatusCompleted, reason); err != nil {
+		return err
+	}
+	b.setTotals()
+	return nil
+}
+
+// syncServiceRecord creates or updates the service book entry of a completed booking.
+func (s *Service) syncServiceRecord(ctx context.Context, b *Booking) error {
+	if s.records == nil || b.Status != StatusCompleted {
+		return nil
+	}
+	if b.Services == nil {
+		if err := s.withServices(ctx, b); err != nil {
+			return err
+		}
+	}
+	centerName, err := s.repo.GetCenterName(ctx, b.ServiceCenterID)
+	if err != nil {
+		return err
+	}
+	recordID, err := s.records.BookingCompleted(ctx, b, centerName)
+	if err != nil {
+		return fmt.Errorf("failed to record service: %w", err)
+	}
+	if b.ServiceRecordID == nil || *b.ServiceRecordID != recordID {
+		if err := s.repo.SetServiceRecordID(ctx, b.ID, &recordID); err != nil {
+			return err
+		}
+		b.ServiceRecordID = &recordID
+	}
+	return nil
+}
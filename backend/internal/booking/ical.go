@@ -0,0 +1,137 @@
+package booking
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// calendarEvent is a booking as a VEVENT.
+type calendarEvent struct {
+	UID         string
+	Sequence    int
+	Stamp       time.Time
+	Start, End  time.Time
+	Location    *time.Location
+	Summary     string
+	Description string
+	Place       string
+	Status      string // TENTATIVE, CONFIRMED, CANCELLED
+}
+
+// icalStatus maps a booking status to the VEVENT STATUS.
+func icalStatus(status string) string {
+	switch status {
+	case StatusScheduled:
+		return "TENTATIVE"
+	case StatusCancelled, StatusNoShow:
+		return "CANCELLED"
+	default:
+		return "CONFIRMED"
+	}
+}
+
+// renderCalendar writes an iCalendar (RFC 5545) document. Times are local to each event's
+// timezone, described by a VTIMEZONE, unless the zone observes daylight saving: such
+// zones are written in UTC, as a fixed-offset VTIMEZONE would be wrong half of the year.
+func renderCalendar(name string, events []calendarEvent) []byte {
+	var buf bytes.Buffer
+	line := func(s string) { writeFolded(&buf, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Alem Auto//Bookings//RU")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+
+	zones := map[string]bool{}
+	for _, e := range events {
+		loc := e.Location
+		if loc == nil || zones[loc.String()] {
+			continue
+		}
+		if offset, ok := fixedOffset(loc, e.Start); ok {
+			zones[loc.String()] = true
+			abbr, _ := e.Start.In(loc).Zone()
+			line("BEGIN:VTIMEZONE")
+			line("TZID:" + loc.String())
+			line("BEGIN:STANDARD")
+			line("DTSTART:19700101T000000")
+			line("TZOFFSETFROM:" + formatOffset(offset))
+			line("TZOFFSETTO:" + formatOffset(offset))
+			line("TZNAME:" + abbr)
+			line("END:STANDARD")
+			line("END:VTIMEZONE")
+		}
+	}
+
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+		if e.Location != nil && zones[e.Location.String()] {
+			tzid := e.Location.String()
+			line("DTSTART;TZID=" + tzid + ":" + e.Start.In(e.Location).Format("20060102T150405"))
+			line("DTEND;TZID=" + tzid + ":" + e.End.In(e.Location).Format("20060102T150405"))
+		} else {
+			line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Place != "" {
+			line("LOCATION:" + escapeText(e.Place))
+		}
+		line("STATUS:" + e.Status)
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// fixedOffset returns the UTC offset of loc if it is the same in winter and summer of the year of t.
+func fixedOffset(loc *time.Location, t time.Time) (int, bool) {
+	_, winter := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, summer := time.Date(t.Year(), time.July, 1, 0, 0, 0, 0, loc).Zone()
+	return winter, winter == summer
+}
+
+// formatOffset formats seconds east of UTC as +HHMM.
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// writeFolded writes a content line, folding it at 75 octets without splitting characters.
+func writeFolded(buf *bytes.Buffer, s string) {
+	const limit = 75
+	width := limit
+	for len(s) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		buf.WriteString(s[:cut])
+		buf.WriteString("\r\n ")
+		s = s[cut:]
+		width = limit - 1 // the leading space counts
+	}
+	buf.WriteString(s)
+	buf.WriteString("\r\n")
+}
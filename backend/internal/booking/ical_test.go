@@ -0,0 +1,73 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRenderCalendar(t *testing.T) {
+	almaty, err := time.LoadLocation("Asia/Almaty")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, almaty)
+	out := string(renderCalendar("Записи", []calendarEvent{{
+		UID:         "b1@alem-auto",
+		Sequence:    2,
+		Stamp:       start.Add(-time.Hour),
+		Start:       start,
+		End:         start.Add(90 * time.Minute),
+		Location:    almaty,
+		Summary:     "Сервис: Alem; Центр, №1",
+		Description: "Услуги: ТО\nКомментарий: стук",
+		Status:      icalStatus(StatusCancelled),
+	}}))
+
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Asia/Almaty\r\n",
+		"TZOFFSETTO:+0500\r\n",
+		"DTSTART;TZID=Asia/Almaty:20260310T100000\r\n",
+		"DTEND;TZID=Asia/Almaty:20260310T113000\r\n",
+		"SEQUENCE:2\r\n",
+		`SUMMARY:Сервис: Alem\; Центр\, №1` + "\r\n",
+		`DESCRIPTION:Услуги: ТО\nКомментарий: стук` + "\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderCalendarDaylightSavingInUTC(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	start := time.Date(2026, 7, 1, 10, 0, 0, 0, berlin)
+	out := string(renderCalendar("x", []calendarEvent{{UID: "b2", Start: start, End: start.Add(time.Hour), Location: berlin}}))
+	if strings.Contains(out, "VTIMEZONE") {
+		t.Error("zones with daylight saving should not get a fixed-offset VTIMEZONE")
+	}
+	if !strings.Contains(out, "DTSTART:20260701T080000Z\r\n") {
+		t.Errorf("expected UTC start:\n%s", out)
+	}
+}
+
+func TestWriteFoldedKeepsRunes(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("ж", 60)
+	out := string(renderCalendar(long, nil))
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("broken character in %q", line)
+		}
+	}
+	if unfolded := strings.ReplaceAll(out, "\r\n ", ""); !strings.Contains(unfolded, strings.Repeat("ж", 60)) {
+		t.Error("folded text does not unfold to the original")
+	}
+}
@@ -0,0 +1,178 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/notify"
+)
+
+// noShowBatch limits the bookings marked as no-show in one run.
+const noShowBatch = 200
+
+// SetNotifier enables reminders and no-show notices to owners.
+func (s *Service) SetNotifier(ch notify.Channel) {
+	s.notifier = ch
+}
+
+// dueReminder picks the reminder to send now for a booking starting at start: the shortest lead
+// whose moment has come. A lead whose moment passed before the booking was made is skipped,
+// so a booking made for this afternoon gets no "tomorrow" reminder.
+func dueReminder(leads []time.Duration, now, start, created time.Time) (time.Duration, bool) {
+	sorted := append([]time.Duration(nil), leads...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, lead := range sorted {
+		at := start.Add(-lead)
+		if now.Before(at) {
+			continue
+		}
+		return lead, !created.After(at)
+	}
+	return 0, false
+}
+
+// reminderMessage is the text of a reminder, with the time in the center's timezone.
+func reminderMessage(e *calendarEntry) *notify.Message {
+	cs := &CenterSchedule{Timezone: e.CenterTimezone}
+	start := e.ScheduledAt.In(cs.location())
+	body := fmt.Sprintf("Вы записаны в «%s» на %s в %s", e.CenterName, start.Format("02.01"), start.Format("15:04"))
+	if e.CenterAddress != nil && *e.CenterAddress != "" {
+		body += ", " + *e.CenterAddress
+	}
+	if e.LicensePlate != nil && *e.LicensePlate != "" {
+		body += ". Автомобиль " + *e.LicensePlate
+	}
+	return &notify.Message{
+		UserID: e.UserID,
+		Kind:   notify.KindBookingReminder,
+		Title:  "Напоминание о записи",
+		Body:   body + ".",
+		Data:   map[string]string{"booking_id": e.ID.String()},
+	}
+}
+
+// SendReminders notifies owners of upcoming bookings at the configured leads and returns
+// how many reminders were sent. A reminder is recorded before sending, so it is sent at most
+// once per booking time, and forgotten again if delivery fails.
+func (s *Service) SendReminders(ctx context.Context) (int, error) {
+	if s.notifier == nil || len(s.cfg.ReminderLeads) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	var maxLead time.Duration
+	for _, lead := range s.cfg.ReminderLeads {
+		if lead > maxLead {
+			maxLead = lead
+		}
+	}
+	entries, err := s.repo.ListReminderCandidates(ctx, now, now.Add(maxLead))
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	ids := make([]uuid.UUID, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	sent, err := s.repo.ListSentReminders(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		lead, ok := dueReminder(s.cfg.ReminderLeads, now, e.ScheduledAt, e.CreatedAt)
+		if !ok || sent[e.ID][lead] {
+			continue
+		}
+		claimed, err := s.repo.ClaimReminder(ctx, e.ID, lead, e.ScheduledAt, s.notifier.Name())
+		if err != nil {
+			return n, err
+		}
+		if !claimed {
+			continue
+		}
+		m := reminderMessage(e)
+		m.SentAt = time.Now()
+		if err := s.notifier.Send(ctx, m); err != nil {
+			log.Printf("booking: reminder for %s failed: %v", e.ID, err)
+			if err := s.repo.ReleaseReminder(ctx, e.ID, lead, e.ScheduledAt); err != nil {
+				return n, err
+			}
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// MarkNoShows moves bookings whose car was not checked in within the grace period after
+// their start to no_show on behalf of the system, and returns how many were marked.
+func (s *Service) MarkNoShows(ctx context.Context) (int, error) {
+	if s.cfg.NoShowGrace <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-s.cfg.NoShowGrace)
+	ids, err := s.repo.ListOverdueIDs(ctx, cutoff, noShowBatch)
+	if err != nil {
+		return 0, err
+	}
+	reason := fmt.Sprintf("not checked in within %s of the start", s.cfg.NoShowGrace)
+
+	n := 0
+	for _, id := range ids {
+		var marked *Booking
+		err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+			b, err := s.repo.LockByID(ctx, tx, id)
+			if err != nil || b == nil {
+				return err
+			}
+			// The center may have checked the car in or rescheduled since the list was read.
+			if !canTransition(ActorSystem, b.Status, StatusNoShow) || b.ScheduledAt.After(cutoff) {
+				return nil
+			}
+			if err := s.applyTransition(ctx, tx, b, ActorSystem, nil, StatusNoShow, reason); err != nil {
+				return err
+			}
+			marked = b
+			return nil
+		})
+		if err != nil {
+			return n, fmt.Errorf("booking %s: %w", id, err)
+		}
+		if marked == nil {
+			continue
+		}
+		n++
+		s.settleParts(ctx, marked)
+		s.notifyNoShow(ctx, marked)
+	}
+	return n, nil
+}
+
+// notifyNoShow tells the owner the booking was closed; delivery failures are only logged.
+func (s *Service) notifyNoShow(ctx context.Context, b *Booking) {
+	if s.notifier == nil {
+		return
+	}
+	name, err := s.repo.GetCenterName(ctx, b.ServiceCenterID)
+	if err != nil {
+		log.Printf("booking: no-show notice for %s: %v", b.ID, err)
+		return
+	}
+	m := &notify.Message{
+		UserID: b.UserID,
+		Kind:   notify.KindBookingNoShow,
+		Title:  "Запись закрыта",
+		Body:   fmt.Sprintf("Запись в «%s» отмечена как неявка. Чтобы приехать в другое время, создайте новую запись.", name),
+		Data:   map[string]string{"booking_id": b.ID.String()},
+		SentAt: time.Now(),
+	}
+	if err := s.notifier.Send(ctx, m); err != nil {
+		log.Printf("booking: no-show notice for %s failed: %v", b.ID, err)
+	}
+}
@@ -0,0 +1,56 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDueReminder(t *testing.T) {
+	leads := []time.Duration{24 * time.Hour, 2 * time.Hour}
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+	longAgo := start.Add(-7 * 24 * time.Hour)
+
+	cases := []struct {
+		name    string
+		now     time.Time
+		created time.Time
+		lead    time.Duration
+		ok      bool
+	}{
+		{"too early", start.Add(-30 * time.Hour), longAgo, 0, false},
+		{"day before", start.Add(-20 * time.Hour), longAgo, 24 * time.Hour, true},
+		{"two hours before", start.Add(-90 * time.Minute), longAgo, 2 * time.Hour, true},
+		{"booked this morning, day reminder skipped", start.Add(-5 * time.Hour), start.Add(-6 * time.Hour), 24 * time.Hour, false},
+		{"booked this morning, short reminder sent", start.Add(-time.Hour), start.Add(-6 * time.Hour), 2 * time.Hour, true},
+		{"booked an hour before", start.Add(-30 * time.Minute), start.Add(-time.Hour), 2 * time.Hour, false},
+	}
+	for _, c := range cases {
+		lead, ok := dueReminder(leads, c.now, start, c.created)
+		if ok != c.ok || (ok && lead != c.lead) {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", c.name, lead, ok, c.lead, c.ok)
+		}
+	}
+}
+
+func TestReminderMessageUsesCenterTime(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Almaty"); err != nil {
+		t.Skip("tzdata not available")
+	}
+	address := "ул. Абая, 1"
+	e := &calendarEntry{
+		Booking:        &Booking{ID: uuid.New(), UserID: uuid.New(), ScheduledAt: time.Date(2026, 3, 10, 5, 0, 0, 0, time.UTC)},
+		CenterName:     "Alem Service",
+		CenterAddress:  &address,
+		CenterTimezone: "Asia/Almaty",
+	}
+	m := reminderMessage(e)
+	if !strings.Contains(m.Body, "10.03 в 10:00") || !strings.Contains(m.Body, address) {
+		t.Errorf("unexpected body: %s", m.Body)
+	}
+	if m.UserID != e.UserID || m.Data["booking_id"] != e.ID.String() {
+		t.Errorf("message not addressed to the booking owner: %+v", m)
+	}
+}
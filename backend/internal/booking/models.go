@@ -4,15 +4,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"alem-auto/internal/inspection"
 )
 
 const (
-	StatusScheduled = "scheduled"
+	StatusScheduled = "scheduled" // requested by the owner, awaiting confirmation
+	StatusConfirmed = "confirmed"
+	StatusCheckedIn = "checked_in" // the car is at the service center
 	StatusCompleted = "completed"
 	StatusCancelled = "cancelled"
 	StatusNoShow    = "no_show"
 )
 
+// Who changed a booking, as recorded in its history.
+const (
+	ActorOwner  = "owner"
+	ActorCenter = "center"
+	ActorSystem = "system"
+)
+
 // Booking represents an appointment at a service center.
 type Booking struct {
 	ID               uuid.UUID  `json:"id"`
@@ -20,33 +30,315 @@ type Booking struct {
 	VehicleID        uuid.UUID  `json:"vehicle_id"`
 	UserID           uuid.UUID  `json:"user_id"`
 	ScheduledAt      time.Time  `json:"scheduled_at"`
+	EndsAt           time.Time  `json:"ends_at"`
 	Status           string     `json:"status"`
 	Notes            *string    `json:"notes,omitempty"`
+	MechanicUserID   *uuid.UUID `json:"mechanic_user_id,omitempty"` // set at check-in
+	InspectionID     *uuid.UUID `json:"inspection_id,omitempty"`    // inspection opened at check-in
+	ServiceRecordID  *uuid.UUID `json:"service_record_id,omitempty"` // service book entry of a completed booking
+	PaidAmount       *float64   `json:"paid_amount,omitempty"`       // prepaid through payments
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Selected services and their totals, filled by the service layer.
+	Services        []*BookingService `json:"services"`
+	DurationMinutes int               `json:"duration_minutes"`
+	QuotedPriceMin  float64           `json:"quoted_price_min"`
+	QuotedPriceMax  float64           `json:"quoted_price_max"`
+	ActualPrice     *float64          `json:"actual_price,omitempty"` // set once every service has an actual price
+}
+
+// BookingService is a service selected for a booking. The name, duration and quoted
+// price are copied from the price list so later price changes don't affect the booking.
+type BookingService struct {
+	ID              uuid.UUID  `json:"id"`
+	BookingID       uuid.UUID  `json:"booking_id"`
+	CenterServiceID *uuid.UUID `json:"center_service_id,omitempty"`
+	Name            string     `json:"name"`
+	Category        *string    `json:"category,omitempty"`
+	DurationMinutes int        `json:"duration_minutes"`
+	QuotedPriceMin  float64    `json:"quoted_price_min"`
+	QuotedPriceMax  float64    `json:"quoted_price_max"`
+	ActualPrice     *float64   `json:"actual_price,omitempty"`
+	Currency        string     `json:"currency"`
 }
 
 // CreateBookingRequest is the request body for creating a booking.
 type CreateBookingRequest struct {
 	ServiceCenterID uuid.UUID `json:"service_center_id" binding:"required"`
 	VehicleID       uuid.UUID `json:"vehicle_id" binding:"required"`
-	ScheduledAt     string    `json:"scheduled_at" binding:"required"` // ISO datetime
-	Notes           string    `json:"notes"`
+	ScheduledAt     string      `json:"scheduled_at" binding:"required"` // ISO datetime
+	ServiceIDs      []uuid.UUID `json:"service_ids,omitempty"`           // services of the center; their durations add up
+	Notes           string      `json:"notes"`
 }
 
 // UpdateBookingRequest is the request body for updating a booking (e.g. status, notes).
+// A status change goes through the same rules as TransitionRequest.
 type UpdateBookingRequest struct {
-	Status *string `json:"status,omitempty"` // scheduled, completed, cancelled, no_show
+	Status *string `json:"status,omitempty"` // confirmed, checked_in, completed, cancelled, no_show
 	Notes  *string `json:"notes,omitempty"`
 }
 
+// TransitionRequest is the request body for changing a booking status.
+type TransitionRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// RescheduleRequest is the request body for moving a booking to another time.
+type RescheduleRequest struct {
+	ScheduledAt string `json:"scheduled_at" binding:"required"` // ISO datetime
+	Reason      string `json:"reason"`
+}
+
+// BookingEvent is an entry of the booking history: a status change or a reschedule.
+type BookingEvent struct {
+	ID             uuid.UUID  `json:"id"`
+	BookingID      uuid.UUID  `json:"booking_id"`
+	FromStatus     *string    `json:"from_status,omitempty"`
+	ToStatus       string     `json:"to_status"`
+	OldScheduledAt *time.Time `json:"old_scheduled_at,omitempty"`
+	NewScheduledAt *time.Time `json:"new_scheduled_at,omitempty"`
+	ActorUserID    *uuid.UUID `json:"actor_user_id,omitempty"`
+	ActorRole      string     `json:"actor_role"` // owner, center, system
+	Reason         *string    `json:"reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
 // ListBookingsFilter holds query filters for listing bookings.
 type ListBookingsFilter struct {
 	ServiceCenterID *uuid.UUID
 	VehicleID       *uuid.UUID
+	MechanicUserID  *uuid.UUID
 	Status          *string
 	From            *time.Time
 	To              *time.Time
 	Limit           int
 	Offset          int
 }
+
+// CompleteRequest is the request body for finishing the work on a booking.
+// Services with a fixed quoted price default to it; others need an actual price.
+type CompleteRequest struct {
+	Prices []ActualPrice `json:"prices,omitempty" binding:"omitempty,dive"`
+	Reason string        `json:"reason"`
+}
+
+// ActualPrice is the price charged for a service of a booking.
+type ActualPrice struct {
+	BookingServiceID uuid.UUID `json:"booking_service_id" binding:"required"`
+	Price            float64   `json:"price" binding:"min=0"`
+}
+
+// CheckInRequest is the request body for receiving a car at the service center.
+type CheckInRequest struct {
+	OdometerKm     *int       `json:"odometer_km,omitempty" binding:"omitempty,min=0"`
+	MechanicUserID *uuid.UUID `json:"mechanic_user_id,omitempty"` // defaults to the user checking in
+	Notes          string     `json:"notes"`
+}
+
+// CheckInResult is a checked-in booking with the inspection opened for the visit.
+type CheckInResult struct {
+	Booking    *Booking               `json:"booking"`
+	Inspection *inspection.Inspection `json:"inspection"`
+}
+
+// AgendaItem is a booking in the daily agenda of a center with details for the front desk.
+type AgendaItem struct {
+	*Booking
+	LicensePlate *string `json:"license_plate,omitempty"`
+	VIN          *string `json:"vin,omitempty"`
+	OwnerName    *string `json:"owner_name,omitempty"`
+	OwnerEmail   string  `json:"owner_email"`
+	MechanicName *string `json:"mechanic_name,omitempty"`
+}
+
+// Agenda is the list of bookings of a center for one local day.
+type Agenda struct {
+	ServiceCenterID uuid.UUID     `json:"service_center_id"`
+	Date            string        `json:"date"` // YYYY-MM-DD
+	Timezone        string        `json:"timezone"`
+	Capacity        int           `json:"capacity"`
+	Items           []*AgendaItem `json:"items"`
+}
+
+// CalendarFeed is a secret-token iCalendar feed of the bookings of a user or, if
+// ServiceCenterID is set, of a service center the user works at.
+type CalendarFeed struct {
+	ID              uuid.UUID  `json:"id"`
+	Token           string     `json:"token"`
+	UserID          uuid.UUID  `json:"user_id"`
+	ServiceCenterID *uuid.UUID `json:"service_center_id,omitempty"`
+	LastAccessedAt  *time.Time `json:"last_accessed_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// CreateCalendarFeedRequest is the request body for creating a feed; it replaces the
+// previous feed of the same scope.
+type CreateCalendarFeedRequest struct {
+	ServiceCenterID *uuid.UUID `json:"service_center_id,omitempty"`
+}
+
+// CenterSchedule holds the booking settings of a service center.
+type CenterSchedule struct {
+	ServiceCenterID        uuid.UUID      `json:"service_center_id"`
+	Timezone               string         `json:"timezone"`
+	Bays                   int            `json:"bays"`
+	Mechanics              int            `json:"mechanics"` // mechanics registered at the center, 0 if none
+	SlotMinutes            int            `json:"slot_minutes"`
+	DefaultDurationMinutes int            `json:"default_duration_minutes"`
+	Hours                  []WorkingHours `json:"hours"`
+	Holidays               []*Holiday     `json:"holidays"`
+}
+
+// WorkingHours is the opening time of a center on one weekday (0 = Sunday).
+type WorkingHours struct {
+	Weekday  int    `json:"weekday" binding:"min=0,max=6"`
+	OpensAt  string `json:"opens_at" binding:"required"`  // HH:MM
+	ClosesAt string `json:"closes_at" binding:"required"` // HH:MM
+}
+
+// Holiday is a date the center does not take bookings.
+type Holiday struct {
+	ID              uuid.UUID `json:"id"`
+	ServiceCenterID uuid.UUID `json:"service_center_id"`
+	Date            string    `json:"date"` // YYYY-MM-DD
+	Reason          *string   `json:"reason,omitempty"`
+}
+
+// CenterService is a service on the price list of a center.
+type CenterService struct {
+	ID              uuid.UUID   `json:"id"`
+	ServiceCenterID uuid.UUID   `json:"service_center_id"`
+	Name            string      `json:"name"`
+	Category        *string     `json:"category,omitempty"`
+	Description     *string     `json:"description,omitempty"`
+	DurationMinutes int         `json:"duration_minutes"`
+	PriceMin        float64     `json:"price_min"`
+	PriceMax        float64     `json:"price_max"` // equal to price_min for a fixed price
+	Currency        string      `json:"currency"`
+	PlatformIDs     []uuid.UUID `json:"platform_ids"`    // vehicle platforms served; empty means any
+	ComponentCodes  []string    `json:"component_codes"` // catalog components the service works on
+	IsActive        bool        `json:"is_active"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// CenterServicesFilter holds query filters for the price list of a center.
+type CenterServicesFilter struct {
+	IncludeInactive bool
+	Category        *string
+	VehicleID       *uuid.UUID // only services applicable to the platform of this vehicle
+	ComponentCode   *string
+}
+
+// Slot is a bookable start time with the number of free bays.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Free  int       `json:"free"`
+}
+
+// UpdateScheduleRequest changes center settings; Hours, if present, replaces the weekly schedule.
+type UpdateScheduleRequest struct {
+	Timezone               *string        `json:"timezone,omitempty"`
+	Bays                   *int           `json:"bays,omitempty"`
+	SlotMinutes            *int           `json:"slot_minutes,omitempty"`
+	DefaultDurationMinutes *int           `json:"default_duration_minutes,omitempty"`
+	Hours                  []WorkingHours `json:"hours,omitempty" binding:"omitempty,dive"`
+}
+
+// AddHolidayRequest is the request body for closing a center on a date.
+type AddHolidayRequest struct {
+	Date   string `json:"date" binding:"required"` // YYYY-MM-DD
+	Reason string `json:"reason"`
+}
+
+// CreateCenterServiceRequest is the request body for adding a service to a center.
+// PriceMax defaults to PriceMin (a fixed price).
+type CreateCenterServiceRequest struct {
+	Name            string      `json:"name" binding:"required"`
+	Category        string      `json:"category"`
+	Description     string      `json:"description"`
+	DurationMinutes int         `json:"duration_minutes" binding:"required,min=1"`
+	PriceMin        float64     `json:"price_min" binding:"min=0"`
+	PriceMax        *float64    `json:"price_max,omitempty"`
+	Currency        string      `json:"currency"` // default KZT
+	PlatformIDs     []uuid.UUID `json:"platform_ids,omitempty"`
+	ComponentCodes  []string    `json:"component_codes,omitempty"`
+}
+
+// UpdateCenterServiceRequest is the request body for changing a center service.
+// PlatformIDs and ComponentCodes, if present, replace the current lists.
+type UpdateCenterServiceRequest struct {
+	Name            *string     `json:"name,omitempty"`
+	Category        *string     `json:"category,omitempty"`
+	Description     *string     `json:"description,omitempty"`
+	DurationMinutes *int        `json:"duration_minutes,omitempty"`
+	PriceMin        *float64    `json:"price_min,omitempty"`
+	PriceMax        *float64    `json:"price_max,omitempty"`
+	Currency        *string     `json:"currency,omitempty"`
+	PlatformIDs     []uuid.UUID `json:"platform_ids,omitempty"`
+	ComponentCodes  []string    `json:"component_codes,omitempty"`
+	IsActive        *bool       `json:"is_active,omitempty"`
+}
+
+// Waitlist entry statuses.
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered" // a slot is held for the user
+	WaitlistBooked    = "booked"
+	WaitlistCancelled = "cancelled"
+	WaitlistExpired   = "expired" // the date range passed
+)
+
+// Waitlist offer statuses.
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+	OfferExpired  = "expired"
+)
+
+// WaitlistEntry is a user waiting for a free slot at a center within a date range.
+type WaitlistEntry struct {
+	ID              uuid.UUID      `json:"id"`
+	UserID          uuid.UUID      `json:"user_id"`
+	VehicleID       uuid.UUID      `json:"vehicle_id"`
+	ServiceCenterID uuid.UUID      `json:"service_center_id"`
+	ServiceIDs      []uuid.UUID    `json:"service_ids"`
+	DateFrom        string         `json:"date_from"` // YYYY-MM-DD, center's timezone
+	DateTo          string         `json:"date_to"`
+	Status          string         `json:"status"`
+	Notes           *string        `json:"notes,omitempty"`
+	QueuedAt        time.Time      `json:"queued_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	Offer           *WaitlistOffer `json:"offer,omitempty"` // the pending offer, if any
+}
+
+// WaitlistOffer is a freed slot held for a waitlisted user until ExpiresAt.
+type WaitlistOffer struct {
+	ID              uuid.UUID  `json:"id"`
+	EntryID         uuid.UUID  `json:"entry_id"`
+	ServiceCenterID uuid.UUID  `json:"service_center_id"`
+	ScheduledAt     time.Time  `json:"scheduled_at"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Status          string     `json:"status"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	BookingID       *uuid.UUID `json:"booking_id,omitempty"` // set on acceptance
+	CreatedAt       time.Time  `json:"created_at"`
+	RespondedAt     *time.Time `json:"responded_at,omitempty"`
+}
+
+// JoinWaitlistRequest is the request body for joining the waitlist of a center.
+type JoinWaitlistRequest struct {
+	ServiceCenterID uuid.UUID   `json:"service_center_id" binding:"required"`
+	VehicleID       uuid.UUID   `json:"vehicle_id" binding:"required"`
+	DateFrom        string      `json:"date_from" binding:"required"` // YYYY-MM-DD
+	DateTo          string      `json:"date_to" binding:"required"`
+	ServiceIDs      []uuid.UUID `json:"service_ids,omitempty"`
+	Notes           string      `json:"notes"`
+}
@@ -0,0 +1,38 @@
+package booking
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// PartsReservations settles the warehouse parts reserved for a booking once it is closed.
+// BookingClosed must be idempotent: it is repeated on retries.
+type PartsReservations interface {
+	// BookingClosed issues the reserved parts of a completed booking and releases those of a
+	// cancelled or missed one.
+	BookingClosed(ctx context.Context, bookingID uuid.UUID, completed bool) error
+}
+
+// SetPartsReservations enables settling reserved parts when bookings are closed.
+func (s *Service) SetPartsReservations(p PartsReservations) {
+	s.parts = p
+}
+
+// settleParts settles the parts reserved for b if it is closed. The booking is already saved,
+// so failures are only logged: when they expire, unsettled reservations of a completed booking
+// are consumed and the others released.
+func (s *Service) settleParts(ctx context.Context, b *Booking) {
+	if s.parts == nil {
+		return
+	}
+	switch b.Status {
+	case StatusCompleted, StatusCancelled, StatusNoShow:
+	default:
+		return
+	}
+	if err := s.parts.BookingClosed(ctx, b.ID, b.Status == StatusCompleted); err != nil {
+		log.Printf("booking: settling parts of %s failed: %v", b.ID, err)
+	}
+}
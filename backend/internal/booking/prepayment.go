@@ -0,0 +1,63 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prepayment returns a booking of the user with what is to be paid for it in advance; nil if
+// it is not found or not theirs. Only upcoming bookings that are not paid yet can be prepaid.
+func (s *Service) Prepayment(ctx context.Context, id, userID uuid.UUID) (*Booking, float64, string, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil || b == nil || b.UserID != userID {
+		return nil, 0, "", err
+	}
+	if b.Status != StatusScheduled && b.Status != StatusConfirmed {
+		return nil, 0, "", fmt.Errorf("booking cannot be prepaid in status %s", b.Status)
+	}
+	if b.PaidAt != nil {
+		return nil, 0, "", fmt.Errorf("booking is already prepaid")
+	}
+	if err := s.withServices(ctx, b); err != nil {
+		return nil, 0, "", err
+	}
+	amount, currency, err := b.prepayment()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return b, amount, currency, nil
+}
+
+// MarkPrepaid records a prepayment confirmed by the payment gateway. A booking already paid
+// keeps its first payment, so redelivered webhooks change nothing.
+func (s *Service) MarkPrepaid(ctx context.Context, id uuid.UUID, amount float64, paidAt time.Time) error {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("booking not found")
+	}
+	if b.PaidAt != nil {
+		return nil
+	}
+	return s.repo.SetPrepayment(ctx, id, &amount, &paidAt)
+}
+
+// MarkPrepaymentRefunded clears the prepayment of a booking after it was fully refunded.
+func (s *Service) MarkPrepaymentRefunded(ctx context.Context, id uuid.UUID) error {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("booking not found")
+	}
+	if b.PaidAt == nil {
+		return nil
+	}
+	return s.repo.SetPrepayment(ctx, id, nil, nil)
+}
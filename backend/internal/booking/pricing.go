@@ -0,0 +1,189 @@
+package booking
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// appliesTo reports whether the service is offered for a vehicle of the given platform.
+// Services without platforms apply to any vehicle; a vehicle without a platform only
+// gets those.
+func (cs *CenterService) appliesTo(platformID *uuid.UUID) bool {
+	if len(cs.PlatformIDs) == 0 {
+		return true
+	}
+	if platformID == nil {
+		return false
+	}
+	for _, id := range cs.PlatformIDs {
+		if id == *platformID {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *CenterService) hasComponent(code string) bool {
+	for _, c := range cs.ComponentCodes {
+		if strings.EqualFold(c, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPriceRange validates a price list range.
+func checkPriceRange(min, max float64) error {
+	if min < 0 {
+		return fmt.Errorf("price_min must not be negative")
+	}
+	if max < min {
+		return fmt.Errorf("price_max must not be less than price_min")
+	}
+	return nil
+}
+
+// newBookingServices snapshots the selected price list entries, in the order of ids.
+func newBookingServices(bookingID uuid.UUID, services []*CenterService, ids []uuid.UUID) ([]*BookingService, error) {
+	found := make(map[uuid.UUID]*CenterService, len(services))
+	for _, svc := range services {
+		found[svc.ID] = svc
+	}
+	lines := make([]*BookingService, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		svc, ok := found[id]
+		if !ok {
+			return nil, fmt.Errorf("service %s is not offered by this service center", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("service %s is selected twice", id)
+		}
+		seen[id] = true
+		centerServiceID := svc.ID
+		lines = append(lines, &BookingService{
+			ID:              uuid.New(),
+			BookingID:       bookingID,
+			CenterServiceID: &centerServiceID,
+			Name:            svc.Name,
+			Category:        svc.Category,
+			DurationMinutes: svc.DurationMinutes,
+			QuotedPriceMin:  svc.PriceMin,
+			QuotedPriceMax:  svc.PriceMax,
+			Currency:        svc.Currency,
+		})
+	}
+	return lines, nil
+}
+
+// bookingDuration is the total duration of the selected services, or the center default.
+func bookingDuration(cs *CenterSchedule, lines []*BookingService) time.Duration {
+	if len(lines) == 0 {
+		return time.Duration(cs.DefaultDurationMinutes) * time.Minute
+	}
+	total := 0
+	for _, l := range lines {
+		total += l.DurationMinutes
+	}
+	return time.Duration(total) * time.Minute
+}
+
+// setTotals fills the duration and price totals of b from its services.
+func (b *Booking) setTotals() {
+	b.DurationMinutes = int(b.EndsAt.Sub(b.ScheduledAt).Minutes())
+	b.QuotedPriceMin, b.QuotedPriceMax, b.ActualPrice = 0, 0, nil
+	if b.Services == nil {
+		b.Services = []*BookingService{}
+	}
+	actual, complete := 0.0, len(b.Services) > 0
+	for _, l := range b.Services {
+		b.QuotedPriceMin += l.QuotedPriceMin
+		b.QuotedPriceMax += l.QuotedPriceMax
+		if l.ActualPrice == nil {
+			complete = false
+		} else {
+			actual += *l.ActualPrice
+		}
+	}
+	if complete {
+		b.ActualPrice = &actual
+	}
+}
+
+// prepayment returns what the owner pays in advance for a booking. Only a fixed price in one
+// currency can be prepaid: a price range is only settled when the work is done.
+func (b *Booking) prepayment() (float64, string, error) {
+	if len(b.Services) == 0 || b.QuotedPriceMin <= 0 || b.QuotedPriceMin != b.QuotedPriceMax {
+		return 0, "", fmt.Errorf("only bookings with a fixed price can be prepaid")
+	}
+	currency := b.Services[0].Currency
+	for _, l := range b.Services[1:] {
+		if l.Currency != currency {
+			return 0, "", fmt.Errorf("services of the booking are priced in different currencies")
+		}
+	}
+	return b.QuotedPriceMin, currency, nil
+}
+
+// applyActualPrices sets the charged price of each service of a booking. Services with a
+// fixed quoted price default to it; for a price range the actual price must be given.
+func applyActualPrices(lines []*BookingService, prices []ActualPrice) error {
+	byID := make(map[uuid.UUID]*BookingService, len(lines))
+	for _, l := range lines {
+		byID[l.ID] = l
+	}
+	for _, p := range prices {
+		l, ok := byID[p.BookingServiceID]
+		if !ok {
+			return fmt.Errorf("service %s is not part of this booking", p.BookingServiceID)
+		}
+		if p.Price < 0 {
+			return fmt.Errorf("price of %s must not be negative", l.Name)
+		}
+		price := p.Price
+		l.ActualPrice = &price
+	}
+	for _, l := range lines {
+		if l.ActualPrice != nil {
+			continue
+		}
+		if l.QuotedPriceMin != l.QuotedPriceMax {
+			return fmt.Errorf("actual price is required for %s", l.Name)
+		}
+		price := l.QuotedPriceMin
+		l.ActualPrice = &price
+	}
+	return nil
+}
+
+// ServiceRecordTitle is the description used for the service book entry of a booking.
+func (b *Booking) ServiceRecordTitle(centerName string) string {
+	names := make([]string, len(b.Services))
+	for i, l := range b.Services {
+		names[i] = l.Name
+	}
+	title := "Визит в сервис"
+	if centerName != "" {
+		title += " " + centerName
+	}
+	if len(names) > 0 {
+		title += ": " + strings.Join(names, ", ")
+	}
+	return title
+}
+
+// ServiceRecordAmount is what was charged for the booking.
+func (b *Booking) ServiceRecordAmount() float64 {
+	if b.ActualPrice != nil {
+		return *b.ActualPrice
+	}
+	return 0
+}
+
+// ServiceRecordDate is the date of the visit in YYYY-MM-DD, as stored in service records.
+func (b *Booking) ServiceRecordDate() string {
+	return b.ScheduledAt.Format("2006-01-02")
+}
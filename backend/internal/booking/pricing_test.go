@@ -0,0 +1,113 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAppliesTo(t *testing.T) {
+	platform, other := uuid.New(), uuid.New()
+	anyPlatform := &CenterService{}
+	only := &CenterService{PlatformIDs: []uuid.UUID{platform}}
+
+	if !anyPlatform.appliesTo(nil) || !anyPlatform.appliesTo(&other) {
+		t.Error("service without platforms should apply to any vehicle")
+	}
+	if !only.appliesTo(&platform) {
+		t.Error("service should apply to its platform")
+	}
+	if only.appliesTo(&other) || only.appliesTo(nil) {
+		t.Error("service should not apply to other platforms or unknown ones")
+	}
+}
+
+func TestNewBookingServicesKeepsOrder(t *testing.T) {
+	a := &CenterService{ID: uuid.New(), Name: "Замена масла", DurationMinutes: 30, PriceMin: 5000, PriceMax: 5000}
+	b := &CenterService{ID: uuid.New(), Name: "Диагностика", DurationMinutes: 45, PriceMin: 8000, PriceMax: 12000}
+	bookingID := uuid.New()
+
+	lines, err := newBookingServices(bookingID, []*CenterService{a, b}, []uuid.UUID{b.ID, a.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].Name != b.Name || lines[1].Name != a.Name {
+		t.Fatalf("unexpected lines: %+v", lines)
+	}
+	if lines[0].BookingID != bookingID || lines[0].QuotedPriceMax != 12000 {
+		t.Errorf("snapshot not copied: %+v", lines[0])
+	}
+	cs := &CenterSchedule{DefaultDurationMinutes: 60}
+	if got := bookingDuration(cs, lines); got != 75*time.Minute {
+		t.Errorf("duration = %s, want 75m", got)
+	}
+	if got := bookingDuration(cs, nil); got != time.Hour {
+		t.Errorf("default duration = %s, want 1h", got)
+	}
+
+	if _, err := newBookingServices(bookingID, []*CenterService{a}, []uuid.UUID{a.ID, a.ID}); err == nil {
+		t.Error("expected error for a service selected twice")
+	}
+	if _, err := newBookingServices(bookingID, []*CenterService{a}, []uuid.UUID{uuid.New()}); err == nil {
+		t.Error("expected error for an unknown service")
+	}
+}
+
+func TestApplyActualPrices(t *testing.T) {
+	fixed := &BookingService{ID: uuid.New(), Name: "Замена масла", QuotedPriceMin: 5000, QuotedPriceMax: 5000}
+	ranged := &BookingService{ID: uuid.New(), Name: "Диагностика", QuotedPriceMin: 8000, QuotedPriceMax: 12000}
+	lines := []*BookingService{fixed, ranged}
+
+	if err := applyActualPrices(lines, nil); err == nil {
+		t.Error("expected error when a price range has no actual price")
+	}
+
+	fixed.ActualPrice, ranged.ActualPrice = nil, nil
+	err := applyActualPrices(lines, []ActualPrice{{BookingServiceID: ranged.ID, Price: 13500}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *fixed.ActualPrice != 5000 || *ranged.ActualPrice != 13500 {
+		t.Errorf("got %v and %v", *fixed.ActualPrice, *ranged.ActualPrice)
+	}
+
+	b := &Booking{Services: lines}
+	b.setTotals()
+	if b.QuotedPriceMin != 13000 || b.QuotedPriceMax != 17000 {
+		t.Errorf("quoted = %v-%v, want 13000-17000", b.QuotedPriceMin, b.QuotedPriceMax)
+	}
+	if b.ActualPrice == nil || *b.ActualPrice != 18500 {
+		t.Errorf("actual = %v, want 18500", b.ActualPrice)
+	}
+
+	if err := applyActualPrices(lines, []ActualPrice{{BookingServiceID: uuid.New(), Price: 1}}); err == nil {
+		t.Error("expected error for a service of another booking")
+	}
+}
+
+func TestPrepayment(t *testing.T) {
+	fixed := func(price float64, currency string) *BookingService {
+		return &BookingService{QuotedPriceMin: price, QuotedPriceMax: price, Currency: currency}
+	}
+	cases := []struct {
+		name     string
+		services []*BookingService
+		want     float64
+		wantErr  bool
+	}{
+		{"fixed prices", []*BookingService{fixed(5000, "KZT"), fixed(3000, "KZT")}, 8000, false},
+		{"no services", nil, 0, true},
+		{"price range", []*BookingService{fixed(5000, "KZT"), {QuotedPriceMin: 1000, QuotedPriceMax: 2000, Currency: "KZT"}}, 0, true},
+		{"free", []*BookingService{fixed(0, "KZT")}, 0, true},
+		{"mixed currencies", []*BookingService{fixed(5000, "KZT"), fixed(10, "USD")}, 0, true},
+	}
+	for _, c := range cases {
+		b := &Booking{Services: c.services}
+		b.setTotals()
+		got, _, err := b.prepayment()
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("%s: prepayment = %v, %v", c.name, got, err)
+		}
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"alem-auto/internal/database"
 )
 
+const bookingColumns = `id, service_center_id, vehicle_id, user_id, scheduled_at, ends_at, status, notes, mechanic_user_id, inspection_id, service_record_id, created_at, updated_at, paid_amount, paid_at`
+
 type Repository struct {
 	db *database.DB
 }
@@ -17,18 +22,45 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(ctx context.Context, b *Booking) error {
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBooking(row rowScanner) (*Booking, error) {
+	b := &Booking{}
+	var notes sql.NullString
+	err := row.Scan(
+		&b.ID, &b.ServiceCenterID, &b.VehicleID, &b.UserID, &b.ScheduledAt, &b.EndsAt, &b.Status, &notes,
+		&b.MechanicUserID, &b.InspectionID, &b.ServiceRecordID, &b.CreatedAt, &b.UpdatedAt, &b.PaidAmount, &b.PaidAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if notes.Valid {
+		b.Notes = &notes.String
+	}
+	return b, nil
+}
+
+// WithTx runs fn inside a database transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.WithTx(ctx, fn)
+}
+
+func (r *Repository) Create(ctx context.Context, q database.Querier, b *Booking) error {
 	query := `
-		INSERT INTO bookings (id, service_center_id, vehicle_id, user_id, scheduled_at, status, notes, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		INSERT INTO bookings (id, service_center_id, vehicle_id, user_id, scheduled_at, ends_at, status, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
 	`
 	var notes interface{}
 	if b.Notes != nil {
 		notes = *b.Notes
 	}
-	_, err := r.db.ExecContext(ctx, query,
-		b.ID, b.ServiceCenterID, b.VehicleID, b.UserID, b.ScheduledAt, b.Status, notes,
-	)
+	err := q.QueryRowContext(ctx, query,
+		b.ID, b.ServiceCenterID, b.VehicleID, b.UserID, b.ScheduledAt, b.EndsAt, b.Status, notes,
+	).Scan(&b.CreatedAt, &b.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
@@ -36,30 +68,29 @@ func (r *Repository) Create(ctx context.Context, b *Booking) error {
 }
 
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*Booking, error) {
-	query := `
-		SELECT id, service_center_id, vehicle_id, user_id, scheduled_at, status, notes, created_at, updated_at
-		FROM bookings WHERE id = $1
-	`
-	b := &Booking{}
-	var notes sql.NullString
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&b.ID, &b.ServiceCenterID, &b.VehicleID, &b.UserID, &b.ScheduledAt, &b.Status, &notes, &b.CreatedAt, &b.UpdatedAt,
-	)
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE id = $1`
+	b, err := scanBooking(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get booking: %w", err)
 	}
-	if notes.Valid {
-		b.Notes = &notes.String
-	}
 	return b, nil
 }
 
 func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter ListBookingsFilter) ([]*Booking, error) {
-	query := `SELECT id, service_center_id, vehicle_id, user_id, scheduled_at, status, notes, created_at, updated_at FROM bookings WHERE user_id = $1`
-	args := []interface{}{userID}
+	return r.list(ctx, "user_id", userID, filter, "scheduled_at DESC")
+}
+
+// ListByCenter returns bookings at a service center, soonest first.
+func (r *Repository) ListByCenter(ctx context.Context, centerID uuid.UUID, filter ListBookingsFilter) ([]*Booking, error) {
+	return r.list(ctx, "service_center_id", centerID, filter, "scheduled_at")
+}
+
+func (r *Repository) list(ctx context.Context, column string, id uuid.UUID, filter ListBookingsFilter, order string) ([]*Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE ` + column + ` = $1`
+	args := []interface{}{id}
 	pos := 2
 	if filter.ServiceCenterID != nil {
 		query += fmt.Sprintf(" AND service_center_id = $%d", pos)
@@ -71,6 +102,11 @@ func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter
 		args = append(args, *filter.VehicleID)
 		pos++
 	}
+	if filter.MechanicUserID != nil {
+		query += fmt.Sprintf(" AND mechanic_user_id = $%d", pos)
+		args = append(args, *filter.MechanicUserID)
+		pos++
+	}
 	if filter.Status != nil {
 		query += fmt.Sprintf(" AND status = $%d", pos)
 		args = append(args, *filter.Status)
@@ -86,7 +122,7 @@ func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter
 		args = append(args, *filter.To)
 		pos++
 	}
-	query += " ORDER BY scheduled_at DESC"
+	query += " ORDER BY " + order
 	limit := 50
 	if filter.Limit > 0 {
 		limit = filter.Limit
@@ -102,38 +138,130 @@ func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter
 
 	var list []*Booking
 	for rows.Next() {
-		b := &Booking{}
-		var notes sql.NullString
+		b, err := scanBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan booking: %w", err)
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// ListAgenda returns bookings of a center starting in [from, to) with vehicle, owner and
+// mechanic details, cancelled ones excluded.
+func (r *Repository) ListAgenda(ctx context.Context, centerID uuid.UUID, from, to time.Time) ([]*AgendaItem, error) {
+	query := `
+		SELECT ` + prefixedBookingColumns("b") + `,
+			v.license_plate, v.vin, o.name, o.email, m.name
+		FROM bookings b
+		JOIN vehicles v ON v.id = b.vehicle_id
+		JOIN users o ON o.id = b.user_id
+		LEFT JOIN users m ON m.id = b.mechanic_user_id
+		WHERE b.service_center_id = $1 AND b.scheduled_at >= $2 AND b.scheduled_at < $3 AND b.status <> $4
+		ORDER BY b.scheduled_at, b.created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, centerID, from, to, StatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agenda: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*AgendaItem{}
+	for rows.Next() {
+		item := &AgendaItem{Booking: &Booking{}}
+		var notes, plate, vin, ownerName, mechanicName sql.NullString
+		b := item.Booking
 		err := rows.Scan(
-			&b.ID, &b.ServiceCenterID, &b.VehicleID, &b.UserID, &b.ScheduledAt, &b.Status, &notes, &b.CreatedAt, &b.UpdatedAt,
+			&b.ID, &b.ServiceCenterID, &b.VehicleID, &b.UserID, &b.ScheduledAt, &b.EndsAt, &b.Status, &notes,
+			&b.MechanicUserID, &b.InspectionID, &b.ServiceRecordID, &b.CreatedAt, &b.UpdatedAt, &b.PaidAmount, &b.PaidAt,
+			&plate, &vin, &ownerName, &item.OwnerEmail, &mechanicName,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("scan booking: %w", err)
+			return nil, fmt.Errorf("scan agenda item: %w", err)
 		}
 		if notes.Valid {
 			b.Notes = &notes.String
 		}
-		list = append(list, b)
+		if plate.Valid {
+			item.LicensePlate = &plate.String
+		}
+		if vin.Valid {
+			item.VIN = &vin.String
+		}
+		if ownerName.Valid {
+			item.OwnerName = &ownerName.String
+		}
+		if mechanicName.Valid {
+			item.MechanicName = &mechanicName.String
+		}
+		list = append(list, item)
 	}
 	return list, rows.Err()
 }
 
-func (r *Repository) Update(ctx context.Context, b *Booking) error {
+// prefixedBookingColumns qualifies bookingColumns with a table alias.
+func prefixedBookingColumns(alias string) string {
+	cols := strings.Split(bookingColumns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+// LockByID reads a booking with a row lock for the duration of tx.
+func (r *Repository) LockByID(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE id = $1 FOR UPDATE`
+	b, err := scanBooking(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock booking: %w", err)
+	}
+	return b, nil
+}
+
+func (r *Repository) Update(ctx context.Context, q database.Querier, b *Booking) error {
 	query := `
-		UPDATE bookings SET scheduled_at = $2, status = $3, notes = $4, updated_at = NOW()
+		UPDATE bookings SET scheduled_at = $2, ends_at = $3, status = $4, notes = $5,
+			mechanic_user_id = $6, inspection_id = $7, sequence = sequence + 1, updated_at = NOW()
 		WHERE id = $1
 	`
 	var notes interface{}
 	if b.Notes != nil {
 		notes = *b.Notes
 	}
-	_, err := r.db.ExecContext(ctx, query, b.ID, b.ScheduledAt, b.Status, notes)
+	_, err := q.ExecContext(ctx, query, b.ID, b.ScheduledAt, b.EndsAt, b.Status, notes, b.MechanicUserID, b.InspectionID)
 	if err != nil {
 		return fmt.Errorf("failed to update booking: %w", err)
 	}
 	return nil
 }
 
+// UpdateNotes changes only the notes of a booking, so it cannot revert a concurrent
+// transition or reschedule of the same row.
+func (r *Repository) UpdateNotes(ctx context.Context, id uuid.UUID, notes *string) error {
+	var value interface{}
+	if notes != nil {
+		value = *notes
+	}
+	_, err := r.db.ExecContext(ctx, "UPDATE bookings SET notes = $2, updated_at = NOW() WHERE id = $1", id, value)
+	if err != nil {
+		return fmt.Errorf("failed to update booking notes: %w", err)
+	}
+	return nil
+}
+
+// SetPrepayment records or, with nil values, clears the prepayment of a booking.
+func (r *Repository) SetPrepayment(ctx context.Context, id uuid.UUID, amount *float64, paidAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE bookings SET paid_amount = $2, paid_at = $3, updated_at = NOW() WHERE id = $1",
+		id, amount, paidAt)
+	if err != nil {
+		return fmt.Errorf("failed to update booking prepayment: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, "DELETE FROM bookings WHERE id = $1", id)
 	if err != nil {
@@ -141,3 +269,859 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+// Schedule and capacity
+
+// activeStatuses are the booking statuses that occupy a bay.
+var activeStatuses = []string{StatusScheduled, StatusConfirmed, StatusCheckedIn}
+
+// GetCenterSchedule loads booking settings, weekly hours and upcoming holidays of a center.
+func (r *Repository) GetCenterSchedule(ctx context.Context, centerID uuid.UUID) (*CenterSchedule, error) {
+	cs := &CenterSchedule{ServiceCenterID: centerID, Hours: []WorkingHours{}, Holidays: []*Holiday{}}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT sc.timezone, sc.bays, sc.slot_minutes, sc.default_duration_minutes,
+			(SELECT COUNT(*) FROM service_center_users scu WHERE scu.service_center_id = sc.id AND scu.role = 'mechanic')
+		FROM service_centers sc WHERE sc.id = $1
+	`, centerID).Scan(&cs.Timezone, &cs.Bays, &cs.SlotMinutes, &cs.DefaultDurationMinutes, &cs.Mechanics)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service center schedule: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT weekday, TO_CHAR(opens_at, 'HH24:MI'), TO_CHAR(closes_at, 'HH24:MI')
+		FROM service_center_hours WHERE service_center_id = $1 ORDER BY weekday
+	`, centerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working hours: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h WorkingHours
+		if err := rows.Scan(&h.Weekday, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, fmt.Errorf("scan working hours: %w", err)
+		}
+		cs.Hours = append(cs.Hours, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hrows, err := r.db.QueryContext(ctx, `
+		SELECT id, service_center_id, TO_CHAR(date, 'YYYY-MM-DD'), reason
+		FROM service_center_holidays WHERE service_center_id = $1 AND date >= CURRENT_DATE - 1 ORDER BY date
+	`, centerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %w", err)
+	}
+	defer hrows.Close()
+	for hrows.Next() {
+		h := &Holiday{}
+		if err := hrows.Scan(&h.ID, &h.ServiceCenterID, &h.Date, &h.Reason); err != nil {
+			return nil, fmt.Errorf("scan holiday: %w", err)
+		}
+		cs.Holidays = append(cs.Holidays, h)
+	}
+	return cs, hrows.Err()
+}
+
+// UpdateCenterSchedule saves center settings and, if replaceHours is set, the weekly hours.
+func (r *Repository) UpdateCenterSchedule(ctx context.Context, cs *CenterSchedule, replaceHours bool) error {
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE service_centers SET timezone = $2, bays = $3, slot_minutes = $4, default_duration_minutes = $5
+			WHERE id = $1
+		`, cs.ServiceCenterID, cs.Timezone, cs.Bays, cs.SlotMinutes, cs.DefaultDurationMinutes)
+		if err != nil {
+			return fmt.Errorf("failed to update service center settings: %w", err)
+		}
+		if !replaceHours {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM service_center_hours WHERE service_center_id = $1`, cs.ServiceCenterID); err != nil {
+			return fmt.Errorf("failed to replace working hours: %w", err)
+		}
+		for _, h := range cs.Hours {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO service_center_hours (service_center_id, weekday, opens_at, closes_at)
+				VALUES ($1, $2, $3, $4)
+			`, cs.ServiceCenterID, h.Weekday, h.OpensAt, h.ClosesAt)
+			if err != nil {
+				return fmt.Errorf("failed to save working hours: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repository) CreateHoliday(ctx context.Context, h *Holiday) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO service_center_holidays (id, service_center_id, date, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (service_center_id, date) DO UPDATE SET reason = EXCLUDED.reason
+	`, h.ID, h.ServiceCenterID, h.Date, h.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to create holiday: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteHoliday(ctx context.Context, centerID uuid.UUID, date string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM service_center_holidays WHERE service_center_id = $1 AND date = $2`, centerID, date)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+	return nil
+}
+
+// CenterRole returns the user's role at the service center (admin, mechanic), or "" if none.
+func (r *Repository) CenterRole(ctx context.Context, userID, centerID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role FROM service_center_users WHERE service_center_id = $1 AND user_id = $2
+	`, centerID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check service center role: %w", err)
+	}
+	return role, nil
+}
+
+// LockCenter serializes booking changes of one center for the duration of tx.
+func (r *Repository) LockCenter(ctx context.Context, tx *sql.Tx, centerID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM service_centers WHERE id = $1 FOR UPDATE`, centerID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("service center not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock service center: %w", err)
+	}
+	return nil
+}
+
+// ListBusyIntervals returns time ranges of active bookings and of slots held by pending waitlist
+// offers of a center overlapping [from, to). excludeID skips the booking being moved or the
+// offer being accepted.
+func (r *Repository) ListBusyIntervals(ctx context.Context, q database.Querier, centerID uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]interval, error) {
+	query := `
+		SELECT scheduled_at, ends_at FROM bookings
+		WHERE service_center_id = $1 AND status = ANY($2) AND scheduled_at < $4 AND ends_at > $3 AND id <> $5
+		UNION ALL
+		SELECT scheduled_at, ends_at FROM waitlist_offers
+		WHERE service_center_id = $1 AND status = $6 AND expires_at > NOW() AND scheduled_at < $4 AND ends_at > $3 AND id <> $5
+	`
+	exclude := uuid.Nil
+	if excludeID != nil {
+		exclude = *excludeID
+	}
+	args := []interface{}{centerID, pq.Array(activeStatuses), from, to, exclude, OfferPending}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list busy intervals: %w", err)
+	}
+	defer rows.Close()
+	var list []interval
+	for rows.Next() {
+		var iv interval
+		if err := rows.Scan(&iv.start, &iv.end); err != nil {
+			return nil, fmt.Errorf("scan busy interval: %w", err)
+		}
+		list = append(list, iv)
+	}
+	return list, rows.Err()
+}
+
+// Center services
+
+const centerServiceColumns = `id, service_center_id, name, category, description, duration_minutes,
+	price_min, price_max, currency, platform_ids, component_codes, is_active, created_at, updated_at`
+
+func scanCenterService(row rowScanner) (*CenterService, error) {
+	cs := &CenterService{}
+	var category, description sql.NullString
+	var platformIDs []string
+	var componentCodes []string
+	err := row.Scan(&cs.ID, &cs.ServiceCenterID, &cs.Name, &category, &description, &cs.DurationMinutes,
+		&cs.PriceMin, &cs.PriceMax, &cs.Currency, pq.Array(&platformIDs), pq.Array(&componentCodes),
+		&cs.IsActive, &cs.CreatedAt, &cs.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if category.Valid {
+		cs.Category = &category.String
+	}
+	if description.Valid {
+		cs.Description = &description.String
+	}
+	cs.PlatformIDs = make([]uuid.UUID, 0, len(platformIDs))
+	for _, v := range platformIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform id %q: %w", v, err)
+		}
+		cs.PlatformIDs = append(cs.PlatformIDs, id)
+	}
+	cs.ComponentCodes = componentCodes
+	if cs.ComponentCodes == nil {
+		cs.ComponentCodes = []string{}
+	}
+	return cs, nil
+}
+
+// uuidArray converts ids for a uuid[] parameter.
+func uuidArray(ids []uuid.UUID) interface{} {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+	return pq.Array(strIDs)
+}
+
+func (r *Repository) CreateCenterService(ctx context.Context, cs *CenterService) error {
+	query := `
+		INSERT INTO service_center_services (id, service_center_id, name, category, description, duration_minutes,
+			price_min, price_max, currency, platform_ids, component_codes, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::uuid[], $11, $12, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, cs.ID, cs.ServiceCenterID, cs.Name, cs.Category, cs.Description,
+		cs.DurationMinutes, cs.PriceMin, cs.PriceMax, cs.Currency, uuidArray(cs.PlatformIDs), pq.Array(cs.ComponentCodes),
+		cs.IsActive).Scan(&cs.CreatedAt, &cs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetCenterServiceByID(ctx context.Context, id uuid.UUID) (*CenterService, error) {
+	query := `SELECT ` + centerServiceColumns + ` FROM service_center_services WHERE id = $1`
+	cs, err := scanCenterService(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	return cs, nil
+}
+
+func (r *Repository) UpdateCenterService(ctx context.Context, cs *CenterService) error {
+	query := `
+		UPDATE service_center_services SET name = $2, category = $3, description = $4, duration_minutes = $5,
+			price_min = $6, price_max = $7, currency = $8, platform_ids = $9::uuid[], component_codes = $10, is_active = $11
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, cs.ID, cs.Name, cs.Category, cs.Description, cs.DurationMinutes,
+		cs.PriceMin, cs.PriceMax, cs.Currency, uuidArray(cs.PlatformIDs), pq.Array(cs.ComponentCodes), cs.IsActive).
+		Scan(&cs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	return nil
+}
+
+// ListCenterServices returns services of a center; ids, if given, limits the result to them.
+func (r *Repository) ListCenterServices(ctx context.Context, centerID uuid.UUID, activeOnly bool, ids []uuid.UUID) ([]*CenterService, error) {
+	query := `SELECT ` + centerServiceColumns + ` FROM service_center_services WHERE service_center_id = $1`
+	args := []interface{}{centerID}
+	if activeOnly {
+		query += ` AND is_active`
+	}
+	if len(ids) > 0 {
+		args = append(args, uuidArray(ids))
+		query += fmt.Sprintf(` AND id = ANY($%d::uuid[])`, len(args))
+	}
+	query += ` ORDER BY category NULLS LAST, name`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	defer rows.Close()
+	list := []*CenterService{}
+	for rows.Next() {
+		cs, err := scanCenterService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan service: %w", err)
+		}
+		list = append(list, cs)
+	}
+	return list, rows.Err()
+}
+
+// Booking services
+
+// CreateServices stores the selected services of a booking.
+func (r *Repository) CreateServices(ctx context.Context, q database.Querier, lines []*BookingService) error {
+	query := `
+		INSERT INTO booking_services (id, booking_id, center_service_id, name, category, duration_minutes,
+			quoted_price_min, quoted_price_max, actual_price, currency, position, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+	`
+	for i, l := range lines {
+		_, err := q.ExecContext(ctx, query, l.ID, l.BookingID, l.CenterServiceID, l.Name, l.Category, l.DurationMinutes,
+			l.QuotedPriceMin, l.QuotedPriceMax, l.ActualPrice, l.Currency, i)
+		if err != nil {
+			return fmt.Errorf("failed to create booking service: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListServices returns the services of the given bookings, grouped by booking.
+func (r *Repository) ListServices(ctx context.Context, q database.Querier, bookingIDs []uuid.UUID) (map[uuid.UUID][]*BookingService, error) {
+	result := make(map[uuid.UUID][]*BookingService, len(bookingIDs))
+	if len(bookingIDs) == 0 {
+		return result, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, booking_id, center_service_id, name, category, duration_minutes,
+			quoted_price_min, quoted_price_max, actual_price, currency
+		FROM booking_services WHERE booking_id = ANY($1::uuid[])
+		ORDER BY booking_id, position
+	`, uuidArray(bookingIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list booking services: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l := &BookingService{}
+		var category sql.NullString
+		var actual sql.NullFloat64
+		err := rows.Scan(&l.ID, &l.BookingID, &l.CenterServiceID, &l.Name, &category, &l.DurationMinutes,
+			&l.QuotedPriceMin, &l.QuotedPriceMax, &actual, &l.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("scan booking service: %w", err)
+		}
+		if category.Valid {
+			l.Category = &category.String
+		}
+		if actual.Valid {
+			l.ActualPrice = &actual.Float64
+		}
+		result[l.BookingID] = append(result[l.BookingID], l)
+	}
+	return result, rows.Err()
+}
+
+// UpdateActualPrices stores the charged prices of booking services.
+func (r *Repository) UpdateActualPrices(ctx context.Context, q database.Querier, lines []*BookingService) error {
+	for _, l := range lines {
+		_, err := q.ExecContext(ctx, `UPDATE booking_services SET actual_price = $2 WHERE id = $1`, l.ID, l.ActualPrice)
+		if err != nil {
+			return fmt.Errorf("failed to update booking service price: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetServiceRecordID links a booking to its service book entry.
+func (r *Repository) SetServiceRecordID(ctx context.Context, id uuid.UUID, recordID *uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE bookings SET service_record_id = $2 WHERE id = $1`, id, recordID)
+	if err != nil {
+		return fmt.Errorf("failed to link service record: %w", err)
+	}
+	return nil
+}
+
+// GetCenterName returns the name of a service center, or "" if it does not exist.
+func (r *Repository) GetCenterName(ctx context.Context, centerID uuid.UUID) (string, error) {
+	var name string
+	err := r.db.QueryRowContext(ctx, `SELECT name FROM service_centers WHERE id = $1`, centerID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get service center name: %w", err)
+	}
+	return name, nil
+}
+
+// Calendar feeds
+
+const calendarFeedColumns = `id, token, user_id, service_center_id, last_accessed_at, revoked_at, created_at`
+
+func scanCalendarFeed(row rowScanner) (*CalendarFeed, error) {
+	f := &CalendarFeed{}
+	err := row.Scan(&f.ID, &f.Token, &f.UserID, &f.ServiceCenterID, &f.LastAccessedAt, &f.RevokedAt, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReplaceCalendarFeed revokes active feeds of the same user and scope and stores f.
+func (r *Repository) ReplaceCalendarFeed(ctx context.Context, f *CalendarFeed) error {
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE calendar_feeds SET revoked_at = NOW()
+			WHERE user_id = $1 AND service_center_id IS NOT DISTINCT FROM $2 AND revoked_at IS NULL
+		`, f.UserID, f.ServiceCenterID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke calendar feeds: %w", err)
+		}
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO calendar_feeds (id, token, user_id, service_center_id, created_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			RETURNING created_at
+		`, f.ID, f.Token, f.UserID, f.ServiceCenterID).Scan(&f.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create calendar feed: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetCalendarFeedByToken returns an active feed by its token and records the access.
+func (r *Repository) GetCalendarFeedByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	query := `
+		UPDATE calendar_feeds SET last_accessed_at = NOW()
+		WHERE token = $1 AND revoked_at IS NULL
+		RETURNING ` + calendarFeedColumns
+	f, err := scanCalendarFeed(r.db.QueryRowContext(ctx, query, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+	return f, nil
+}
+
+func (r *Repository) ListCalendarFeeds(ctx context.Context, userID uuid.UUID) ([]*CalendarFeed, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+calendarFeedColumns+` FROM calendar_feeds
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar feeds: %w", err)
+	}
+	defer rows.Close()
+	list := []*CalendarFeed{}
+	for rows.Next() {
+		f, err := scanCalendarFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan calendar feed: %w", err)
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// RevokeCalendarFeed revokes a feed of the user; it reports false if there was none.
+func (r *Repository) RevokeCalendarFeed(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE calendar_feeds SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke calendar feed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetUserRole returns the platform role of a user, or "" if the user does not exist.
+func (r *Repository) GetUserRole(ctx context.Context, userID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+	return role, nil
+}
+
+// calendarEntry is a booking with what a calendar event shows about it.
+type calendarEntry struct {
+	*Booking
+	Sequence       int
+	CenterName     string
+	CenterAddress  *string
+	CenterTimezone string
+	LicensePlate   *string
+}
+
+// ListCalendarEntries returns bookings by owner (column user_id) or center (service_center_id)
+// that end after since, oldest first.
+func (r *Repository) ListCalendarEntries(ctx context.Context, column string, id uuid.UUID, since time.Time, limit int) ([]*calendarEntry, error) {
+	return r.queryCalendarEntries(ctx, `b.`+column+` = $1 AND b.ends_at >= $2 ORDER BY b.scheduled_at LIMIT $3`, id, since, limit)
+}
+
+func (r *Repository) GetCalendarEntry(ctx context.Context, bookingID uuid.UUID) (*calendarEntry, error) {
+	list, err := r.queryCalendarEntries(ctx, `b.id = $1`, bookingID)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (r *Repository) queryCalendarEntries(ctx context.Context, where string, args ...interface{}) ([]*calendarEntry, error) {
+	query := `
+		SELECT ` + prefixedBookingColumns("b") + `, b.sequence, sc.name, sc.address, sc.timezone, v.license_plate
+		FROM bookings b
+		JOIN service_centers sc ON sc.id = b.service_center_id
+		JOIN vehicles v ON v.id = b.vehicle_id
+		WHERE ` + where
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar entries: %w", err)
+	}
+	defer rows.Close()
+	var list []*calendarEntry
+	for rows.Next() {
+		e := &calendarEntry{Booking: &Booking{}}
+		var notes sql.NullString
+		b := e.Booking
+		err := rows.Scan(
+			&b.ID, &b.ServiceCenterID, &b.VehicleID, &b.UserID, &b.ScheduledAt, &b.EndsAt, &b.Status, &notes,
+			&b.MechanicUserID, &b.InspectionID, &b.ServiceRecordID, &b.CreatedAt, &b.UpdatedAt,
+			&e.Sequence, &e.CenterName, &e.CenterAddress, &e.CenterTimezone, &e.LicensePlate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan calendar entry: %w", err)
+		}
+		if notes.Valid {
+			b.Notes = &notes.String
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// Reminders and no-shows
+
+// openStatuses are the statuses of bookings whose car has not arrived yet.
+var openStatuses = []string{StatusScheduled, StatusConfirmed}
+
+// ListReminderCandidates returns open bookings starting in (from, to], soonest first.
+func (r *Repository) ListReminderCandidates(ctx context.Context, from, to time.Time) ([]*calendarEntry, error) {
+	return r.queryCalendarEntries(ctx, `b.status = ANY($1) AND b.scheduled_at > $2 AND b.scheduled_at <= $3 ORDER BY b.scheduled_at`,
+		pq.Array(openStatuses), from, to)
+}
+
+// ListSentReminders returns, per booking, the leads of reminders already sent for its current start time.
+func (r *Repository) ListSentReminders(ctx context.Context, bookingIDs []uuid.UUID) (map[uuid.UUID]map[time.Duration]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.booking_id, r.lead_minutes FROM booking_reminders r
+		JOIN bookings b ON b.id = r.booking_id AND b.scheduled_at = r.scheduled_at
+		WHERE r.booking_id = ANY($1::uuid[])
+	`, uuidArray(bookingIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sent reminders: %w", err)
+	}
+	defer rows.Close()
+	sent := map[uuid.UUID]map[time.Duration]bool{}
+	for rows.Next() {
+		var id uuid.UUID
+		var minutes int
+		if err := rows.Scan(&id, &minutes); err != nil {
+			return nil, fmt.Errorf("scan sent reminder: %w", err)
+		}
+		if sent[id] == nil {
+			sent[id] = map[time.Duration]bool{}
+		}
+		sent[id][time.Duration(minutes)*time.Minute] = true
+	}
+	return sent, rows.Err()
+}
+
+// ClaimReminder records a reminder before it is sent; false means it was already recorded.
+func (r *Repository) ClaimReminder(ctx context.Context, bookingID uuid.UUID, lead time.Duration, scheduledAt time.Time, channel string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO booking_reminders (booking_id, lead_minutes, scheduled_at, channel, sent_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT DO NOTHING
+	`, bookingID, int(lead/time.Minute), scheduledAt, channel)
+	if err != nil {
+		return false, fmt.Errorf("failed to record reminder: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ReleaseReminder forgets a claimed reminder that could not be delivered, so it is retried.
+func (r *Repository) ReleaseReminder(ctx context.Context, bookingID uuid.UUID, lead time.Duration, scheduledAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM booking_reminders WHERE booking_id = $1 AND lead_minutes = $2 AND scheduled_at = $3
+	`, bookingID, int(lead/time.Minute), scheduledAt)
+	if err != nil {
+		return fmt.Errorf("failed to release reminder: %w", err)
+	}
+	return nil
+}
+
+// ListOverdueIDs returns open bookings that started at or before the given time, oldest first.
+func (r *Repository) ListOverdueIDs(ctx context.Context, startedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM bookings
+		WHERE status = ANY($1) AND scheduled_at <= $2
+		ORDER BY scheduled_at LIMIT $3
+	`, pq.Array(openStatuses), startedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overdue bookings: %w", err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan booking id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Waitlist
+
+const waitlistEntryColumns = `id, user_id, vehicle_id, service_center_id, service_ids, TO_CHAR(date_from, 'YYYY-MM-DD'),
+	TO_CHAR(date_to, 'YYYY-MM-DD'), status, notes, queued_at, created_at, updated_at`
+
+func scanWaitlistEntry(row rowScanner) (*WaitlistEntry, error) {
+	e := &WaitlistEntry{}
+	var serviceIDs []string
+	err := row.Scan(&e.ID, &e.UserID, &e.VehicleID, &e.ServiceCenterID, pq.Array(&serviceIDs), &e.DateFrom, &e.DateTo,
+		&e.Status, &e.Notes, &e.QueuedAt, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.ServiceIDs = make([]uuid.UUID, 0, len(serviceIDs))
+	for _, v := range serviceIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service id %q: %w", v, err)
+		}
+		e.ServiceIDs = append(e.ServiceIDs, id)
+	}
+	return e, nil
+}
+
+func (r *Repository) CreateWaitlistEntry(ctx context.Context, e *WaitlistEntry) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO booking_waitlist (id, user_id, vehicle_id, service_center_id, service_ids, date_from, date_to, status, notes,
+			queued_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5::uuid[], $6, $7, $8, $9, NOW(), NOW(), NOW())
+		RETURNING queued_at, created_at, updated_at
+	`, e.ID, e.UserID, e.VehicleID, e.ServiceCenterID, uuidArray(e.ServiceIDs), e.DateFrom, e.DateTo, e.Status, e.Notes).
+		Scan(&e.QueuedAt, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+	return nil
+}
+
+// LockWaitlistEntry reads an entry with a row lock for the duration of tx.
+func (r *Repository) LockWaitlistEntry(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*WaitlistEntry, error) {
+	e, err := scanWaitlistEntry(tx.QueryRowContext(ctx, `SELECT `+waitlistEntryColumns+` FROM booking_waitlist WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock waitlist entry: %w", err)
+	}
+	return e, nil
+}
+
+// ListWaitlistByUser returns the entries of a user, newest first.
+func (r *Repository) ListWaitlistByUser(ctx context.Context, userID uuid.UUID) ([]*WaitlistEntry, error) {
+	return r.listWaitlist(ctx, r.db, `user_id = $1 ORDER BY created_at DESC`, userID)
+}
+
+// ListWaitingEntries returns the entries of a center waiting for a slot on the local date day, in queue order.
+func (r *Repository) ListWaitingEntries(ctx context.Context, q database.Querier, centerID uuid.UUID, day string) ([]*WaitlistEntry, error) {
+	return r.listWaitlist(ctx, q, `service_center_id = $1 AND status = $2 AND date_from <= $3 AND date_to >= $3 ORDER BY queued_at`,
+		centerID, WaitlistWaiting, day)
+}
+
+func (r *Repository) listWaitlist(ctx context.Context, q database.Querier, where string, args ...interface{}) ([]*WaitlistEntry, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+waitlistEntryColumns+` FROM booking_waitlist WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list waitlist: %w", err)
+	}
+	defer rows.Close()
+	list := []*WaitlistEntry{}
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan waitlist entry: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// UpdateWaitlistEntry saves the status and queue position of an entry.
+func (r *Repository) UpdateWaitlistEntry(ctx context.Context, q database.Querier, e *WaitlistEntry) error {
+	err := q.QueryRowContext(ctx, `
+		UPDATE booking_waitlist SET status = $2, queued_at = $3 WHERE id = $1 RETURNING updated_at
+	`, e.ID, e.Status, e.QueuedAt).Scan(&e.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+	return nil
+}
+
+// ExpireWaitlistEntries closes waiting entries whose date range has passed.
+func (r *Repository) ExpireWaitlistEntries(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE booking_waitlist SET status = $1 WHERE status = $2 AND date_to < CURRENT_DATE
+	`, WaitlistExpired, WaitlistWaiting)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire waitlist entries: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+const offerColumns = `id, entry_id, service_center_id, scheduled_at, ends_at, status, expires_at, booking_id, created_at, responded_at`
+
+func scanOffer(row rowScanner) (*WaitlistOffer, error) {
+	o := &WaitlistOffer{}
+	err := row.Scan(&o.ID, &o.EntryID, &o.ServiceCenterID, &o.ScheduledAt, &o.EndsAt, &o.Status, &o.ExpiresAt,
+		&o.BookingID, &o.CreatedAt, &o.RespondedAt)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (r *Repository) CreateOffer(ctx context.Context, q database.Querier, o *WaitlistOffer) error {
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO waitlist_offers (id, entry_id, service_center_id, scheduled_at, ends_at, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING created_at
+	`, o.ID, o.EntryID, o.ServiceCenterID, o.ScheduledAt, o.EndsAt, o.Status, o.ExpiresAt).Scan(&o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create waitlist offer: %w", err)
+	}
+	return nil
+}
+
+// LockOffer reads an offer with a row lock for the duration of tx.
+func (r *Repository) LockOffer(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*WaitlistOffer, error) {
+	o, err := scanOffer(tx.QueryRowContext(ctx, `SELECT `+offerColumns+` FROM waitlist_offers WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock waitlist offer: %w", err)
+	}
+	return o, nil
+}
+
+// GetOfferCenter returns the center of an offer, uuid.Nil if the offer does not exist.
+func (r *Repository) GetOfferCenter(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	var centerID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT service_center_id FROM waitlist_offers WHERE id = $1`, id).Scan(&centerID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get waitlist offer: %w", err)
+	}
+	return centerID, nil
+}
+
+// ListPendingOffers returns the pending offer of each of the given entries that has one.
+func (r *Repository) ListPendingOffers(ctx context.Context, q database.Querier, entryIDs []uuid.UUID) (map[uuid.UUID]*WaitlistOffer, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+offerColumns+` FROM waitlist_offers WHERE entry_id = ANY($1::uuid[]) AND status = $2
+	`, uuidArray(entryIDs), OfferPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list waitlist offers: %w", err)
+	}
+	defer rows.Close()
+	offers := map[uuid.UUID]*WaitlistOffer{}
+	for rows.Next() {
+		o, err := scanOffer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan waitlist offer: %w", err)
+		}
+		offers[o.EntryID] = o
+	}
+	return offers, rows.Err()
+}
+
+// ListExpiredOfferIDs returns pending offers whose hold ran out.
+func (r *Repository) ListExpiredOfferIDs(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM waitlist_offers WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at LIMIT $3
+	`, OfferPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired offers: %w", err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan offer id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateOffer saves the outcome of an offer.
+func (r *Repository) UpdateOffer(ctx context.Context, q database.Querier, o *WaitlistOffer) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE waitlist_offers SET status = $2, booking_id = $3, responded_at = $4 WHERE id = $1
+	`, o.ID, o.Status, o.BookingID, o.RespondedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update waitlist offer: %w", err)
+	}
+	return nil
+}
+
+// History
+
+func (r *Repository) CreateEvent(ctx context.Context, q database.Querier, e *BookingEvent) error {
+	query := `
+		INSERT INTO booking_events (id, booking_id, from_status, to_status, old_scheduled_at, new_scheduled_at,
+			actor_user_id, actor_role, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING created_at
+	`
+	err := q.QueryRowContext(ctx, query, e.ID, e.BookingID, e.FromStatus, e.ToStatus, e.OldScheduledAt, e.NewScheduledAt,
+		e.ActorUserID, e.ActorRole, e.Reason).Scan(&e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create booking event: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListEvents(ctx context.Context, bookingID uuid.UUID) ([]*BookingEvent, error) {
+	query := `
+		SELECT id, booking_id, from_status, to_status, old_scheduled_at, new_scheduled_at, actor_user_id, actor_role, reason, created_at
+		FROM booking_events WHERE booking_id = $1 ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list booking events: %w", err)
+	}
+	defer rows.Close()
+	list := []*BookingEvent{}
+	for rows.Next() {
+		e := &BookingEvent{}
+		err := rows.Scan(&e.ID, &e.BookingID, &e.FromStatus, &e.ToStatus, &e.OldScheduledAt, &e.NewScheduledAt,
+			&e.ActorUserID, &e.ActorRole, &e.Reason, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan booking event: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
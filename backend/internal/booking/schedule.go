@@ -0,0 +1,147 @@
+package booking
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// interval is a half-open time range [start, end).
+type interval struct {
+	start, end time.Time
+}
+
+// capacity is how many bookings the center can serve at the same time: one per bay,
+// and no more than the registered mechanics if any are registered.
+func (cs *CenterSchedule) capacity() int {
+	c := cs.Bays
+	if cs.Mechanics > 0 && cs.Mechanics < c {
+		c = cs.Mechanics
+	}
+	return c
+}
+
+func (cs *CenterSchedule) location() *time.Location {
+	if loc, err := time.LoadLocation(cs.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+func (cs *CenterSchedule) isHoliday(day time.Time) bool {
+	date := day.Format("2006-01-02")
+	for _, h := range cs.Holidays {
+		if h.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// openingWindow returns the working interval of the local day, or false if the center is closed.
+func (cs *CenterSchedule) openingWindow(day time.Time) (interval, bool) {
+	if cs.isHoliday(day) {
+		return interval{}, false
+	}
+	for _, h := range cs.Hours {
+		if h.Weekday != int(day.Weekday()) {
+			continue
+		}
+		opens, err1 := time.Parse("15:04", h.OpensAt)
+		closes, err2 := time.Parse("15:04", h.ClosesAt)
+		if err1 != nil || err2 != nil {
+			return interval{}, false
+		}
+		midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+		return interval{
+			start: midnight.Add(time.Duration(opens.Hour())*time.Hour + time.Duration(opens.Minute())*time.Minute),
+			end:   midnight.Add(time.Duration(closes.Hour())*time.Hour + time.Duration(closes.Minute())*time.Minute),
+		}, true
+	}
+	return interval{}, false
+}
+
+// checkWorkingTime verifies that [start, end) lies within working hours of one day
+// and that start is on the center's slot grid.
+func (cs *CenterSchedule) checkWorkingTime(start, end time.Time) error {
+	local := start.In(cs.location())
+	window, ok := cs.openingWindow(local)
+	if !ok {
+		return fmt.Errorf("service center is closed on %s", local.Format("2006-01-02"))
+	}
+	if start.Before(window.start) || end.After(window.end) {
+		return fmt.Errorf("booking must be within working hours %s-%s",
+			window.start.Format("15:04"), window.end.Format("15:04"))
+	}
+	if cs.SlotMinutes > 0 && int(start.Sub(window.start).Minutes())%cs.SlotMinutes != 0 {
+		return fmt.Errorf("booking must start on a %d-minute slot", cs.SlotMinutes)
+	}
+	return nil
+}
+
+// maxConcurrent returns the highest number of busy intervals overlapping at any moment of [start, end).
+func maxConcurrent(busy []interval, start, end time.Time) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	for _, b := range busy {
+		if !b.start.Before(end) || !b.end.After(start) {
+			continue
+		}
+		s, e := b.start, b.end
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		edges = append(edges, edge{s, 1}, edge{e, -1})
+	}
+	// Ends sort before starts at the same instant: back-to-back bookings don't overlap.
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+	cur, max := 0, 0
+	for _, e := range edges {
+		cur += e.delta
+		if cur > max {
+			max = cur
+		}
+	}
+	return max
+}
+
+// freeSlots lists start times between the local dates from and to (inclusive) where a
+// booking of the given duration fits, skipping times before now.
+func (cs *CenterSchedule) freeSlots(busy []interval, from, to time.Time, duration time.Duration, now time.Time) []Slot {
+	loc := cs.location()
+	step := time.Duration(cs.SlotMinutes) * time.Minute
+	if step <= 0 {
+		step = duration
+	}
+	capacity := cs.capacity()
+	slots := []Slot{}
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	last := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		window, ok := cs.openingWindow(day)
+		if !ok {
+			continue
+		}
+		for t := window.start; !t.Add(duration).After(window.end); t = t.Add(step) {
+			if t.Before(now) {
+				continue
+			}
+			free := capacity - maxConcurrent(busy, t, t.Add(duration))
+			if free > 0 {
+				slots = append(slots, Slot{Start: t, End: t.Add(duration), Free: free})
+			}
+		}
+	}
+	return slots
+}
@@ -0,0 +1,57 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreeSlotsRespectCapacityAndHours(t *testing.T) {
+	cs := &CenterSchedule{
+		Timezone:    "Asia/Almaty",
+		Bays:        2,
+		SlotMinutes: 60,
+		Hours:       []WorkingHours{{Weekday: int(time.Monday), OpensAt: "09:00", ClosesAt: "12:00"}},
+		Holidays:    []*Holiday{{Date: "2026-03-09"}},
+	}
+	loc := cs.location()
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 0, 0, 0, loc) }
+
+	// Two overlapping bookings fill both bays at 10:00-11:00.
+	busy := []interval{{at(2, 9), at(2, 11)}, {at(2, 10), at(2, 11)}}
+	slots := cs.freeSlots(busy, at(2, 0), at(9, 0), time.Hour, at(1, 0))
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots, got %+v", slots)
+	}
+	if !slots[0].Start.Equal(at(2, 9)) || slots[0].Free != 1 {
+		t.Fatalf("expected 09:00 with one free bay, got %+v", slots[0])
+	}
+	if !slots[1].Start.Equal(at(2, 11)) || slots[1].Free != 2 {
+		t.Fatalf("expected 11:00 with two free bays, got %+v", slots[1])
+	}
+
+	if err := cs.checkWorkingTime(at(2, 11), at(2, 12)); err != nil {
+		t.Fatalf("expected 11:00-12:00 to be bookable, got %v", err)
+	}
+	if err := cs.checkWorkingTime(at(2, 11), at(2, 13)); err == nil {
+		t.Fatal("expected booking past closing time to be rejected")
+	}
+	if err := cs.checkWorkingTime(at(9, 9), at(9, 10)); err == nil {
+		t.Fatal("expected booking on a holiday to be rejected")
+	}
+	if err := cs.checkWorkingTime(at(2, 9).Add(30*time.Minute), at(2, 10).Add(30*time.Minute)); err == nil {
+		t.Fatal("expected booking off the slot grid to be rejected")
+	}
+}
+
+func TestMaxConcurrentBackToBack(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	busy := []interval{{base, base.Add(time.Hour)}, {base.Add(time.Hour), base.Add(2 * time.Hour)}}
+	if got := maxConcurrent(busy, base, base.Add(2*time.Hour)); got != 1 {
+		t.Fatalf("back-to-back bookings must not overlap, got %d", got)
+	}
+
+	cs := &CenterSchedule{Bays: 3, Mechanics: 2}
+	if got := cs.capacity(); got != 2 {
+		t.Fatalf("expected capacity limited by mechanics, got %d", got)
+	}
+}
@@ -2,11 +2,15 @@ package booking
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"alem-auto/config"
 	"alem-auto/internal/inspection"
+	"alem-auto/internal/notify"
 	"alem-auto/internal/vehicle"
 )
 
@@ -14,45 +18,33 @@ type Service struct {
 	repo              *Repository
 	vehicleService    *vehicle.Service
 	inspectionService *inspection.Service
+	cfg               config.BookingConfig
+	records           ServiceRecorder
+	notifier          notify.Channel
+	parts             PartsReservations
 }
 
-func NewService(repo *Repository, vehicleService *vehicle.Service, inspectionService *inspection.Service) *Service {
+func NewService(repo *Repository, vehicleService *vehicle.Service, inspectionService *inspection.Service, cfg config.BookingConfig) *Service {
 	return &Service{
 		repo:              repo,
 		vehicleService:    vehicleService,
 		inspectionService: inspectionService,
+		cfg:               cfg,
 	}
 }
 
 func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *CreateBookingRequest) (*Booking, error) {
-	// Verify vehicle exists and user owns it (via vehicle_owners or at least has access)
-	veh, err := s.vehicleService.GetVehicleByID(ctx, req.VehicleID)
-	if err != nil || veh == nil {
-		return nil, fmt.Errorf("vehicle not found")
-	}
-	// Check ownership: user must be in vehicle_owners for this vehicle
-	owners, err := s.vehicleService.GetCurrentOwnersByVehicleID(ctx, req.VehicleID)
-	if err != nil || len(owners) == 0 {
-		return nil, fmt.Errorf("vehicle not found or access denied")
-	}
-	owned := false
-	for _, o := range owners {
-		if o.UserID == userID {
-			owned = true
-			break
-		}
+	veh, err := s.ownedVehicle(ctx, userID, req.VehicleID)
+	if err != nil {
+		return nil, err
 	}
-	if !owned {
-		return nil, fmt.Errorf("vehicle not found or access denied")
+	cs, err := s.repo.GetCenterSchedule(ctx, req.ServiceCenterID)
+	if err != nil {
+		return nil, err
 	}
-	// Verify service center exists (inspection package has GetServiceCenterByID via repo; we need to expose or use inspection)
-	// Inspection service doesn't expose GetServiceCenterByID. We need to add it or call repo. For simplicity we'll add a method to inspection.Service or use the repo from inspection. Checking inspection service...
-	// inspection.Service has CreateServiceCenter, CreateInspection etc. We need GetServiceCenterByID. The inspection repository has it. So we need to add GetServiceCenterByID to inspection.Service and use it here.
-	sc, err := s.inspectionService.GetServiceCenterByID(ctx, req.ServiceCenterID)
-	if err != nil || sc == nil {
+	if cs == nil {
 		return nil, fmt.Errorf("service center not found")
 	}
-	_ = sc
 
 	scheduledAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
 	if err != nil {
@@ -61,31 +53,64 @@ func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *CreateBooki
 	if scheduledAt.Before(time.Now()) {
 		return nil, fmt.Errorf("scheduled_at must be in the future")
 	}
+	id := uuid.New()
+	lines, err := s.selectServices(ctx, req.ServiceCenterID, id, req.ServiceIDs, veh)
+	if err != nil {
+		return nil, err
+	}
 
 	b := &Booking{
-		ID:              uuid.New(),
+		ID:              id,
 		ServiceCenterID: req.ServiceCenterID,
 		VehicleID:       req.VehicleID,
 		UserID:          userID,
 		ScheduledAt:     scheduledAt,
+		EndsAt:          scheduledAt.Add(bookingDuration(cs, lines)),
 		Status:          StatusScheduled,
+		Services:        lines,
 	}
 	if req.Notes != "" {
 		b.Notes = &req.Notes
 	}
-	if err := s.repo.Create(ctx, b); err != nil {
+	if err := s.reserve(ctx, cs, b); err != nil {
 		return nil, err
 	}
+	b.setTotals()
 	return b, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Booking, error) {
+// ownedVehicle returns the vehicle if the user is among its current owners.
+func (s *Service) ownedVehicle(ctx context.Context, userID, vehicleID uuid.UUID) (*vehicle.Vehicle, error) {
+	// Verify vehicle exists and user owns it (via vehicle_owners or at least has access)
+	veh, err := s.vehicleService.GetVehicleByID(ctx, vehicleID)
+	if err != nil || veh == nil {
+		return nil, fmt.Errorf("vehicle not found")
+	}
+	// Check ownership: user must be in vehicle_owners for this vehicle
+	owners, err := s.vehicleService.GetCurrentOwnersByVehicleID(ctx, vehicleID)
+	if err != nil || len(owners) == 0 {
+		return nil, fmt.Errorf("vehicle not found or access denied")
+	}
+	for _, o := range owners {
+		if o.UserID == userID {
+			return veh, nil
+		}
+	}
+	return nil, fmt.Errorf("vehicle not found or access denied")
+}
+
+// GetByID returns a booking to its owner or the service center staff, nil otherwise.
+func (s *Service) GetByID(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string) (*Booking, error) {
 	b, err := s.repo.GetByID(ctx, id)
-	if err != nil {
+	if err != nil || b == nil {
 		return nil, err
 	}
-	if b == nil || b.UserID != userID {
-		return nil, nil
+	actor, err := s.actorFor(ctx, b, userID, role)
+	if err != nil || actor == "" {
+		return nil, err
+	}
+	if err := s.withServices(ctx, b); err != nil {
+		return nil, err
 	}
 	return b, nil
 }
@@ -94,34 +119,48 @@ func (s *Service) List(ctx context.Context, userID uuid.UUID, filter ListBooking
 	if filter.Limit <= 0 {
 		filter.Limit = 50
 	}
-	return s.repo.ListByUserID(ctx, userID, filter)
+	list, err := s.repo.ListByUserID(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withServices(ctx, list...); err != nil {
+		return nil, err
+	}
+	return list, nil
 }
 
-func (s *Service) Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, req *UpdateBookingRequest) (*Booking, error) {
+// Update changes notes (owner only) and, through Transition, the status of a booking.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string, req *UpdateBookingRequest) (*Booking, error) {
 	b, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if b == nil || b.UserID != userID {
+	if b == nil {
 		return nil, nil
 	}
-	if req.Status != nil {
-		switch *req.Status {
-		case StatusScheduled, StatusCompleted, StatusCancelled, StatusNoShow:
-			b.Status = *req.Status
-		default:
-			return nil, fmt.Errorf("invalid status")
-		}
+	actor, err := s.actorFor(ctx, b, userID, role)
+	if err != nil || actor == "" {
+		return nil, err
 	}
 	if req.Notes != nil {
+		if actor != ActorOwner {
+			return nil, fmt.Errorf("only the owner can change notes")
+		}
 		b.Notes = req.Notes
+		if err := s.repo.UpdateNotes(ctx, b.ID, b.Notes); err != nil {
+			return nil, err
+		}
 	}
-	if err := s.repo.Update(ctx, b); err != nil {
+	if req.Status != nil && *req.Status != b.Status {
+		return s.Transition(ctx, id, userID, role, &TransitionRequest{Status: *req.Status})
+	}
+	if err := s.withServices(ctx, b); err != nil {
 		return nil, err
 	}
 	return b, nil
 }
 
+// Delete removes a finished or cancelled booking of the user; active bookings must be cancelled first.
 func (s *Service) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
 	b, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -130,5 +169,222 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) er
 	if b == nil || b.UserID != userID {
 		return nil
 	}
+	if isActive(b.Status) {
+		return fmt.Errorf("cancel the booking before deleting it")
+	}
 	return s.repo.Delete(ctx, id)
 }
+
+// actorFor returns how the user acts on a booking: as its owner, as the service center
+// (its staff or the platform), or "" if the user has no access to it.
+func (s *Service) actorFor(ctx context.Context, b *Booking, userID uuid.UUID, role string) (string, error) {
+	if b.UserID == userID {
+		return ActorOwner, nil
+	}
+	if role == "platform" {
+		return ActorCenter, nil
+	}
+	centerRole, err := s.repo.CenterRole(ctx, userID, b.ServiceCenterID)
+	if err != nil {
+		return "", err
+	}
+	if centerRole != "" {
+		return ActorCenter, nil
+	}
+	return "", nil
+}
+
+// checkCutoff rejects owner changes too close to the start of the booking.
+func (s *Service) checkCutoff(actor string, b *Booking, now time.Time) error {
+	if actor == ActorOwner && s.cfg.CancelCutoff > 0 && b.ScheduledAt.Sub(now) < s.cfg.CancelCutoff {
+		return fmt.Errorf("booking can no longer be changed less than %s before its start, contact the service center",
+			s.cfg.CancelCutoff)
+	}
+	return nil
+}
+
+// Transition changes the status of a booking if the actor is allowed to and records it in the history.
+// A nil booking means it was not found for this user.
+func (s *Service) Transition(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string, req *TransitionRequest) (*Booking, error) {
+	var b *Booking
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		b, err = s.repo.LockByID(ctx, tx, id)
+		if err != nil || b == nil {
+			return err
+		}
+		actor, err := s.actorFor(ctx, b, userID, role)
+		if err != nil {
+			return err
+		}
+		if actor == "" {
+			b = nil
+			return nil
+		}
+		if req.Status == StatusCheckedIn {
+			return fmt.Errorf("use check-in to receive the car, it opens an inspection")
+		}
+		if req.Status == StatusCompleted {
+			return s.completeTx(ctx, tx, b, actor, userID, nil, req.Reason)
+		}
+		return s.applyTransition(ctx, tx, b, actor, &userID, req.Status, req.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	if b.Status == StatusCancelled {
+		s.offerFreed(ctx, b.ServiceCenterID, b.ScheduledAt, b.EndsAt, uuid.Nil)
+	}
+	s.settleParts(ctx, b)
+	if b.Status == StatusCompleted {
+		if err := s.syncServiceRecord(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.withServices(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *Service) applyTransition(ctx context.Context, tx *sql.Tx, b *Booking, actor string, actorID *uuid.UUID, to, reason string) error {
+	if !canTransition(actor, b.Status, to) {
+		return fmt.Errorf("%s cannot move booking from %s to %s", actor, b.Status, to)
+	}
+	now := time.Now()
+	switch to {
+	case StatusCancelled:
+		if err := s.checkCutoff(actor, b, now); err != nil {
+			return err
+		}
+	case StatusNoShow:
+		if now.Before(b.ScheduledAt) {
+			return fmt.Errorf("booking cannot be marked no-show before its start")
+		}
+	}
+	from := b.Status
+	b.Status = to
+	if err := s.repo.Update(ctx, tx, b); err != nil {
+		return err
+	}
+	e := &BookingEvent{
+		ID:          uuid.New(),
+		BookingID:   b.ID,
+		FromStatus:  &from,
+		ToStatus:    to,
+		ActorUserID: actorID,
+		ActorRole:   actor,
+	}
+	if r := strings.TrimSpace(reason); r != "" {
+		e.Reason = &r
+	}
+	return s.repo.CreateEvent(ctx, tx, e)
+}
+
+// Reschedule moves a booking to another time, keeping its duration. A booking moved by its
+// owner needs to be confirmed by the service center again.
+func (s *Service) Reschedule(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string, req *RescheduleRequest) (*Booking, error) {
+	newStart, err := time.Parse(time.RFC3339, req.ScheduledAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduled_at, use ISO8601: %w", err)
+	}
+	now := time.Now()
+	if newStart.Before(now) {
+		return nil, fmt.Errorf("scheduled_at must be in the future")
+	}
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, current.ServiceCenterID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+
+	var b *Booking
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockCenter(ctx, tx, current.ServiceCenterID); err != nil {
+			return err
+		}
+		var err error
+		b, err = s.repo.LockByID(ctx, tx, id)
+		if err != nil || b == nil {
+			return err
+		}
+		actor, err := s.actorFor(ctx, b, userID, role)
+		if err != nil {
+			return err
+		}
+		if actor == "" {
+			b = nil
+			return nil
+		}
+		if !canReschedule(b.Status) {
+			return fmt.Errorf("booking cannot be rescheduled in status %s", b.Status)
+		}
+		if err := s.checkCutoff(actor, b, now); err != nil {
+			return err
+		}
+		newEnd := newStart.Add(b.EndsAt.Sub(b.ScheduledAt))
+		if err := cs.checkWorkingTime(newStart, newEnd); err != nil {
+			return err
+		}
+		busy, err := s.repo.ListBusyIntervals(ctx, tx, b.ServiceCenterID, newStart, newEnd, &b.ID)
+		if err != nil {
+			return err
+		}
+		if maxConcurrent(busy, newStart, newEnd) >= cs.capacity() {
+			return ErrSlotUnavailable
+		}
+
+		from, oldStart := b.Status, b.ScheduledAt
+		b.ScheduledAt, b.EndsAt = newStart, newEnd
+		if actor == ActorOwner {
+			b.Status = StatusScheduled
+		}
+		if err := s.repo.Update(ctx, tx, b); err != nil {
+			return err
+		}
+		e := &BookingEvent{
+			ID:             uuid.New(),
+			BookingID:      b.ID,
+			FromStatus:     &from,
+			ToStatus:       b.Status,
+			OldScheduledAt: &oldStart,
+			NewScheduledAt: &newStart,
+			ActorUserID:    &userID,
+			ActorRole:      actor,
+		}
+		if r := strings.TrimSpace(req.Reason); r != "" {
+			e.Reason = &r
+		}
+		return s.repo.CreateEvent(ctx, tx, e)
+	})
+	if err != nil || b == nil {
+		return nil, err
+	}
+	s.offerFreed(ctx, current.ServiceCenterID, current.ScheduledAt, current.EndsAt, uuid.Nil)
+	if err := s.withServices(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// History returns status changes and reschedules of a booking, oldest first.
+func (s *Service) History(ctx context.Context, id uuid.UUID, userID uuid.UUID, role string) ([]*BookingEvent, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	actor, err := s.actorFor(ctx, b, userID, role)
+	if err != nil || actor == "" {
+		return nil, err
+	}
+	return s.repo.ListEvents(ctx, id)
+}
@@ -0,0 +1,44 @@
+package booking
+
+// transitions lists, per actor, the statuses a booking may move to from each status.
+// Owners can only cancel; the service center runs the visit; the system handles no-shows.
+var transitions = map[string]map[string][]string{
+	ActorOwner: {
+		StatusScheduled: {StatusCancelled},
+		StatusConfirmed: {StatusCancelled},
+	},
+	ActorCenter: {
+		StatusScheduled: {StatusConfirmed, StatusCheckedIn, StatusCancelled, StatusNoShow},
+		StatusConfirmed: {StatusCheckedIn, StatusCancelled, StatusNoShow},
+		StatusCheckedIn: {StatusCompleted},
+	},
+	ActorSystem: {
+		StatusScheduled: {StatusCancelled, StatusNoShow},
+		StatusConfirmed: {StatusCancelled, StatusNoShow},
+	},
+}
+
+// canTransition reports whether actor may move a booking from one status to another.
+func canTransition(actor, from, to string) bool {
+	for _, s := range transitions[actor][from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isActive reports whether a booking in this status occupies a bay.
+func isActive(status string) bool {
+	for _, s := range activeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// canReschedule reports whether a booking in this status can be moved to another time.
+func canReschedule(status string) bool {
+	return status == StatusScheduled || status == StatusConfirmed
+}
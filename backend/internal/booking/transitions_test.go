@@ -0,0 +1,26 @@
+package booking
+
+import "testing"
+
+func TestCanTransitionByActor(t *testing.T) {
+	cases := []struct {
+		actor, from, to string
+		want            bool
+	}{
+		{ActorOwner, StatusScheduled, StatusCancelled, true},
+		{ActorOwner, StatusCancelled, StatusScheduled, false},
+		{ActorOwner, StatusScheduled, StatusCompleted, false},
+		{ActorOwner, StatusCheckedIn, StatusCancelled, false},
+		{ActorCenter, StatusScheduled, StatusConfirmed, true},
+		{ActorCenter, StatusConfirmed, StatusCheckedIn, true},
+		{ActorCenter, StatusScheduled, StatusCompleted, false},
+		{ActorCenter, StatusCheckedIn, StatusCompleted, true},
+		{ActorCenter, StatusCompleted, StatusCancelled, false},
+		{ActorSystem, StatusConfirmed, StatusNoShow, true},
+	}
+	for _, tc := range cases {
+		if got := canTransition(tc.actor, tc.from, tc.to); got != tc.want {
+			t.Errorf("%s: %s -> %s: got %v, want %v", tc.actor, tc.from, tc.to, got, tc.want)
+		}
+	}
+}
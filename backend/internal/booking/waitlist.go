@@ -0,0 +1,443 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/notify"
+)
+
+// ErrOfferUnavailable is returned when a waitlist offer was already answered or its hold ran out.
+var ErrOfferUnavailable = errors.New("offer has expired or was already answered")
+
+// defaultWaitlistHold is used when no hold is configured.
+const defaultWaitlistHold = 30 * time.Minute
+
+// JoinWaitlist puts the user in the queue of a center for the dates from..to (in the center's
+// timezone). Joining is only allowed while no slot for the selected services is free in the range.
+func (s *Service) JoinWaitlist(ctx context.Context, userID uuid.UUID, req *JoinWaitlistRequest) (*WaitlistEntry, error) {
+	veh, err := s.ownedVehicle(ctx, userID, req.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, req.ServiceCenterID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+	loc := cs.location()
+	fromDay, err := time.ParseInLocation("2006-01-02", req.DateFrom, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date_from, use YYYY-MM-DD")
+	}
+	toDay, err := time.ParseInLocation("2006-01-02", req.DateTo, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date_to, use YYYY-MM-DD")
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if fromDay.Before(today) {
+		return nil, fmt.Errorf("date_from must not be in the past")
+	}
+	if toDay.Before(fromDay) {
+		return nil, fmt.Errorf("date_to must not be before date_from")
+	}
+	if toDay.Sub(fromDay) > maxAvailabilityDays*24*time.Hour {
+		return nil, fmt.Errorf("date range must not exceed %d days", maxAvailabilityDays)
+	}
+	lines, err := s.selectServices(ctx, req.ServiceCenterID, uuid.Nil, req.ServiceIDs, veh)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.repo.ListBusyIntervals(ctx, s.repo.db, req.ServiceCenterID, fromDay, toDay.AddDate(0, 0, 1), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs.freeSlots(busy, fromDay, toDay, bookingDuration(cs, lines), time.Now())) > 0 {
+		return nil, fmt.Errorf("there are free slots in this date range, book one of them")
+	}
+
+	e := &WaitlistEntry{
+		ID:              uuid.New(),
+		UserID:          userID,
+		VehicleID:       req.VehicleID,
+		ServiceCenterID: req.ServiceCenterID,
+		ServiceIDs:      req.ServiceIDs,
+		DateFrom:        fromDay.Format("2006-01-02"),
+		DateTo:          toDay.Format("2006-01-02"),
+		Status:          WaitlistWaiting,
+	}
+	if e.ServiceIDs == nil {
+		e.ServiceIDs = []uuid.UUID{}
+	}
+	if req.Notes != "" {
+		e.Notes = &req.Notes
+	}
+	if err := s.repo.CreateWaitlistEntry(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListWaitlist returns the waitlist entries of the user with their pending offers.
+func (s *Service) ListWaitlist(ctx context.Context, userID uuid.UUID) ([]*WaitlistEntry, error) {
+	list, err := s.repo.ListWaitlistByUser(ctx, userID)
+	if err != nil || len(list) == 0 {
+		return list, err
+	}
+	ids := make([]uuid.UUID, len(list))
+	for i, e := range list {
+		ids[i] = e.ID
+	}
+	offers, err := s.repo.ListPendingOffers(ctx, s.repo.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		e.Offer = offers[e.ID]
+	}
+	return list, nil
+}
+
+// LeaveWaitlist cancels an entry of the user and releases the slot held for it, if any.
+// It returns false if the entry does not exist or belongs to someone else.
+func (s *Service) LeaveWaitlist(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	found := false
+	var released *WaitlistOffer
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		e, err := s.repo.LockWaitlistEntry(ctx, tx, id)
+		if err != nil || e == nil || e.UserID != userID {
+			return err
+		}
+		found = true
+		if e.Status != WaitlistWaiting && e.Status != WaitlistOffered {
+			return fmt.Errorf("waitlist entry is already %s", e.Status)
+		}
+		offers, err := s.repo.ListPendingOffers(ctx, tx, []uuid.UUID{e.ID})
+		if err != nil {
+			return err
+		}
+		if o := offers[e.ID]; o != nil {
+			now := time.Now()
+			o.Status = OfferDeclined
+			o.RespondedAt = &now
+			if err := s.repo.UpdateOffer(ctx, tx, o); err != nil {
+				return err
+			}
+			released = o
+		}
+		e.Status = WaitlistCancelled
+		return s.repo.UpdateWaitlistEntry(ctx, tx, e)
+	})
+	if err != nil {
+		return found, err
+	}
+	if released != nil {
+		s.offerFreed(ctx, released.ServiceCenterID, released.ScheduledAt, released.EndsAt, id)
+	}
+	return found, nil
+}
+
+// AcceptOffer turns the slot held by an offer into a booking of the user. It returns nil if
+// the offer does not exist or was made to someone else.
+func (s *Service) AcceptOffer(ctx context.Context, offerID, userID uuid.UUID) (*Booking, error) {
+	centerID, err := s.repo.GetOfferCenter(ctx, offerID)
+	if err != nil || centerID == uuid.Nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, centerID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+
+	var b *Booking
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockCenter(ctx, tx, centerID); err != nil {
+			return err
+		}
+		o, err := s.repo.LockOffer(ctx, tx, offerID)
+		if err != nil || o == nil {
+			return err
+		}
+		e, err := s.repo.LockWaitlistEntry(ctx, tx, o.EntryID)
+		if err != nil || e == nil || e.UserID != userID {
+			return err
+		}
+		now := time.Now()
+		if o.Status != OfferPending || !now.Before(o.ExpiresAt) || !now.Before(o.ScheduledAt) {
+			return ErrOfferUnavailable
+		}
+		veh, err := s.ownedVehicle(ctx, userID, e.VehicleID)
+		if err != nil {
+			return err
+		}
+		id := uuid.New()
+		lines, err := s.selectServices(ctx, centerID, id, e.ServiceIDs, veh)
+		if err != nil {
+			return err
+		}
+		nb := &Booking{
+			ID:              id,
+			ServiceCenterID: centerID,
+			VehicleID:       e.VehicleID,
+			UserID:          userID,
+			ScheduledAt:     o.ScheduledAt,
+			EndsAt:          o.ScheduledAt.Add(bookingDuration(cs, lines)),
+			Status:          StatusScheduled,
+			Notes:           e.Notes,
+			Services:        lines,
+		}
+		if err := cs.checkWorkingTime(nb.ScheduledAt, nb.EndsAt); err != nil {
+			return err
+		}
+		if err := s.reserveTx(ctx, tx, cs, nb, &o.ID); err != nil {
+			return err
+		}
+		o.Status = OfferAccepted
+		o.BookingID = &nb.ID
+		o.RespondedAt = &now
+		if err := s.repo.UpdateOffer(ctx, tx, o); err != nil {
+			return err
+		}
+		e.Status = WaitlistBooked
+		if err := s.repo.UpdateWaitlistEntry(ctx, tx, e); err != nil {
+			return err
+		}
+		b = nb
+		return nil
+	})
+	if err != nil || b == nil {
+		return nil, err
+	}
+	b.setTotals()
+	return b, nil
+}
+
+// DeclineOffer gives up the held slot; the entry goes back to the end of the queue and the
+// slot is offered to the next user. It returns false if the offer is not the user's.
+func (s *Service) DeclineOffer(ctx context.Context, offerID, userID uuid.UUID) (bool, error) {
+	var declined *WaitlistOffer
+	found := false
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		o, err := s.repo.LockOffer(ctx, tx, offerID)
+		if err != nil || o == nil {
+			return err
+		}
+		e, err := s.repo.LockWaitlistEntry(ctx, tx, o.EntryID)
+		if err != nil || e == nil || e.UserID != userID {
+			return err
+		}
+		found = true
+		if o.Status != OfferPending {
+			return ErrOfferUnavailable
+		}
+		now := time.Now()
+		o.Status = OfferDeclined
+		o.RespondedAt = &now
+		if err := s.repo.UpdateOffer(ctx, tx, o); err != nil {
+			return err
+		}
+		if e.Status == WaitlistOffered {
+			e.Status = WaitlistWaiting
+			e.QueuedAt = now
+			if err := s.repo.UpdateWaitlistEntry(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+		declined = o
+		return nil
+	})
+	if err != nil {
+		return found, err
+	}
+	if declined != nil {
+		s.offerFreed(ctx, declined.ServiceCenterID, declined.ScheduledAt, declined.EndsAt, declined.EntryID)
+	}
+	return found, nil
+}
+
+// ExpireWaitlist releases slots whose hold ran out without an answer, passing them on to the
+// next users in the queue, and closes entries whose dates have passed. It returns how many
+// offers expired.
+func (s *Service) ExpireWaitlist(ctx context.Context) (int, error) {
+	ids, err := s.repo.ListExpiredOfferIDs(ctx, time.Now(), noShowBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		var expired *WaitlistOffer
+		err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+			o, err := s.repo.LockOffer(ctx, tx, id)
+			if err != nil || o == nil {
+				return err
+			}
+			// The user may have answered since the list was read.
+			if o.Status != OfferPending || time.Now().Before(o.ExpiresAt) {
+				return nil
+			}
+			o.Status = OfferExpired
+			if err := s.repo.UpdateOffer(ctx, tx, o); err != nil {
+				return err
+			}
+			e, err := s.repo.LockWaitlistEntry(ctx, tx, o.EntryID)
+			if err != nil {
+				return err
+			}
+			if e != nil && e.Status == WaitlistOffered {
+				e.Status = WaitlistWaiting
+				e.QueuedAt = time.Now()
+				if err := s.repo.UpdateWaitlistEntry(ctx, tx, e); err != nil {
+					return err
+				}
+			}
+			expired = o
+			return nil
+		})
+		if err != nil {
+			return n, fmt.Errorf("offer %s: %w", id, err)
+		}
+		if expired == nil {
+			continue
+		}
+		n++
+		s.offerFreed(ctx, expired.ServiceCenterID, expired.ScheduledAt, expired.EndsAt, expired.EntryID)
+	}
+	if _, err := s.repo.ExpireWaitlistEntries(ctx); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// nearestSlot picks the free slot starting closest to at, the earlier one on a tie.
+func nearestSlot(slots []Slot, at time.Time) (Slot, bool) {
+	var best Slot
+	var bestDiff time.Duration
+	for i, sl := range slots {
+		diff := sl.Start.Sub(at)
+		if diff < 0 {
+			diff = -diff
+		}
+		if i == 0 || diff < bestDiff {
+			best, bestDiff = sl, diff
+		}
+	}
+	return best, len(slots) > 0
+}
+
+// offerFreed offers time freed at a center between start and end to the users waiting for that
+// day, in queue order. Each offer holds the slot closest to the freed time that fits the user's
+// services. skip is an entry that has just given this slot up. Errors are only logged: the
+// change that freed the slot has already been saved.
+func (s *Service) offerFreed(ctx context.Context, centerID uuid.UUID, start, end time.Time, skip uuid.UUID) {
+	now := time.Now()
+	if !end.After(now) {
+		return
+	}
+	cs, err := s.repo.GetCenterSchedule(ctx, centerID)
+	if err != nil || cs == nil {
+		if err != nil {
+			log.Printf("booking: waitlist offers for %s: %v", centerID, err)
+		}
+		return
+	}
+	hold := s.cfg.WaitlistHold
+	if hold <= 0 {
+		hold = defaultWaitlistHold
+	}
+	loc := cs.location()
+	local := start.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	type made struct {
+		entry *WaitlistEntry
+		offer *WaitlistOffer
+	}
+	var offers []made
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockCenter(ctx, tx, centerID); err != nil {
+			return err
+		}
+		entries, err := s.repo.ListWaitingEntries(ctx, tx, centerID, day.Format("2006-01-02"))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.ID == skip {
+				continue
+			}
+			lines, err := s.selectServices(ctx, centerID, uuid.Nil, e.ServiceIDs, nil)
+			if err != nil {
+				// A service was withdrawn since the user joined; leave the entry waiting.
+				log.Printf("booking: waitlist entry %s: %v", e.ID, err)
+				continue
+			}
+			busy, err := s.repo.ListBusyIntervals(ctx, tx, centerID, day, day.AddDate(0, 0, 1), nil)
+			if err != nil {
+				return err
+			}
+			slot, ok := nearestSlot(cs.freeSlots(busy, day, day, bookingDuration(cs, lines), now), start)
+			if !ok {
+				continue
+			}
+			o := &WaitlistOffer{
+				ID:              uuid.New(),
+				EntryID:         e.ID,
+				ServiceCenterID: centerID,
+				ScheduledAt:     slot.Start,
+				EndsAt:          slot.End,
+				Status:          OfferPending,
+				ExpiresAt:       now.Add(hold),
+			}
+			if slot.Start.Before(o.ExpiresAt) {
+				o.ExpiresAt = slot.Start
+			}
+			if err := s.repo.CreateOffer(ctx, tx, o); err != nil {
+				return err
+			}
+			e.Status = WaitlistOffered
+			if err := s.repo.UpdateWaitlistEntry(ctx, tx, e); err != nil {
+				return err
+			}
+			offers = append(offers, made{e, o})
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("booking: waitlist offers for %s: %v", centerID, err)
+		return
+	}
+	if s.notifier == nil || len(offers) == 0 {
+		return
+	}
+	name, err := s.repo.GetCenterName(ctx, centerID)
+	if err != nil {
+		log.Printf("booking: waitlist offers for %s: %v", centerID, err)
+		return
+	}
+	for _, m := range offers {
+		at := m.offer.ScheduledAt.In(loc)
+		msg := &notify.Message{
+			UserID: m.entry.UserID,
+			Kind:   notify.KindWaitlistOffer,
+			Title:  "Освободилось время",
+			Body: fmt.Sprintf("В «%s» освободилось время %s в %s. Подтвердите запись до %s.",
+				name, at.Format("02.01"), at.Format("15:04"), m.offer.ExpiresAt.In(loc).Format("15:04")),
+			Data:   map[string]string{"offer_id": m.offer.ID.String(), "entry_id": m.entry.ID.String()},
+			SentAt: time.Now(),
+		}
+		if err := s.notifier.Send(ctx, msg); err != nil {
+			log.Printf("booking: waitlist offer %s notice failed: %v", m.offer.ID, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNearestSlot(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	slots := []Slot{
+		{Start: at(9, 0), End: at(10, 0), Free: 1},
+		{Start: at(11, 0), End: at(12, 0), Free: 1},
+		{Start: at(13, 0), End: at(14, 0), Free: 2},
+	}
+
+	cases := []struct {
+		name  string
+		freed time.Time
+		want  time.Time
+	}{
+		{"exact", at(11, 0), at(11, 0)},
+		{"closer to later", at(12, 40), at(13, 0)},
+		{"tie picks earlier", at(12, 0), at(11, 0)},
+		{"before all", at(7, 0), at(9, 0)},
+		{"after all", at(18, 0), at(13, 0)},
+	}
+	for _, c := range cases {
+		got, ok := nearestSlot(slots, c.freed)
+		if !ok || !got.Start.Equal(c.want) {
+			t.Errorf("%s: got %s (%v), want %s", c.name, got.Start.Format("15:04"), ok, c.want.Format("15:04"))
+		}
+	}
+	if _, ok := nearestSlot(nil, at(9, 0)); ok {
+		t.Error("no slots: want ok=false")
+	}
+}
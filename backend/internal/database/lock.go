@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+)
+
+// LockKey превращает имя блокировки в ключ pg_advisory_lock
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// WithAdvisoryLock выполняет fn, удерживая сессионную advisory-блокировку key.
+// Если блокировку держит другое соединение (например, другая реплика сервера),
+// fn не вызывается и возвращается false. Блокировка снимается после fn или
+// вместе с соединением, если процесс упадет.
+func (db *DB) WithAdvisoryLock(ctx context.Context, key int64, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		// Снимаем блокировку даже при отмененном ctx, иначе соединение вернется в пул с ней
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+	}()
+	return true, fn(ctx)
+}
@@ -0,0 +1,41 @@
+package directory
+
+import "math"
+
+const (
+	earthRadiusKm   = 6371.0
+	defaultRadiusKm = 10.0
+	maxRadiusKm     = 300.0
+)
+
+// distanceSQL is the great-circle (haversine) distance in kilometres between the center
+// and the point ($1 latitude, $2 longitude).
+const distanceSQL = `2 * 6371 * ASIN(LEAST(1, SQRT(
+		POWER(SIN(RADIANS(sc.latitude - $1) / 2), 2) +
+		COS(RADIANS($1)) * COS(RADIANS(sc.latitude)) * POWER(SIN(RADIANS(sc.longitude - $2) / 2), 2)
+	)))`
+
+// box is a latitude/longitude range in degrees.
+type box struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// boundingBox returns a range that contains every point within radiusKm of (lat, lon), so that
+// the index on coordinates can narrow the search before exact distances are computed. Near the
+// poles and across the antimeridian it spans all longitudes.
+func boundingBox(lat, lon, radiusKm float64) box {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	b := box{MinLat: lat - dLat, MaxLat: lat + dLat, MinLon: -180, MaxLon: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		b.MinLat, b.MaxLat = math.Max(b.MinLat, -90), math.Min(b.MaxLat, 90)
+		return b
+	}
+	// The widest longitude span is at the latitude farthest from the equator.
+	widest := math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat))
+	dLon := dLat / math.Cos(widest*math.Pi/180)
+	if lon-dLon >= -180 && lon+dLon <= 180 {
+		b.MinLon, b.MaxLon = lon-dLon, lon+dLon
+	}
+	return b
+}
@@ -0,0 +1,46 @@
+package directory
+
+import (
+	"math"
+	"testing"
+)
+
+// haversine mirrors distanceSQL.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	a := math.Pow(math.Sin((lat2-lat1)*rad/2), 2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin((lon2-lon1)*rad/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func TestBoundingBoxContainsRadius(t *testing.T) {
+	lat, lon := 43.238, 76.889 // Almaty
+	b := boundingBox(lat, lon, 10)
+	if got := haversine(lat, lon, b.MaxLat, lon); got < 10-1e-9 {
+		t.Errorf("north edge at %.2f km, want >= 10", got)
+	}
+	if got := haversine(lat, lon, lat, b.MaxLon); got < 10-1e-9 {
+		t.Errorf("east edge at %.2f km, want >= 10", got)
+	}
+	// A point 10 km away diagonally must fall inside the box.
+	d := 10 / math.Sqrt2 / earthRadiusKm * 180 / math.Pi
+	pLat, pLon := lat+d, lon+d/math.Cos((lat+d)*math.Pi/180)
+	if dist := haversine(lat, lon, pLat, pLon); dist > 10.01 {
+		t.Fatalf("test point is %.2f km away", dist)
+	}
+	if pLat > b.MaxLat || pLon > b.MaxLon {
+		t.Errorf("point (%.4f, %.4f) outside %+v", pLat, pLon, b)
+	}
+	if b.MaxLon-b.MinLon > 1 {
+		t.Errorf("box too wide: %+v", b)
+	}
+}
+
+func TestBoundingBoxNearPoleAndAntimeridian(t *testing.T) {
+	if b := boundingBox(89.99, 10, 50); b.MaxLat != 90 || b.MinLon != -180 || b.MaxLon != 180 {
+		t.Errorf("near pole: %+v", b)
+	}
+	if b := boundingBox(64.7, 179.95, 20); b.MinLon != -180 || b.MaxLon != 180 {
+		t.Errorf("across antimeridian: %+v", b)
+	}
+}
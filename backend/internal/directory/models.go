@@ -0,0 +1,53 @@
+package directory
+
+import (
+	"github.com/google/uuid"
+	"alem-auto/internal/booking"
+	"alem-auto/internal/inspection"
+)
+
+// Search orders.
+const (
+	SortDistance = "distance" // nearest first; requires coordinates
+	SortRating   = "rating"   // best rated first, then nearest
+	SortName     = "name"
+)
+
+// SearchFilter holds query filters for the public directory.
+type SearchFilter struct {
+	Lat, Lon *float64  // search around this point
+	RadiusKm float64   // with coordinates; 0 means defaultRadiusKm
+	City     *string
+	Category *string    // only centers offering an active service of this category
+	Query    *string    // matches the center name or the names of its services
+	MakeID   *uuid.UUID // only centers specializing in this make, or multi-brand ones
+	Sort     string
+	Limit    int
+	Offset   int
+}
+
+// Center is a service center in search results.
+type Center struct {
+	*inspection.ServiceCenter
+	DistanceKm *float64               `json:"distance_km,omitempty"` // only when searching around a point
+	Categories []string               `json:"categories"`            // categories of active services
+	Hours      []booking.WorkingHours `json:"hours"`
+}
+
+// Profile is the public page of a service center.
+type Profile struct {
+	Center
+	Services []*booking.CenterService `json:"services"`
+	Holidays []*booking.Holiday       `json:"holidays"`
+}
+
+// UpdateCenterRequest is the request body for changing the directory entry of a center.
+type UpdateCenterRequest struct {
+	Name      *string     `json:"name,omitempty"`
+	Address   *string     `json:"address,omitempty"`
+	City      *string     `json:"city,omitempty"`
+	Latitude  *float64    `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64    `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180"`
+	Phones    []string    `json:"phones,omitempty"`
+	MakeIDs   []uuid.UUID `json:"make_ids,omitempty"` // empty list: multi-brand
+}
@@ -0,0 +1,197 @@
+package directory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"alem-auto/internal/booking"
+	"alem-auto/internal/database"
+	"alem-auto/internal/inspection"
+)
+
+const centerColumns = `sc.id, sc.name, sc.address, sc.city, sc.latitude, sc.longitude, sc.phones, sc.make_ids,
+		sc.rating_avg, sc.rating_count, sc.created_at`
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// categoriesSQL lists the categories of active services of the center.
+const categoriesSQL = `ARRAY(
+		SELECT DISTINCT s.category FROM service_center_services s
+		WHERE s.service_center_id = sc.id AND s.is_active AND s.category IS NOT NULL
+		ORDER BY 1
+	)`
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Search returns centers matching the filter. With coordinates, only centers within the
+// radius are returned and distances are filled in.
+func (r *Repository) Search(ctx context.Context, f *SearchFilter) ([]*Center, error) {
+	query, args := searchQuery(f)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search service centers: %w", err)
+	}
+	defer rows.Close()
+	list := []*Center{}
+	for rows.Next() {
+		c, err := scanCenter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan service center: %w", err)
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// searchQuery builds the SQL and arguments of Search.
+func searchQuery(f *SearchFilter) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	from := `service_centers sc`
+	distance := `NULL::float8`
+	var where []string
+	if f.Lat != nil && f.Lon != nil {
+		arg(*f.Lat)
+		arg(*f.Lon)
+		b := boundingBox(*f.Lat, *f.Lon, f.RadiusKm)
+		from = `(SELECT sc.*, ` + distanceSQL + ` AS distance_km FROM service_centers sc
+			WHERE sc.latitude BETWEEN ` + arg(b.MinLat) + ` AND ` + arg(b.MaxLat) + `
+				AND sc.longitude BETWEEN ` + arg(b.MinLon) + ` AND ` + arg(b.MaxLon) + `) sc`
+		distance = `sc.distance_km`
+		where = append(where, `sc.distance_km <= `+arg(f.RadiusKm))
+	}
+	if f.City != nil {
+		where = append(where, `LOWER(sc.city) = LOWER(`+arg(*f.City)+`)`)
+	}
+	if f.Category != nil {
+		where = append(where, `EXISTS (SELECT 1 FROM service_center_services s
+			WHERE s.service_center_id = sc.id AND s.is_active AND LOWER(s.category) = LOWER(`+arg(*f.Category)+`))`)
+	}
+	if f.Query != nil {
+		p := arg("%" + likeEscaper.Replace(*f.Query) + "%")
+		where = append(where, `(sc.name ILIKE `+p+` ESCAPE '\' OR EXISTS (SELECT 1 FROM service_center_services s
+			WHERE s.service_center_id = sc.id AND s.is_active AND s.name ILIKE `+p+` ESCAPE '\'))`)
+	}
+	if f.MakeID != nil {
+		// A center without makes is multi-brand and serves every make.
+		where = append(where, `(cardinality(sc.make_ids) = 0 OR sc.make_ids @> ARRAY[`+arg(f.MakeID.String())+`::uuid])`)
+	}
+
+	query := `SELECT ` + centerColumns + `, ` + distance + `, ` + categoriesSQL + ` FROM ` + from
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	switch f.Sort {
+	case SortDistance:
+		query += ` ORDER BY sc.distance_km, sc.name`
+	case SortRating:
+		query += ` ORDER BY sc.rating_avg DESC NULLS LAST, sc.rating_count DESC, ` + distance + ` NULLS LAST, sc.name`
+	default:
+		query += ` ORDER BY sc.name`
+	}
+	query += ` LIMIT ` + arg(f.Limit) + ` OFFSET ` + arg(f.Offset)
+	return query, args
+}
+
+func scanCenter(rows *sql.Rows) (*Center, error) {
+	sc := &inspection.ServiceCenter{}
+	c := &Center{ServiceCenter: sc}
+	var makeIDs []string
+	var distance sql.NullFloat64
+	err := rows.Scan(
+		&sc.ID, &sc.Name, &sc.Address, &sc.City, &sc.Latitude, &sc.Longitude, pq.Array(&sc.Phones), pq.Array(&makeIDs),
+		&sc.RatingAvg, &sc.RatingCount, &sc.CreatedAt, &distance, pq.Array(&c.Categories),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if distance.Valid {
+		c.DistanceKm = &distance.Float64
+	}
+	if sc.Phones == nil {
+		sc.Phones = []string{}
+	}
+	if c.Categories == nil {
+		c.Categories = []string{}
+	}
+	sc.MakeIDs = make([]uuid.UUID, 0, len(makeIDs))
+	for _, v := range makeIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid make id %q: %w", v, err)
+		}
+		sc.MakeIDs = append(sc.MakeIDs, id)
+	}
+	return c, nil
+}
+
+// ListHours returns the weekly hours of the given centers.
+func (r *Repository) ListHours(ctx context.Context, centerIDs []uuid.UUID) (map[uuid.UUID][]booking.WorkingHours, error) {
+	ids := make([]string, len(centerIDs))
+	for i, id := range centerIDs {
+		ids[i] = id.String()
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT service_center_id, weekday, TO_CHAR(opens_at, 'HH24:MI'), TO_CHAR(closes_at, 'HH24:MI')
+		FROM service_center_hours WHERE service_center_id = ANY($1::uuid[])
+		ORDER BY service_center_id, weekday
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working hours: %w", err)
+	}
+	defer rows.Close()
+	hours := map[uuid.UUID][]booking.WorkingHours{}
+	for rows.Next() {
+		var centerID uuid.UUID
+		var h booking.WorkingHours
+		if err := rows.Scan(&centerID, &h.Weekday, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, fmt.Errorf("scan working hours: %w", err)
+		}
+		hours[centerID] = append(hours[centerID], h)
+	}
+	return hours, rows.Err()
+}
+
+// CountMakes returns how many of the given ids exist in the catalog.
+func (r *Repository) CountMakes(ctx context.Context, ids []uuid.UUID) (int, error) {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM makes WHERE id = ANY($1::uuid[])`, pq.Array(strIDs)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check makes: %w", err)
+	}
+	return n, nil
+}
+
+// CenterRole returns the role of the user at a service center, or "" if the user does not work there.
+func (r *Repository) CenterRole(ctx context.Context, userID, centerID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role FROM service_center_users WHERE user_id = $1 AND service_center_id = $2
+	`, userID, centerID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get center role: %w", err)
+	}
+	return role, nil
+}
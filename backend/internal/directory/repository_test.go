@@ -0,0 +1,38 @@
+package directory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchQueryMakeFilter(t *testing.T) {
+	makeID := uuid.New()
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   string
+	}{
+		{"no make", SearchFilter{Limit: 20}, ""},
+		{"make includes multi-brand centers", SearchFilter{MakeID: &makeID, Limit: 20},
+			`(cardinality(sc.make_ids) = 0 OR sc.make_ids @> ARRAY[$1::uuid])`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := searchQuery(&tt.filter)
+			if tt.want == "" {
+				if strings.Contains(query, "make_ids @>") {
+					t.Errorf("query filters by make: %s", query)
+				}
+				return
+			}
+			if !strings.Contains(query, tt.want) {
+				t.Errorf("query = %s, want it to contain %s", query, tt.want)
+			}
+			if args[0] != makeID.String() {
+				t.Errorf("args[0] = %v, want %s", args[0], makeID)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/booking"
+	"alem-auto/internal/inspection"
+)
+
+type Service struct {
+	repo              *Repository
+	inspectionService *inspection.Service
+	bookingService    *booking.Service
+}
+
+func NewService(repo *Repository, inspectionService *inspection.Service, bookingService *booking.Service) *Service {
+	return &Service{
+		repo:              repo,
+		inspectionService: inspectionService,
+		bookingService:    bookingService,
+	}
+}
+
+// Search finds service centers; around a point they are sorted by distance unless another order is asked for.
+func (s *Service) Search(ctx context.Context, filter SearchFilter) ([]*Center, error) {
+	if (filter.Lat == nil) != (filter.Lon == nil) {
+		return nil, fmt.Errorf("lat and lon must be given together")
+	}
+	near := filter.Lat != nil
+	if near {
+		if *filter.Lat < -90 || *filter.Lat > 90 || *filter.Lon < -180 || *filter.Lon > 180 {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+		if filter.RadiusKm <= 0 {
+			filter.RadiusKm = defaultRadiusKm
+		}
+		if filter.RadiusKm > maxRadiusKm {
+			return nil, fmt.Errorf("radius must not exceed %.0f km", maxRadiusKm)
+		}
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = SortName
+		if near {
+			filter.Sort = SortDistance
+		}
+	case SortDistance:
+		if !near {
+			return nil, fmt.Errorf("lat and lon are required to sort by distance")
+		}
+	case SortRating, SortName:
+	default:
+		return nil, fmt.Errorf("invalid sort: %s", filter.Sort)
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	list, err := s.repo.Search(ctx, &filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+	ids := make([]uuid.UUID, len(list))
+	for i, c := range list {
+		ids[i] = c.ID
+	}
+	hours, err := s.repo.ListHours(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		c.Hours = hours[c.ID]
+		if c.Hours == nil {
+			c.Hours = []booking.WorkingHours{}
+		}
+	}
+	return list, nil
+}
+
+// Get returns the public page of a center with its hours, holidays and active services, nil if not found.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Profile, error) {
+	sc, err := s.inspectionService.GetServiceCenterByID(ctx, id)
+	if err != nil || sc == nil {
+		return nil, err
+	}
+	schedule, err := s.bookingService.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.bookingService.ListCenterServices(ctx, id, booking.CenterServicesFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{
+		Center:   Center{ServiceCenter: sc, Categories: []string{}, Hours: []booking.WorkingHours{}},
+		Services: services,
+		Holidays: []*booking.Holiday{},
+	}
+	if schedule != nil {
+		if schedule.Hours != nil {
+			p.Hours = schedule.Hours
+		}
+		if schedule.Holidays != nil {
+			p.Holidays = schedule.Holidays
+		}
+	}
+	seen := map[string]bool{}
+	for _, cs := range services {
+		if cs.Category != nil && !seen[*cs.Category] {
+			seen[*cs.Category] = true
+			p.Categories = append(p.Categories, *cs.Category)
+		}
+	}
+	return p, nil
+}
+
+// Update changes the directory entry of a center; admins of the center and platform users may edit it.
+func (s *Service) Update(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, req *UpdateCenterRequest) (*Profile, error) {
+	if role != "platform" {
+		centerRole, err := s.repo.CenterRole(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if centerRole != "admin" {
+			return nil, fmt.Errorf("only service center admins can edit the center")
+		}
+	}
+	sc, err := s.inspectionService.GetServiceCenterByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, fmt.Errorf("service center not found")
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		sc.Name = name
+	}
+	if req.Address != nil {
+		sc.Address = optionalString(*req.Address)
+	}
+	if req.City != nil {
+		sc.City = optionalString(*req.City)
+	}
+	if req.Latitude != nil {
+		sc.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		sc.Longitude = req.Longitude
+	}
+	if (sc.Latitude == nil) != (sc.Longitude == nil) {
+		return nil, fmt.Errorf("latitude and longitude must be set together")
+	}
+	if req.Phones != nil {
+		sc.Phones = make([]string, 0, len(req.Phones))
+		for _, phone := range req.Phones {
+			if phone = strings.TrimSpace(phone); phone != "" {
+				sc.Phones = append(sc.Phones, phone)
+			}
+		}
+	}
+	if req.MakeIDs != nil {
+		n, err := s.repo.CountMakes(ctx, req.MakeIDs)
+		if err != nil {
+			return nil, err
+		}
+		if n != len(uniqueIDs(req.MakeIDs)) {
+			return nil, fmt.Errorf("unknown make in make_ids")
+		}
+		sc.MakeIDs = uniqueIDs(req.MakeIDs)
+	}
+
+	if err := s.inspectionService.UpdateServiceCenter(ctx, sc); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+func optionalString(v string) *string {
+	if v = strings.TrimSpace(v); v == "" {
+		return nil
+	}
+	return &v
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,316 @@
+package fines
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/media"
+)
+
+// evidenceLinkType is the asset link type of dispute evidence; the link id is the dispute's,
+// so a later dispute of the same fine starts without evidence.
+const evidenceLinkType = "fine_dispute"
+
+// disputeTransitions lists the statuses a reviewer may move a dispute to.
+var disputeTransitions = map[string][]string{
+	DisputeStatusSubmitted:   {DisputeStatusUnderReview, DisputeStatusAccepted, DisputeStatusRejected},
+	DisputeStatusUnderReview: {DisputeStatusAccepted, DisputeStatusRejected},
+}
+
+func canTransitionDispute(from, to string) bool {
+	for _, s := range disputeTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isDisputeOpen(d *Dispute) bool {
+	return d.Status == DisputeStatusSubmitted || d.Status == DisputeStatusUnderReview
+}
+
+// SubmitDispute opens a dispute for a fine of the user and moves the fine to disputed.
+func (s *Service) SubmitDispute(ctx context.Context, fineID, userID uuid.UUID, req *SubmitDisputeRequest) (*DisputeDetails, error) {
+	if _, ok := DisputeReasons[req.Reason]; !ok {
+		return nil, fmt.Errorf("invalid reason: %s", req.Reason)
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	if len(req.AssetIDs) > 0 && s.media == nil {
+		return nil, fmt.Errorf("media storage is not configured")
+	}
+
+	now := time.Now()
+	d := &Dispute{
+		ID:          uuid.New(),
+		FineID:      fineID,
+		UserID:      userID,
+		Reason:      req.Reason,
+		Text:        strings.TrimSpace(req.Text),
+		Status:      DisputeStatusSubmitted,
+		SubmittedAt: now,
+	}
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		f, err := s.repo.LockByID(ctx, tx, fineID)
+		if err != nil {
+			return err
+		}
+		if f == nil || f.UserID != userID {
+			return fmt.Errorf("fine not found")
+		}
+		switch f.Status {
+		case StatusPending, StatusOverdue:
+		case StatusDisputed:
+			return fmt.Errorf("fine is already disputed")
+		default:
+			return fmt.Errorf("fine cannot be disputed in status %s", f.Status)
+		}
+		if err := s.repo.CreateDispute(ctx, tx, d); err != nil {
+			return err
+		}
+		if err := s.repo.CreateDisputeEvent(ctx, tx, &DisputeEvent{
+			ID:          uuid.New(),
+			DisputeID:   d.ID,
+			Status:      DisputeStatusSubmitted,
+			ActorUserID: &userID,
+		}); err != nil {
+			return err
+		}
+		for _, assetID := range req.AssetIDs {
+			if err := s.media.LinkOwnAsset(ctx, tx, userID, assetID, evidenceLinkType, d.ID); err != nil {
+				return fmt.Errorf("failed to attach evidence: %w", err)
+			}
+		}
+		f.Status = StatusDisputed
+		return s.repo.UpdateTx(ctx, tx, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.disputeDetails(ctx, d)
+}
+
+// GetDispute returns the latest dispute of a fine of the user, or nil if there is none.
+func (s *Service) GetDispute(ctx context.Context, fineID, userID uuid.UUID) (*DisputeDetails, error) {
+	f, err := s.repo.GetByID(ctx, fineID)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil || f.UserID != userID {
+		return nil, nil
+	}
+	d, err := s.repo.GetLatestDisputeByFineID(ctx, fineID)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	return s.disputeDetails(ctx, d)
+}
+
+// AddDisputeEvidence attaches an uploaded media asset to an open dispute.
+func (s *Service) AddDisputeEvidence(ctx context.Context, fineID, userID uuid.UUID, assetID uuid.UUID) (*DisputeDetails, error) {
+	if s.media == nil {
+		return nil, fmt.Errorf("media storage is not configured")
+	}
+	details, err := s.GetDispute(ctx, fineID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, fmt.Errorf("dispute not found")
+	}
+	if !isDisputeOpen(details.Dispute) {
+		return nil, fmt.Errorf("dispute is already %s", details.Dispute.Status)
+	}
+	if err := s.media.LinkOwnAsset(ctx, s.repo.db, userID, assetID, evidenceLinkType, details.Dispute.ID); err != nil {
+		return nil, err
+	}
+	return s.disputeDetails(ctx, details.Dispute)
+}
+
+// ListDisputes returns disputes for reviewers, oldest first.
+func (s *Service) ListDisputes(ctx context.Context, status *string, limit, offset int) ([]*Dispute, error) {
+	return s.repo.ListDisputes(ctx, status, limit, offset)
+}
+
+// ReviewDispute records a reviewer's decision. An accepted dispute cancels the fine,
+// a rejected one returns it to pending (or overdue if the deadline has passed).
+func (s *Service) ReviewDispute(ctx context.Context, disputeID, reviewerID uuid.UUID, req *ReviewDisputeRequest) (*DisputeDetails, error) {
+	var d *Dispute
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		d, err = s.repo.LockDispute(ctx, tx, disputeID)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			return fmt.Errorf("dispute not found")
+		}
+		if !canTransitionDispute(d.Status, req.Status) {
+			return fmt.Errorf("cannot move dispute from %s to %s", d.Status, req.Status)
+		}
+
+		now := time.Now()
+		d.Status = req.Status
+		if d.ReviewedAt == nil {
+			d.ReviewedAt = &now
+		}
+		var comment *string
+		if c := strings.TrimSpace(req.Comment); c != "" {
+			comment = &c
+		}
+		if req.Status == DisputeStatusAccepted || req.Status == DisputeStatusRejected {
+			d.ResolvedAt = &now
+			d.ResolutionComment = comment
+
+			f, err := s.repo.LockByID(ctx, tx, d.FineID)
+			if err != nil {
+				return err
+			}
+			if f != nil && f.Status == StatusDisputed {
+				if req.Status == DisputeStatusAccepted {
+					f.Status = StatusCancelled
+				} else {
+					f.Status = StatusPending
+					if isPastDue(f, now) {
+						f.Status = StatusOverdue
+					}
+				}
+				if err := s.repo.UpdateTx(ctx, tx, f); err != nil {
+					return err
+				}
+			}
+		}
+		if err := s.repo.UpdateDispute(ctx, tx, d); err != nil {
+			return err
+		}
+		return s.repo.CreateDisputeEvent(ctx, tx, &DisputeEvent{
+			ID:          uuid.New(),
+			DisputeID:   d.ID,
+			Status:      req.Status,
+			Comment:     comment,
+			ActorUserID: &reviewerID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.disputeDetails(ctx, d)
+}
+
+func (s *Service) disputeDetails(ctx context.Context, d *Dispute) (*DisputeDetails, error) {
+	history, err := s.repo.ListDisputeEvents(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	details := &DisputeDetails{Dispute: d, History: history}
+	if s.media != nil {
+		evidence, err := s.media.GetAssetsByLink(ctx, evidenceLinkType, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		details.Evidence = evidence
+	}
+	if details.History == nil {
+		details.History = []*DisputeEvent{}
+	}
+	if details.Evidence == nil {
+		details.Evidence = []*media.Asset{}
+	}
+	return details, nil
+}
+
+var complaintTemplate = template.Must(template.New("complaint").Parse(`В ________________________________
+(наименование органа, вынесшего постановление)
+
+от: ____________________________ (ФИО)
+ИИН: {{if .IIN}}{{.IIN}}{{else}}____________{{end}}
+адрес: ____________________________
+телефон: __________________________
+
+ЖАЛОБА
+на постановление по делу об административном правонарушении
+
+{{.IssuedAt}} в отношении меня вынесено постановление о наложении административного штрафа
+{{- if .Article}} по ст. {{.Article}} КоАП РК{{end}} в размере {{.Amount}} {{.Currency}}
+{{- if .Plate}} (транспортное средство с гос. номером {{.Plate}}){{end}}.
+Описание нарушения: {{.Description}}.
+
+С постановлением не согласен(на), так как {{.ReasonText}}.
+
+{{.Text}}
+{{if .EvidenceCount}}
+К жалобе прилагаю доказательства: {{.EvidenceCount}} файл(ов).
+{{end}}
+На основании изложенного прошу отменить постановление и прекратить производство по делу.
+
+Дата: {{.Today}}                Подпись: ____________
+`))
+
+type complaintData struct {
+	IIN           string
+	IssuedAt      string
+	Article       string
+	Amount        string
+	Currency      string
+	Plate         string
+	Description   string
+	ReasonText    string
+	Text          string
+	EvidenceCount int
+	Today         string
+}
+
+// ComplaintLetter renders a prefilled complaint letter for the latest dispute of a fine.
+// Personal details the service does not store are left as blanks to fill in by hand.
+func (s *Service) ComplaintLetter(ctx context.Context, fineID, userID uuid.UUID) (string, error) {
+	f, err := s.repo.GetByID(ctx, fineID)
+	if err != nil {
+		return "", err
+	}
+	if f == nil || f.UserID != userID {
+		return "", fmt.Errorf("fine not found")
+	}
+	details, err := s.GetDispute(ctx, fineID, userID)
+	if err != nil {
+		return "", err
+	}
+	if details == nil {
+		return "", fmt.Errorf("dispute not found")
+	}
+
+	data := complaintData{
+		IssuedAt:      f.IssuedAt.Format("02.01.2006"),
+		Amount:        fmt.Sprintf("%.2f", f.Amount),
+		Currency:      f.Currency,
+		Description:   strings.TrimRight(f.Description, "."),
+		ReasonText:    DisputeReasons[details.Dispute.Reason],
+		Text:          details.Dispute.Text,
+		EvidenceCount: len(details.Evidence),
+		Today:         time.Now().Format("02.01.2006"),
+	}
+	if data.IIN, err = s.repo.GetUserIIN(ctx, userID); err != nil {
+		return "", err
+	}
+	if f.Article != nil {
+		data.Article = *f.Article
+	}
+	if f.VehicleID != nil {
+		if data.Plate, err = s.repo.GetVehiclePlate(ctx, *f.VehicleID); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := complaintTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render letter: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,78 @@
+package fines
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseRecorder receives paid-fine events and keeps a linked expense entry for each
+// paid fine. Both methods must be idempotent: they are repeated on retries.
+type ExpenseRecorder interface {
+	// FinePaid creates or updates the expense entry of a paid fine and returns its ID.
+	FinePaid(ctx context.Context, f *Fine) (uuid.UUID, error)
+	// FineUnpaid removes the expense entry of a fine that was un-paid or deleted.
+	FineUnpaid(ctx context.Context, fineID uuid.UUID) error
+}
+
+// SetExpenseRecorder enables expense entries for paid fines.
+func (s *Service) SetExpenseRecorder(r ExpenseRecorder) {
+	s.expenses = r
+}
+
+// syncExpense brings the expense entry of f in line with its status.
+func (s *Service) syncExpense(ctx context.Context, f *Fine) error {
+	if s.expenses == nil {
+		return nil
+	}
+	if f.Status == StatusPaid {
+		recordID, err := s.expenses.FinePaid(ctx, f)
+		if err != nil {
+			return fmt.Errorf("failed to record fine expense: %w", err)
+		}
+		if f.ExpenseRecordID == nil || *f.ExpenseRecordID != recordID {
+			if err := s.repo.SetExpenseRecordID(ctx, f.ID, &recordID); err != nil {
+				return err
+			}
+			f.ExpenseRecordID = &recordID
+		}
+		return nil
+	}
+	if f.ExpenseRecordID == nil {
+		return nil
+	}
+	if err := s.expenses.FineUnpaid(ctx, f.ID); err != nil {
+		return fmt.Errorf("failed to remove fine expense: %w", err)
+	}
+	if err := s.repo.SetExpenseRecordID(ctx, f.ID, nil); err != nil {
+		return err
+	}
+	f.ExpenseRecordID = nil
+	return nil
+}
+
+// ExpenseTitle is the description used for the expense entry and payment of a fine.
+func (f *Fine) ExpenseTitle() string {
+	if f.Article != nil {
+		return fmt.Sprintf("Штраф по ст. %s: %s", *f.Article, f.Description)
+	}
+	return f.Description
+}
+
+// ExpenseAmount is what was actually paid for the fine.
+func (f *Fine) ExpenseAmount() float64 {
+	if f.PaidAmount != nil {
+		return *f.PaidAmount
+	}
+	return f.Amount
+}
+
+// ExpenseDate is the payment date in YYYY-MM-DD, as stored in expense entries.
+func (f *Fine) ExpenseDate() string {
+	if f.PaidAt != nil {
+		return f.PaidAt.Format("2006-01-02")
+	}
+	return time.Now().Format("2006-01-02")
+}
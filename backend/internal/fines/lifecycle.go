@@ -0,0 +1,55 @@
+package fines
+
+import (
+	"math"
+	"time"
+
+	"alem-auto/config"
+)
+
+// applyTerms sets the payment deadline and the reduced-payment window from IssuedAt.
+// Terms are stored on the fine so that later config changes don't move existing deadlines.
+func applyTerms(f *Fine, terms config.FinesConfig) {
+	issued := dateOnly(f.IssuedAt)
+	if terms.PaymentTermDays > 0 {
+		due := issued.AddDate(0, 0, terms.PaymentTermDays)
+		f.DueAt = &due
+	}
+	if terms.DiscountDays > 0 && terms.DiscountPercent > 0 {
+		until := issued.AddDate(0, 0, terms.DiscountDays)
+		f.DiscountUntil = &until
+		f.DiscountPercent = terms.DiscountPercent
+	}
+}
+
+// computePayment fills the computed amount fields of f as of now.
+func computePayment(f *Fine, now time.Time) {
+	f.RemainingAmount = 0
+	f.AmountToPay = 0
+	f.Savings = 0
+	f.DiscountAvailable = false
+	if f.Status == StatusPaid || f.Status == StatusCancelled {
+		return
+	}
+	f.RemainingAmount = f.Amount
+	f.AmountToPay = f.Amount
+	if f.Status != StatusOverdue && f.DiscountUntil != nil && f.DiscountPercent > 0 &&
+		!dateOnly(now).After(dateOnly(*f.DiscountUntil)) {
+		f.DiscountAvailable = true
+		f.AmountToPay = roundMoney(f.Amount * (1 - f.DiscountPercent/100))
+		f.Savings = roundMoney(f.RemainingAmount - f.AmountToPay)
+	}
+}
+
+// isPastDue reports whether the payment deadline has passed as of now.
+func isPastDue(f *Fine, now time.Time) bool {
+	return f.DueAt != nil && dateOnly(now).After(dateOnly(*f.DueAt))
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
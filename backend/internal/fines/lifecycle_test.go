@@ -0,0 +1,69 @@
+package fines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/config"
+)
+
+func TestComputePaymentDiscountWindow(t *testing.T) {
+	terms := config.FinesConfig{PaymentTermDays: 30, DiscountDays: 7, DiscountPercent: 50}
+	f := &Fine{Amount: 14768, Status: StatusPending, IssuedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	applyTerms(f, terms)
+
+	if got := f.DueAt.Format("2006-01-02"); got != "2026-03-31" {
+		t.Fatalf("expected due_at 2026-03-31, got %s", got)
+	}
+
+	computePayment(f, time.Date(2026, 3, 8, 18, 0, 0, 0, time.UTC))
+	if !f.DiscountAvailable || f.AmountToPay != 7384 || f.Savings != 7384 {
+		t.Fatalf("expected discount on the last day of the window, got %+v", f)
+	}
+
+	computePayment(f, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC))
+	if f.DiscountAvailable || f.AmountToPay != 14768 || f.Savings != 0 {
+		t.Fatalf("expected full amount after the window, got %+v", f)
+	}
+	if isPastDue(f, time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Fatal("fine must not be past due on the due date")
+	}
+	if !isPastDue(f, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("fine must be past due the day after the due date")
+	}
+
+	f.Status = StatusPaid
+	computePayment(f, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	if f.RemainingAmount != 0 || f.AmountToPay != 0 {
+		t.Fatalf("expected nothing to pay for a paid fine, got %+v", f)
+	}
+}
+
+func TestUpdateRejectsSystemStatuses(t *testing.T) {
+	s := &Service{}
+	for _, status := range []string{StatusDisputed, StatusPaid} {
+		status := status
+		f, err := s.Update(context.Background(), uuid.New(), uuid.New(), &UpdateFineRequest{Status: &status})
+		if err == nil || f != nil {
+			t.Fatalf("expected status %s to be rejected, got %+v, %v", status, f, err)
+		}
+	}
+}
+
+func TestApplyRegistryStatusKeepsLocalPayment(t *testing.T) {
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	paidAt, paid := now.Add(-time.Hour), 7384.0
+	f := &Fine{Amount: 14768, Status: StatusPaid, PaidAt: &paidAt, PaidAmount: &paid}
+	applyRegistryStatus(f, &RegistryFine{Amount: 14768, Status: "unpaid"}, now)
+	if f.Status != StatusPaid || f.PaidAt != &paidAt || f.PaidAmount != &paid {
+		t.Fatalf("expected a locally paid fine to stay paid, got %+v", f)
+	}
+
+	f = &Fine{Amount: 14768, Status: StatusPending}
+	applyRegistryStatus(f, &RegistryFine{Amount: 14768, Status: "paid"}, now)
+	if f.Status != StatusPaid || f.PaidAt == nil || f.PaidAmount == nil || *f.PaidAmount != 14768 {
+		t.Fatalf("expected the registry payment with its amount, got %+v", f)
+	}
+}
@@ -4,12 +4,41 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"alem-auto/internal/media"
 )
 
 const (
 	StatusPending  = "pending"
 	StatusPaid     = "paid"
 	StatusDisputed = "disputed"
+	StatusOverdue   = "overdue"   // выставляется фоновой задачей после due_at
+	StatusCancelled = "cancelled" // отменен по итогам обжалования
+)
+
+const (
+	DisputeStatusSubmitted   = "submitted"
+	DisputeStatusUnderReview = "under_review"
+	DisputeStatusAccepted    = "accepted"
+	DisputeStatusRejected    = "rejected"
+)
+
+// DisputeReasons are the accepted dispute reason codes with their letter wording.
+var DisputeReasons = map[string]string{
+	"not_driver":       "в момент фиксации правонарушения транспортным средством управляло другое лицо",
+	"vehicle_sold":     "на момент фиксации правонарушения транспортное средство мне не принадлежало",
+	"wrong_plate":      "государственный номер распознан неверно",
+	"no_sign":          "дорожный знак отсутствовал или не был виден",
+	"already_paid":     "штраф уже был оплачен",
+	"procedure_breach": "при вынесении постановления нарушен установленный порядок",
+	"other":            "иные обстоятельства, изложенные ниже",
+}
+
+const (
+	SourceManual = "manual"
+
+	SyncStatusRunning = "running"
+	SyncStatusSuccess = "success"
+	SyncStatusFailed  = "failed"
 )
 
 // Fine represents a traffic or administrative fine.
@@ -26,6 +55,23 @@ type Fine struct {
 	Status      string     `json:"status"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	DueAt           *time.Time `json:"due_at,omitempty"`
+	DiscountUntil   *time.Time `json:"discount_until,omitempty"`
+	DiscountPercent float64    `json:"discount_percent"`
+	PaidAmount      *float64   `json:"paid_amount,omitempty"`
+
+	Source     string     `json:"source"` // manual or the registry provider name
+	ExternalID *string    `json:"external_id,omitempty"`
+	SyncedAt   *time.Time `json:"synced_at,omitempty"`
+
+	ExpenseRecordID *uuid.UUID `json:"expense_record_id,omitempty"` // service_records entry of a paid fine
+
+	// Computed on read, not stored.
+	RemainingAmount   float64 `json:"remaining_amount"`
+	AmountToPay       float64 `json:"amount_to_pay"` // with the discount applied if paid today
+	Savings           float64 `json:"savings"`       // how much paying today saves
+	DiscountAvailable bool    `json:"discount_available"`
 }
 
 // CreateFineRequest is the request body for creating a fine.
@@ -38,10 +84,10 @@ type CreateFineRequest struct {
 	IssuedAt    string     `json:"issued_at" binding:"required"` // ISO date YYYY-MM-DD
 }
 
-// UpdateFineRequest is the request body for updating a fine (e.g. mark paid).
+// UpdateFineRequest is the request body for updating a fine. Payments are recorded by the
+// payment flow, not by the client.
 type UpdateFineRequest struct {
-	Status *string `json:"status,omitempty"` // paid, disputed
-	PaidAt *string `json:"paid_at,omitempty"` // ISO datetime, set when status=paid
+	Status *string `json:"status,omitempty"` // pending
 }
 
 // ListFinesFilter holds query filters for listing fines.
@@ -51,3 +97,136 @@ type ListFinesFilter struct {
 	Limit     int
 	Offset    int
 }
+
+// SummaryFilter narrows the fines summary. From and To bound issued_at (inclusive).
+type SummaryFilter struct {
+	VehicleID *uuid.UUID
+	From      *time.Time
+	To        *time.Time
+}
+
+// Totals aggregates a group of fines in one currency. Paid is what was actually paid,
+// Outstanding is the face amount of fines still to pay (pending, overdue or disputed).
+type Totals struct {
+	Currency    string  `json:"currency"`
+	Count       int     `json:"count"`
+	Amount      float64 `json:"amount"`
+	Paid        float64 `json:"paid"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+type StatusTotals struct {
+	Status string `json:"status"`
+	Totals
+}
+
+type VehicleTotals struct {
+	VehicleID    *uuid.UUID `json:"vehicle_id,omitempty"` // nil for fines without a vehicle
+	LicensePlate *string    `json:"license_plate,omitempty"`
+	Totals
+}
+
+type ArticleTotals struct {
+	Article     *string `json:"article,omitempty"` // nil for fines without an article
+	Description string  `json:"description"`       // most common description for the article
+	Totals
+}
+
+type MonthTotals struct {
+	Month string `json:"month"` // YYYY-MM of issued_at
+	Totals
+}
+
+// Summary is the fines dashboard of a user. Amounts in different currencies are never added
+// together: every group has one entry per currency.
+type Summary struct {
+	Totals        []*Totals        `json:"totals"`
+	ByStatus      []*StatusTotals  `json:"by_status"`
+	ByVehicle     []*VehicleTotals `json:"by_vehicle"`
+	ByArticle     []*ArticleTotals `json:"by_article"`     // most expensive first
+	ByMonth       []*MonthTotals   `json:"by_month"`       // chronological
+	TopViolations []*ArticleTotals `json:"top_violations"` // most frequent first
+}
+
+// SyncRun is one registry sync attempt for a user.
+type SyncRun struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Provider   string     `json:"provider"`
+	Status     string     `json:"status"` // running, success, failed
+	Fetched    int        `json:"fetched"`
+	Created    int        `json:"created"`
+	Updated    int        `json:"updated"`
+	Matched    int        `json:"matched"` // manual fines linked to a registry record
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// SyncStatusResponse is returned by the sync status endpoint.
+type SyncStatusResponse struct {
+	Enabled  bool       `json:"enabled"`
+	Provider string     `json:"provider,omitempty"`
+	LastRun  *SyncRun   `json:"last_run,omitempty"`
+	Runs     []*SyncRun `json:"runs"`
+}
+
+// SyncTarget is a vehicle of a user to look up in the registry.
+type SyncTarget struct {
+	UserID       uuid.UUID
+	IIN          *string
+	VehicleID    uuid.UUID
+	LicensePlate string
+}
+
+// Dispute is a user's appeal against a fine. Evidence is stored as media assets
+// linked to the dispute with link_type "fine_dispute".
+type Dispute struct {
+	ID                uuid.UUID  `json:"id"`
+	FineID            uuid.UUID  `json:"fine_id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	Reason            string     `json:"reason"`
+	Text              string     `json:"text"`
+	Status            string     `json:"status"` // submitted, under_review, accepted, rejected
+	ResolutionComment *string    `json:"resolution_comment,omitempty"`
+	SubmittedAt       time.Time  `json:"submitted_at"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// DisputeEvent is an entry of the dispute status history.
+type DisputeEvent struct {
+	ID          uuid.UUID  `json:"id"`
+	DisputeID   uuid.UUID  `json:"dispute_id"`
+	Status      string     `json:"status"`
+	Comment     *string    `json:"comment,omitempty"`
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// DisputeDetails is a dispute with its history and evidence.
+type DisputeDetails struct {
+	Dispute  *Dispute        `json:"dispute"`
+	History  []*DisputeEvent `json:"history"`
+	Evidence []*media.Asset  `json:"evidence"`
+}
+
+// SubmitDisputeRequest is the request body for disputing a fine.
+type SubmitDisputeRequest struct {
+	Reason   string      `json:"reason" binding:"required"`
+	Text     string      `json:"text" binding:"required"`
+	AssetIDs []uuid.UUID `json:"asset_ids,omitempty"` // evidence uploaded via /media
+}
+
+// AddEvidenceRequest is the request body for attaching evidence to a dispute.
+type AddEvidenceRequest struct {
+	AssetID uuid.UUID `json:"asset_id" binding:"required"`
+}
+
+// ReviewDisputeRequest is the request body for a reviewer's decision.
+type ReviewDisputeRequest struct {
+	Status  string `json:"status" binding:"required"` // under_review, accepted, rejected
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,103 @@
+package fines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RegistryQuery identifies whose fines to look up in an external registry.
+type RegistryQuery struct {
+	IIN          string
+	LicensePlate string
+}
+
+// RegistryFine is a fine as reported by an external registry.
+type RegistryFine struct {
+	ExternalID   string     `json:"external_id"`
+	LicensePlate string     `json:"license_plate"`
+	Amount       float64    `json:"amount"`
+	Currency     string     `json:"currency"`
+	Article      string     `json:"article"`
+	Description  string     `json:"description"`
+	IssuedAt     string     `json:"issued_at"` // YYYY-MM-DD
+	Status       string     `json:"status"`    // unpaid, paid
+	PaidAt       *time.Time `json:"paid_at,omitempty"`
+	PaidAmount   *float64   `json:"paid_amount,omitempty"` // what was paid, if the registry reports it
+}
+
+// Provider fetches fines from a government registry or an aggregator.
+type Provider interface {
+	Name() string
+	FetchFines(ctx context.Context, q RegistryQuery) ([]RegistryFine, error)
+}
+
+// IINHeader carries the owner's IIN to the registry. It is not put in the URL, where it would
+// end up in access logs.
+const IINHeader = "X-IIN"
+
+// HTTPProvider talks to a registry exposing GET {base}/fines?plate= with the IIN in IINHeader.
+type HTTPProvider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPProvider(name, baseURL, apiKey string, timeout time.Duration) *HTTPProvider {
+	if name == "" {
+		name = "registry"
+	}
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	return &HTTPProvider{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPProvider) Name() string {
+	return p.name
+}
+
+func (p *HTTPProvider) FetchFines(ctx context.Context, q RegistryQuery) ([]RegistryFine, error) {
+	params := url.Values{}
+	if q.LicensePlate != "" {
+		params.Set("plate", q.LicensePlate)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/fines?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if q.IIN != "" {
+		req.Header.Set(IINHeader, q.IIN)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Fines []RegistryFine `json:"fines"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return body.Fines, nil
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"alem-auto/internal/database"
 )
 
+const fineColumns = `id, user_id, vehicle_id, amount, currency, article, description, issued_at, paid_at, status, created_at, updated_at,
+		due_at, discount_until, discount_percent, paid_amount, source, external_id, synced_at, expense_record_id`
+
 type Repository struct {
 	db *database.DB
 }
@@ -17,12 +21,73 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFine(row rowScanner) (*Fine, error) {
+	f := &Fine{}
+	var vehicleID sql.NullString
+	var article sql.NullString
+	var paidAt, dueAt, discountUntil, syncedAt sql.NullTime
+	var paidAmount sql.NullFloat64
+	var externalID, expenseRecordID sql.NullString
+	err := row.Scan(
+		&f.ID, &f.UserID, &vehicleID, &f.Amount, &f.Currency, &article, &f.Description,
+		&f.IssuedAt, &paidAt, &f.Status, &f.CreatedAt, &f.UpdatedAt,
+		&dueAt, &discountUntil, &f.DiscountPercent, &paidAmount, &f.Source, &externalID, &syncedAt,
+		&expenseRecordID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if vehicleID.Valid {
+		if v, err := uuid.Parse(vehicleID.String); err == nil {
+			f.VehicleID = &v
+		}
+	}
+	if article.Valid {
+		f.Article = &article.String
+	}
+	if paidAt.Valid {
+		t := paidAt.Time
+		f.PaidAt = &t
+	}
+	if dueAt.Valid {
+		t := dueAt.Time
+		f.DueAt = &t
+	}
+	if discountUntil.Valid {
+		t := discountUntil.Time
+		f.DiscountUntil = &t
+	}
+	if paidAmount.Valid {
+		v := paidAmount.Float64
+		f.PaidAmount = &v
+	}
+	if externalID.Valid {
+		f.ExternalID = &externalID.String
+	}
+	if syncedAt.Valid {
+		t := syncedAt.Time
+		f.SyncedAt = &t
+	}
+	if expenseRecordID.Valid {
+		if v, err := uuid.Parse(expenseRecordID.String); err == nil {
+			f.ExpenseRecordID = &v
+		}
+	}
+	return f, nil
+}
+
 func (r *Repository) Create(ctx context.Context, f *Fine) error {
 	query := `
-		INSERT INTO fines (id, user_id, vehicle_id, amount, currency, article, description, issued_at, paid_at, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		INSERT INTO fines (id, user_id, vehicle_id, amount, currency, article, description, issued_at, paid_at, status,
+			due_at, discount_until, discount_percent, paid_amount, source, external_id, synced_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
 	`
-	var vehicleID, article, paidAt interface{}
+	var vehicleID, article, paidAt, dueAt, discountUntil, paidAmount, externalID, syncedAt interface{}
 	if f.VehicleID != nil {
 		vehicleID = *f.VehicleID
 	}
@@ -32,8 +97,27 @@ func (r *Repository) Create(ctx context.Context, f *Fine) error {
 	if f.PaidAt != nil {
 		paidAt = *f.PaidAt
 	}
+	if f.DueAt != nil {
+		dueAt = *f.DueAt
+	}
+	if f.DiscountUntil != nil {
+		discountUntil = *f.DiscountUntil
+	}
+	if f.PaidAmount != nil {
+		paidAmount = *f.PaidAmount
+	}
+	if f.ExternalID != nil {
+		externalID = *f.ExternalID
+	}
+	if f.SyncedAt != nil {
+		syncedAt = *f.SyncedAt
+	}
+	if f.Source == "" {
+		f.Source = SourceManual
+	}
 	_, err := r.db.ExecContext(ctx, query,
 		f.ID, f.UserID, vehicleID, f.Amount, f.Currency, article, f.Description, f.IssuedAt, paidAt, f.Status,
+		dueAt, discountUntil, f.DiscountPercent, paidAmount, f.Source, externalID, syncedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create fine: %w", err)
@@ -42,44 +126,19 @@ func (r *Repository) Create(ctx context.Context, f *Fine) error {
 }
 
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*Fine, error) {
-	query := `
-		SELECT id, user_id, vehicle_id, amount, currency, article, description, issued_at, paid_at, status, created_at, updated_at
-		FROM fines WHERE id = $1
-	`
-	f := &Fine{}
-	var vehicleID sql.NullString
-	var article sql.NullString
-	var paidAt sql.NullTime
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&f.ID, &f.UserID, &vehicleID, &f.Amount, &f.Currency, &article, &f.Description,
-		&f.IssuedAt, &paidAt, &f.Status, &f.CreatedAt, &f.UpdatedAt,
-	)
+	query := `SELECT ` + fineColumns + ` FROM fines WHERE id = $1`
+	f, err := scanFine(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get fine: %w", err)
 	}
-	if vehicleID.Valid {
-		if v, err := uuid.Parse(vehicleID.String); err == nil {
-			f.VehicleID = &v
-		}
-	}
-	if article.Valid {
-		f.Article = &article.String
-	}
-	if paidAt.Valid {
-		t := paidAt.Time
-		f.PaidAt = &t
-	}
 	return f, nil
 }
 
 func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter ListFinesFilter) ([]*Fine, error) {
-	query := `
-		SELECT id, user_id, vehicle_id, amount, currency, article, description, issued_at, paid_at, status, created_at, updated_at
-		FROM fines WHERE user_id = $1
-	`
+	query := `SELECT ` + fineColumns + ` FROM fines WHERE user_id = $1`
 	args := []interface{}{userID}
 	pos := 2
 	if filter.VehicleID != nil {
@@ -108,48 +167,198 @@ func (r *Repository) ListByUserID(ctx context.Context, userID uuid.UUID, filter
 
 	var list []*Fine
 	for rows.Next() {
-		f := &Fine{}
-		var vehicleID sql.NullString
-		var article sql.NullString
-		var paidAt sql.NullTime
-		err := rows.Scan(
-			&f.ID, &f.UserID, &vehicleID, &f.Amount, &f.Currency, &article, &f.Description,
-			&f.IssuedAt, &paidAt, &f.Status, &f.CreatedAt, &f.UpdatedAt,
-		)
+		f, err := scanFine(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scan fine: %w", err)
 		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// totalsColumns aggregates count, face amount, paid and outstanding amounts of a group; the
+// group must include f.currency.
+const totalsColumns = `f.currency, COUNT(*), COALESCE(SUM(amount), 0),
+		COALESCE(SUM(COALESCE(paid_amount, amount)) FILTER (WHERE status = 'paid'), 0),
+		COALESCE(SUM(amount) FILTER (WHERE status IN ('pending', 'overdue', 'disputed')), 0)`
+
+// totalsDest returns the scan destinations of totalsColumns.
+func totalsDest(t *Totals) []interface{} {
+	return []interface{}{&t.Currency, &t.Count, &t.Amount, &t.Paid, &t.Outstanding}
+}
+
+func summaryWhere(userID uuid.UUID, filter SummaryFilter) (string, []interface{}) {
+	where := `f.user_id = $1`
+	args := []interface{}{userID}
+	if filter.VehicleID != nil {
+		args = append(args, *filter.VehicleID)
+		where += fmt.Sprintf(" AND f.vehicle_id = $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, filter.From.Format("2006-01-02"))
+		where += fmt.Sprintf(" AND f.issued_at >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, filter.To.Format("2006-01-02"))
+		where += fmt.Sprintf(" AND f.issued_at <= $%d", len(args))
+	}
+	return where, args
+}
+
+// Summary aggregates the fines of a user by status, vehicle, article and month, per currency.
+func (r *Repository) Summary(ctx context.Context, userID uuid.UUID, filter SummaryFilter, topN int) (*Summary, error) {
+	where, args := summaryWhere(userID, filter)
+	sum := &Summary{
+		Totals:        []*Totals{},
+		ByStatus:      []*StatusTotals{},
+		ByVehicle:     []*VehicleTotals{},
+		ByArticle:     []*ArticleTotals{},
+		ByMonth:       []*MonthTotals{},
+		TopViolations: []*ArticleTotals{},
+	}
+
+	err := r.queryTotals(ctx, `SELECT `+totalsColumns+` FROM fines f WHERE `+where+
+		` GROUP BY f.currency ORDER BY f.currency`, args, func(row rowScanner) error {
+		t := &Totals{}
+		if err := row.Scan(totalsDest(t)...); err != nil {
+			return err
+		}
+		sum.Totals = append(sum.Totals, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.queryTotals(ctx, `SELECT f.status, `+totalsColumns+` FROM fines f WHERE `+where+
+		` GROUP BY f.status, f.currency ORDER BY 4 DESC`, args, func(row rowScanner) error {
+		st := &StatusTotals{}
+		if err := row.Scan(append([]interface{}{&st.Status}, totalsDest(&st.Totals)...)...); err != nil {
+			return err
+		}
+		sum.ByStatus = append(sum.ByStatus, st)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.queryTotals(ctx, `SELECT f.vehicle_id, v.license_plate, `+totalsColumns+`
+		FROM fines f LEFT JOIN vehicles v ON v.id = f.vehicle_id
+		WHERE `+where+` GROUP BY f.vehicle_id, v.license_plate, f.currency ORDER BY 5 DESC`, args, func(row rowScanner) error {
+		vt := &VehicleTotals{}
+		var vehicleID, plate sql.NullString
+		if err := row.Scan(append([]interface{}{&vehicleID, &plate}, totalsDest(&vt.Totals)...)...); err != nil {
+			return err
+		}
 		if vehicleID.Valid {
-			if v, err := uuid.Parse(vehicleID.String); err == nil {
-				f.VehicleID = &v
+			if id, err := uuid.Parse(vehicleID.String); err == nil {
+				vt.VehicleID = &id
 			}
 		}
-		if article.Valid {
-			f.Article = &article.String
+		if plate.Valid {
+			vt.LicensePlate = &plate.String
 		}
-		if paidAt.Valid {
-			t := paidAt.Time
-			f.PaidAt = &t
+		sum.ByVehicle = append(sum.ByVehicle, vt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	articleQuery := `SELECT f.article, MODE() WITHIN GROUP (ORDER BY f.description), ` + totalsColumns + `
+		FROM fines f WHERE ` + where + ` GROUP BY f.article, f.currency`
+	scanArticle := func(dst *[]*ArticleTotals) func(row rowScanner) error {
+		return func(row rowScanner) error {
+			at := &ArticleTotals{}
+			var article sql.NullString
+			if err := row.Scan(append([]interface{}{&article, &at.Description}, totalsDest(&at.Totals)...)...); err != nil {
+				return err
+			}
+			if article.Valid {
+				at.Article = &article.String
+			}
+			*dst = append(*dst, at)
+			return nil
 		}
-		list = append(list, f)
 	}
-	return list, rows.Err()
+	if err := r.queryTotals(ctx, articleQuery+` ORDER BY 5 DESC, 4 DESC`, args, scanArticle(&sum.ByArticle)); err != nil {
+		return nil, err
+	}
+	if topN <= 0 {
+		topN = 5
+	}
+	topQuery := articleQuery + fmt.Sprintf(` ORDER BY 4 DESC, 5 DESC LIMIT %d`, topN)
+	if err := r.queryTotals(ctx, topQuery, args, scanArticle(&sum.TopViolations)); err != nil {
+		return nil, err
+	}
+
+	err = r.queryTotals(ctx, `SELECT TO_CHAR(f.issued_at, 'YYYY-MM') AS month, `+totalsColumns+`
+		FROM fines f WHERE `+where+` GROUP BY month, f.currency ORDER BY month, f.currency`, args, func(row rowScanner) error {
+		mt := &MonthTotals{}
+		if err := row.Scan(append([]interface{}{&mt.Month}, totalsDest(&mt.Totals)...)...); err != nil {
+			return err
+		}
+		sum.ByMonth = append(sum.ByMonth, mt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+func (r *Repository) queryTotals(ctx context.Context, query string, args []interface{}, scan func(row rowScanner) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to summarize fines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("scan fines summary: %w", err)
+		}
+	}
+	return rows.Err()
 }
 
 func (r *Repository) Update(ctx context.Context, f *Fine) error {
+	return r.update(ctx, r.db, f)
+}
+
+// UpdateTx updates a fine inside an open transaction.
+func (r *Repository) UpdateTx(ctx context.Context, tx *sql.Tx, f *Fine) error {
+	return r.update(ctx, tx, f)
+}
+
+func (r *Repository) update(ctx context.Context, q database.Querier, f *Fine) error {
 	query := `
-		UPDATE fines SET amount = $2, currency = $3, article = $4, description = $5, issued_at = $6, paid_at = $7, status = $8, updated_at = NOW()
+		UPDATE fines SET amount = $2, currency = $3, article = $4, description = $5, issued_at = $6, paid_at = $7, status = $8,
+			paid_amount = $9, vehicle_id = $10, source = $11, external_id = $12, synced_at = $13, updated_at = NOW()
 		WHERE id = $1
 	`
-	var article, paidAt interface{}
+	var article, paidAt, paidAmount, vehicleID, externalID, syncedAt interface{}
 	if f.Article != nil {
 		article = *f.Article
 	}
 	if f.PaidAt != nil {
 		paidAt = *f.PaidAt
 	}
-	_, err := r.db.ExecContext(ctx, query,
-		f.ID, f.Amount, f.Currency, article, f.Description, f.IssuedAt, paidAt, f.Status,
+	if f.PaidAmount != nil {
+		paidAmount = *f.PaidAmount
+	}
+	if f.VehicleID != nil {
+		vehicleID = *f.VehicleID
+	}
+	if f.ExternalID != nil {
+		externalID = *f.ExternalID
+	}
+	if f.SyncedAt != nil {
+		syncedAt = *f.SyncedAt
+	}
+	_, err := q.ExecContext(ctx, query,
+		f.ID, f.Amount, f.Currency, article, f.Description, f.IssuedAt, paidAt, f.Status, paidAmount,
+		vehicleID, f.Source, externalID, syncedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update fine: %w", err)
@@ -157,6 +366,69 @@ func (r *Repository) Update(ctx context.Context, f *Fine) error {
 	return nil
 }
 
+// MarkOverdue moves pending fines whose due date is before asOf to overdue.
+func (r *Repository) MarkOverdue(ctx context.Context, asOf time.Time) (int64, error) {
+	query := `UPDATE fines SET status = 'overdue', updated_at = NOW() WHERE status = 'pending' AND due_at < $1::date`
+	res, err := r.db.ExecContext(ctx, query, asOf.Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark overdue fines: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// SetExpenseRecordID links a fine to its expense entry, or unlinks it when recordID is nil.
+func (r *Repository) SetExpenseRecordID(ctx context.Context, id uuid.UUID, recordID *uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE fines SET expense_record_id = $2 WHERE id = $1`, id, recordID)
+	if err != nil {
+		return fmt.Errorf("failed to link expense record: %w", err)
+	}
+	return nil
+}
+
+// LockByID reads a fine with a row lock for the duration of tx.
+func (r *Repository) LockByID(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Fine, error) {
+	query := `SELECT ` + fineColumns + ` FROM fines WHERE id = $1 FOR UPDATE`
+	f, err := scanFine(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock fine: %w", err)
+	}
+	return f, nil
+}
+
+// WithTx runs fn inside a database transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.WithTx(ctx, fn)
+}
+
+// GetVehiclePlate returns the license plate of a vehicle, or "" if it has none.
+func (r *Repository) GetVehiclePlate(ctx context.Context, vehicleID uuid.UUID) (string, error) {
+	var plate sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT license_plate FROM vehicles WHERE id = $1`, vehicleID).Scan(&plate)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get vehicle plate: %w", err)
+	}
+	return plate.String, nil
+}
+
+// GetUserIIN returns the IIN of a user, or "" if it is not set.
+func (r *Repository) GetUserIIN(ctx context.Context, userID uuid.UUID) (string, error) {
+	var iin sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT iin FROM users WHERE id = $1`, userID).Scan(&iin)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user iin: %w", err)
+	}
+	return iin.String, nil
+}
+
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, "DELETE FROM fines WHERE id = $1", id)
 	if err != nil {
@@ -165,3 +437,248 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// Registry sync
+
+func (r *Repository) GetByExternalID(ctx context.Context, userID uuid.UUID, source, externalID string) (*Fine, error) {
+	query := `SELECT ` + fineColumns + ` FROM fines WHERE user_id = $1 AND source = $2 AND external_id = $3`
+	f, err := scanFine(r.db.QueryRowContext(ctx, query, userID, source, externalID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fine by external id: %w", err)
+	}
+	return f, nil
+}
+
+// FindManualMatch looks for a manually entered fine that describes the same violation
+// as a registry record: same user, same issue date and amount, and the same vehicle and
+// article when both sides have them.
+func (r *Repository) FindManualMatch(ctx context.Context, userID uuid.UUID, vehicleID uuid.UUID, issuedAt time.Time, amount float64, article string) (*Fine, error) {
+	query := `SELECT ` + fineColumns + ` FROM fines
+		WHERE user_id = $1 AND external_id IS NULL
+			AND issued_at = $2::date AND amount = $3
+			AND (vehicle_id IS NULL OR vehicle_id = $4)
+			AND ($5 = '' OR article IS NULL OR article = $5)
+		ORDER BY (vehicle_id IS NOT NULL) DESC, created_at
+		LIMIT 1`
+	f, err := scanFine(r.db.QueryRowContext(ctx, query, userID, issuedAt.Format("2006-01-02"), amount, vehicleID, article))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find matching fine: %w", err)
+	}
+	return f, nil
+}
+
+// ListSyncTargets returns current owners' vehicles with a license plate, optionally for one user.
+func (r *Repository) ListSyncTargets(ctx context.Context, userID *uuid.UUID) ([]*SyncTarget, error) {
+	query := `
+		SELECT vo.user_id, u.iin, v.id, v.license_plate
+		FROM vehicle_owners vo
+		JOIN vehicles v ON v.id = vo.vehicle_id
+		JOIN users u ON u.id = vo.user_id
+		WHERE vo.is_current = TRUE AND v.license_plate IS NOT NULL AND v.license_plate <> ''
+	`
+	args := []interface{}{}
+	if userID != nil {
+		query += " AND vo.user_id = $1"
+		args = append(args, *userID)
+	}
+	query += " ORDER BY vo.user_id, v.id"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync targets: %w", err)
+	}
+	defer rows.Close()
+	var list []*SyncTarget
+	for rows.Next() {
+		t := &SyncTarget{}
+		var iin sql.NullString
+		if err := rows.Scan(&t.UserID, &iin, &t.VehicleID, &t.LicensePlate); err != nil {
+			return nil, fmt.Errorf("scan sync target: %w", err)
+		}
+		if iin.Valid {
+			t.IIN = &iin.String
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) CreateSyncRun(ctx context.Context, run *SyncRun) error {
+	query := `INSERT INTO fines_sync_runs (id, user_id, provider, status, started_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, query, run.ID, run.UserID, run.Provider, run.Status, run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sync run: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FinishSyncRun(ctx context.Context, run *SyncRun) error {
+	query := `
+		UPDATE fines_sync_runs SET status = $2, fetched = $3, created = $4, updated = $5, matched = $6, error = $7, finished_at = $8
+		WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		run.ID, run.Status, run.Fetched, run.Created, run.Updated, run.Matched, run.Error, run.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish sync run: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListSyncRuns(ctx context.Context, userID uuid.UUID, limit int) ([]*SyncRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `
+		SELECT id, user_id, provider, status, fetched, created, updated, matched, error, started_at, finished_at
+		FROM fines_sync_runs WHERE user_id = $1 ORDER BY started_at DESC LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	defer rows.Close()
+	var list []*SyncRun
+	for rows.Next() {
+		run := &SyncRun{}
+		err := rows.Scan(&run.ID, &run.UserID, &run.Provider, &run.Status, &run.Fetched, &run.Created,
+			&run.Updated, &run.Matched, &run.Error, &run.StartedAt, &run.FinishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan sync run: %w", err)
+		}
+		list = append(list, run)
+	}
+	return list, rows.Err()
+}
+
+// Disputes
+
+const disputeColumns = `id, fine_id, user_id, reason, text, status, resolution_comment, submitted_at, reviewed_at, resolved_at, created_at, updated_at`
+
+func scanDispute(row rowScanner) (*Dispute, error) {
+	d := &Dispute{}
+	err := row.Scan(&d.ID, &d.FineID, &d.UserID, &d.Reason, &d.Text, &d.Status, &d.ResolutionComment,
+		&d.SubmittedAt, &d.ReviewedAt, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *Repository) CreateDispute(ctx context.Context, tx *sql.Tx, d *Dispute) error {
+	query := `
+		INSERT INTO fine_disputes (id, fine_id, user_id, reason, text, status, submitted_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := tx.QueryRowContext(ctx, query, d.ID, d.FineID, d.UserID, d.Reason, d.Text, d.Status, d.SubmittedAt).
+		Scan(&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+	return nil
+}
+
+// GetLatestDisputeByFineID returns the most recent dispute of a fine.
+func (r *Repository) GetLatestDisputeByFineID(ctx context.Context, fineID uuid.UUID) (*Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM fine_disputes WHERE fine_id = $1 ORDER BY submitted_at DESC LIMIT 1`
+	d, err := scanDispute(r.db.QueryRowContext(ctx, query, fineID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	return d, nil
+}
+
+func (r *Repository) LockDispute(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM fine_disputes WHERE id = $1 FOR UPDATE`
+	d, err := scanDispute(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock dispute: %w", err)
+	}
+	return d, nil
+}
+
+func (r *Repository) UpdateDispute(ctx context.Context, tx *sql.Tx, d *Dispute) error {
+	query := `
+		UPDATE fine_disputes SET status = $2, resolution_comment = $3, reviewed_at = $4, resolved_at = $5
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, query, d.ID, d.Status, d.ResolutionComment, d.ReviewedAt, d.ResolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListDisputes(ctx context.Context, status *string, limit, offset int) ([]*Dispute, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `SELECT ` + disputeColumns + ` FROM fine_disputes`
+	args := []interface{}{}
+	if status != nil {
+		query += ` WHERE status = $1`
+		args = append(args, *status)
+	}
+	query += fmt.Sprintf(` ORDER BY submitted_at LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	defer rows.Close()
+	var list []*Dispute
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan dispute: %w", err)
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) CreateDisputeEvent(ctx context.Context, tx *sql.Tx, e *DisputeEvent) error {
+	query := `
+		INSERT INTO fine_dispute_events (id, dispute_id, status, comment, actor_user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`
+	err := tx.QueryRowContext(ctx, query, e.ID, e.DisputeID, e.Status, e.Comment, e.ActorUserID).Scan(&e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create dispute event: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListDisputeEvents(ctx context.Context, disputeID uuid.UUID) ([]*DisputeEvent, error) {
+	query := `
+		SELECT id, dispute_id, status, comment, actor_user_id, created_at
+		FROM fine_dispute_events WHERE dispute_id = $1 ORDER BY created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispute events: %w", err)
+	}
+	defer rows.Close()
+	var list []*DisputeEvent
+	for rows.Next() {
+		e := &DisputeEvent{}
+		if err := rows.Scan(&e.ID, &e.DisputeID, &e.Status, &e.Comment, &e.ActorUserID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan dispute event: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"time"
 
+	"alem-auto/config"
+	"alem-auto/internal/media"
 	"github.com/google/uuid"
 )
 
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	terms    config.FinesConfig
+	media    *media.Service
+	expenses ExpenseRecorder
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, terms config.FinesConfig, mediaService *media.Service) *Service {
+	return &Service{repo: repo, terms: terms, media: mediaService}
 }
 
 func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *CreateFineRequest) (*Fine, error) {
@@ -41,9 +46,15 @@ func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *CreateFineR
 	if req.Article != "" {
 		f.Article = &req.Article
 	}
+	applyTerms(f, s.terms)
+	now := time.Now()
+	if isPastDue(f, now) {
+		f.Status = StatusOverdue
+	}
 	if err := s.repo.Create(ctx, f); err != nil {
 		return nil, err
 	}
+	computePayment(f, now)
 	return f, nil
 }
 
@@ -58,6 +69,7 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID, userID uuid.UUID) (
 	if f.UserID != userID {
 		return nil, nil // not found for this user (don't leak existence)
 	}
+	computePayment(f, time.Now())
 	return f, nil
 }
 
@@ -65,10 +77,32 @@ func (s *Service) List(ctx context.Context, userID uuid.UUID, filter ListFinesFi
 	if filter.Limit <= 0 {
 		filter.Limit = 50
 	}
-	return s.repo.ListByUserID(ctx, userID, filter)
+	list, err := s.repo.ListByUserID(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, f := range list {
+		computePayment(f, now)
+	}
+	return list, nil
 }
 
+// Summary returns aggregated fines of the user for the dashboard.
+func (s *Service) Summary(ctx context.Context, userID uuid.UUID, filter SummaryFilter) (*Summary, error) {
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	return s.repo.Summary(ctx, userID, filter, 5)
+}
+
+// Update lets the owner reset an unpaid fine to pending. Paying goes through MarkPaid, un-paying
+// through MarkRefunded and disputes through SubmitDispute and ReviewDispute, so none of those
+// statuses can be set or left here.
 func (s *Service) Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, req *UpdateFineRequest) (*Fine, error) {
+	if req.Status != nil && *req.Status != StatusPending {
+		return nil, fmt.Errorf("invalid status: only %s can be set, payments and disputes set the others", StatusPending)
+	}
 	f, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -76,26 +110,26 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, userID uuid.UUID, re
 	if f == nil || f.UserID != userID {
 		return nil, nil
 	}
+	now := time.Now()
 	if req.Status != nil {
-		switch *req.Status {
-		case StatusPaid, StatusPending, StatusDisputed:
-			f.Status = *req.Status
-			if *req.Status == StatusPaid && f.PaidAt == nil {
-				now := time.Now()
-				f.PaidAt = &now
-			}
-		default:
-			return nil, fmt.Errorf("invalid status")
+		if f.Status == StatusPaid {
+			return nil, fmt.Errorf("fine is paid: only a refund of its payment can reopen it")
 		}
-	}
-	if req.PaidAt != nil {
-		if t, err := time.Parse(time.RFC3339, *req.PaidAt); err == nil {
-			f.PaidAt = &t
+		if f.Status == StatusDisputed || f.Status == StatusCancelled {
+			return nil, fmt.Errorf("fine status is managed by its dispute")
+		}
+		f.Status = StatusPending
+		if isPastDue(f, now) {
+			f.Status = StatusOverdue
 		}
 	}
 	if err := s.repo.Update(ctx, f); err != nil {
 		return nil, err
 	}
+	if err := s.syncExpense(ctx, f); err != nil {
+		return nil, err
+	}
+	computePayment(f, now)
 	return f, nil
 }
 
@@ -107,5 +141,68 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) er
 	if f == nil || f.UserID != userID {
 		return nil // no-op, not found or not owner
 	}
+	if s.expenses != nil && f.ExpenseRecordID != nil {
+		if err := s.expenses.FineUnpaid(ctx, f.ID); err != nil {
+			return fmt.Errorf("failed to remove fine expense: %w", err)
+		}
+	}
 	return s.repo.Delete(ctx, id)
 }
+
+// MarkPaid records a payment confirmed by the payment gateway. The paid date and amount
+// come from the system, not from the client. Repeated calls only re-sync the expense entry.
+func (s *Service) MarkPaid(ctx context.Context, id uuid.UUID, amount float64, paidAt time.Time) (*Fine, error) {
+	f, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("fine not found")
+	}
+	if f.Status != StatusPaid {
+		f.Status = StatusPaid
+		f.PaidAt = &paidAt
+		f.PaidAmount = &amount
+		if err := s.repo.Update(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.syncExpense(ctx, f); err != nil {
+		return nil, err
+	}
+	computePayment(f, time.Now())
+	return f, nil
+}
+
+// MarkRefunded reverts a fine to unpaid after its payment was fully refunded.
+func (s *Service) MarkRefunded(ctx context.Context, id uuid.UUID) (*Fine, error) {
+	f, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("fine not found")
+	}
+	now := time.Now()
+	if f.Status == StatusPaid {
+		f.Status = StatusPending
+		f.PaidAt = nil
+		f.PaidAmount = nil
+		if isPastDue(f, now) {
+			f.Status = StatusOverdue
+		}
+		if err := s.repo.Update(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.syncExpense(ctx, f); err != nil {
+		return nil, err
+	}
+	computePayment(f, now)
+	return f, nil
+}
+
+// MarkOverdue moves unpaid fines past their due date to overdue.
+func (s *Service) MarkOverdue(ctx context.Context) (int64, error) {
+	return s.repo.MarkOverdue(ctx, time.Now())
+}
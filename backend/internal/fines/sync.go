@@ -0,0 +1,232 @@
+package fines
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/config"
+)
+
+// Syncer pulls fines from an external registry and upserts them per user vehicle.
+type Syncer struct {
+	fines    *Service
+	repo     *Repository
+	provider Provider
+	terms    config.FinesConfig
+}
+
+func NewSyncer(service *Service, provider Provider) *Syncer {
+	return &Syncer{fines: service, repo: service.repo, provider: provider, terms: service.terms}
+}
+
+// SyncUser syncs fines for all current vehicles of one user and records the run.
+func (s *Syncer) SyncUser(ctx context.Context, userID uuid.UUID) (*SyncRun, error) {
+	targets, err := s.repo.ListSyncTargets(ctx, &userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncTargets(ctx, userID, targets)
+}
+
+// SyncAll syncs fines for every user that owns a vehicle with a license plate.
+func (s *Syncer) SyncAll(ctx context.Context) error {
+	targets, err := s.repo.ListSyncTargets(ctx, nil)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[uuid.UUID][]*SyncTarget)
+	var order []uuid.UUID
+	for _, t := range targets {
+		if _, ok := byUser[t.UserID]; !ok {
+			order = append(order, t.UserID)
+		}
+		byUser[t.UserID] = append(byUser[t.UserID], t)
+	}
+	for _, userID := range order {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.syncTargets(ctx, userID, byUser[userID]); err != nil {
+			log.Printf("fines: sync for user %s failed: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// Status returns the recent sync history of a user.
+func (s *Syncer) Status(ctx context.Context, userID uuid.UUID, limit int) (*SyncStatusResponse, error) {
+	runs, err := s.repo.ListSyncRuns(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []*SyncRun{}
+	}
+	resp := &SyncStatusResponse{Enabled: true, Provider: s.provider.Name(), Runs: runs}
+	if len(runs) > 0 {
+		resp.LastRun = runs[0]
+	}
+	return resp, nil
+}
+
+func (s *Syncer) syncTargets(ctx context.Context, userID uuid.UUID, targets []*SyncTarget) (*SyncRun, error) {
+	run := &SyncRun{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Provider:  s.provider.Name(),
+		Status:    SyncStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.repo.CreateSyncRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, t := range targets {
+		q := RegistryQuery{LicensePlate: t.LicensePlate}
+		if t.IIN != nil {
+			q.IIN = *t.IIN
+		}
+		records, err := s.provider.FetchFines(ctx, q)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", t.LicensePlate, err))
+			continue
+		}
+		run.Fetched += len(records)
+		for i := range records {
+			if err := s.upsert(ctx, run, t, &records[i]); err != nil {
+				errs = append(errs, fmt.Sprintf("%s/%s: %v", t.LicensePlate, records[i].ExternalID, err))
+			}
+		}
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = SyncStatusSuccess
+	if len(errs) > 0 {
+		run.Status = SyncStatusFailed
+		msg := strings.Join(errs, "; ")
+		run.Error = &msg
+	}
+	if err := s.repo.FinishSyncRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// upsert creates or refreshes a fine from a registry record. A manually entered fine that
+// matches the record is linked to it instead of creating a duplicate.
+func (s *Syncer) upsert(ctx context.Context, run *SyncRun, t *SyncTarget, rec *RegistryFine) error {
+	if rec.ExternalID == "" {
+		return fmt.Errorf("record without external_id")
+	}
+	issuedAt, err := time.Parse("2006-01-02", rec.IssuedAt)
+	if err != nil {
+		return fmt.Errorf("invalid issued_at: %w", err)
+	}
+	now := time.Now()
+	source := s.provider.Name()
+
+	f, err := s.repo.GetByExternalID(ctx, t.UserID, source, rec.ExternalID)
+	if err != nil {
+		return err
+	}
+	matched := false
+	if f == nil {
+		f, err = s.repo.FindManualMatch(ctx, t.UserID, t.VehicleID, issuedAt, rec.Amount, rec.Article)
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			f.Source = source
+			f.ExternalID = &rec.ExternalID
+			matched = true
+		}
+	}
+
+	if f == nil {
+		f = &Fine{
+			ID:          uuid.New(),
+			UserID:      t.UserID,
+			VehicleID:   &t.VehicleID,
+			Amount:      rec.Amount,
+			Currency:    rec.Currency,
+			Description: rec.Description,
+			IssuedAt:    issuedAt,
+			Status:      StatusPending,
+			Source:      source,
+			ExternalID:  &rec.ExternalID,
+			SyncedAt:    &now,
+		}
+		if f.Currency == "" {
+			f.Currency = "KZT"
+		}
+		if rec.Article != "" {
+			f.Article = &rec.Article
+		}
+		applyTerms(f, s.terms)
+		applyRegistryStatus(f, rec, now)
+		if err := s.repo.Create(ctx, f); err != nil {
+			return err
+		}
+		run.Created++
+		return s.fines.syncExpense(ctx, f)
+	}
+
+	if f.VehicleID == nil {
+		f.VehicleID = &t.VehicleID
+	}
+	f.Amount = rec.Amount
+	if rec.Article != "" {
+		f.Article = &rec.Article
+	}
+	if f.Description == "" {
+		f.Description = rec.Description
+	}
+	f.SyncedAt = &now
+	applyRegistryStatus(f, rec, now)
+	if err := s.repo.Update(ctx, f); err != nil {
+		return err
+	}
+	if matched {
+		run.Matched++
+	} else {
+		run.Updated++
+	}
+	return s.fines.syncExpense(ctx, f)
+}
+
+// applyRegistryStatus takes the payment state from the registry. A fine paid here is never
+// reopened by a registry that has not caught up yet: only a refund un-pays it. A user's
+// dispute and a fine cancelled by an accepted dispute are kept until the registry reports
+// the fine as paid.
+func applyRegistryStatus(f *Fine, rec *RegistryFine, now time.Time) {
+	if f.Status == StatusPaid {
+		return
+	}
+	if rec.Status == "paid" {
+		paidAt := now
+		if rec.PaidAt != nil {
+			paidAt = *rec.PaidAt
+		}
+		paidAmount := rec.Amount
+		if rec.PaidAmount != nil {
+			paidAmount = *rec.PaidAmount
+		}
+		f.Status = StatusPaid
+		f.PaidAt = &paidAt
+		f.PaidAmount = &paidAmount
+		return
+	}
+	if f.Status == StatusDisputed || f.Status == StatusCancelled {
+		return
+	}
+	f.Status = StatusPending
+	if isPastDue(f, now) {
+		f.Status = StatusOverdue
+	}
+}
@@ -8,10 +8,17 @@ import (
 
 // ServiceCenter представляет автобокс/сервис
 type ServiceCenter struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Address   *string   `json:"address,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Address     *string     `json:"address,omitempty"`
+	City        *string     `json:"city,omitempty"`
+	Latitude    *float64    `json:"latitude,omitempty"`
+	Longitude   *float64    `json:"longitude,omitempty"`
+	Phones      []string    `json:"phones"`
+	MakeIDs     []uuid.UUID `json:"make_ids"`             // марки, на которых специализируется сервис; пусто — все марки
+	RatingAvg   *float64    `json:"rating_avg,omitempty"` // средняя оценка опубликованных отзывов
+	RatingCount int         `json:"rating_count"`
+	CreatedAt   time.Time   `json:"created_at"`
 }
 
 // ServiceCenterUser представляет пользователя сервиса (мастер/админ)
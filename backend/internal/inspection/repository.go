@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"alem-auto/internal/database"
 )
 
@@ -22,11 +23,12 @@ func NewRepository(db *database.DB) *Repository {
 
 func (r *Repository) CreateServiceCenter(ctx context.Context, sc *ServiceCenter) error {
 	query := `
-		INSERT INTO service_centers (id, name, address)
-		VALUES ($1, $2, $3)
+		INSERT INTO service_centers (id, name, address, city, latitude, longitude, phones, make_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::uuid[])
 	`
 
-	_, err := r.db.ExecContext(ctx, query, sc.ID, sc.Name, sc.Address)
+	_, err := r.db.ExecContext(ctx, query, sc.ID, sc.Name, sc.Address, sc.City, sc.Latitude, sc.Longitude,
+		pq.Array(sc.Phones), uuidArray(sc.MakeIDs))
 	if err != nil {
 		return fmt.Errorf("failed to create service center: %w", err)
 	}
@@ -34,17 +36,49 @@ func (r *Repository) CreateServiceCenter(ctx context.Context, sc *ServiceCenter)
 	return nil
 }
 
-func (r *Repository) GetServiceCenterByID(ctx context.Context, id uuid.UUID) (*ServiceCenter, error) {
-	query := `
-		SELECT id, name, address, created_at
-		FROM service_centers
-		WHERE id = $1
-	`
+const serviceCenterColumns = `id, name, address, city, latitude, longitude, phones, make_ids, rating_avg, rating_count, created_at`
 
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanServiceCenter(row rowScanner) (*ServiceCenter, error) {
 	sc := &ServiceCenter{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&sc.ID, &sc.Name, &sc.Address, &sc.CreatedAt,
+	var makeIDs []string
+	err := row.Scan(
+		&sc.ID, &sc.Name, &sc.Address, &sc.City, &sc.Latitude, &sc.Longitude, pq.Array(&sc.Phones), pq.Array(&makeIDs),
+		&sc.RatingAvg, &sc.RatingCount, &sc.CreatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Phones == nil {
+		sc.Phones = []string{}
+	}
+	sc.MakeIDs = make([]uuid.UUID, 0, len(makeIDs))
+	for _, v := range makeIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid make id %q: %w", v, err)
+		}
+		sc.MakeIDs = append(sc.MakeIDs, id)
+	}
+	return sc, nil
+}
+
+func uuidArray(ids []uuid.UUID) interface{} {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+	return pq.Array(strIDs)
+}
+
+func (r *Repository) GetServiceCenterByID(ctx context.Context, id uuid.UUID) (*ServiceCenter, error) {
+	query := `SELECT ` + serviceCenterColumns + ` FROM service_centers WHERE id = $1`
+
+	sc, err := scanServiceCenter(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -55,6 +89,23 @@ func (r *Repository) GetServiceCenterByID(ctx context.Context, id uuid.UUID) (*S
 	return sc, nil
 }
 
+// UpdateServiceCenter saves the name, address, location, contacts and specialization of a center.
+func (r *Repository) UpdateServiceCenter(ctx context.Context, sc *ServiceCenter) error {
+	query := `
+		UPDATE service_centers SET name = $2, address = $3, city = $4, latitude = $5, longitude = $6,
+			phones = $7, make_ids = $8::uuid[]
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, sc.ID, sc.Name, sc.Address, sc.City, sc.Latitude, sc.Longitude,
+		pq.Array(sc.Phones), uuidArray(sc.MakeIDs))
+	if err != nil {
+		return fmt.Errorf("failed to update service center: %w", err)
+	}
+
+	return nil
+}
+
 // ServiceCenterUser methods
 
 func (r *Repository) CreateServiceCenterUser(ctx context.Context, scu *ServiceCenterUser) error {
@@ -143,6 +194,14 @@ func (r *Repository) CreateInspection(ctx context.Context, i *Inspection) error
 	return nil
 }
 
+func (r *Repository) DeleteInspection(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM inspections WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete inspection: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) GetInspectionByID(ctx context.Context, id uuid.UUID) (*Inspection, error) {
 	query := `
 		SELECT id, vehicle_id, service_center_id, created_by_user_id, odometer_km, notes, created_at
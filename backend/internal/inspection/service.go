@@ -34,6 +34,10 @@ func (s *Service) GetServiceCenterByID(ctx context.Context, id uuid.UUID) (*Serv
 	return s.repo.GetServiceCenterByID(ctx, id)
 }
 
+func (s *Service) UpdateServiceCenter(ctx context.Context, sc *ServiceCenter) error {
+	return s.repo.UpdateServiceCenter(ctx, sc)
+}
+
 func (s *Service) CreateServiceCenterUser(ctx context.Context, scu *ServiceCenterUser) error {
 	if scu.ID == uuid.Nil {
 		scu.ID = uuid.New()
@@ -91,6 +95,11 @@ func (s *Service) CreateInspection(ctx context.Context, i *Inspection) error {
 	return s.repo.CreateInspection(ctx, i)
 }
 
+// DeleteInspection удаляет осмотр вместе с наблюдениями
+func (s *Service) DeleteInspection(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteInspection(ctx, id)
+}
+
 func (s *Service) GetInspectionByID(ctx context.Context, id uuid.UUID) (*Inspection, error) {
 	return s.repo.GetInspectionByID(ctx, id)
 }
@@ -17,6 +17,7 @@ type Asset struct {
 	SHA256         *string   `json:"sha256,omitempty"`
 	Version        int       `json:"version"`
 	OwnerScope     string    `json:"owner_scope"` // catalog, vehicle, inspection
+	UserID         *uuid.UUID `json:"user_id,omitempty"` // кто загрузил
 	CreatedAt      time.Time `json:"created_at"`
 }
 
@@ -24,7 +25,7 @@ type Asset struct {
 type AssetLink struct {
 	ID       uuid.UUID `json:"id"`
 	AssetID  uuid.UUID `json:"asset_id"`
-	LinkType string    `json:"link_type"` // vehicle, inspection, component, component_observation
+	LinkType string    `json:"link_type"` // vehicle, inspection, component, component_observation, fine, review, fine_dispute
 	LinkID   uuid.UUID `json:"link_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
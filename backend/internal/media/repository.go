@@ -21,13 +21,13 @@ func NewRepository(db *database.DB) *Repository {
 
 func (r *Repository) CreateAsset(ctx context.Context, a *Asset) error {
 	query := `
-		INSERT INTO assets (id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO assets (id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		a.ID, a.StorageProvider, a.Bucket, a.ObjectKey, a.ContentType,
-		a.SizeBytes, a.SHA256, a.Version, a.OwnerScope,
+		a.SizeBytes, a.SHA256, a.Version, a.OwnerScope, a.UserID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create asset: %w", err)
@@ -38,7 +38,7 @@ func (r *Repository) CreateAsset(ctx context.Context, a *Asset) error {
 
 func (r *Repository) GetAssetByID(ctx context.Context, id uuid.UUID) (*Asset, error) {
 	query := `
-		SELECT id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope, created_at
+		SELECT id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope, user_id, created_at
 		FROM assets
 		WHERE id = $1
 	`
@@ -46,7 +46,7 @@ func (r *Repository) GetAssetByID(ctx context.Context, id uuid.UUID) (*Asset, er
 	a := &Asset{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&a.ID, &a.StorageProvider, &a.Bucket, &a.ObjectKey, &a.ContentType,
-		&a.SizeBytes, &a.SHA256, &a.Version, &a.OwnerScope, &a.CreatedAt,
+		&a.SizeBytes, &a.SHA256, &a.Version, &a.OwnerScope, &a.UserID, &a.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -60,7 +60,7 @@ func (r *Repository) GetAssetByID(ctx context.Context, id uuid.UUID) (*Asset, er
 
 func (r *Repository) GetAssetBySHA256(ctx context.Context, sha256 string) (*Asset, error) {
 	query := `
-		SELECT id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope, created_at
+		SELECT id, storage_provider, bucket, object_key, content_type, size_bytes, sha256, version, owner_scope, user_id, created_at
 		FROM assets
 		WHERE sha256 = $1
 		ORDER BY created_at DESC
@@ -70,7 +70,7 @@ func (r *Repository) GetAssetBySHA256(ctx context.Context, sha256 string) (*Asse
 	a := &Asset{}
 	err := r.db.QueryRowContext(ctx, query, sha256).Scan(
 		&a.ID, &a.StorageProvider, &a.Bucket, &a.ObjectKey, &a.ContentType,
-		&a.SizeBytes, &a.SHA256, &a.Version, &a.OwnerScope, &a.CreatedAt,
+		&a.SizeBytes, &a.SHA256, &a.Version, &a.OwnerScope, &a.UserID, &a.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -110,12 +110,17 @@ func (r *Repository) UpdateAsset(ctx context.Context, a *Asset) error {
 // AssetLink methods
 
 func (r *Repository) CreateAssetLink(ctx context.Context, al *AssetLink) error {
+	return r.CreateAssetLinkTx(ctx, r.db, al)
+}
+
+// CreateAssetLinkTx создает связь через q, в том числе внутри чужой транзакции
+func (r *Repository) CreateAssetLinkTx(ctx context.Context, q database.Querier, al *AssetLink) error {
 	query := `
 		INSERT INTO asset_links (id, asset_id, link_type, link_id)
 		VALUES ($1, $2, $3, $4)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, al.ID, al.AssetID, al.LinkType, al.LinkID)
+	_, err := q.ExecContext(ctx, query, al.ID, al.AssetID, al.LinkType, al.LinkID)
 	if err != nil {
 		return fmt.Errorf("failed to create asset link: %w", err)
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"alem-auto/config"
+	"alem-auto/internal/database"
 )
 
 type Service struct {
@@ -26,7 +27,7 @@ func NewService(repo *Repository, s3Client *S3Client, cfg config.S3Config) *Serv
 }
 
 // PrepareUpload подготавливает загрузку файла и возвращает pre-signed URL
-func (s *Service) PrepareUpload(ctx context.Context, req *UploadRequest) (*UploadResponse, error) {
+func (s *Service) PrepareUpload(ctx context.Context, userID uuid.UUID, req *UploadRequest) (*UploadResponse, error) {
 	// Валидация owner_scope
 	validScopes := map[string]bool{
 		"catalog":    true,
@@ -52,6 +53,7 @@ func (s *Service) PrepareUpload(ctx context.Context, req *UploadRequest) (*Uploa
 		SizeBytes:      &req.SizeBytes,
 		Version:        1,
 		OwnerScope:     req.OwnerScope,
+		UserID:         &userID,
 	}
 
 	err := s.repo.CreateAsset(ctx, asset)
@@ -144,10 +146,13 @@ func (s *Service) LinkAsset(ctx context.Context, assetID uuid.UUID, linkType str
 	}
 
 	// Проверяем существование ассета
-	_, err := s.repo.GetAssetByID(ctx, assetID)
+	asset, err := s.repo.GetAssetByID(ctx, assetID)
 	if err != nil {
 		return fmt.Errorf("asset not found: %w", err)
 	}
+	if asset == nil {
+		return fmt.Errorf("asset not found")
+	}
 
 	assetLink := &AssetLink{
 		ID:       uuid.New(),
@@ -159,6 +164,25 @@ func (s *Service) LinkAsset(ctx context.Context, assetID uuid.UUID, linkType str
 	return s.repo.CreateAssetLink(ctx, assetLink)
 }
 
+// LinkOwnAsset связывает ассет, загруженный пользователем, с его сущностью через q, чтобы связь
+// создавалась в транзакции вызывающего. Только для сервисов: доказательства по спорам ("fine_dispute")
+// и фото отзывов ("review") недоступны через общий POST /media/:id/link.
+func (s *Service) LinkOwnAsset(ctx context.Context, q database.Querier, userID, assetID uuid.UUID, linkType string, linkID uuid.UUID) error {
+	asset, err := s.repo.GetAssetByID(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset == nil || asset.UserID == nil || *asset.UserID != userID {
+		return fmt.Errorf("asset %s not found", assetID)
+	}
+	return s.repo.CreateAssetLinkTx(ctx, q, &AssetLink{
+		ID:       uuid.New(),
+		AssetID:  assetID,
+		LinkType: linkType,
+		LinkID:   linkID,
+	})
+}
+
 // GetAssetLinks возвращает все связи ассета
 func (s *Service) GetAssetLinks(ctx context.Context, assetID uuid.UUID) ([]*AssetLink, error) {
 	return s.repo.GetAssetLinksByAssetID(ctx, assetID)
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification kinds.
+const (
+	KindBookingReminder = "booking_reminder"
+	KindBookingNoShow   = "booking_no_show"
+	KindWaitlistOffer   = "waitlist_offer"
+	KindLowStock        = "low_stock"
+)
+
+// Message is a notification addressed to a user.
+type Message struct {
+	UserID uuid.UUID         `json:"user_id"`
+	Kind   string            `json:"kind"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Data   map[string]string `json:"data,omitempty"` // e.g. booking_id for deep links
+	SentAt time.Time         `json:"sent_at"`
+}
+
+// Channel delivers notifications: push, SMS, e-mail or a log in development.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m *Message) error
+}
+
+// LogChannel writes notifications to the server log.
+type LogChannel struct{}
+
+func (LogChannel) Name() string {
+	return "log"
+}
+
+func (LogChannel) Send(ctx context.Context, m *Message) error {
+	log.Printf("notify: %s to user %s: %s — %s", m.Kind, m.UserID, m.Title, m.Body)
+	return nil
+}
+
+// WebhookChannel posts notifications as JSON to a delivery gateway.
+type WebhookChannel struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewWebhookChannel(url, apiKey string, timeout time.Duration) *WebhookChannel {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookChannel{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+func (c *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, m *Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package payments
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusCreated           = "created"
+	StatusPending           = "pending"
+	StatusSucceeded         = "succeeded"
+	StatusFailed            = "failed"
+	StatusCancelled         = "cancelled"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
+)
+
+const (
+	PayableFine    = "fine"
+	PayableBooking = "booking"
+)
+
+const (
+	EventPaymentPending   = "payment.pending"
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+	EventPaymentCancelled = "payment.cancelled"
+	EventRefundSucceeded  = "refund.succeeded"
+)
+
+// PaymentIntent is a single attempt to pay for a payable entity (fine, booking).
+type PaymentIntent struct {
+	ID                uuid.UUID  `json:"id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	PayableType       string     `json:"payable_type"`
+	PayableID         uuid.UUID  `json:"payable_id"`
+	Amount            float64    `json:"amount"`
+	Currency          string     `json:"currency"`
+	Description       *string    `json:"description,omitempty"`
+	Provider          string     `json:"provider"`
+	ProviderPaymentID *string    `json:"provider_payment_id,omitempty"`
+	CheckoutURL       *string    `json:"checkout_url,omitempty"`
+	Status            string     `json:"status"`
+	RefundedAmount    float64    `json:"refunded_amount"`
+	FailureReason     *string    `json:"failure_reason,omitempty"`
+	PaidAt            *time.Time `json:"paid_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Refund is a full or partial refund of a succeeded payment. It is pending while the
+// provider is being called and then succeeded or failed.
+type Refund struct {
+	ID               uuid.UUID  `json:"id"`
+	PaymentIntentID  uuid.UUID  `json:"payment_intent_id"`
+	Amount           float64    `json:"amount"`
+	Reason           *string    `json:"reason,omitempty"`
+	Status           string     `json:"status"`
+	ProviderRefundID *string    `json:"provider_refund_id,omitempty"`
+	FailureReason    *string    `json:"failure_reason,omitempty"`
+	CreatedByUserID  *uuid.UUID `json:"created_by_user_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// WebhookEvent is a provider notification after signature verification.
+type WebhookEvent struct {
+	EventID           string    `json:"event_id"`
+	Type              string    `json:"type"` // payment.succeeded, payment.failed, ...
+	ProviderPaymentID string    `json:"provider_payment_id"`
+	Amount            float64   `json:"amount"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	OccurredAt        time.Time `json:"occurred_at"`
+}
+
+// Payable is what the payer owes for an entity at the moment the intent is created.
+type Payable struct {
+	Amount      float64
+	Currency    string
+	Description string
+}
+
+// CreateIntentRequest is the request body for starting a payment.
+type CreateIntentRequest struct {
+	PayableType string    `json:"payable_type" binding:"required"` // fine, booking
+	PayableID   uuid.UUID `json:"payable_id" binding:"required"`
+}
+
+// RefundRequest is the request body for refunding a payment. Amount defaults to the rest.
+type RefundRequest struct {
+	Amount *float64 `json:"amount,omitempty"`
+	Reason string   `json:"reason"`
+}
+
+// ListIntentsFilter holds query filters for listing payment intents.
+type ListIntentsFilter struct {
+	PayableType *string
+	PayableID   *uuid.UUID
+	Status      *string
+	Limit       int
+	Offset      int
+}
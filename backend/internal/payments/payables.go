@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/booking"
+	"alem-auto/internal/fines"
+)
+
+// PayableHandler connects a payable entity type to payments.
+// MarkPaid and MarkRefunded must be idempotent: webhooks may be redelivered.
+type PayableHandler interface {
+	// GetPayable returns what userID owes for the entity, or nil if it is not found or not theirs.
+	GetPayable(ctx context.Context, userID, id uuid.UUID) (*Payable, error)
+	MarkPaid(ctx context.Context, id uuid.UUID, amount float64, paidAt time.Time) error
+	MarkRefunded(ctx context.Context, id uuid.UUID) error
+}
+
+// FinePayable lets users pay their fines.
+type FinePayable struct {
+	fines *fines.Service
+}
+
+func NewFinePayable(finesService *fines.Service) *FinePayable {
+	return &FinePayable{fines: finesService}
+}
+
+func (p *FinePayable) GetPayable(ctx context.Context, userID, id uuid.UUID) (*Payable, error) {
+	f, err := p.fines.GetByID(ctx, id, userID)
+	if err != nil || f == nil {
+		return nil, err
+	}
+	if f.Status != fines.StatusPending && f.Status != fines.StatusOverdue {
+		return nil, fmt.Errorf("fine cannot be paid in status %s", f.Status)
+	}
+	return &Payable{Amount: f.AmountToPay, Currency: f.Currency, Description: f.ExpenseTitle()}, nil
+}
+
+func (p *FinePayable) MarkPaid(ctx context.Context, id uuid.UUID, amount float64, paidAt time.Time) error {
+	_, err := p.fines.MarkPaid(ctx, id, amount, paidAt)
+	return err
+}
+
+func (p *FinePayable) MarkRefunded(ctx context.Context, id uuid.UUID) error {
+	_, err := p.fines.MarkRefunded(ctx, id)
+	return err
+}
+
+// BookingPayable lets users prepay bookings with a fixed price.
+type BookingPayable struct {
+	bookings *booking.Service
+}
+
+func NewBookingPayable(bookingService *booking.Service) *BookingPayable {
+	return &BookingPayable{bookings: bookingService}
+}
+
+func (p *BookingPayable) GetPayable(ctx context.Context, userID, id uuid.UUID) (*Payable, error) {
+	b, amount, currency, err := p.bookings.Prepayment(ctx, id, userID)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("Предоплата записи на %s", b.ScheduledAt.Format("02.01.2006 15:04"))
+	return &Payable{Amount: amount, Currency: currency, Description: description}, nil
+}
+
+func (p *BookingPayable) MarkPaid(ctx context.Context, id uuid.UUID, amount float64, paidAt time.Time) error {
+	return p.bookings.MarkPrepaid(ctx, id, amount, paidAt)
+}
+
+func (p *BookingPayable) MarkRefunded(ctx context.Context, id uuid.UUID) error {
+	return p.bookings.MarkPrepaymentRefunded(ctx, id)
+}
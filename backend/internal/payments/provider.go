@@ -0,0 +1,105 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSignature is returned for a webhook whose signature does not verify.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrInvalidPayload is returned for a webhook body that cannot be decoded or misses fields.
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+	// ErrUnknownProvider is returned for a webhook addressed to a provider that is not configured.
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+// ProviderPayment is what a provider returns when a payment is registered.
+type ProviderPayment struct {
+	ProviderPaymentID string
+	CheckoutURL       string
+}
+
+// Provider is a payment gateway (Kaspi, Halyk, CloudPayments, ...).
+type Provider interface {
+	Name() string
+	CreatePayment(ctx context.Context, intent *PaymentIntent) (*ProviderPayment, error)
+	// Refund must treat repeated calls with the same idempotency key as one refund.
+	Refund(ctx context.Context, intent *PaymentIntent, amount float64, idempotencyKey string) (providerRefundID string, err error)
+	// ParseWebhook verifies the signature and decodes the notification; it returns
+	// ErrInvalidSignature or ErrInvalidPayload for a notification to reject.
+	ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// FakeSignatureHeader carries hex(HMAC-SHA256(secret, body)) for the fake provider.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider accepts every payment and sends the payer to the local simulator.
+// Its webhooks are signed the same way real gateways do, so the full flow can be tested locally.
+type FakeProvider struct {
+	secret          string
+	checkoutBaseURL string
+}
+
+func NewFakeProvider(secret, checkoutBaseURL string) *FakeProvider {
+	return &FakeProvider{secret: secret, checkoutBaseURL: strings.TrimRight(checkoutBaseURL, "/")}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreatePayment(ctx context.Context, intent *PaymentIntent) (*ProviderPayment, error) {
+	id := "fake_" + uuid.New().String()
+	q := url.Values{}
+	q.Set("amount", fmt.Sprintf("%.2f", intent.Amount))
+	q.Set("currency", intent.Currency)
+	return &ProviderPayment{
+		ProviderPaymentID: id,
+		CheckoutURL:       fmt.Sprintf("%s/checkout/%s?%s", p.checkoutBaseURL, id, q.Encode()),
+	}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intent *PaymentIntent, amount float64, idempotencyKey string) (string, error) {
+	return "fake_refund_" + idempotencyKey, nil
+}
+
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if !VerifySignature(p.secret, body, header.Get(FakeSignatureHeader)) {
+		return nil, ErrInvalidSignature
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if event.EventID == "" || event.ProviderPaymentID == "" {
+		return nil, fmt.Errorf("%w: missing event_id or provider_payment_id", ErrInvalidPayload)
+	}
+	return &event, nil
+}
+
+// Sign returns hex(HMAC-SHA256(secret, body)).
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature compares the signature in constant time.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected := Sign(secret, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
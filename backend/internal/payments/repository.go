@@ -0,0 +1,260 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/database"
+)
+
+const intentColumns = `id, user_id, payable_type, payable_id, amount, currency, description, provider, provider_payment_id,
+		checkout_url, status, refunded_amount, failure_reason, paid_at, created_at, updated_at`
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// WithTx runs fn inside a database transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.WithTx(ctx, fn)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIntent(row rowScanner) (*PaymentIntent, error) {
+	p := &PaymentIntent{}
+	err := row.Scan(
+		&p.ID, &p.UserID, &p.PayableType, &p.PayableID, &p.Amount, &p.Currency, &p.Description, &p.Provider,
+		&p.ProviderPaymentID, &p.CheckoutURL, &p.Status, &p.RefundedAmount, &p.FailureReason, &p.PaidAt,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *Repository) CreateIntent(ctx context.Context, p *PaymentIntent) error {
+	query := `
+		INSERT INTO payment_intents (id, user_id, payable_type, payable_id, amount, currency, description, provider, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		p.ID, p.UserID, p.PayableType, p.PayableID, p.Amount, p.Currency, p.Description, p.Provider, p.Status,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment intent: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetIntentByID(ctx context.Context, id uuid.UUID) (*PaymentIntent, error) {
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE id = $1`
+	p, err := scanIntent(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment intent: %w", err)
+	}
+	return p, nil
+}
+
+// LockIntent reads an intent with a row lock for the duration of tx.
+func (r *Repository) LockIntent(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*PaymentIntent, error) {
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE id = $1 FOR UPDATE`
+	p, err := scanIntent(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock payment intent: %w", err)
+	}
+	return p, nil
+}
+
+// LockIntentByProviderPaymentID reads an intent by the gateway's payment ID with a row lock.
+func (r *Repository) LockIntentByProviderPaymentID(ctx context.Context, tx *sql.Tx, provider, providerPaymentID string) (*PaymentIntent, error) {
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE provider = $1 AND provider_payment_id = $2 FOR UPDATE`
+	p, err := scanIntent(tx.QueryRowContext(ctx, query, provider, providerPaymentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock payment intent: %w", err)
+	}
+	return p, nil
+}
+
+// FindActiveIntent returns an unfinished intent of the user for the same payable and amount.
+func (r *Repository) FindActiveIntent(ctx context.Context, userID uuid.UUID, payableType string, payableID uuid.UUID, amount float64) (*PaymentIntent, error) {
+	query := `SELECT ` + intentColumns + ` FROM payment_intents
+		WHERE user_id = $1 AND payable_type = $2 AND payable_id = $3 AND amount = $4 AND status IN ('created', 'pending')
+		ORDER BY created_at DESC LIMIT 1`
+	p, err := scanIntent(r.db.QueryRowContext(ctx, query, userID, payableType, payableID, amount))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find active payment intent: %w", err)
+	}
+	return p, nil
+}
+
+func (r *Repository) ListIntentsByUserID(ctx context.Context, userID uuid.UUID, filter ListIntentsFilter) ([]*PaymentIntent, error) {
+	query := `SELECT ` + intentColumns + ` FROM payment_intents WHERE user_id = $1`
+	args := []interface{}{userID}
+	pos := 2
+	if filter.PayableType != nil {
+		query += fmt.Sprintf(" AND payable_type = $%d", pos)
+		args = append(args, *filter.PayableType)
+		pos++
+	}
+	if filter.PayableID != nil {
+		query += fmt.Sprintf(" AND payable_id = $%d", pos)
+		args = append(args, *filter.PayableID)
+		pos++
+	}
+	if filter.Status != nil {
+		query += fmt.Sprintf(" AND status = $%d", pos)
+		args = append(args, *filter.Status)
+		pos++
+	}
+	query += " ORDER BY created_at DESC"
+	limit := 50
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", pos, pos+1)
+	args = append(args, limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment intents: %w", err)
+	}
+	defer rows.Close()
+	var list []*PaymentIntent
+	for rows.Next() {
+		p, err := scanIntent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan payment intent: %w", err)
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// UpdateIntent writes the mutable fields of an intent. q is the DB or an open transaction.
+func (r *Repository) UpdateIntent(ctx context.Context, q database.Querier, p *PaymentIntent) error {
+	query := `
+		UPDATE payment_intents SET provider_payment_id = $2, checkout_url = $3, status = $4, refunded_amount = $5,
+			failure_reason = $6, paid_at = $7
+		WHERE id = $1
+	`
+	_, err := q.ExecContext(ctx, query,
+		p.ID, p.ProviderPaymentID, p.CheckoutURL, p.Status, p.RefundedAmount, p.FailureReason, p.PaidAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update payment intent: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CreateRefund(ctx context.Context, q database.Querier, rf *Refund) error {
+	query := `
+		INSERT INTO payment_refunds (id, payment_intent_id, amount, reason, status, provider_refund_id, created_by_user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING created_at
+	`
+	err := q.QueryRowContext(ctx, query,
+		rf.ID, rf.PaymentIntentID, rf.Amount, rf.Reason, rf.Status, rf.ProviderRefundID, rf.CreatedByUserID,
+	).Scan(&rf.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return nil
+}
+
+// UpdateRefund writes the outcome of a refund. q is the DB or an open transaction.
+func (r *Repository) UpdateRefund(ctx context.Context, q database.Querier, rf *Refund) error {
+	query := `UPDATE payment_refunds SET status = $2, provider_refund_id = $3, failure_reason = $4 WHERE id = $1`
+	_, err := q.ExecContext(ctx, query, rf.ID, rf.Status, rf.ProviderRefundID, rf.FailureReason)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	return nil
+}
+
+// PendingRefundAmount sums the refunds of an intent still waiting for the provider.
+func (r *Repository) PendingRefundAmount(ctx context.Context, q database.Querier, intentID uuid.UUID) (float64, error) {
+	var amount float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM payment_refunds WHERE payment_intent_id = $1 AND status = $2
+	`, intentID, StatusPending).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum pending refunds: %w", err)
+	}
+	return amount, nil
+}
+
+func (r *Repository) ListRefunds(ctx context.Context, intentID uuid.UUID) ([]*Refund, error) {
+	query := `
+		SELECT id, payment_intent_id, amount, reason, status, provider_refund_id, failure_reason, created_by_user_id, created_at
+		FROM payment_refunds WHERE payment_intent_id = $1 ORDER BY created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, intentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %w", err)
+	}
+	defer rows.Close()
+	var list []*Refund
+	for rows.Next() {
+		rf := &Refund{}
+		err := rows.Scan(&rf.ID, &rf.PaymentIntentID, &rf.Amount, &rf.Reason, &rf.Status, &rf.ProviderRefundID, &rf.FailureReason,
+			&rf.CreatedByUserID, &rf.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan refund: %w", err)
+		}
+		list = append(list, rf)
+	}
+	return list, rows.Err()
+}
+
+// SaveWebhookEvent stores a received event once per (provider, event_id) and reports
+// whether it has already been processed.
+func (r *Repository) SaveWebhookEvent(ctx context.Context, provider string, event *WebhookEvent, payload []byte) (processed bool, err error) {
+	insert := `
+		INSERT INTO payment_webhook_events (id, provider, event_id, event_type, payload, received_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (provider, event_id) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, insert, uuid.New(), provider, event.EventID, event.Type, payload); err != nil {
+		return false, fmt.Errorf("failed to save webhook event: %w", err)
+	}
+	var processedAt sql.NullTime
+	err = r.db.QueryRowContext(ctx,
+		`SELECT processed_at FROM payment_webhook_events WHERE provider = $1 AND event_id = $2`,
+		provider, event.EventID,
+	).Scan(&processedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to read webhook event: %w", err)
+	}
+	return processedAt.Valid, nil
+}
+
+func (r *Repository) MarkWebhookEventProcessed(ctx context.Context, provider, eventID string, intentID *uuid.UUID) error {
+	query := `UPDATE payment_webhook_events SET processed_at = NOW(), payment_intent_id = $3 WHERE provider = $1 AND event_id = $2`
+	if _, err := r.db.ExecContext(ctx, query, provider, eventID, intentID); err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo     *Repository
+	provider Provider
+	payables map[string]PayableHandler
+}
+
+func NewService(repo *Repository, provider Provider) *Service {
+	return &Service{
+		repo:     repo,
+		provider: provider,
+		payables: make(map[string]PayableHandler),
+	}
+}
+
+// RegisterPayable enables payments for an entity type (PayableFine, PayableBooking).
+func (s *Service) RegisterPayable(payableType string, h PayableHandler) {
+	s.payables[payableType] = h
+}
+
+// CreateIntent starts a payment for a payable entity of the user. An unfinished intent
+// for the same entity and amount is returned instead of creating a second one.
+func (s *Service) CreateIntent(ctx context.Context, userID uuid.UUID, req *CreateIntentRequest) (*PaymentIntent, error) {
+	h, ok := s.payables[req.PayableType]
+	if !ok {
+		return nil, fmt.Errorf("payable type %s is not supported", req.PayableType)
+	}
+	payable, err := h.GetPayable(ctx, userID, req.PayableID)
+	if err != nil {
+		return nil, err
+	}
+	if payable == nil {
+		return nil, fmt.Errorf("%s not found", req.PayableType)
+	}
+	if payable.Amount <= 0 {
+		return nil, fmt.Errorf("nothing to pay")
+	}
+
+	existing, err := s.repo.FindActiveIntent(ctx, userID, req.PayableType, req.PayableID, payable.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Provider == s.provider.Name() && existing.CheckoutURL != nil {
+		return existing, nil
+	}
+
+	currency := payable.Currency
+	if currency == "" {
+		currency = "KZT"
+	}
+	p := &PaymentIntent{
+		ID:          uuid.New(),
+		UserID:      userID,
+		PayableType: req.PayableType,
+		PayableID:   req.PayableID,
+		Amount:      payable.Amount,
+		Currency:    currency,
+		Provider:    s.provider.Name(),
+		Status:      StatusCreated,
+	}
+	if payable.Description != "" {
+		p.Description = &payable.Description
+	}
+	if err := s.repo.CreateIntent(ctx, p); err != nil {
+		return nil, err
+	}
+
+	pp, err := s.provider.CreatePayment(ctx, p)
+	if err != nil {
+		reason := err.Error()
+		p.Status = StatusFailed
+		p.FailureReason = &reason
+		if uerr := s.repo.UpdateIntent(ctx, s.repo.db, p); uerr != nil {
+			log.Printf("payments: failed to record provider error for %s: %v", p.ID, uerr)
+		}
+		return nil, fmt.Errorf("payment provider error: %w", err)
+	}
+	p.ProviderPaymentID = &pp.ProviderPaymentID
+	p.CheckoutURL = &pp.CheckoutURL
+	p.Status = StatusPending
+	if err := s.repo.UpdateIntent(ctx, s.repo.db, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) GetIntent(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*PaymentIntent, error) {
+	p, err := s.repo.GetIntentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.UserID != userID {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (s *Service) ListIntents(ctx context.Context, userID uuid.UUID, filter ListIntentsFilter) ([]*PaymentIntent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	return s.repo.ListIntentsByUserID(ctx, userID, filter)
+}
+
+func (s *Service) ListRefunds(ctx context.Context, intentID uuid.UUID) ([]*Refund, error) {
+	return s.repo.ListRefunds(ctx, intentID)
+}
+
+// HandleWebhook verifies and applies a provider notification. Events are recorded by
+// (provider, event_id); an event is only marked processed after its side effects succeed,
+// so a failed attempt is retried on redelivery and a processed one is ignored.
+func (s *Service) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) error {
+	if providerName != s.provider.Name() {
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, providerName)
+	}
+	event, err := s.provider.ParseWebhook(header, body)
+	if err != nil {
+		return err
+	}
+	processed, err := s.repo.SaveWebhookEvent(ctx, providerName, event, body)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	var intent *PaymentIntent
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		intent, err = s.repo.LockIntentByProviderPaymentID(ctx, tx, providerName, event.ProviderPaymentID)
+		if err != nil || intent == nil {
+			return err
+		}
+		next, ok := nextStatus(intent.Status, event.Type)
+		if !ok {
+			return nil
+		}
+		if next == StatusSucceeded && event.Amount > 0 && math.Abs(event.Amount-intent.Amount) > 0.005 {
+			reason := fmt.Sprintf("amount mismatch: expected %.2f, got %.2f", intent.Amount, event.Amount)
+			intent.FailureReason = &reason
+			log.Printf("payments: intent %s: %s", intent.ID, reason)
+			return s.repo.UpdateIntent(ctx, tx, intent)
+		}
+		intent.Status = next
+		switch next {
+		case StatusSucceeded:
+			paidAt := event.OccurredAt
+			if paidAt.IsZero() {
+				paidAt = time.Now()
+			}
+			intent.PaidAt = &paidAt
+			intent.FailureReason = nil
+		case StatusFailed:
+			if event.FailureReason != "" {
+				intent.FailureReason = &event.FailureReason
+			}
+		case StatusRefunded:
+			intent.RefundedAmount = intent.Amount
+		}
+		return s.repo.UpdateIntent(ctx, tx, intent)
+	})
+	if err != nil {
+		return err
+	}
+	if intent == nil {
+		log.Printf("payments: webhook %s for unknown payment %s", event.EventID, event.ProviderPaymentID)
+		return s.repo.MarkWebhookEventProcessed(ctx, providerName, event.EventID, nil)
+	}
+	if err := s.applyToPayable(ctx, intent); err != nil {
+		return err
+	}
+	return s.repo.MarkWebhookEventProcessed(ctx, providerName, event.EventID, &intent.ID)
+}
+
+// Refund returns money for a succeeded payment. Without an amount the remaining sum is refunded.
+// The refund is recorded as pending before the provider is called, outside any transaction,
+// with the refund ID as idempotency key; the outcome is applied in a second transaction.
+func (s *Service) Refund(ctx context.Context, intentID uuid.UUID, actorID uuid.UUID, req *RefundRequest) (*PaymentIntent, error) {
+	var intent *PaymentIntent
+	var rf *Refund
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		intent, err = s.repo.LockIntent(ctx, tx, intentID)
+		if err != nil {
+			return err
+		}
+		if intent == nil {
+			return fmt.Errorf("payment not found")
+		}
+		if intent.Status != StatusSucceeded && intent.Status != StatusPartiallyRefunded {
+			return fmt.Errorf("payment cannot be refunded in status %s", intent.Status)
+		}
+		pending, err := s.repo.PendingRefundAmount(ctx, tx, intent.ID)
+		if err != nil {
+			return err
+		}
+		remaining := math.Round((intent.Amount-intent.RefundedAmount-pending)*100) / 100
+		amount := remaining
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if amount <= 0 || amount > remaining {
+			return fmt.Errorf("refund amount must be between 0 and %.2f", remaining)
+		}
+		rf = &Refund{
+			ID:              uuid.New(),
+			PaymentIntentID: intent.ID,
+			Amount:          amount,
+			Status:          StatusPending,
+			CreatedByUserID: &actorID,
+		}
+		if req.Reason != "" {
+			rf.Reason = &req.Reason
+		}
+		return s.repo.CreateRefund(ctx, tx, rf)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	providerRefundID, err := s.provider.Refund(ctx, intent, rf.Amount, rf.ID.String())
+	if err != nil {
+		reason := err.Error()
+		rf.Status = StatusFailed
+		rf.FailureReason = &reason
+		if uerr := s.repo.UpdateRefund(ctx, s.repo.db, rf); uerr != nil {
+			log.Printf("payments: refund %s: %v", rf.ID, uerr)
+		}
+		return nil, fmt.Errorf("payment provider error: %w", err)
+	}
+
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		intent, err = s.repo.LockIntent(ctx, tx, intentID)
+		if err != nil {
+			return err
+		}
+		if intent == nil {
+			return fmt.Errorf("payment not found")
+		}
+		rf.Status = StatusSucceeded
+		rf.ProviderRefundID = &providerRefundID
+		if err := s.repo.UpdateRefund(ctx, tx, rf); err != nil {
+			return err
+		}
+		intent.RefundedAmount = math.Min(math.Round((intent.RefundedAmount+rf.Amount)*100)/100, intent.Amount)
+		intent.Status = StatusPartiallyRefunded
+		if intent.RefundedAmount >= intent.Amount {
+			intent.Status = StatusRefunded
+		}
+		return s.repo.UpdateIntent(ctx, tx, intent)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyToPayable(ctx, intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// applyToPayable propagates the final payment state to the paid entity.
+func (s *Service) applyToPayable(ctx context.Context, intent *PaymentIntent) error {
+	h, ok := s.payables[intent.PayableType]
+	if !ok {
+		return nil
+	}
+	switch intent.Status {
+	case StatusSucceeded:
+		paidAt := time.Now()
+		if intent.PaidAt != nil {
+			paidAt = *intent.PaidAt
+		}
+		return h.MarkPaid(ctx, intent.PayableID, intent.Amount, paidAt)
+	case StatusRefunded:
+		return h.MarkRefunded(ctx, intent.PayableID)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package payments
+
+// nextStatus returns the status a payment moves to when a provider event arrives.
+// ok is false when the event does not apply to the current status; such events are
+// ignored, which keeps redelivered and out-of-order webhooks harmless.
+func nextStatus(current, eventType string) (next string, ok bool) {
+	switch eventType {
+	case EventPaymentPending:
+		if current == StatusCreated {
+			return StatusPending, true
+		}
+	case EventPaymentSucceeded:
+		// A failed attempt can still be completed by the payer on the same checkout.
+		switch current {
+		case StatusCreated, StatusPending, StatusFailed:
+			return StatusSucceeded, true
+		}
+	case EventPaymentFailed:
+		switch current {
+		case StatusCreated, StatusPending:
+			return StatusFailed, true
+		}
+	case EventPaymentCancelled:
+		switch current {
+		case StatusCreated, StatusPending:
+			return StatusCancelled, true
+		}
+	case EventRefundSucceeded:
+		switch current {
+		case StatusSucceeded, StatusPartiallyRefunded:
+			return StatusRefunded, true
+		}
+	}
+	return current, false
+}
@@ -0,0 +1,40 @@
+package payments
+
+import "testing"
+
+func TestNextStatus(t *testing.T) {
+	cases := []struct {
+		current, event, want string
+		ok                   bool
+	}{
+		{StatusCreated, EventPaymentPending, StatusPending, true},
+		{StatusPending, EventPaymentSucceeded, StatusSucceeded, true},
+		{StatusFailed, EventPaymentSucceeded, StatusSucceeded, true},
+		{StatusSucceeded, EventPaymentSucceeded, StatusSucceeded, false}, // redelivery
+		{StatusSucceeded, EventPaymentFailed, StatusSucceeded, false},    // late failure
+		{StatusPending, EventPaymentCancelled, StatusCancelled, true},
+		{StatusCancelled, EventPaymentSucceeded, StatusCancelled, false},
+		{StatusPartiallyRefunded, EventRefundSucceeded, StatusRefunded, true},
+		{StatusPending, EventRefundSucceeded, StatusPending, false},
+	}
+	for _, tc := range cases {
+		got, ok := nextStatus(tc.current, tc.event)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("nextStatus(%s, %s) = %s, %v; want %s, %v", tc.current, tc.event, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event_id":"evt_1"}`)
+	sig := Sign("secret", body)
+	if !VerifySignature("secret", body, sig) {
+		t.Fatal("expected signature to verify")
+	}
+	if VerifySignature("other", body, sig) {
+		t.Fatal("expected signature with a different secret to fail")
+	}
+	if VerifySignature("secret", []byte(`{"event_id":"evt_2"}`), sig) {
+		t.Fatal("expected signature over a different body to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package reviews
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/media"
+)
+
+const (
+	StatusPublished = "published"
+	StatusHidden    = "hidden"
+)
+
+// FlagReasons lists why a user may flag a review for moderation.
+var FlagReasons = map[string]bool{
+	"spam":      true,
+	"offensive": true,
+	"fake":      true,
+	"personal":  true, // personal data of staff or other customers
+	"other":     true,
+}
+
+// Review is an owner's rating of a service center for a completed booking or an inspection.
+type Review struct {
+	ID                uuid.UUID      `json:"id"`
+	ServiceCenterID   uuid.UUID      `json:"service_center_id"`
+	UserID            uuid.UUID      `json:"user_id"`
+	AuthorName        *string        `json:"author_name,omitempty"`
+	BookingID         *uuid.UUID     `json:"booking_id,omitempty"`
+	InspectionID      *uuid.UUID     `json:"inspection_id,omitempty"`
+	Rating            int            `json:"rating"`
+	Text              *string        `json:"text,omitempty"`
+	Status            string         `json:"status"`
+	FlagsCount        int            `json:"flags_count"`
+	ModerationReason  *string        `json:"moderation_reason,omitempty"`
+	ModeratedByUserID *uuid.UUID     `json:"moderated_by_user_id,omitempty"`
+	ModeratedAt       *time.Time     `json:"moderated_at,omitempty"`
+	ReplyText         *string        `json:"reply_text,omitempty"`
+	ReplyUserID       *uuid.UUID     `json:"reply_user_id,omitempty"`
+	RepliedAt         *time.Time     `json:"replied_at,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Photos            []*media.Asset `json:"photos"`
+}
+
+// RatingSummary is the aggregated rating of a center over published reviews.
+type RatingSummary struct {
+	ServiceCenterID uuid.UUID   `json:"service_center_id"`
+	Average         *float64    `json:"average,omitempty"`
+	Count           int         `json:"count"`
+	Distribution    map[int]int `json:"distribution"` // rating -> number of reviews
+}
+
+// CenterReviews is a page of published reviews of a center with its rating.
+type CenterReviews struct {
+	Summary *RatingSummary `json:"summary"`
+	Reviews []*Review      `json:"reviews"`
+}
+
+// CreateReviewRequest is the request body for reviewing a center. Exactly one of
+// BookingID and InspectionID must be set.
+type CreateReviewRequest struct {
+	BookingID    *uuid.UUID  `json:"booking_id,omitempty"`
+	InspectionID *uuid.UUID  `json:"inspection_id,omitempty"`
+	Rating       int         `json:"rating" binding:"required,min=1,max=5"`
+	Text         string      `json:"text"`
+	AssetIDs     []uuid.UUID `json:"asset_ids,omitempty"` // uploaded photos
+}
+
+// UpdateReviewRequest is the request body for the author changing a review.
+type UpdateReviewRequest struct {
+	Rating   *int        `json:"rating,omitempty" binding:"omitempty,min=1,max=5"`
+	Text     *string     `json:"text,omitempty"`
+	AssetIDs []uuid.UUID `json:"asset_ids,omitempty"` // photos to add
+}
+
+// ReplyRequest is the request body for the service center's public answer.
+type ReplyRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// FlagRequest is the request body for reporting a review.
+type FlagRequest struct {
+	Reason  string `json:"reason" binding:"required"` // spam, offensive, fake, personal, other
+	Comment string `json:"comment"`
+}
+
+// ModerateRequest is the request body for a moderator's decision.
+type ModerateRequest struct {
+	Status string `json:"status" binding:"required"` // published, hidden
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,250 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"alem-auto/internal/database"
+)
+
+const reviewColumns = `r.id, r.service_center_id, r.user_id, u.name, r.booking_id, r.inspection_id, r.rating, r.text, r.status,
+		r.flags_count, r.moderation_reason, r.moderated_by_user_id, r.moderated_at, r.reply_text, r.reply_user_id, r.replied_at,
+		r.created_at, r.updated_at`
+
+const reviewFrom = ` FROM reviews r JOIN users u ON u.id = r.user_id`
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReview(row rowScanner) (*Review, error) {
+	r := &Review{}
+	err := row.Scan(
+		&r.ID, &r.ServiceCenterID, &r.UserID, &r.AuthorName, &r.BookingID, &r.InspectionID, &r.Rating, &r.Text, &r.Status,
+		&r.FlagsCount, &r.ModerationReason, &r.ModeratedByUserID, &r.ModeratedAt, &r.ReplyText, &r.ReplyUserID, &r.RepliedAt,
+		&r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WithTx runs fn inside a database transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.WithTx(ctx, fn)
+}
+
+func (r *Repository) Create(ctx context.Context, q database.Querier, rv *Review) error {
+	query := `
+		INSERT INTO reviews (id, service_center_id, user_id, booking_id, inspection_id, rating, text, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := q.QueryRowContext(ctx, query, rv.ID, rv.ServiceCenterID, rv.UserID, rv.BookingID, rv.InspectionID,
+		rv.Rating, rv.Text, rv.Status).Scan(&rv.CreatedAt, &rv.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrAlreadyReviewed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*Review, error) {
+	rv, err := scanReview(r.db.QueryRowContext(ctx, `SELECT `+reviewColumns+reviewFrom+` WHERE r.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return rv, nil
+}
+
+// LockByID reads a review with a row lock for the duration of tx.
+func (r *Repository) LockByID(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Review, error) {
+	rv, err := scanReview(tx.QueryRowContext(ctx, `SELECT `+reviewColumns+reviewFrom+` WHERE r.id = $1 FOR UPDATE OF r`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock review: %w", err)
+	}
+	return rv, nil
+}
+
+func (r *Repository) Update(ctx context.Context, q database.Querier, rv *Review) error {
+	query := `
+		UPDATE reviews SET rating = $2, text = $3, status = $4, flags_count = $5, moderation_reason = $6,
+			moderated_by_user_id = $7, moderated_at = $8, reply_text = $9, reply_user_id = $10, replied_at = $11
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := q.QueryRowContext(ctx, query, rv.ID, rv.Rating, rv.Text, rv.Status, rv.FlagsCount, rv.ModerationReason,
+		rv.ModeratedByUserID, rv.ModeratedAt, rv.ReplyText, rv.ReplyUserID, rv.RepliedAt).Scan(&rv.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update review: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, q database.Querier, id uuid.UUID) error {
+	_, err := q.ExecContext(ctx, "DELETE FROM reviews WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+	return nil
+}
+
+// ListByCenter returns published reviews of a center, newest first.
+func (r *Repository) ListByCenter(ctx context.Context, centerID uuid.UUID, limit, offset int) ([]*Review, error) {
+	return r.list(ctx, ` WHERE r.service_center_id = $1 AND r.status = $2 ORDER BY r.created_at DESC LIMIT $3 OFFSET $4`,
+		centerID, StatusPublished, limit, offset)
+}
+
+// ListByUser returns all reviews written by a user, newest first.
+func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*Review, error) {
+	return r.list(ctx, ` WHERE r.user_id = $1 ORDER BY r.created_at DESC`, userID)
+}
+
+// ListFlagged returns reviews with flags for moderators, most flagged first.
+func (r *Repository) ListFlagged(ctx context.Context, limit, offset int) ([]*Review, error) {
+	return r.list(ctx, ` WHERE r.flags_count > 0 AND r.moderated_at IS NULL ORDER BY r.flags_count DESC, r.created_at LIMIT $1 OFFSET $2`,
+		limit, offset)
+}
+
+func (r *Repository) list(ctx context.Context, where string, args ...interface{}) ([]*Review, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+reviewColumns+reviewFrom+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+	list := []*Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan review: %w", err)
+		}
+		list = append(list, rv)
+	}
+	return list, rows.Err()
+}
+
+// CreateFlag records a user's flag; it reports false if the user had already flagged the review.
+func (r *Repository) CreateFlag(ctx context.Context, tx *sql.Tx, reviewID, userID uuid.UUID, reason string, comment *string) (bool, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO review_flags (id, review_id, user_id, reason, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (review_id, user_id) DO NOTHING
+	`, uuid.New(), reviewID, userID, reason, comment)
+	if err != nil {
+		return false, fmt.Errorf("failed to flag review: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// LockCenter locks a service center row for the duration of tx, so concurrent review
+// changes of one center recompute its rating one after another.
+func (r *Repository) LockCenter(ctx context.Context, tx *sql.Tx, centerID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM service_centers WHERE id = $1 FOR UPDATE`, centerID).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to lock service center: %w", err)
+	}
+	return nil
+}
+
+// RefreshCenterRating recomputes the stored rating of a center from its published reviews.
+func (r *Repository) RefreshCenterRating(ctx context.Context, q database.Querier, centerID uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE service_centers sc SET
+			rating_avg = agg.avg, rating_count = agg.cnt
+		FROM (
+			SELECT ROUND(AVG(rating)::numeric, 2) AS avg, COUNT(*) AS cnt
+			FROM reviews WHERE service_center_id = $1 AND status = $2
+		) agg
+		WHERE sc.id = $1
+	`, centerID, StatusPublished)
+	if err != nil {
+		return fmt.Errorf("failed to refresh center rating: %w", err)
+	}
+	return nil
+}
+
+// GetRatingSummary returns the stored rating of a center with the distribution of ratings.
+func (r *Repository) GetRatingSummary(ctx context.Context, centerID uuid.UUID) (*RatingSummary, error) {
+	s := &RatingSummary{ServiceCenterID: centerID, Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	err := r.db.QueryRowContext(ctx, `SELECT rating_avg, rating_count FROM service_centers WHERE id = $1`, centerID).
+		Scan(&s.Average, &s.Count)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get center rating: %w", err)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rating, COUNT(*) FROM reviews
+		WHERE service_center_id = $1 AND status = $2
+		GROUP BY rating
+	`, centerID, StatusPublished)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating distribution: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rating, n int
+		if err := rows.Scan(&rating, &n); err != nil {
+			return nil, fmt.Errorf("scan rating distribution: %w", err)
+		}
+		s.Distribution[rating] = n
+	}
+	return s, rows.Err()
+}
+
+// GetBooking returns the owner, center and status of a booking; ok is false if it does not exist.
+func (r *Repository) GetBooking(ctx context.Context, id uuid.UUID) (userID, centerID uuid.UUID, status string, ok bool, err error) {
+	err = r.db.QueryRowContext(ctx, `SELECT user_id, service_center_id, status FROM bookings WHERE id = $1`, id).
+		Scan(&userID, &centerID, &status)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, uuid.Nil, "", false, nil
+	}
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", false, fmt.Errorf("failed to get booking: %w", err)
+	}
+	return userID, centerID, status, true, nil
+}
+
+// CenterRole returns the role of the user at a service center, or "" if the user does not work there.
+func (r *Repository) CenterRole(ctx context.Context, userID, centerID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role FROM service_center_users WHERE user_id = $1 AND service_center_id = $2
+	`, userID, centerID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get center role: %w", err)
+	}
+	return role, nil
+}
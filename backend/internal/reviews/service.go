@@ -0,0 +1,407 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/inspection"
+	"alem-auto/internal/media"
+	"alem-auto/internal/vehicle"
+)
+
+// ErrAlreadyReviewed is returned for a second review of the same booking or inspection.
+var ErrAlreadyReviewed = errors.New("this visit has already been reviewed")
+
+// autoHideFlags is the number of flags after which a review is hidden until a moderator looks at it.
+const autoHideFlags = 5
+
+type Service struct {
+	repo              *Repository
+	inspectionService *inspection.Service
+	vehicleService    *vehicle.Service
+	media             *media.Service
+}
+
+func NewService(repo *Repository, inspectionService *inspection.Service, vehicleService *vehicle.Service, mediaService *media.Service) *Service {
+	return &Service{
+		repo:              repo,
+		inspectionService: inspectionService,
+		vehicleService:    vehicleService,
+		media:             mediaService,
+	}
+}
+
+// Create publishes a review of the center that served the user: the booking must be the
+// user's and completed, the inspection must be of a vehicle the user currently owns.
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req *CreateReviewRequest) (*Review, error) {
+	if (req.BookingID == nil) == (req.InspectionID == nil) {
+		return nil, fmt.Errorf("exactly one of booking_id and inspection_id is required")
+	}
+	if len(req.AssetIDs) > 0 && s.media == nil {
+		return nil, fmt.Errorf("media storage is not configured")
+	}
+	rv := &Review{
+		ID:     uuid.New(),
+		UserID: userID,
+		Rating: req.Rating,
+		Status: StatusPublished,
+	}
+	if text := strings.TrimSpace(req.Text); text != "" {
+		rv.Text = &text
+	}
+
+	if req.BookingID != nil {
+		ownerID, centerID, status, ok, err := s.repo.GetBooking(ctx, *req.BookingID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || ownerID != userID {
+			return nil, fmt.Errorf("booking not found")
+		}
+		if status != "completed" {
+			return nil, fmt.Errorf("only completed bookings can be reviewed")
+		}
+		rv.BookingID = req.BookingID
+		rv.ServiceCenterID = centerID
+	} else {
+		insp, err := s.inspectionService.GetInspectionByID(ctx, *req.InspectionID)
+		if err != nil {
+			return nil, err
+		}
+		if insp == nil {
+			return nil, fmt.Errorf("inspection not found")
+		}
+		owns, err := s.ownsVehicle(ctx, userID, insp.VehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if !owns {
+			return nil, fmt.Errorf("inspection not found")
+		}
+		rv.InspectionID = req.InspectionID
+		rv.ServiceCenterID = insp.ServiceCenterID
+	}
+
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockCenter(ctx, tx, rv.ServiceCenterID); err != nil {
+			return err
+		}
+		if err := s.repo.Create(ctx, tx, rv); err != nil {
+			return err
+		}
+		if err := s.linkPhotos(ctx, tx, userID, rv.ID, req.AssetIDs); err != nil {
+			return err
+		}
+		return s.repo.RefreshCenterRating(ctx, tx, rv.ServiceCenterID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, rv.ID)
+}
+
+func (s *Service) ownsVehicle(ctx context.Context, userID, vehicleID uuid.UUID) (bool, error) {
+	owners, err := s.vehicleService.GetCurrentOwnersByVehicleID(ctx, vehicleID)
+	if err != nil {
+		return false, err
+	}
+	for _, o := range owners {
+		if o.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkPhotos attaches photos the user uploaded to a review inside tx.
+func (s *Service) linkPhotos(ctx context.Context, tx *sql.Tx, userID, reviewID uuid.UUID, assetIDs []uuid.UUID) error {
+	for _, assetID := range assetIDs {
+		if err := s.media.LinkOwnAsset(ctx, tx, userID, assetID, "review", reviewID); err != nil {
+			return fmt.Errorf("failed to attach photo %s: %w", assetID, err)
+		}
+	}
+	return nil
+}
+
+// get loads a review with its photos.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*Review, error) {
+	rv, err := s.repo.GetByID(ctx, id)
+	if err != nil || rv == nil {
+		return nil, err
+	}
+	if err := s.withPhotos(ctx, rv); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func (s *Service) withPhotos(ctx context.Context, list ...*Review) error {
+	for _, rv := range list {
+		rv.Photos = []*media.Asset{}
+		if s.media == nil {
+			continue
+		}
+		photos, err := s.media.GetAssetsByLink(ctx, "review", rv.ID)
+		if err != nil {
+			return err
+		}
+		if photos != nil {
+			rv.Photos = photos
+		}
+	}
+	return nil
+}
+
+// GetByID returns a published review, or any review to its author and moderators.
+func (s *Service) GetByID(ctx context.Context, id, userID uuid.UUID, role string) (*Review, error) {
+	rv, err := s.get(ctx, id)
+	if err != nil || rv == nil {
+		return nil, err
+	}
+	if rv.Status != StatusPublished && rv.UserID != userID && !isModerator(role) {
+		return nil, nil
+	}
+	return rv, nil
+}
+
+// Update lets the author change the rating, text and add photos.
+func (s *Service) Update(ctx context.Context, id, userID uuid.UUID, req *UpdateReviewRequest) (*Review, error) {
+	if len(req.AssetIDs) > 0 && s.media == nil {
+		return nil, fmt.Errorf("media storage is not configured")
+	}
+	found := true
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rv, err := s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rv == nil || rv.UserID != userID {
+			found = false
+			return nil
+		}
+		if err := s.repo.LockCenter(ctx, tx, rv.ServiceCenterID); err != nil {
+			return err
+		}
+		if req.Rating != nil {
+			rv.Rating = *req.Rating
+		}
+		if req.Text != nil {
+			rv.Text = nil
+			if text := strings.TrimSpace(*req.Text); text != "" {
+				rv.Text = &text
+			}
+		}
+		if err := s.repo.Update(ctx, tx, rv); err != nil {
+			return err
+		}
+		if err := s.linkPhotos(ctx, tx, userID, rv.ID, req.AssetIDs); err != nil {
+			return err
+		}
+		return s.repo.RefreshCenterRating(ctx, tx, rv.ServiceCenterID)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+// Delete removes a review of the author; false means it was not found.
+func (s *Service) Delete(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	found := true
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rv, err := s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rv == nil || rv.UserID != userID {
+			found = false
+			return nil
+		}
+		if err := s.repo.LockCenter(ctx, tx, rv.ServiceCenterID); err != nil {
+			return err
+		}
+		if err := s.repo.Delete(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.repo.RefreshCenterRating(ctx, tx, rv.ServiceCenterID)
+	})
+	return found, err
+}
+
+// ListForCenter returns published reviews of a center with its rating.
+func (s *Service) ListForCenter(ctx context.Context, centerID uuid.UUID, limit, offset int) (*CenterReviews, error) {
+	summary, err := s.repo.GetRatingSummary(ctx, centerID)
+	if err != nil || summary == nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	list, err := s.repo.ListByCenter(ctx, centerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withPhotos(ctx, list...); err != nil {
+		return nil, err
+	}
+	return &CenterReviews{Summary: summary, Reviews: list}, nil
+}
+
+// ListMine returns the reviews written by the user.
+func (s *Service) ListMine(ctx context.Context, userID uuid.UUID) ([]*Review, error) {
+	list, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withPhotos(ctx, list...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Reply sets the public answer of the service center; admins of the center and platform users may reply.
+func (s *Service) Reply(ctx context.Context, id, userID uuid.UUID, role string, req *ReplyRequest) (*Review, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	found := true
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rv, err := s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rv == nil {
+			found = false
+			return nil
+		}
+		if role != "platform" {
+			centerRole, err := s.repo.CenterRole(ctx, userID, rv.ServiceCenterID)
+			if err != nil {
+				return err
+			}
+			if centerRole != "admin" {
+				return fmt.Errorf("only service center admins can reply to reviews")
+			}
+		}
+		now := time.Now()
+		rv.ReplyText = &text
+		rv.ReplyUserID = &userID
+		rv.RepliedAt = &now
+		return s.repo.Update(ctx, tx, rv)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+// Flag reports a review for moderation. Flagging twice has no effect; enough flags
+// hide the review until a moderator decides.
+func (s *Service) Flag(ctx context.Context, id, userID uuid.UUID, req *FlagRequest) (*Review, error) {
+	if !FlagReasons[req.Reason] {
+		return nil, fmt.Errorf("invalid reason: %s", req.Reason)
+	}
+	found := true
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rv, err := s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rv == nil || rv.Status != StatusPublished {
+			found = false
+			return nil
+		}
+		if err := s.repo.LockCenter(ctx, tx, rv.ServiceCenterID); err != nil {
+			return err
+		}
+		if rv.UserID == userID {
+			return fmt.Errorf("cannot flag your own review")
+		}
+		var comment *string
+		if c := strings.TrimSpace(req.Comment); c != "" {
+			comment = &c
+		}
+		added, err := s.repo.CreateFlag(ctx, tx, id, userID, req.Reason, comment)
+		if err != nil || !added {
+			return err
+		}
+		rv.FlagsCount++
+		// A moderator's earlier decision is reopened by new flags.
+		rv.ModeratedAt, rv.ModeratedByUserID = nil, nil
+		if rv.FlagsCount >= autoHideFlags {
+			rv.Status = StatusHidden
+			reason := "hidden automatically after user reports"
+			rv.ModerationReason = &reason
+		}
+		if err := s.repo.Update(ctx, tx, rv); err != nil {
+			return err
+		}
+		return s.repo.RefreshCenterRating(ctx, tx, rv.ServiceCenterID)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+// ListFlagged returns flagged reviews awaiting moderation.
+func (s *Service) ListFlagged(ctx context.Context, limit, offset int) ([]*Review, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	list, err := s.repo.ListFlagged(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withPhotos(ctx, list...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Moderate publishes or hides a review and updates the center rating.
+func (s *Service) Moderate(ctx context.Context, id, moderatorID uuid.UUID, req *ModerateRequest) (*Review, error) {
+	if req.Status != StatusPublished && req.Status != StatusHidden {
+		return nil, fmt.Errorf("invalid status: %s", req.Status)
+	}
+	found := true
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rv, err := s.repo.LockByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rv == nil {
+			found = false
+			return nil
+		}
+		if err := s.repo.LockCenter(ctx, tx, rv.ServiceCenterID); err != nil {
+			return err
+		}
+		now := time.Now()
+		rv.Status = req.Status
+		rv.ModeratedAt = &now
+		rv.ModeratedByUserID = &moderatorID
+		rv.ModerationReason = nil
+		if reason := strings.TrimSpace(req.Reason); reason != "" {
+			rv.ModerationReason = &reason
+		}
+		if err := s.repo.Update(ctx, tx, rv); err != nil {
+			return err
+		}
+		return s.repo.RefreshCenterRating(ctx, tx, rv.ServiceCenterID)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+func isModerator(role string) bool {
+	return role == "admin" || role == "platform"
+}
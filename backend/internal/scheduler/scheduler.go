@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"alem-auto/internal/database"
+)
+
+// Job is a task run periodically by the scheduler.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on their intervals. Each run holds a Postgres advisory lock named
+// after the job, so with several server replicas a job runs on one of them at a time;
+// the others skip that tick. Jobs must still be idempotent: the next tick may run on another replica.
+type Scheduler struct {
+	db   *database.DB
+	jobs []Job
+}
+
+func New(db *database.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// Add registers a job; it must be called before Run.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Run starts all jobs and blocks until ctx is cancelled and running jobs return.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	key := database.LockKey("scheduler:" + job.Name)
+	for {
+		// A false result means another instance holds the lock and runs the job.
+		if _, err := s.db.WithAdvisoryLock(ctx, key, job.Run); err != nil && ctx.Err() == nil {
+			log.Printf("scheduler: job %s failed: %v", job.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
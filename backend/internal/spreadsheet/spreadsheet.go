@@ -0,0 +1,117 @@
+// Package spreadsheet reads and writes simple tables as CSV or XLSX. XLSX support covers the
+// first worksheet of a workbook with text and number cells, which is what bulk imports and
+// exports need; formulas are read as their cached values.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// maxFileSize bounds what is read into memory.
+const maxFileSize = 32 << 20
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// FormatFromName returns the format of a file by its extension, "" if it is not supported.
+func FormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	}
+	return ""
+}
+
+// Read returns the rows of a CSV or XLSX table. Trailing empty rows are dropped.
+func Read(r io.Reader, format string) ([][]string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("file exceeds %d MB", maxFileSize>>20)
+	}
+	var rows [][]string
+	switch format {
+	case FormatCSV:
+		rows, err = readCSV(data)
+	case FormatXLSX:
+		rows, err = readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, use csv or xlsx", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for len(rows) > 0 && isBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// Write writes rows as CSV or XLSX. CSV starts with a byte order mark so spreadsheet
+// programs read it as UTF-8.
+func Write(w io.Writer, format string, rows [][]string) error {
+	switch format {
+	case FormatCSV:
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		return nil
+	case FormatXLSX:
+		return writeXLSX(w, rows)
+	}
+	return fmt.Errorf("unsupported format %q, use csv or xlsx", format)
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// readCSV accepts comma and semicolon separated files; the separator is taken from the
+// header line.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte{';'}) > bytes.Count(header, []byte{','}) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+func isBlank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
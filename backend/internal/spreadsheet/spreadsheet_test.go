@@ -0,0 +1,86 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestXLSXRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"sku", "name", "quantity"},
+		{"OIL-5W30", "Масло <синтетика> & Co", "12"},
+		{"FLT-001", "", "3"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatXLSX, rows); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf, FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("got %q, want %q", got, rows)
+	}
+}
+
+func TestReadCSVSemicolonWithBOM(t *testing.T) {
+	data := "\xEF\xBB\xBFsku;name\nA-1;\"Фильтр; воздушный\"\n\n"
+	got, err := Read(bytes.NewBufferString(data), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"sku", "name"}, {"A-1", "Фильтр; воздушный"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(col); got != name {
+			t.Errorf("columnName(%d) = %s, want %s", col, got, name)
+		}
+		if name == "AAA" {
+			continue // beyond maxColumns
+		}
+		if got, err := columnIndex(name + "7"); err != nil || got != col {
+			t.Errorf("columnIndex(%s7) = %d, %v", name, got, err)
+		}
+	}
+}
+
+// TestReadXLSXSharedStrings reads a workbook laid out the way spreadsheet programs save it:
+// shared strings, number cells, skipped cells and a sheet found through the workbook rels.
+func TestReadXLSXSharedStrings(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Склад" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId3" Target="worksheets/stock.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>Артикул</t></si><si><t>Кол-во</t></si><si><r><t>BRK-</t></r><r><t>10</t></r></si></sst>`,
+		"xl/worksheets/stock.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>4.5</v></c></row>
+			</sheetData></worksheet>`,
+	} {
+		f, _ := zw.Create(name)
+		f.Write([]byte(body))
+	}
+	zw.Close()
+
+	got, err := Read(&buf, FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"Артикул", "", "Кол-во"}, nil, {"BRK-10", "", "4.5"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
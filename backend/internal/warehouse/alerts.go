@@ -0,0 +1,111 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"alem-auto/internal/notify"
+)
+
+// SetNotifier enables low-stock alerts.
+func (s *Service) SetNotifier(ch notify.Channel) {
+	s.notifier = ch
+}
+
+// crossedMinimum reports whether a change took stock from the minimum or above to below it.
+// Stock that was already low does not alert again on every issue.
+func crossedMinimum(before, after, min int) bool {
+	return min > 0 && before >= min && after < min
+}
+
+// LowStock returns items below their minimum at the locations the user may see, with what is
+// already on order.
+func (s *Service) LowStock(ctx context.Context, userID uuid.UUID, role string, locationID *uuid.UUID) ([]*LowStock, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListLowStock(ctx, locationID, scope.ids())
+	if err != nil {
+		return nil, err
+	}
+	for _, ls := range list {
+		if short := ls.MinQuantity - ls.Quantity - ls.OnOrder; short > 0 {
+			ls.Shortage = short
+		}
+	}
+	return list, nil
+}
+
+// withTx runs fn in a transaction like Repository.WithTx. Movements posted in it may take
+// stock below the minimum; their alerts are sent only once the transaction has committed, so
+// a change that is rolled back never alerts.
+func (s *Service) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	var posted []*Movement
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		s.posted.Store(tx, &posted)
+		defer s.posted.Delete(tx)
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range posted {
+		if m.QuantityDelta < 0 && m.QuantityAfter != nil {
+			s.notifyLowStock(ctx, m.ItemID, m.LocationID, *m.QuantityAfter-m.QuantityDelta, *m.QuantityAfter)
+		}
+	}
+	return nil
+}
+
+// queueLowStock remembers a movement posted in tx for the alerts withTx sends after commit.
+func (s *Service) queueLowStock(tx *sql.Tx, m *Movement) {
+	if s.notifier == nil {
+		return
+	}
+	if v, ok := s.posted.Load(tx); ok {
+		list := v.(*[]*Movement)
+		*list = append(*list, m)
+	}
+}
+
+// notifyLowStock sends the low stock alert if the change from before to quantity crossed the
+// item's minimum; failures are only logged.
+func (s *Service) notifyLowStock(ctx context.Context, itemID, locationID uuid.UUID, before, quantity int) {
+	item, err := s.repo.GetItemByID(ctx, itemID)
+	if err != nil || item == nil {
+		log.Printf("warehouse: low stock alert for item %s: %v", itemID, err)
+		return
+	}
+	if !crossedMinimum(before, quantity, item.MinQuantity) {
+		return
+	}
+	loc, err := s.repo.GetLocationByID(ctx, locationID)
+	if err != nil || loc == nil {
+		log.Printf("warehouse: low stock alert for %s: location %s: %v", item.SKU, locationID, err)
+		return
+	}
+	users, err := s.repo.ListStockKeepers(ctx, loc.ID)
+	if err != nil {
+		log.Printf("warehouse: low stock alert for %s: %v", item.SKU, err)
+		return
+	}
+	for _, userID := range users {
+		m := &notify.Message{
+			UserID: userID,
+			Kind:   notify.KindLowStock,
+			Title:  "Заканчивается запас",
+			Body: fmt.Sprintf("%s «%s»: на складе «%s» осталось %d %s при минимуме %d.",
+				item.SKU, item.Name, loc.Name, quantity, item.Unit, item.MinQuantity),
+			Data:   map[string]string{"item_id": item.ID.String(), "location_id": loc.ID.String()},
+			SentAt: time.Now(),
+		}
+		if err := s.notifier.Send(ctx, m); err != nil {
+			log.Printf("warehouse: low stock alert for %s failed: %v", item.SKU, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// ErrBarcodeInUse is returned when a barcode already belongs to another item.
+var ErrBarcodeInUse = errors.New("barcode is already assigned to an item")
+
+const (
+	maxBarcodeLen    = 64
+	maxPartNumberLen = 100
+	maxSearchLen     = 100
+)
+
+// checkDigit returns the GS1 check digit of the digits before it (EAN-13, EAN-8, UPC-A).
+func checkDigit(body string) int {
+	sum := 0
+	weight := 3
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return (10 - sum%10) % 10
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validGTIN reports whether code is all digits and ends with its check digit.
+func validGTIN(code string) bool {
+	return len(code) >= 2 && isDigits(code) && checkDigit(code[:len(code)-1]) == int(code[len(code)-1]-'0')
+}
+
+// normalizeBarcode validates a code of the given type, detecting the type if it is empty, and
+// returns the code as stored.
+func normalizeBarcode(code, typ string) (string, string, error) {
+	code = strings.TrimSpace(code)
+	if typ == "" {
+		switch {
+		case isDigits(code) && len(code) == 13:
+			typ = BarcodeEAN13
+		case isDigits(code) && len(code) == 8:
+			typ = BarcodeEAN8
+		case isDigits(code) && len(code) == 12:
+			typ = BarcodeUPCA
+		default:
+			typ = BarcodeInternal
+		}
+	}
+	switch typ {
+	case BarcodeEAN13, BarcodeEAN8:
+		n := 13
+		if typ == BarcodeEAN8 {
+			n = 8
+		}
+		if len(code) != n || !isDigits(code) {
+			return "", "", fmt.Errorf("%s barcode must be %d digits", typ, n)
+		}
+		if !validGTIN(code) {
+			return "", "", fmt.Errorf("invalid check digit in barcode %s", code)
+		}
+		return code, typ, nil
+	case BarcodeUPCA:
+		if len(code) == 12 && isDigits(code) {
+			code = "0" + code
+		}
+		if len(code) != 13 || code[0] != '0' || !isDigits(code) {
+			return "", "", fmt.Errorf("upc_a barcode must be 12 digits")
+		}
+		if !validGTIN(code) {
+			return "", "", fmt.Errorf("invalid check digit in barcode %s", code[1:])
+		}
+		return code, typ, nil
+	case BarcodeInternal:
+		code = strings.ToUpper(code)
+		if code == "" || len(code) > maxBarcodeLen {
+			return "", "", fmt.Errorf("internal barcode must be 1 to %d characters", maxBarcodeLen)
+		}
+		for _, r := range code {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._/", r)) {
+				return "", "", fmt.Errorf("internal barcode may only contain letters, digits and - . _ /")
+			}
+		}
+		return code, typ, nil
+	}
+	return "", "", fmt.Errorf("type must be %s, %s, %s or %s", BarcodeEAN13, BarcodeEAN8, BarcodeUPCA, BarcodeInternal)
+}
+
+// scanCandidates returns the stored forms a scanned code may have: a 12-digit UPC-A is kept
+// with a leading zero and internal codes in upper case.
+func scanCandidates(code string) []string {
+	code = strings.TrimSpace(code)
+	list := []string{code}
+	if upper := strings.ToUpper(code); upper != code {
+		list = append(list, upper)
+	}
+	if len(code) == 12 && validGTIN(code) {
+		list = append(list, "0"+code)
+	}
+	return list
+}
+
+// normalizePartNumber keeps only the letters and digits of a part number, in upper case, so
+// "04465-02220" and "0446502220" match.
+func normalizePartNumber(number string) string {
+	var b strings.Builder
+	for _, r := range number {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// ListBarcodes returns the barcodes of an item.
+func (s *Service) ListBarcodes(ctx context.Context, itemID uuid.UUID) ([]*Barcode, error) {
+	return s.repo.ListBarcodes(ctx, itemID)
+}
+
+// AddBarcode validates a barcode, including its check digit, and assigns it to an item. Adding
+// a code the item already has returns it unchanged.
+func (s *Service) AddBarcode(ctx context.Context, itemID uuid.UUID, req *AddBarcodeRequest) (*Barcode, error) {
+	code, typ, err := normalizeBarcode(req.Code, req.Type)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.repo.GetItemByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	b := &Barcode{ID: uuid.New(), ItemID: item.ID, Code: code, Type: typ}
+	err = s.repo.CreateBarcode(ctx, b)
+	if errors.Is(err, errDuplicateKey) {
+		existing, err := s.repo.GetBarcodeByCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ItemID == item.ID {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrBarcodeInUse, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DeleteBarcode removes a barcode from an item and reports whether it was there.
+func (s *Service) DeleteBarcode(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	return s.repo.DeleteBarcode(ctx, itemID, id)
+}
+
+// ListPartNumbers returns the OEM and aftermarket numbers of an item.
+func (s *Service) ListPartNumbers(ctx context.Context, itemID uuid.UUID) ([]*PartNumber, error) {
+	return s.repo.ListPartNumbers(ctx, itemID)
+}
+
+// AddPartNumber adds an OEM or aftermarket number to an item. Adding a number the item
+// already has under the same kind returns it unchanged.
+func (s *Service) AddPartNumber(ctx context.Context, itemID uuid.UUID, req *AddPartNumberRequest) (*PartNumber, error) {
+	kind := req.Kind
+	if kind == "" {
+		kind = PartNumberOEM
+	}
+	if kind != PartNumberOEM && kind != PartNumberAftermarket {
+		return nil, fmt.Errorf("kind must be %s or %s", PartNumberOEM, PartNumberAftermarket)
+	}
+	number := strings.TrimSpace(req.Number)
+	if len(number) > maxPartNumberLen || normalizePartNumber(number) == "" {
+		return nil, fmt.Errorf("number must have letters or digits and not exceed %d characters", maxPartNumberLen)
+	}
+	var brand *string
+	if req.Brand != nil {
+		if b := strings.TrimSpace(*req.Brand); b != "" {
+			if len(b) > 100 {
+				return nil, fmt.Errorf("brand must not exceed 100 characters")
+			}
+			brand = &b
+		}
+	}
+	item, err := s.repo.GetItemByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	pn := &PartNumber{ID: uuid.New(), ItemID: item.ID, Kind: kind, Brand: brand, Number: number}
+	return s.repo.CreatePartNumber(ctx, pn, normalizePartNumber(number))
+}
+
+// DeletePartNumber removes a part number from an item and reports whether it was there.
+func (s *Service) DeletePartNumber(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	return s.repo.DeletePartNumber(ctx, itemID, id)
+}
+
+// findItemByCode returns the item a scanned code belongs to: by barcode, else by SKU. It
+// returns nil if there is none.
+func (s *Service) findItemByCode(ctx context.Context, code string) (item *Item, matchedBy string, err error) {
+	code = strings.TrimSpace(code)
+	if code == "" || len(code) > maxBarcodeLen*2 {
+		return nil, "", nil
+	}
+	item, err = s.repo.GetItemByBarcode(ctx, scanCandidates(code))
+	if err != nil || item != nil {
+		return item, "barcode", err
+	}
+	item, err = s.repo.GetItemBySKU(ctx, code)
+	if err != nil || item != nil {
+		return item, "sku", err
+	}
+	return nil, "", nil
+}
+
+// LookupCode returns the item a scanned code belongs to with its codes and the stock at the
+// locations the user may see; nil if no item has the code.
+func (s *Service) LookupCode(ctx context.Context, userID uuid.UUID, role, code string) (*ItemLookup, error) {
+	item, matchedBy, err := s.findItemByCode(ctx, code)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	res := &ItemLookup{Item: item, MatchedBy: matchedBy}
+	if res.Barcodes, err = s.repo.ListBarcodes(ctx, item.ID); err != nil {
+		return nil, err
+	}
+	if res.PartNumbers, err = s.repo.ListPartNumbers(ctx, item.ID); err != nil {
+		return nil, err
+	}
+	if res.Stock, err = s.GetStock(ctx, userID, role, item.ID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
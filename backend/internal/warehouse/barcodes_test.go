@@ -0,0 +1,67 @@
+package warehouse
+
+import "testing"
+
+func TestValidGTIN(t *testing.T) {
+	for _, code := range []string{"4006381333931", "4600682000068", "96385074", "0036000291452"} {
+		if !validGTIN(code) {
+			t.Errorf("validGTIN(%s) = false, want true", code)
+		}
+	}
+	for _, code := range []string{"4006381333932", "96385075", "40063813339A1", ""} {
+		if validGTIN(code) {
+			t.Errorf("validGTIN(%q) = true, want false", code)
+		}
+	}
+}
+
+func TestNormalizeBarcode(t *testing.T) {
+	cases := []struct {
+		code, typ         string
+		wantCode, wantTyp string
+		wantErr           bool
+	}{
+		{"4006381333931", "", "4006381333931", BarcodeEAN13, false},
+		{" 96385074 ", "", "96385074", BarcodeEAN8, false},
+		{"036000291452", "", "0036000291452", BarcodeUPCA, false},
+		{"0036000291452", BarcodeUPCA, "0036000291452", BarcodeUPCA, false},
+		{"4006381333932", "", "", "", true},
+		{"036000291453", "", "", "", true},
+		{"40063813", BarcodeEAN13, "", "", true},
+		{"shelf-a/12", "", "SHELF-A/12", BarcodeInternal, false},
+		{"4006381333932", BarcodeInternal, "4006381333932", BarcodeInternal, false},
+		{"box #1", "", "", "", true},
+		{"4006381333931", "qr", "", "", true},
+	}
+	for _, c := range cases {
+		code, typ, err := normalizeBarcode(c.code, c.typ)
+		if (err != nil) != c.wantErr || code != c.wantCode || typ != c.wantTyp {
+			t.Errorf("normalizeBarcode(%q, %q) = %q, %q, %v", c.code, c.typ, code, typ, err)
+		}
+	}
+}
+
+func TestScanCandidates(t *testing.T) {
+	got := scanCandidates("036000291452")
+	if len(got) != 2 || got[1] != "0036000291452" {
+		t.Errorf("UPC-A scan: got %v", got)
+	}
+	got = scanCandidates("shelf-a")
+	if len(got) != 2 || got[1] != "SHELF-A" {
+		t.Errorf("internal scan: got %v", got)
+	}
+}
+
+func TestNormalizePartNumber(t *testing.T) {
+	cases := map[string]string{
+		"04465-02220":   "0446502220",
+		" p 85 020 ":    "P85020",
+		"0 986.494.104": "0986494104",
+		"--":            "",
+	}
+	for in, want := range cases {
+		if got := normalizePartNumber(in); got != want {
+			t.Errorf("normalizePartNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
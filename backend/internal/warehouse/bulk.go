@@ -0,0 +1,401 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrImportInvalid is returned when an import has row errors; nothing is saved.
+var ErrImportInvalid = errors.New("import has invalid rows, nothing was saved")
+
+// importReference marks the stock movements made by imports.
+const importReference = "import"
+
+var importFields = []string{FieldSKU, FieldName, FieldDescription, FieldUnit, FieldCategory, FieldMinQuantity, FieldQuantity, FieldUnitCost}
+
+// exportHeader is the header of exported item tables; it can be imported back as is.
+var exportHeader = []string{FieldSKU, FieldName, FieldDescription, FieldUnit, FieldCategory, FieldMinQuantity, FieldQuantity,
+	"reserved", "available", "value"}
+
+// importRow is one parsed table row; nil fields were left empty and keep their current value.
+type importRow struct {
+	line        int
+	sku         string
+	name        *string
+	description *string
+	unit        *string
+	category    *string
+	minQuantity *int
+	quantity    *int
+	unitCost    *float64
+}
+
+// importColumns maps fields to column indexes of the header; a mapping names the header of a
+// field, other fields are found by their own name. Headers are compared case-insensitively.
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	byName := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if _, dup := byName[h]; !dup && h != "" {
+			byName[h] = i
+		}
+	}
+	known := make(map[string]bool, len(importFields))
+	for _, f := range importFields {
+		known[f] = true
+	}
+	for f := range mapping {
+		if !known[f] {
+			return nil, fmt.Errorf("unknown field %q in mapping, use one of %s", f, strings.Join(importFields, ", "))
+		}
+	}
+	cols := make(map[string]int, len(importFields))
+	for _, f := range importFields {
+		name := f
+		mapped, ok := mapping[f]
+		if ok {
+			name = mapped
+		}
+		i, found := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !found {
+			if ok {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the header", mapped, f)
+			}
+			continue
+		}
+		cols[f] = i
+	}
+	if _, ok := cols[FieldSKU]; !ok {
+		return nil, fmt.Errorf("the table needs a %s column", FieldSKU)
+	}
+	return cols, nil
+}
+
+// parseImportRows validates the data rows of a table; line numbers count the header as 1.
+func parseImportRows(rows [][]string, cols map[string]int) ([]*importRow, []ImportRowError) {
+	var parsed []*importRow
+	var errs []ImportRowError
+	seen := map[string]int{}
+	for i, cells := range rows {
+		line := i + 2
+		cell := func(field string) (string, bool) {
+			c, ok := cols[field]
+			if !ok || c >= len(cells) {
+				return "", false
+			}
+			v := strings.TrimSpace(cells[c])
+			return v, v != ""
+		}
+		blank := true
+		for _, v := range cells {
+			if strings.TrimSpace(v) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+		fail := func(field, format string, args ...interface{}) {
+			errs = append(errs, ImportRowError{Row: line, Column: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		r := &importRow{line: line}
+		r.sku, _ = cell(FieldSKU)
+		switch {
+		case r.sku == "":
+			fail(FieldSKU, "sku is required")
+			continue
+		case len(r.sku) > 100:
+			fail(FieldSKU, "sku must not exceed 100 characters")
+			continue
+		case seen[r.sku] != 0:
+			fail(FieldSKU, "sku %s is already in row %d", r.sku, seen[r.sku])
+			continue
+		}
+		seen[r.sku] = line
+		ok := true
+		for _, f := range []struct {
+			field string
+			max   int
+			dst   **string
+		}{
+			{FieldName, 255, &r.name},
+			{FieldDescription, 0, &r.description},
+			{FieldUnit, 50, &r.unit},
+			{FieldCategory, maxCategoryLen, &r.category},
+		} {
+			if v, set := cell(f.field); set {
+				if f.max > 0 && len([]rune(v)) > f.max {
+					fail(f.field, "%s must not exceed %d characters", f.field, f.max)
+					ok = false
+					continue
+				}
+				*f.dst = &v
+			}
+		}
+		for _, f := range []struct {
+			field string
+			dst   **int
+		}{
+			{FieldMinQuantity, &r.minQuantity},
+			{FieldQuantity, &r.quantity},
+		} {
+			if v, set := cell(f.field); set {
+				n, err := parseCount(v)
+				if err != nil {
+					fail(f.field, "%s: %v", f.field, err)
+					ok = false
+					continue
+				}
+				*f.dst = &n
+			}
+		}
+		if v, set := cell(FieldUnitCost); set {
+			c, err := strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(v, " ", ""), ",", "."), 64)
+			if err != nil || c < 0 || math.IsInf(c, 0) || math.IsNaN(c) {
+				fail(FieldUnitCost, "unit_cost must be a non-negative number")
+				ok = false
+			} else {
+				r.unitCost = &c
+			}
+		}
+		if ok {
+			parsed = append(parsed, r)
+		}
+	}
+	return parsed, errs
+}
+
+// parseCount reads a non-negative whole number; spreadsheets may store it as "12.0".
+func parseCount(v string) (int, error) {
+	v = strings.ReplaceAll(v, " ", "")
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+		if ferr != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+			return 0, fmt.Errorf("%q is not a whole number", v)
+		}
+		n = int(f)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return n, nil
+}
+
+// apply merges a row into the item it describes and reports whether anything changed.
+func (r *importRow) apply(item *Item) bool {
+	changed := false
+	set := func(dst *string, v *string) {
+		if v != nil && *dst != *v {
+			*dst = *v
+			changed = true
+		}
+	}
+	set(&item.Name, r.name)
+	set(&item.Description, r.description)
+	set(&item.Unit, r.unit)
+	set(&item.Category, r.category)
+	if r.minQuantity != nil && item.MinQuantity != *r.minQuantity {
+		item.MinQuantity = *r.minQuantity
+		changed = true
+	}
+	return changed
+}
+
+// ImportItems creates or updates items by SKU from a table whose first row is the header.
+// With SetStock, on-hand stock at the location is set to the quantity column through adjust
+// movements costed at unit_cost. Any row error rejects the whole import with ErrImportInvalid;
+// a dry run only reports what would be done.
+func (s *Service) ImportItems(ctx context.Context, table [][]string, opts ImportOptions) (*ImportResult, error) {
+	if len(table) == 0 {
+		return nil, fmt.Errorf("the table is empty")
+	}
+	cols, err := importColumns(table[0], opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cols[FieldQuantity]; opts.SetStock && !ok {
+		return nil, fmt.Errorf("setting stock needs a %s column", FieldQuantity)
+	}
+	var loc *Location
+	if opts.SetStock {
+		if loc, err = s.activeLocation(ctx, opts.LocationID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, errs := parseImportRows(table[1:], cols)
+	res := &ImportResult{DryRun: opts.DryRun, Errors: errs}
+	skus := make([]string, len(rows))
+	for i, r := range rows {
+		skus[i] = r.sku
+	}
+	existing, err := s.repo.ListItemsBySKU(ctx, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	type plannedRow struct {
+		row   *importRow
+		item  *Item
+		save  bool
+		stock bool
+	}
+	var plan []plannedRow
+	var itemIDs []uuid.UUID
+	for _, r := range rows {
+		item := existing[r.sku]
+		p := plannedRow{row: r}
+		if item == nil {
+			if r.name == nil {
+				res.Errors = append(res.Errors, ImportRowError{Row: r.line, Column: FieldName, Message: "name is required for a new item"})
+				continue
+			}
+			item = &Item{ID: uuid.New(), SKU: r.sku, Unit: "pcs"}
+			r.apply(item)
+			p.save = true
+			res.Created++
+		} else if r.apply(item) {
+			p.save = true
+			res.Updated++
+		} else {
+			res.Unchanged++
+		}
+		p.item = item
+		p.stock = opts.SetStock && r.quantity != nil
+		if p.stock {
+			itemIDs = append(itemIDs, item.ID)
+		}
+		plan = append(plan, p)
+	}
+	res.Rows = len(rows) + len(errs)
+
+	if len(itemIDs) > 0 {
+		current, err := s.repo.ListLocationStock(ctx, loc.ID, itemIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range plan {
+			p := &plan[i]
+			if !p.stock {
+				continue
+			}
+			st := current[p.item.ID]
+			have, reserved := 0, 0
+			if st != nil {
+				have, reserved = st.Quantity, st.Reserved
+			}
+			switch {
+			case *p.row.quantity < reserved:
+				res.Errors = append(res.Errors, ImportRowError{Row: p.row.line, Column: FieldQuantity,
+					Message: fmt.Sprintf("%d are reserved, stock cannot be set to %d", reserved, *p.row.quantity)})
+			case *p.row.quantity != have:
+				res.StockAdjusted++
+			default:
+				p.stock = false
+			}
+		}
+	}
+	sortImportErrors(res.Errors)
+	if opts.DryRun {
+		return res, nil
+	}
+	if len(res.Errors) > 0 {
+		return res, ErrImportInvalid
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range plan {
+			if p.save {
+				if err := s.repo.UpsertItem(ctx, tx, p.item); err != nil {
+					return err
+				}
+			}
+			if !p.stock {
+				continue
+			}
+			if err := s.repo.EnsureStock(ctx, tx, p.item.ID, loc.ID); err != nil {
+				return err
+			}
+			// The stock may have moved since it was read; lock it and set it exactly.
+			current, err := s.repo.LockStock(ctx, tx, p.item.ID, loc.ID)
+			if err != nil {
+				return err
+			}
+			delta := *p.row.quantity - current.Quantity
+			if delta == 0 {
+				continue
+			}
+			updated, err := s.addStock(ctx, tx, p.item.ID, loc.ID, delta)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", p.row.line, err)
+			}
+			ref := importReference
+			m := &Movement{
+				ID:            uuid.New(),
+				ItemID:        p.item.ID,
+				LocationID:    loc.ID,
+				QuantityDelta: delta,
+				Type:          MovementTypeAdjust,
+				Reference:     &ref,
+			}
+			var unitCost *float64
+			if delta > 0 {
+				unitCost = p.row.unitCost
+			}
+			if err := s.post(ctx, tx, m, updated, unitCost); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func sortImportErrors(errs []ImportRowError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Row < errs[j].Row })
+}
+
+// ExportItems returns all items with their stock as a table with a header row, summed over
+// all locations or taken at one.
+func (s *Service) ExportItems(ctx context.Context, locationID *uuid.UUID) ([][]string, error) {
+	list, err := s.repo.ListItemBalances(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	table := make([][]string, 0, len(list)+1)
+	table = append(table, exportHeader)
+	for _, b := range list {
+		table = append(table, []string{
+			b.Item.SKU,
+			b.Item.Name,
+			b.Item.Description,
+			b.Item.Unit,
+			b.Item.Category,
+			strconv.Itoa(b.Item.MinQuantity),
+			strconv.Itoa(b.Quantity),
+			strconv.Itoa(b.Reserved),
+			strconv.Itoa(b.Quantity - b.Reserved),
+			strconv.FormatFloat(b.Value, 'f', 2, 64),
+		})
+	}
+	return table, nil
+}
+
+// GetLocationByCode returns a location by its code, nil if there is none.
+func (s *Service) GetLocationByCode(ctx context.Context, code string) (*Location, error) {
+	return s.repo.GetLocationByCode(ctx, code)
+}
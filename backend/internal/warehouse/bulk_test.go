@@ -0,0 +1,97 @@
+package warehouse
+
+import "testing"
+
+func TestImportColumns(t *testing.T) {
+	header := []string{"Артикул", "Name", "qty", "Unit_Cost"}
+	cols, err := importColumns(header, map[string]string{FieldSKU: "артикул", FieldQuantity: "QTY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{FieldSKU: 0, FieldName: 1, FieldQuantity: 2, FieldUnitCost: 3}
+	if len(cols) != len(want) {
+		t.Fatalf("got %v, want %v", cols, want)
+	}
+	for f, i := range want {
+		if cols[f] != i {
+			t.Errorf("%s: got column %d, want %d", f, cols[f], i)
+		}
+	}
+
+	if _, err := importColumns(header, map[string]string{"price": "Name"}); err == nil {
+		t.Error("unknown field in mapping: expected an error")
+	}
+	if _, err := importColumns(header, map[string]string{FieldName: "Title"}); err == nil {
+		t.Error("mapped column missing from header: expected an error")
+	}
+	if _, err := importColumns([]string{"name"}, nil); err == nil {
+		t.Error("no sku column: expected an error")
+	}
+}
+
+func TestParseImportRows(t *testing.T) {
+	cols := map[string]int{FieldSKU: 0, FieldName: 1, FieldQuantity: 2, FieldUnitCost: 3}
+	rows := [][]string{
+		{"A-1", "Filter", "12.0", "1 250,50"},
+		{"", "", "", ""},
+		{"A-2", "Pad", "-1", ""},
+		{"A-1", "Filter again", "1", ""},
+		{"", "No sku", "", ""},
+		{"A-3", "", "", "abc"},
+		{"A-4"},
+	}
+	parsed, errs := parseImportRows(rows, cols)
+	if len(parsed) != 2 || parsed[0].sku != "A-1" || parsed[1].sku != "A-4" {
+		t.Fatalf("parsed %d rows, want A-1 and A-4", len(parsed))
+	}
+	first := parsed[0]
+	if first.line != 2 || first.name == nil || *first.name != "Filter" {
+		t.Errorf("first row: line %d, name %v", first.line, first.name)
+	}
+	if first.quantity == nil || *first.quantity != 12 {
+		t.Errorf("first row quantity: got %v, want 12", first.quantity)
+	}
+	if first.unitCost == nil || *first.unitCost != 1250.5 {
+		t.Errorf("first row unit cost: got %v, want 1250.5", first.unitCost)
+	}
+	if parsed[1].name != nil || parsed[1].quantity != nil {
+		t.Error("short row: empty cells must stay unset")
+	}
+
+	wantErrs := []ImportRowError{
+		{Row: 4, Column: FieldQuantity},
+		{Row: 5, Column: FieldSKU},
+		{Row: 6, Column: FieldSKU},
+		{Row: 7, Column: FieldUnitCost},
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("got errors %+v, want %d", errs, len(wantErrs))
+	}
+	for i, w := range wantErrs {
+		if errs[i].Row != w.Row || errs[i].Column != w.Column {
+			t.Errorf("error %d: got row %d %s, want row %d %s", i, errs[i].Row, errs[i].Column, w.Row, w.Column)
+		}
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"7", 7, true},
+		{"1 000", 1000, true},
+		{"3.0", 3, true},
+		{"3,0", 3, true},
+		{"2.5", 0, false},
+		{"-4", 0, false},
+		{"x", 0, false},
+	}
+	for _, c := range cases {
+		got, err := parseCount(c.in)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("parseCount(%q) = %d, %v", c.in, got, err)
+		}
+	}
+}
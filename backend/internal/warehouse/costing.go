@@ -0,0 +1,194 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// costingMethod is the configured method; anything but "average" means FIFO.
+func (s *Service) costingMethod() string {
+	if s.cfg.CostingMethod == CostingAverage {
+		return CostingAverage
+	}
+	return CostingFIFO
+}
+
+// roundMoney rounds an amount to tiyn; unit costs keep four decimals.
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func roundUnitCost(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// consumeFIFO takes qty from the oldest layers, updating them in place. It returns the cost of
+// what was taken, the layers that changed and the quantity the layers could not cover.
+func consumeFIFO(layers []*CostLayer, qty int) (cost float64, touched []*CostLayer, short int) {
+	for _, l := range layers {
+		if qty == 0 {
+			break
+		}
+		take := l.Remaining
+		if take > qty {
+			take = qty
+		}
+		if take <= 0 {
+			continue
+		}
+		l.Remaining -= take
+		qty -= take
+		cost += float64(take) * l.UnitCost
+		touched = append(touched, l)
+	}
+	return cost, touched, qty
+}
+
+// issueCost returns the cost of issuing qty from stock of have units worth value, and the
+// layers consumed. Layers are consumed oldest first under both methods so they keep matching
+// the quantity on hand; only FIFO takes its cost from them. Issuing everything takes the whole
+// value so no rounding residue is left behind.
+func issueCost(method string, layers []*CostLayer, qty, have int, value float64) (float64, []*CostLayer) {
+	fifo, touched, short := consumeFIFO(layers, qty)
+	switch {
+	case qty >= have:
+		return roundMoney(value), touched
+	case have <= 0:
+		return 0, touched
+	case method == CostingAverage:
+		return roundMoney(value * float64(qty) / float64(have)), touched
+	}
+	// Stock the layers do not cover is costed at the average.
+	fifo += float64(short) * value / float64(have)
+	return roundMoney(fifo), touched
+}
+
+// cost sets the unit and total cost of a movement whose quantity was already applied to stock,
+// and updates the value of the stock and its layers. unitCost is the purchase cost of incoming
+// stock; without it the current average cost, or the last known one, is used.
+func (s *Service) cost(ctx context.Context, tx *sql.Tx, m *Movement, stock *Stock, unitCost *float64) error {
+	have := stock.Quantity - m.QuantityDelta
+	total := 0.0
+	switch {
+	case m.QuantityDelta > 0:
+		var uc float64
+		switch {
+		case unitCost != nil:
+			uc = *unitCost
+		case have > 0 && stock.Value > 0:
+			uc = stock.Value / float64(have)
+		default:
+			last, err := s.repo.LastUnitCost(ctx, tx, m.ItemID, m.LocationID)
+			if err != nil {
+				return err
+			}
+			if last != nil {
+				uc = *last
+			}
+		}
+		uc = roundUnitCost(uc)
+		layer := &CostLayer{
+			ID:         uuid.New(),
+			ItemID:     m.ItemID,
+			LocationID: m.LocationID,
+			MovementID: &m.ID,
+			UnitCost:   uc,
+			Quantity:   m.QuantityDelta,
+			Remaining:  m.QuantityDelta,
+		}
+		if err := s.repo.CreateCostLayer(ctx, tx, layer); err != nil {
+			return err
+		}
+		m.UnitCost = &uc
+		total = roundMoney(uc * float64(m.QuantityDelta))
+	case m.QuantityDelta < 0:
+		qty := -m.QuantityDelta
+		layers, err := s.repo.ListOpenCostLayers(ctx, tx, m.ItemID, m.LocationID)
+		if err != nil {
+			return err
+		}
+		c, touched := issueCost(s.costingMethod(), layers, qty, have, stock.Value)
+		for _, l := range touched {
+			if err := s.repo.UpdateCostLayerRemaining(ctx, tx, l); err != nil {
+				return err
+			}
+		}
+		uc := roundUnitCost(c / float64(qty))
+		m.UnitCost = &uc
+		total = -c
+	}
+	m.TotalCost = &total
+	if total == 0 {
+		return nil
+	}
+	value, err := s.repo.AddStockValue(ctx, tx, m.ItemID, m.LocationID, total)
+	if err != nil {
+		return err
+	}
+	stock.Value = value
+	return nil
+}
+
+// post costs a movement whose quantity was already applied to stock, moves it through the
+// item's lots and records it. tx must come from withTx, which sends low stock alerts once
+// it commits.
+func (s *Service) post(ctx context.Context, tx *sql.Tx, m *Movement, stock *Stock, unitCost *float64) error {
+	m.QuantityAfter = &stock.Quantity
+	if err := s.cost(ctx, tx, m, stock, unitCost); err != nil {
+		return err
+	}
+	if err := s.trackLots(ctx, tx, m); err != nil {
+		return err
+	}
+	if err := s.repo.CreateMovement(ctx, tx, m); err != nil {
+		return err
+	}
+	s.queueLowStock(tx, m)
+	return nil
+}
+
+// Valuation returns what the stock was worth at the end of the asOf day, or now if asOf is nil.
+func (s *Service) Valuation(ctx context.Context, asOf *time.Time, locationID *uuid.UUID) (*Valuation, error) {
+	at := time.Now()
+	before := at
+	if asOf != nil {
+		at = *asOf
+		before = asOf.AddDate(0, 0, 1)
+	}
+	var locationIDs []uuid.UUID
+	if locationID != nil {
+		locationIDs = []uuid.UUID{*locationID}
+	}
+	lines, err := s.repo.ListValuation(ctx, before, locationIDs)
+	if err != nil {
+		return nil, err
+	}
+	v := &Valuation{AsOf: at, Method: s.costingMethod(), Lines: lines}
+	for _, l := range lines {
+		if l.Quantity > 0 {
+			l.UnitCost = roundUnitCost(l.Value / float64(l.Quantity))
+		}
+		v.Total += l.Value
+	}
+	v.Total = roundMoney(v.Total)
+	return v, nil
+}
+
+// Margins returns revenue, cost of goods and margin of the parts sold per work order reference.
+func (s *Service) Margins(ctx context.Context, filter MarginFilter) ([]*MarginLine, error) {
+	list, err := s.repo.ListMargins(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		m.Margin = roundMoney(m.Revenue - m.Cost)
+		if m.Revenue > 0 {
+			m.MarginPercent = roundMoney(m.Margin / m.Revenue * 100)
+		}
+	}
+	return list, nil
+}
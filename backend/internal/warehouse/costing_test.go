@@ -0,0 +1,48 @@
+package warehouse
+
+import "testing"
+
+func layers() []*CostLayer {
+	return []*CostLayer{
+		{UnitCost: 100, Quantity: 5, Remaining: 2},
+		{UnitCost: 130, Quantity: 4, Remaining: 4},
+	}
+}
+
+func TestIssueCost(t *testing.T) {
+	// 2 × 100 + 4 × 130 = 720 for 6 units on hand
+	cases := []struct {
+		name   string
+		method string
+		qty    int
+		have   int
+		value  float64
+		want   float64
+	}{
+		{"fifo from oldest layer", CostingFIFO, 2, 6, 720, 200},
+		{"fifo across layers", CostingFIFO, 3, 6, 720, 330},
+		{"average", CostingAverage, 3, 6, 720, 360},
+		{"everything takes the whole value", CostingAverage, 6, 6, 720.01, 720.01},
+		{"fifo beyond layers at average", CostingFIFO, 7, 8, 800, 820},
+	}
+	for _, c := range cases {
+		got, _ := issueCost(c.method, layers(), c.qty, c.have, c.value)
+		if got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestConsumeFIFO(t *testing.T) {
+	ls := layers()
+	cost, touched, short := consumeFIFO(ls, 3)
+	if cost != 330 || short != 0 || len(touched) != 2 {
+		t.Fatalf("got cost %v, short %d, %d touched", cost, short, len(touched))
+	}
+	if ls[0].Remaining != 0 || ls[1].Remaining != 3 {
+		t.Errorf("remaining %d, %d", ls[0].Remaining, ls[1].Remaining)
+	}
+	if _, _, short = consumeFIFO(ls, 5); short != 2 {
+		t.Errorf("short = %d, want 2", short)
+	}
+}
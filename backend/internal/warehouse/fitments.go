@@ -0,0 +1,256 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxComponentCodeLen = 255 // matches components.code
+	maxEngineCodeLen    = 100
+	minFitYear          = 1900
+	maxFitYear          = 2100
+	// maxFittingItems bounds the answer for broad component codes such as "brakes".
+	maxFittingItems = 100
+)
+
+// normalizeComponentCode trims a component code and lowercases it; codes are dotted paths such
+// as brakes.pad.front.left.
+func normalizeComponentCode(code string) (string, error) {
+	code = strings.Trim(strings.ToLower(strings.TrimSpace(code)), ".")
+	if code == "" || len(code) > maxComponentCodeLen || strings.Contains(code, "..") || strings.ContainsAny(code, " \t") {
+		return "", fmt.Errorf("component_code must be a dotted code such as brakes.pad.front of at most %d characters", maxComponentCodeLen)
+	}
+	return code, nil
+}
+
+// normalizeEngineCode compares engine codes case-insensitively; nil or blank means any engine.
+func normalizeEngineCode(code *string) *string {
+	if code == nil {
+		return nil
+	}
+	c := strings.ToUpper(strings.TrimSpace(*code))
+	if c == "" {
+		return nil
+	}
+	return &c
+}
+
+// fits reports whether the year and engine constraints of a fitment allow the vehicle. What the
+// vehicle does not record is not held against it: a fitment for 2015-2018 still lists for a
+// vehicle of unknown year, with the years shown so the mechanic can check.
+func fits(f *Fitment, v *FitVehicle) bool {
+	if v.Year != nil {
+		if f.YearFrom != nil && *v.Year < *f.YearFrom {
+			return false
+		}
+		if f.YearTo != nil && *v.Year > *f.YearTo {
+			return false
+		}
+	}
+	if f.EngineCode != nil {
+		if engine := normalizeEngineCode(v.EngineCode); engine != nil && *engine != *f.EngineCode {
+			return false
+		}
+	}
+	return true
+}
+
+// fitmentRank orders fitments from the most specific: a platform, then a generation, then a
+// model; constraints on years and engine make a fitment more specific still.
+func fitmentRank(f *Fitment) int {
+	var rank int
+	switch {
+	case f.VehiclePlatformID != nil:
+		rank = 30
+	case f.GenerationID != nil:
+		rank = 20
+	default:
+		rank = 10
+	}
+	if f.YearFrom != nil || f.YearTo != nil {
+		rank += 2
+	}
+	if f.EngineCode != nil {
+		rank += 2
+	}
+	return rank
+}
+
+// ListFitments returns the vehicles an item is mapped to.
+func (s *Service) ListFitments(ctx context.Context, itemID uuid.UUID) ([]*Fitment, error) {
+	return s.repo.ListFitments(ctx, itemID)
+}
+
+// AddFitment maps an item to a catalog component on a platform, a generation or a model.
+func (s *Service) AddFitment(ctx context.Context, itemID uuid.UUID, req *AddFitmentRequest) (*Fitment, error) {
+	code, err := normalizeComponentCode(req.ComponentCode)
+	if err != nil {
+		return nil, err
+	}
+	var modelID *string
+	if req.ModelID != nil {
+		if m := strings.TrimSpace(*req.ModelID); m != "" {
+			modelID = &m
+		}
+	}
+	targets := 0
+	for _, set := range []bool{req.VehiclePlatformID != nil, req.GenerationID != nil, modelID != nil} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, fmt.Errorf("set exactly one of vehicle_platform_id, generation_id and model_id")
+	}
+	for _, y := range []*int{req.YearFrom, req.YearTo} {
+		if y != nil && (*y < minFitYear || *y > maxFitYear) {
+			return nil, fmt.Errorf("years must be between %d and %d", minFitYear, maxFitYear)
+		}
+	}
+	if req.YearFrom != nil && req.YearTo != nil && *req.YearFrom > *req.YearTo {
+		return nil, fmt.Errorf("year_from must not be after year_to")
+	}
+	engine := normalizeEngineCode(req.EngineCode)
+	if engine != nil && len(*engine) > maxEngineCodeLen {
+		return nil, fmt.Errorf("engine_code must not exceed %d characters", maxEngineCodeLen)
+	}
+	item, err := s.repo.GetItemByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	f := &Fitment{
+		ID:                uuid.New(),
+		ItemID:            item.ID,
+		ComponentCode:     code,
+		VehiclePlatformID: req.VehiclePlatformID,
+		GenerationID:      req.GenerationID,
+		ModelID:           modelID,
+		YearFrom:          req.YearFrom,
+		YearTo:            req.YearTo,
+		EngineCode:        engine,
+		Notes:             strings.TrimSpace(req.Notes),
+	}
+	err = s.repo.CreateFitment(ctx, f)
+	switch {
+	case errors.Is(err, errDuplicateKey):
+		return nil, fmt.Errorf("the item already has this fitment")
+	case errors.Is(err, errMissingReference):
+		return nil, fmt.Errorf("vehicle platform, generation or model not found")
+	case err != nil:
+		return nil, err
+	}
+	return f, nil
+}
+
+// DeleteFitment removes a fitment from an item and reports whether it was there.
+func (s *Service) DeleteFitment(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	return s.repo.DeleteFitment(ctx, itemID, id)
+}
+
+// VehicleParts returns the items available at the locations the user may see, or at one of
+// them, that fit a component of a vehicle, with their OEM and aftermarket numbers. The
+// component is a catalog component id or code; fitments for a broader code (brakes.pad for
+// brakes.pad.front) and for narrower ones are included. The most specific fits come first. It
+// returns nil if the vehicle does not exist.
+func (s *Service) VehicleParts(ctx context.Context, userID uuid.UUID, role string, vehicleID uuid.UUID, component string, locationID *uuid.UUID) (*VehicleParts, error) {
+	code := strings.TrimSpace(component)
+	if id, err := uuid.Parse(code); err == nil {
+		if code, err = s.repo.GetComponentCode(ctx, id); err != nil {
+			return nil, err
+		}
+		if code == "" {
+			return nil, fmt.Errorf("component not found")
+		}
+	}
+	code, err := normalizeComponentCode(code)
+	if err != nil {
+		return nil, err
+	}
+	var locationIDs []uuid.UUID
+	if locationID != nil {
+		loc, err := s.usableLocation(ctx, userID, role, locationID)
+		if err != nil {
+			return nil, err
+		}
+		locationIDs = []uuid.UUID{loc.ID}
+	} else {
+		scope, err := s.scope(ctx, userID, role)
+		if err != nil {
+			return nil, err
+		}
+		locationIDs = scope.ids()
+	}
+	v, err := s.repo.GetFitVehicle(ctx, vehicleID)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	res := &VehicleParts{Vehicle: v, ComponentCode: code, Items: []*FittingItem{}}
+	if v.VehiclePlatformID == nil && v.GenerationID == nil && v.ModelID == nil {
+		return res, nil
+	}
+	fitments, err := s.repo.ListVehicleFitments(ctx, v, code)
+	if err != nil {
+		return nil, err
+	}
+	byItem := map[uuid.UUID][]*Fitment{}
+	var itemIDs []uuid.UUID
+	for _, f := range fitments {
+		if !fits(f, v) {
+			continue
+		}
+		if byItem[f.ItemID] == nil {
+			itemIDs = append(itemIDs, f.ItemID)
+		}
+		byItem[f.ItemID] = append(byItem[f.ItemID], f)
+	}
+	if len(itemIDs) == 0 {
+		return res, nil
+	}
+	stock, err := s.repo.ListAvailableStock(ctx, itemIDs, locationIDs)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListItemsByID(ctx, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	rank := map[uuid.UUID]int{}
+	for _, id := range itemIDs {
+		item := items[id]
+		if item == nil || len(stock[id]) == 0 {
+			continue
+		}
+		fi := &FittingItem{Item: item, Fitments: byItem[id], Stock: stock[id]}
+		for _, st := range fi.Stock {
+			fi.Available += st.Available
+		}
+		sort.SliceStable(fi.Fitments, func(i, j int) bool { return fitmentRank(fi.Fitments[i]) > fitmentRank(fi.Fitments[j]) })
+		rank[id] = fitmentRank(fi.Fitments[0])
+		res.Items = append(res.Items, fi)
+	}
+	sort.SliceStable(res.Items, func(i, j int) bool {
+		a, b := res.Items[i], res.Items[j]
+		if rank[a.Item.ID] != rank[b.Item.ID] {
+			return rank[a.Item.ID] > rank[b.Item.ID]
+		}
+		return a.Item.SKU < b.Item.SKU
+	})
+	if len(res.Items) > maxFittingItems {
+		res.Items = res.Items[:maxFittingItems]
+	}
+	for _, fi := range res.Items {
+		if fi.PartNumbers, err = s.repo.ListPartNumbers(ctx, fi.Item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package warehouse
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func intp(v int) *int { return &v }
+
+func TestNormalizeComponentCode(t *testing.T) {
+	cases := map[string]string{
+		" Brakes.Pad.Front ": "brakes.pad.front",
+		"brakes.pad.":        "brakes.pad",
+		"brakes..pad":        "",
+		"brake pad":          "",
+		"":                   "",
+	}
+	for in, want := range cases {
+		got, err := normalizeComponentCode(in)
+		if got != want || (want == "") != (err != nil) {
+			t.Errorf("%q: got %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestFits(t *testing.T) {
+	engine := "CJSA"
+	f := &Fitment{YearFrom: intp(2015), YearTo: intp(2018), EngineCode: &engine}
+	lower := " cjsa "
+	other := "CHPA"
+	cases := []struct {
+		name string
+		v    *FitVehicle
+		want bool
+	}{
+		{"within years, same engine in any case", &FitVehicle{Year: intp(2016), EngineCode: &lower}, true},
+		{"before the years", &FitVehicle{Year: intp(2014), EngineCode: &engine}, false},
+		{"after the years", &FitVehicle{Year: intp(2019)}, false},
+		{"another engine", &FitVehicle{Year: intp(2016), EngineCode: &other}, false},
+		{"unknown year and engine", &FitVehicle{}, true},
+	}
+	for _, c := range cases {
+		if got := fits(f, c.v); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if !fits(&Fitment{}, &FitVehicle{Year: intp(1990)}) {
+		t.Error("a fitment without constraints fits any year")
+	}
+}
+
+func TestFitmentRank(t *testing.T) {
+	platform, generation := uuid.New(), uuid.New()
+	model := "golf"
+	byPlatform := &Fitment{VehiclePlatformID: &platform}
+	byGeneration := &Fitment{GenerationID: &generation, YearFrom: intp(2015)}
+	byModel := &Fitment{ModelID: &model, YearFrom: intp(2015), EngineCode: &model}
+	if !(fitmentRank(byPlatform) > fitmentRank(byGeneration) && fitmentRank(byGeneration) > fitmentRank(byModel)) {
+		t.Errorf("ranks %d, %d, %d", fitmentRank(byPlatform), fitmentRank(byGeneration), fitmentRank(byModel))
+	}
+	if fitmentRank(byGeneration) <= fitmentRank(&Fitment{GenerationID: &generation}) {
+		t.Error("year constraints should make a fitment more specific")
+	}
+}
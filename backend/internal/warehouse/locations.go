@@ -0,0 +1,284 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrLocationAccess is returned when a mechanic works with a location outside their centers.
+var ErrLocationAccess = errors.New("no access to this warehouse location")
+
+// locationScope is the set of locations a user may work with.
+type locationScope struct {
+	all     bool
+	allowed map[uuid.UUID]bool
+}
+
+// allows reports whether the location is in the scope.
+func (sc locationScope) allows(id uuid.UUID) bool {
+	return sc.all || sc.allowed[id]
+}
+
+// ids returns the locations of the scope for repository filters, nil meaning all of them.
+func (sc locationScope) ids() []uuid.UUID {
+	if sc.all {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(sc.allowed))
+	for id := range sc.allowed {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// scope returns the locations the user may work with: all of them for admins, the storerooms
+// of the user's service centers for mechanics.
+func (s *Service) scope(ctx context.Context, userID uuid.UUID, role string) (locationScope, error) {
+	if role == "admin" {
+		return locationScope{all: true}, nil
+	}
+	ids, err := s.repo.ListUserLocationIDs(ctx, userID)
+	if err != nil {
+		return locationScope{}, err
+	}
+	sc := locationScope{allowed: make(map[uuid.UUID]bool, len(ids))}
+	for _, id := range ids {
+		sc.allowed[id] = true
+	}
+	return sc, nil
+}
+
+// usableLocation returns an active location the user may move stock at; nil id means the
+// main warehouse.
+func (s *Service) usableLocation(ctx context.Context, userID uuid.UUID, role string, id *uuid.UUID) (*Location, error) {
+	l, err := s.activeLocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(l.ID) {
+		return nil, ErrLocationAccess
+	}
+	return l, nil
+}
+
+// activeLocation returns an active location; nil id means the main warehouse.
+func (s *Service) activeLocation(ctx context.Context, id *uuid.UUID) (*Location, error) {
+	var l *Location
+	var err error
+	if id == nil {
+		l, err = s.repo.GetLocationByCode(ctx, MainLocationCode)
+	} else {
+		l, err = s.repo.GetLocationByID(ctx, *id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, fmt.Errorf("location not found")
+	}
+	if !l.IsActive {
+		return nil, fmt.Errorf("location %s is inactive", l.Code)
+	}
+	return l, nil
+}
+
+// ListLocations returns the locations the user may see.
+func (s *Service) ListLocations(ctx context.Context, userID uuid.UUID, role string) ([]*Location, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListLocations(ctx, scope.ids())
+}
+
+func (s *Service) CreateLocation(ctx context.Context, req *CreateLocationRequest) (*Location, error) {
+	code := strings.TrimSpace(req.Code)
+	name := strings.TrimSpace(req.Name)
+	if code == "" || name == "" {
+		return nil, fmt.Errorf("code and name are required")
+	}
+	l := &Location{
+		ID:              uuid.New(),
+		Code:            code,
+		Name:            name,
+		ServiceCenterID: req.ServiceCenterID,
+		IsActive:        true,
+	}
+	if err := s.repo.CreateLocation(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *Service) UpdateLocation(ctx context.Context, id uuid.UUID, req *UpdateLocationRequest) (*Location, error) {
+	l, err := s.repo.GetLocationByID(ctx, id)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		l.Name = name
+	}
+	if req.ServiceCenterID != nil {
+		l.ServiceCenterID = req.ServiceCenterID
+	}
+	if req.IsActive != nil {
+		if !*req.IsActive && l.Code == MainLocationCode {
+			return nil, fmt.Errorf("the main warehouse cannot be deactivated")
+		}
+		l.IsActive = *req.IsActive
+	}
+	if err := s.repo.UpdateLocation(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Transfer moves stock of an item from one location to another in one transaction. The user
+// must have access to the source location. With an idempotency key that was already applied,
+// the earlier transfer is returned with replayed set.
+func (s *Service) Transfer(ctx context.Context, userID uuid.UUID, role string, req *TransferRequest) (t *Transfer, replayed bool, err error) {
+	if req.Quantity <= 0 {
+		return nil, false, fmt.Errorf("quantity must be positive")
+	}
+	if req.FromLocationID == req.ToLocationID {
+		return nil, false, fmt.Errorf("source and destination must differ")
+	}
+	item, err := s.repo.GetItemByID(ctx, req.ItemID)
+	if err != nil || item == nil {
+		return nil, false, fmt.Errorf("item not found")
+	}
+	key, err := idempotencyKey(req.IdempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	lot, err := newLotInput(nil, nil, req.LotID)
+	if err != nil {
+		return nil, false, err
+	}
+	from, err := s.usableLocation(ctx, userID, role, &req.FromLocationID)
+	if err != nil {
+		return nil, false, err
+	}
+	to, err := s.repo.GetLocationByID(ctx, req.ToLocationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if to == nil {
+		return nil, false, fmt.Errorf("destination location not found")
+	}
+	if !to.IsActive {
+		return nil, false, fmt.Errorf("location %s is inactive", to.Code)
+	}
+	if key != nil {
+		if t, err = s.replayTransfer(ctx, req, *key); t != nil || err != nil {
+			return t, t != nil, err
+		}
+	}
+
+	transferID := uuid.New()
+	out := &Movement{
+		ID:             uuid.New(),
+		ItemID:         req.ItemID,
+		LocationID:     from.ID,
+		QuantityDelta:  -req.Quantity,
+		Type:           MovementTypeTransfer,
+		Reference:      req.Reference,
+		TransferID:     &transferID,
+		IdempotencyKey: key,
+		lot:            lot,
+	}
+	in := &Movement{
+		ID:            uuid.New(),
+		ItemID:        req.ItemID,
+		LocationID:    to.ID,
+		QuantityDelta: req.Quantity,
+		Type:          MovementTypeTransfer,
+		Reference:     req.Reference,
+		TransferID:    &transferID,
+	}
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		// Rows are locked in location order so opposite transfers cannot deadlock.
+		legs := []*Movement{out, in}
+		sort.Slice(legs, func(i, j int) bool { return legs[i].LocationID.String() < legs[j].LocationID.String() })
+		stock := make(map[*Movement]*Stock, len(legs))
+		for _, m := range legs {
+			updated, err := s.addStock(ctx, tx, m.ItemID, m.LocationID, m.QuantityDelta)
+			if err != nil {
+				return err
+			}
+			stock[m] = updated
+		}
+		// The stock arrives at the cost it left with, in the lots it left from.
+		if err := s.post(ctx, tx, out, stock[out], nil); err != nil {
+			return err
+		}
+		if len(out.Lots) > 0 {
+			in.lot = &lotInput{from: out.Lots}
+		}
+		unitCost := -*out.TotalCost / float64(req.Quantity)
+		return s.post(ctx, tx, in, stock[in], &unitCost)
+	})
+	if errors.Is(err, errDuplicateKey) && key != nil {
+		// A concurrent request with the same key won; ours was rolled back.
+		t, err = s.replayTransfer(ctx, req, *key)
+		return t, t != nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return newTransfer(out, in), false, nil
+}
+
+// replayTransfer returns the transfer recorded with key if it matches req, nil if the key is new.
+func (s *Service) replayTransfer(ctx context.Context, req *TransferRequest, key string) (*Transfer, error) {
+	m, err := s.repo.GetMovementByKey(ctx, s.repo.db, key)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	if m.Type != MovementTypeTransfer || m.TransferID == nil || m.ItemID != req.ItemID ||
+		m.LocationID != req.FromLocationID || m.QuantityDelta != -req.Quantity {
+		return nil, ErrIdempotencyKeyReused
+	}
+	legs, err := s.repo.ListTransferLegs(ctx, *m.TransferID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legs) != 2 || legs[1].LocationID != req.ToLocationID {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return newTransfer(legs[0], legs[1]), nil
+}
+
+// newTransfer describes a transfer by its legs; the stock shown is the one right after it.
+func newTransfer(out, in *Movement) *Transfer {
+	t := &Transfer{
+		ID:        *out.TransferID,
+		ItemID:    out.ItemID,
+		Quantity:  in.QuantityDelta,
+		Reference: out.Reference,
+		From:      &Stock{ItemID: out.ItemID, LocationID: out.LocationID},
+		To:        &Stock{ItemID: in.ItemID, LocationID: in.LocationID},
+		CreatedAt: out.CreatedAt,
+	}
+	if out.QuantityAfter != nil {
+		t.From.Quantity = *out.QuantityAfter
+	}
+	if in.QuantityAfter != nil {
+		t.To.Quantity = *in.QuantityAfter
+	}
+	return t
+}
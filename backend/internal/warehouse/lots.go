@@ -0,0 +1,278 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	dateLayout   = "2006-01-02"
+	maxLotNumber = 100 // matches warehouse_lots.lot_number
+)
+
+// lotPicking is the configured picking order; anything but "fifo" means FEFO.
+func (s *Service) lotPicking() string {
+	if s.cfg.LotPicking == LotPickingFIFO {
+		return LotPickingFIFO
+	}
+	return LotPickingFEFO
+}
+
+// newLotInput validates the lot fields of a request; it returns nil if none is set.
+func newLotInput(number, expiresAt *string, lotID *uuid.UUID) (*lotInput, error) {
+	if number == nil && expiresAt == nil && lotID == nil {
+		return nil, nil
+	}
+	in := &lotInput{lotID: lotID}
+	if number != nil {
+		in.number = strings.TrimSpace(*number)
+		if len(in.number) > maxLotNumber {
+			return nil, fmt.Errorf("lot_number must not exceed %d characters", maxLotNumber)
+		}
+	}
+	if expiresAt != nil {
+		d, err := time.Parse(dateLayout, strings.TrimSpace(*expiresAt))
+		if err != nil {
+			return nil, fmt.Errorf("expires_at must be a date in YYYY-MM-DD format")
+		}
+		e := d.Format(dateLayout)
+		in.expiresAt = &e
+	}
+	return in, nil
+}
+
+// lotTake is the quantity an issue takes from one lot.
+type lotTake struct {
+	lot *Lot
+	qty int
+}
+
+// allocateLots takes qty from lots in the order given, skipping lots that expired before today
+// if skipExpired is set. It returns what to take from each lot and the quantity the lots could
+// not cover; lots are not changed.
+func allocateLots(lots []*Lot, qty int, today string, skipExpired bool) ([]lotTake, int) {
+	var takes []lotTake
+	for _, l := range lots {
+		if qty == 0 {
+			break
+		}
+		if skipExpired && expiredBy(l, today) {
+			continue
+		}
+		take := l.Remaining
+		if take > qty {
+			take = qty
+		}
+		if take <= 0 {
+			continue
+		}
+		takes = append(takes, lotTake{lot: l, qty: take})
+		qty -= take
+	}
+	return takes, qty
+}
+
+// expiredBy reports whether a lot expired before the day.
+func expiredBy(l *Lot, day string) bool {
+	return l.ExpiresAt != nil && *l.ExpiresAt < day
+}
+
+// trackLots moves the quantity of a movement into or out of the lots of a lot-tracked item; it
+// is a no-op for other items. Incoming stock goes into the lot named by the caller, or one named
+// after the reference, expiring after the shelf life unless a date is given; "in" movements must
+// name their lot. Outgoing stock comes from the chosen lot or is picked by expiry. Issues ("out")
+// never take expired stock; stock that was on hand before tracking started is left untraced.
+func (s *Service) trackLots(ctx context.Context, tx *sql.Tx, m *Movement) error {
+	track, shelfLife, err := s.repo.GetItemLotSettings(ctx, tx, m.ItemID)
+	if err != nil || !track {
+		return err
+	}
+	now := time.Now()
+	today := now.Format(dateLayout)
+
+	if m.QuantityDelta > 0 {
+		if m.lot != nil && len(m.lot.from) > 0 {
+			// A transfer: the lots arrive with their numbers and expiry.
+			for _, from := range m.lot.from {
+				l, err := s.repo.AddToLot(ctx, tx, m.ItemID, m.LocationID, from.LotNumber, from.ExpiresAt, from.Quantity)
+				if err != nil {
+					return err
+				}
+				if err := s.recordLot(ctx, tx, m, l, from.Quantity); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var number string
+		var expiresAt *string
+		if m.lot != nil {
+			number, expiresAt = m.lot.number, m.lot.expiresAt
+		}
+		if number == "" {
+			if m.Type == MovementTypeIn {
+				return fmt.Errorf("lot_number is required for lot-tracked items")
+			}
+			number = "ADJ-" + now.Format("20060102")
+			if m.Reference != nil && *m.Reference != "" && len(*m.Reference) <= maxLotNumber {
+				number = *m.Reference
+			}
+		}
+		if expiresAt == nil && shelfLife != nil {
+			e := now.AddDate(0, 0, *shelfLife).Format(dateLayout)
+			expiresAt = &e
+		}
+		l, err := s.repo.AddToLot(ctx, tx, m.ItemID, m.LocationID, number, expiresAt, m.QuantityDelta)
+		if err != nil {
+			return err
+		}
+		return s.recordLot(ctx, tx, m, l, m.QuantityDelta)
+	}
+
+	qty := -m.QuantityDelta
+	issue := m.Type == MovementTypeOut
+	if m.lot != nil && m.lot.lotID != nil {
+		l, err := s.repo.LockLot(ctx, tx, *m.lot.lotID)
+		if err != nil {
+			return err
+		}
+		if l == nil || l.ItemID != m.ItemID || l.LocationID != m.LocationID {
+			return fmt.Errorf("lot not found at this location")
+		}
+		if issue && expiredBy(l, today) {
+			return fmt.Errorf("%w: lot %s expired on %s", ErrInsufficientStock, l.LotNumber, *l.ExpiresAt)
+		}
+		if l.Remaining < qty {
+			return fmt.Errorf("%w: lot %s has %d, requested %d", ErrInsufficientStock, l.LotNumber, l.Remaining, qty)
+		}
+		l.Remaining -= qty
+		if err := s.repo.UpdateLotRemaining(ctx, tx, l); err != nil {
+			return err
+		}
+		return s.recordLot(ctx, tx, m, l, -qty)
+	}
+
+	lots, err := s.repo.ListOpenLots(ctx, tx, m.ItemID, m.LocationID, s.lotPicking() == LotPickingFEFO)
+	if err != nil {
+		return err
+	}
+	takes, short := allocateLots(lots, qty, today, issue)
+	if short > 0 && m.QuantityAfter != nil {
+		inLots := 0
+		for _, l := range lots {
+			inLots += l.Remaining
+		}
+		if untraced := *m.QuantityAfter + qty - inLots; short > untraced {
+			return fmt.Errorf("%w: %d of the stock has expired", ErrInsufficientStock, short-untraced)
+		}
+	}
+	for _, t := range takes {
+		t.lot.Remaining -= t.qty
+		if err := s.repo.UpdateLotRemaining(ctx, tx, t.lot); err != nil {
+			return err
+		}
+		if err := s.recordLot(ctx, tx, m, t.lot, -t.qty); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLot links a movement to a lot it moved delta into or out of.
+func (s *Service) recordLot(ctx context.Context, tx *sql.Tx, m *Movement, l *Lot, delta int) error {
+	if err := s.repo.CreateLotMovement(ctx, tx, l.ID, m.ID, delta); err != nil {
+		return err
+	}
+	qty := delta
+	if qty < 0 {
+		qty = -qty
+	}
+	m.Lots = append(m.Lots, &MovementLot{LotID: l.ID, LotNumber: l.LotNumber, ExpiresAt: l.ExpiresAt, Quantity: qty})
+	return nil
+}
+
+// ListLots returns the lots of an item with stock left at the locations the user may see, in
+// expiry order.
+func (s *Service) ListLots(ctx context.Context, userID uuid.UUID, role string, itemID uuid.UUID, filter LotsFilter) ([]*Lot, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	filter.ItemID = &itemID
+	return s.repo.ListLots(ctx, filter, scope.ids())
+}
+
+// ExpiringLots returns the lots with stock left that expire within days, including those
+// already expired.
+func (s *Service) ExpiringLots(ctx context.Context, userID uuid.UUID, role string, days int, filter LotsFilter) ([]*ExpiringLot, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("days must not be negative")
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	today, _ := time.Parse(dateLayout, time.Now().Format(dateLayout))
+	by := today.AddDate(0, 0, days).Format(dateLayout)
+	filter.ExpiresBy = &by
+	filter.IncludeEmpty = false
+	lots, err := s.repo.ListLots(ctx, filter, scope.ids())
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ExpiringLot, 0, len(lots))
+	for _, l := range lots {
+		e := &ExpiringLot{Lot: l}
+		if d, err := time.Parse(dateLayout, *l.ExpiresAt); err == nil {
+			e.DaysLeft = int(d.Sub(today).Hours() / 24)
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+// maxTraceLots bounds the lots of one number a trace covers; there is one per location it
+// reached.
+const maxTraceLots = 1000
+
+// TraceLot returns where the stock of a lot number of an item went at the locations the user
+// may see: every movement into and out of the lot, with the vehicles of the bookings it was
+// issued for.
+func (s *Service) TraceLot(ctx context.Context, userID uuid.UUID, role string, itemID uuid.UUID, lotNumber string) (*LotTrace, error) {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if lotNumber == "" {
+		return nil, fmt.Errorf("lot_number is required")
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := s.repo.ListLots(ctx, LotsFilter{ItemID: &itemID, LotNumber: lotNumber, IncludeEmpty: true, Limit: maxTraceLots}, scope.ids())
+	if err != nil {
+		return nil, err
+	}
+	trace := &LotTrace{ItemID: itemID, LotNumber: lotNumber, Lots: lots, Movements: []*LotTraceLine{}, VehicleIDs: []uuid.UUID{}}
+	if len(lots) == 0 {
+		return trace, nil
+	}
+	ids := make([]uuid.UUID, len(lots))
+	for i, l := range lots {
+		ids[i] = l.ID
+	}
+	if trace.Movements, err = s.repo.ListLotTrace(ctx, ids); err != nil {
+		return nil, err
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, t := range trace.Movements {
+		if t.VehicleID != nil && !seen[*t.VehicleID] {
+			seen[*t.VehicleID] = true
+			trace.VehicleIDs = append(trace.VehicleIDs, *t.VehicleID)
+		}
+	}
+	return trace, nil
+}
@@ -0,0 +1,76 @@
+package warehouse
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func date(s string) *string { return &s }
+
+func TestAllocateLots(t *testing.T) {
+	lots := func() []*Lot {
+		return []*Lot{
+			{LotNumber: "A", ExpiresAt: date("2026-01-10"), Remaining: 3},
+			{LotNumber: "B", ExpiresAt: date("2026-03-01"), Remaining: 4},
+			{LotNumber: "C", Remaining: 5},
+		}
+	}
+	cases := []struct {
+		name        string
+		qty         int
+		skipExpired bool
+		want        string // lot numbers and quantities taken
+		short       int
+	}{
+		{"first lot first", 2, false, "A2", 0},
+		{"across lots", 8, false, "A3B4C1", 0},
+		{"expired lots skipped", 5, true, "B4C1", 0},
+		{"more than the lots hold", 20, false, "A3B4C5", 8},
+		{"expired stock does not cover", 12, true, "B4C5", 3},
+	}
+	for _, c := range cases {
+		takes, short := allocateLots(lots(), c.qty, "2026-02-01", c.skipExpired)
+		got := ""
+		for _, tk := range takes {
+			got += tk.lot.LotNumber + string(rune('0'+tk.qty))
+		}
+		if got != c.want || short != c.short {
+			t.Errorf("%s: got %s short %d, want %s short %d", c.name, got, short, c.want, c.short)
+		}
+	}
+}
+
+func TestExpiredBy(t *testing.T) {
+	l := &Lot{ExpiresAt: date("2026-02-01")}
+	if expiredBy(l, "2026-02-01") {
+		t.Error("a lot is usable on its expiry day")
+	}
+	if !expiredBy(l, "2026-02-02") {
+		t.Error("a lot is expired the day after")
+	}
+	if expiredBy(&Lot{}, "2099-01-01") {
+		t.Error("a lot without expiry never expires")
+	}
+}
+
+func TestNewLotInput(t *testing.T) {
+	if in, err := newLotInput(nil, nil, nil); in != nil || err != nil {
+		t.Errorf("no fields: got %v, %v", in, err)
+	}
+	in, err := newLotInput(date(" L-42 "), date("2026-05-31"), nil)
+	if err != nil || in.number != "L-42" || *in.expiresAt != "2026-05-31" {
+		t.Errorf("got %+v, %v", in, err)
+	}
+	if _, err := newLotInput(nil, date("31.05.2026"), nil); err == nil {
+		t.Error("a date in another format should be rejected")
+	}
+	long := string(make([]byte, maxLotNumber+1))
+	if _, err := newLotInput(&long, nil, nil); err == nil {
+		t.Error("a too long lot number should be rejected")
+	}
+	id := uuid.New()
+	if in, err := newLotInput(nil, nil, &id); err != nil || *in.lotID != id {
+		t.Errorf("lot id: got %+v, %v", in, err)
+	}
+}
@@ -7,61 +7,746 @@ import (
 )
 
 const (
-	MovementTypeIn     = "in"
-	MovementTypeOut    = "out"
-	MovementTypeAdjust = "adjust"
+	MovementTypeIn       = "in"
+	MovementTypeOut      = "out"
+	MovementTypeAdjust   = "adjust"
+	MovementTypeTransfer = "transfer" // one leg of a transfer between locations
 )
 
+// Costing methods: how the cost of issued stock is computed.
+const (
+	CostingFIFO    = "fifo"    // oldest receipts are issued first, at their purchase cost
+	CostingAverage = "average" // issues are costed at the weighted average cost of the stock on hand
+)
+
+// Lot picking orders: which lots of a lot-tracked item stock is issued from.
+const (
+	LotPickingFEFO = "fefo" // first expiring first, lots without expiry last
+	LotPickingFIFO = "fifo" // oldest lots first
+)
+
+// MainLocationCode is the platform's main warehouse; stock requests without a location go there.
+const MainLocationCode = "main"
+
+// Location is a place where stock is kept: the main warehouse or a service center's storeroom.
+type Location struct {
+	ID              uuid.UUID  `json:"id"`
+	Code            string     `json:"code"`
+	Name            string     `json:"name"`
+	ServiceCenterID *uuid.UUID `json:"service_center_id,omitempty"` // mechanics of this center may use the location
+	IsActive        bool       `json:"is_active"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
 // Item represents a warehouse item (part/product).
 type Item struct {
-	ID          uuid.UUID `json:"id"`
-	SKU         string    `json:"sku"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Unit        string    `json:"unit"`
-	MinQuantity int       `json:"min_quantity"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	SKU           string    `json:"sku"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Unit          string    `json:"unit"`
+	Category      string    `json:"category"` // groups items for stocktakes, e.g. "oils" or "brakes"
+	MinQuantity   int       `json:"min_quantity"`
+	TrackLots     bool      `json:"track_lots"`                // stock is kept in lots with expiry dates
+	ShelfLifeDays *int      `json:"shelf_life_days,omitempty"` // expiry of lots received without one
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
-// Stock represents current stock level for an item.
+// Stock represents current stock level for an item at a location.
 type Stock struct {
-	ID        uuid.UUID `json:"id"`
+	ID         uuid.UUID `json:"id"`
+	ItemID     uuid.UUID `json:"item_id"`
+	LocationID uuid.UUID `json:"location_id"`
+	Quantity   int       `json:"quantity"`  // on hand
+	Reserved   int       `json:"reserved"`  // held by active reservations
+	Available  int       `json:"available"` // on hand and not reserved
+	Value      float64   `json:"value"`     // cost of the stock on hand
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ItemStock is the stock of an item over the locations visible to the user.
+type ItemStock struct {
 	ItemID    uuid.UUID `json:"item_id"`
-	Quantity  int       `json:"quantity"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Quantity  int       `json:"quantity"` // on hand, total over Locations
+	Reserved  int       `json:"reserved"`
+	Available int       `json:"available"`
+	Locations []*Stock  `json:"locations"`
 }
 
 // Movement represents a stock movement (in/out/adjust).
 type Movement struct {
-	ID            uuid.UUID `json:"id"`
-	ItemID        uuid.UUID `json:"item_id"`
-	QuantityDelta int       `json:"quantity_delta"` // positive for in, negative for out
-	Type          string    `json:"type"`          // in, out, adjust
-	Reference     *string   `json:"reference,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID             uuid.UUID      `json:"id"`
+	ItemID         uuid.UUID      `json:"item_id"`
+	LocationID     uuid.UUID      `json:"location_id"`
+	QuantityDelta  int            `json:"quantity_delta"` // positive for in, negative for out
+	Type           string         `json:"type"`           // in, out, adjust, transfer
+	Reference      *string        `json:"reference,omitempty"`
+	TransferID     *uuid.UUID     `json:"transfer_id,omitempty"` // shared by both legs of a transfer
+	IdempotencyKey *string        `json:"idempotency_key,omitempty"`
+	QuantityAfter  *int           `json:"quantity_after,omitempty"` // stock level right after the movement
+	UnitCost       *float64       `json:"unit_cost,omitempty"`      // purchase cost for receipts, cost of goods for issues
+	TotalCost      *float64       `json:"total_cost,omitempty"`     // change of the stock value, negative for issues
+	SalePrice      *float64       `json:"sale_price,omitempty"`     // per unit, for parts sold on a work order
+	Lots           []*MovementLot `json:"lots,omitempty"`           // lots of a lot-tracked item the movement went into or came from
+	CreatedAt      time.Time      `json:"created_at"`
+
+	lot *lotInput // which lot the movement uses, if the caller chose one
+}
+
+// CostLayer is what is left of one receipt at a location; FIFO issues consume the oldest first.
+type CostLayer struct {
+	ID         uuid.UUID  `json:"id"`
+	ItemID     uuid.UUID  `json:"item_id"`
+	LocationID uuid.UUID  `json:"location_id"`
+	MovementID *uuid.UUID `json:"movement_id,omitempty"`
+	UnitCost   float64    `json:"unit_cost"`
+	Quantity   int        `json:"quantity"`
+	Remaining  int        `json:"remaining"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 // CreateItemRequest is the request body for creating an item.
 type CreateItemRequest struct {
-	SKU         string `json:"sku" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Unit        string `json:"unit"`
-	MinQuantity int    `json:"min_quantity"`
+	SKU           string `json:"sku" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	Unit          string `json:"unit"`
+	Category      string `json:"category"`
+	MinQuantity   int    `json:"min_quantity"`
+	TrackLots     bool   `json:"track_lots"`
+	ShelfLifeDays *int   `json:"shelf_life_days,omitempty"`
 }
 
 // UpdateItemRequest is the request body for updating an item.
 type UpdateItemRequest struct {
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Unit        *string `json:"unit,omitempty"`
-	MinQuantity *int    `json:"min_quantity,omitempty"`
+	Name          *string `json:"name,omitempty"`
+	Description   *string `json:"description,omitempty"`
+	Unit          *string `json:"unit,omitempty"`
+	Category      *string `json:"category,omitempty"`
+	MinQuantity   *int    `json:"min_quantity,omitempty"`
+	TrackLots     *bool   `json:"track_lots,omitempty"`      // enabling it puts the current stock in an OPENING lot
+	ShelfLifeDays *int    `json:"shelf_life_days,omitempty"` // 0 clears it
 }
 
 // AdjustStockRequest is the request body for stock adjustment.
 type AdjustStockRequest struct {
-	QuantityDelta int     `json:"quantity_delta"` // positive = in, negative = out
-	Type          string  `json:"type"`           // in, out, adjust
-	Reference     *string `json:"reference,omitempty"`
+	LocationID     *uuid.UUID `json:"location_id,omitempty"` // defaults to the main warehouse
+	QuantityDelta  int        `json:"quantity_delta"`        // positive = in, negative = out
+	Type           string     `json:"type"`                  // in, out, adjust
+	Reference      *string    `json:"reference,omitempty"`
+	UnitCost       *float64   `json:"unit_cost,omitempty"`       // for stock coming in; defaults to the current average cost
+	SalePrice      *float64   `json:"sale_price,omitempty"`      // for stock going out, per unit; use the work order as reference
+	IdempotencyKey *string    `json:"idempotency_key,omitempty"` // or the Idempotency-Key header; a repeated key returns the first result
+	LotNumber      *string    `json:"lot_number,omitempty"`      // for stock of a lot-tracked item coming in; required for "in"
+	ExpiresAt      *string    `json:"expires_at,omitempty"`      // YYYY-MM-DD expiry of the incoming lot; defaults to the shelf life
+	LotID          *uuid.UUID `json:"lot_id,omitempty"`          // for stock going out: take it from this lot instead of picking
+}
+
+// MovementsFilter narrows the movement list.
+type MovementsFilter struct {
+	ItemID     *uuid.UUID
+	LocationID *uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// CreateLocationRequest is the request body for creating a location.
+type CreateLocationRequest struct {
+	Code            string     `json:"code" binding:"required"`
+	Name            string     `json:"name" binding:"required"`
+	ServiceCenterID *uuid.UUID `json:"service_center_id,omitempty"`
+}
+
+// UpdateLocationRequest is the request body for updating a location.
+type UpdateLocationRequest struct {
+	Name            *string    `json:"name,omitempty"`
+	ServiceCenterID *uuid.UUID `json:"service_center_id,omitempty"`
+	IsActive        *bool      `json:"is_active,omitempty"`
+}
+
+// TransferRequest is the request body for moving stock between locations.
+type TransferRequest struct {
+	ItemID         uuid.UUID  `json:"item_id" binding:"required"`
+	FromLocationID uuid.UUID  `json:"from_location_id" binding:"required"`
+	ToLocationID   uuid.UUID  `json:"to_location_id" binding:"required"`
+	Quantity       int        `json:"quantity" binding:"required"`
+	Reference      *string    `json:"reference,omitempty"`
+	IdempotencyKey *string    `json:"idempotency_key,omitempty"` // or the Idempotency-Key header
+	LotID          *uuid.UUID `json:"lot_id,omitempty"`          // move this lot instead of picking
+}
+
+// Transfer is stock moved between two locations, with the resulting stock at both.
+type Transfer struct {
+	ID        uuid.UUID `json:"id"`
+	ItemID    uuid.UUID `json:"item_id"`
+	Quantity  int       `json:"quantity"`
+	Reference *string   `json:"reference,omitempty"`
+	From      *Stock    `json:"from"`
+	To        *Stock    `json:"to"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LowStock is an item whose stock at a location is below its minimum.
+type LowStock struct {
+	ItemID              uuid.UUID  `json:"item_id"`
+	SKU                 string     `json:"sku"`
+	Name                string     `json:"name"`
+	Unit                string     `json:"unit"`
+	LocationID          uuid.UUID  `json:"location_id"`
+	LocationCode        string     `json:"location_code"`
+	Quantity            int        `json:"quantity"`
+	MinQuantity         int        `json:"min_quantity"`
+	OnOrder             int        `json:"on_order"` // still expected on sent purchase orders to the location
+	Shortage            int        `json:"shortage"` // min_quantity - quantity - on_order, at least 0
+	PreferredSupplierID *uuid.UUID `json:"preferred_supplier_id,omitempty"`
+}
+
+// Supplier is a company we buy parts from.
+type Supplier struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	ContactName  *string   `json:"contact_name,omitempty"`
+	Phone        *string   `json:"phone,omitempty"`
+	Email        *string   `json:"email,omitempty"`
+	LeadTimeDays int       `json:"lead_time_days"`
+	Notes        *string   `json:"notes,omitempty"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SupplierItem is an item as sold by a supplier.
+type SupplierItem struct {
+	SupplierID       uuid.UUID `json:"supplier_id"`
+	ItemID           uuid.UUID `json:"item_id"`
+	SupplierSKU      *string   `json:"supplier_sku,omitempty"`
+	Price            float64   `json:"price"`
+	Currency         string    `json:"currency"`
+	MinOrderQuantity int       `json:"min_order_quantity"`
+	IsPreferred      bool      `json:"is_preferred"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Purchase order statuses.
+const (
+	POStatusDraft             = "draft"
+	POStatusSent              = "sent"
+	POStatusPartiallyReceived = "partially_received"
+	POStatusReceived          = "received"
+	POStatusCancelled         = "cancelled"
+)
+
+// PurchaseOrder is an order of items from a supplier, delivered to a location.
+type PurchaseOrder struct {
+	ID         uuid.UUID            `json:"id"`
+	Number     int64                `json:"number"`
+	SupplierID uuid.UUID            `json:"supplier_id"`
+	LocationID uuid.UUID            `json:"location_id"`
+	Status     string               `json:"status"`
+	Currency   string               `json:"currency"`
+	Notes      *string              `json:"notes,omitempty"`
+	CreatedBy  *uuid.UUID           `json:"created_by,omitempty"`
+	SentAt     *time.Time           `json:"sent_at,omitempty"`
+	ExpectedAt *string              `json:"expected_at,omitempty"` // YYYY-MM-DD
+	ReceivedAt *time.Time           `json:"received_at,omitempty"`
+	Total      float64              `json:"total"`
+	Lines      []*PurchaseOrderLine `json:"lines"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// PurchaseOrderLine is an ordered item and how much of it has arrived.
+type PurchaseOrderLine struct {
+	ID               uuid.UUID `json:"id"`
+	OrderID          uuid.UUID `json:"order_id"`
+	ItemID           uuid.UUID `json:"item_id"`
+	Quantity         int       `json:"quantity"`
+	ReceivedQuantity int       `json:"received_quantity"`
+	UnitPrice        float64   `json:"unit_price"`
+}
+
+// CreateSupplierRequest is the request body for creating a supplier.
+type CreateSupplierRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	ContactName  *string `json:"contact_name,omitempty"`
+	Phone        *string `json:"phone,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	LeadTimeDays int     `json:"lead_time_days"`
+	Notes        *string `json:"notes,omitempty"`
+}
+
+// UpdateSupplierRequest is the request body for updating a supplier.
+type UpdateSupplierRequest struct {
+	Name         *string `json:"name,omitempty"`
+	ContactName  *string `json:"contact_name,omitempty"`
+	Phone        *string `json:"phone,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	LeadTimeDays *int    `json:"lead_time_days,omitempty"`
+	Notes        *string `json:"notes,omitempty"`
+	IsActive     *bool   `json:"is_active,omitempty"`
+}
+
+// SupplierItemRequest is the request body for setting a supplier's SKU and price of an item.
+type SupplierItemRequest struct {
+	SupplierSKU      *string `json:"supplier_sku,omitempty"`
+	Price            float64 `json:"price"`
+	Currency         string  `json:"currency"`
+	MinOrderQuantity int     `json:"min_order_quantity"`
+	IsPreferred      bool    `json:"is_preferred"`
+}
+
+// PurchaseOrderLineRequest is an item of a purchase order request; the price defaults to the supplier's.
+type PurchaseOrderLineRequest struct {
+	ItemID    uuid.UUID `json:"item_id" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required"`
+	UnitPrice *float64  `json:"unit_price,omitempty"`
+}
+
+// CreatePurchaseOrderRequest is the request body for a draft purchase order.
+type CreatePurchaseOrderRequest struct {
+	SupplierID uuid.UUID                  `json:"supplier_id" binding:"required"`
+	LocationID uuid.UUID                  `json:"location_id" binding:"required"`
+	Lines      []PurchaseOrderLineRequest `json:"lines" binding:"required"`
+	Notes      *string                    `json:"notes,omitempty"`
+}
+
+// PurchaseOrdersFilter narrows the purchase order list.
+type PurchaseOrdersFilter struct {
+	Status     string
+	SupplierID *uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// ReceiveLine is a quantity of an item that arrived.
+type ReceiveLine struct {
+	ItemID    uuid.UUID `json:"item_id" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required"`
+	LotNumber *string   `json:"lot_number,omitempty"` // required for lot-tracked items
+	ExpiresAt *string   `json:"expires_at,omitempty"` // YYYY-MM-DD; defaults to the shelf life
+}
+
+// ReceivePurchaseOrderRequest books a delivery against a purchase order.
+type ReceivePurchaseOrderRequest struct {
+	Lines          []ReceiveLine `json:"lines" binding:"required"`
+	IdempotencyKey *string       `json:"idempotency_key,omitempty"` // or the Idempotency-Key header
+}
+
+// ValuationLine is the quantity and value of an item at a location.
+type ValuationLine struct {
+	ItemID     uuid.UUID `json:"item_id"`
+	SKU        string    `json:"sku"`
+	Name       string    `json:"name"`
+	LocationID uuid.UUID `json:"location_id"`
+	Quantity   int       `json:"quantity"`
+	Value      float64   `json:"value"`
+	UnitCost   float64   `json:"unit_cost"` // average over the quantity
+}
+
+// Valuation is what the stock was worth at a moment.
+type Valuation struct {
+	AsOf   time.Time        `json:"as_of"`
+	Method string           `json:"method"`
+	Total  float64          `json:"total"`
+	Lines  []*ValuationLine `json:"lines"`
+}
+
+// MarginLine is revenue and cost of the parts sold under one reference (a work order).
+type MarginLine struct {
+	Reference     string  `json:"reference"`
+	Quantity      int     `json:"quantity"`
+	Revenue       float64 `json:"revenue"`
+	Cost          float64 `json:"cost"`
+	Margin        float64 `json:"margin"`
+	MarginPercent float64 `json:"margin_percent"` // of revenue
+}
+
+// MarginFilter narrows the margin report; dates are inclusive days.
+type MarginFilter struct {
+	From       *time.Time
+	To         *time.Time
+	Reference  *string
+	LocationID *uuid.UUID
+}
+
+// Reservation reference types: what the stock is held for.
+const (
+	ReferenceBooking   = "booking"
+	ReferenceWorkOrder = "work_order"
+)
+
+const (
+	ReservationActive   = "active"
+	ReservationConsumed = "consumed" // turned into an "out" movement when the job was done
+	ReservationReleased = "released" // the job was cancelled
+	ReservationExpired  = "expired"
+)
+
+// Reservation holds stock of an item at a location for a booking or work order.
+type Reservation struct {
+	ID            uuid.UUID  `json:"id"`
+	ItemID        uuid.UUID  `json:"item_id"`
+	LocationID    uuid.UUID  `json:"location_id"`
+	Quantity      int        `json:"quantity"`
+	ReferenceType string     `json:"reference_type"`
+	ReferenceID   string     `json:"reference_id"`
+	Status        string     `json:"status"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	MovementID    *uuid.UUID `json:"movement_id,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CreateReservationRequest is the request body for reserving stock.
+type CreateReservationRequest struct {
+	ItemID        uuid.UUID  `json:"item_id" binding:"required"`
+	LocationID    *uuid.UUID `json:"location_id,omitempty"` // defaults to the main warehouse
+	Quantity      int        `json:"quantity" binding:"required"`
+	ReferenceType string     `json:"reference_type" binding:"required"` // booking, work_order
+	ReferenceID   string     `json:"reference_id" binding:"required"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // defaults to the configured TTL
+}
+
+// CompleteReservationRequest is the optional body for consuming a reservation.
+type CompleteReservationRequest struct {
+	SalePrice *float64 `json:"sale_price,omitempty"` // per unit, for the margin report
+}
+
+// ReservationsFilter narrows the reservation list.
+type ReservationsFilter struct {
+	ItemID        *uuid.UUID
+	ReferenceType string
+	ReferenceID   string
+	Status        string
+	Limit         int
+	Offset        int
+}
+
+// Columns of item tables for bulk import and export; sku identifies the item.
+const (
+	FieldSKU         = "sku"
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldUnit        = "unit"
+	FieldCategory    = "category"
+	FieldMinQuantity = "min_quantity"
+	FieldQuantity    = "quantity"  // on-hand stock, set with SetStock
+	FieldUnitCost    = "unit_cost" // cost of stock added by the import
+)
+
+// ImportOptions control a bulk item import.
+type ImportOptions struct {
+	Mapping    map[string]string // field -> column header; other fields use the column named like the field
+	DryRun     bool              // validate and report without saving
+	SetStock   bool              // set on-hand stock to the quantity column with adjust movements
+	LocationID *uuid.UUID        // where SetStock sets stock; defaults to the main warehouse
+}
+
+// ImportRowError is a validation error of one table row; Row is the row number in the file.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult reports what an import did, or would do for a dry run.
+type ImportResult struct {
+	DryRun        bool             `json:"dry_run"`
+	Rows          int              `json:"rows"`
+	Created       int              `json:"created"`
+	Updated       int              `json:"updated"`
+	Unchanged     int              `json:"unchanged"`
+	StockAdjusted int              `json:"stock_adjusted"`
+	Errors        []ImportRowError `json:"errors"`
+}
+
+// ItemBalance is an item with its stock summed over the exported locations.
+type ItemBalance struct {
+	Item     *Item
+	Quantity int
+	Reserved int
+	Value    float64
+}
+
+// Stocktake statuses.
+const (
+	StocktakeOpen      = "open"
+	StocktakePosted    = "posted" // variances were booked as adjust movements
+	StocktakeCancelled = "cancelled"
+)
+
+// Count modes: a count either replaces what was counted of an item so far or adds to it.
+const (
+	CountSet = "set"
+	CountAdd = "add" // e.g. one barcode scan per box
+)
+
+// Stocktake is a physical count of a location, or of one item category at it. The expected
+// quantities are frozen when it starts.
+type Stocktake struct {
+	ID         uuid.UUID        `json:"id"`
+	Number     int64            `json:"number"`
+	LocationID uuid.UUID        `json:"location_id"`
+	Category   *string          `json:"category,omitempty"` // nil counts every item
+	Status     string           `json:"status"`
+	Notes      *string          `json:"notes,omitempty"`
+	StartedBy  *uuid.UUID       `json:"started_by,omitempty"`
+	PostedBy   *uuid.UUID       `json:"posted_by,omitempty"`
+	PostedAt   *time.Time       `json:"posted_at,omitempty"`
+	Items      int              `json:"items"`     // lines in the count
+	Counted    int              `json:"counted"`   // lines counted so far
+	Variances  int              `json:"variances"` // counted lines that differ from the expected quantity
+	Lines      []*StocktakeLine `json:"lines,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// StocktakeLine is an item to count with its expected and counted quantity.
+type StocktakeLine struct {
+	ID               uuid.UUID  `json:"id"`
+	StocktakeID      uuid.UUID  `json:"stocktake_id"`
+	ItemID           uuid.UUID  `json:"item_id"`
+	SKU              string     `json:"sku"`
+	Name             string     `json:"name"`
+	ExpectedQuantity int        `json:"expected_quantity"`
+	CountedQuantity  *int       `json:"counted_quantity,omitempty"` // nil until counted
+	Variance         *int       `json:"variance,omitempty"`         // counted minus expected
+	MovementID       *uuid.UUID `json:"movement_id,omitempty"`      // the adjustment posted for the variance
+}
+
+// StocktakeCount is one count entry, kept as the audit trail of a stocktake.
+type StocktakeCount struct {
+	ID              uuid.UUID  `json:"id"`
+	StocktakeID     uuid.UUID  `json:"stocktake_id"`
+	ItemID          uuid.UUID  `json:"item_id"`
+	Mode            string     `json:"mode"`
+	Quantity        int        `json:"quantity"`
+	CountedQuantity int        `json:"counted_quantity"` // the item's count after this entry
+	Code            *string    `json:"code,omitempty"`   // the scanned code
+	CountedBy       *uuid.UUID `json:"counted_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// StartStocktakeRequest is the request body for starting a stocktake.
+type StartStocktakeRequest struct {
+	LocationID *uuid.UUID `json:"location_id,omitempty"` // defaults to the main warehouse
+	Category   *string    `json:"category,omitempty"`    // count only items of this category
+	Notes      *string    `json:"notes,omitempty"`
+}
+
+// StocktakeCountRequest records a count of an item, given by ID or by a scanned code.
+type StocktakeCountRequest struct {
+	ItemID   *uuid.UUID `json:"item_id,omitempty"`
+	Code     *string    `json:"code,omitempty"`     // barcode or SKU of the scanned item
+	Mode     string     `json:"mode"`               // set (default) or add
+	Quantity *int       `json:"quantity,omitempty"` // required to set; adding defaults to 1
+}
+
+// StocktakesFilter narrows the stocktake list.
+type StocktakesFilter struct {
+	Status     string
+	LocationID *uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// Barcode types.
+const (
+	BarcodeEAN13    = "ean13"
+	BarcodeEAN8     = "ean8"
+	BarcodeUPCA     = "upc_a"    // kept as the EAN-13 with a leading zero, as most scanners read it
+	BarcodeInternal = "internal" // our own labels: letters, digits and - . _ /, no check digit
+)
+
+// Barcode is a code printed on an item's packaging; a code belongs to one item.
+type Barcode struct {
+	ID        uuid.UUID `json:"id"`
+	ItemID    uuid.UUID `json:"item_id"`
+	Code      string    `json:"code"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddBarcodeRequest is the request body for adding a barcode to an item.
+type AddBarcodeRequest struct {
+	Code string `json:"code" binding:"required"`
+	Type string `json:"type"` // detected from the code if empty
+}
+
+// Part number kinds.
+const (
+	PartNumberOEM         = "oem"         // the vehicle maker's number
+	PartNumberAftermarket = "aftermarket" // another manufacturer's number for the same part
+)
+
+// PartNumber is a number an item is sold under besides its SKU.
+type PartNumber struct {
+	ID        uuid.UUID `json:"id"`
+	ItemID    uuid.UUID `json:"item_id"`
+	Kind      string    `json:"kind"`
+	Brand     *string   `json:"brand,omitempty"` // who the number belongs to, e.g. Toyota or Brembo
+	Number    string    `json:"number"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddPartNumberRequest is the request body for adding a part number to an item.
+type AddPartNumberRequest struct {
+	Number string  `json:"number" binding:"required"`
+	Kind   string  `json:"kind"` // oem (default) or aftermarket
+	Brand  *string `json:"brand,omitempty"`
+}
+
+// ItemsFilter narrows the item list. Query searches SKU, name, description, part numbers and
+// barcodes and tolerates typos; results are then ordered by relevance.
+type ItemsFilter struct {
+	Query    string
+	Category string
+	Limit    int
+	Offset   int
+}
+
+// ItemLookup is the item a scanned code belongs to, with what a mechanic needs at the shelf.
+type ItemLookup struct {
+	Item        *Item         `json:"item"`
+	MatchedBy   string        `json:"matched_by"` // barcode or sku
+	Barcodes    []*Barcode    `json:"barcodes"`
+	PartNumbers []*PartNumber `json:"part_numbers"`
+	Stock       *ItemStock    `json:"stock"`
+}
+
+// LotOpening is the lot the stock on hand goes into when lot tracking is enabled for an item.
+const LotOpening = "OPENING"
+
+// Lot is a batch of an item at a location.
+type Lot struct {
+	ID           uuid.UUID `json:"id"`
+	ItemID       uuid.UUID `json:"item_id"`
+	SKU          string    `json:"sku"`
+	Name         string    `json:"name"`
+	LocationID   uuid.UUID `json:"location_id"`
+	LocationCode string    `json:"location_code"`
+	LotNumber    string    `json:"lot_number"`
+	ExpiresAt    *string   `json:"expires_at,omitempty"` // YYYY-MM-DD
+	Quantity     int       `json:"quantity"`             // received in total
+	Remaining    int       `json:"remaining"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MovementLot is the part of a movement that went into or came from one lot.
+type MovementLot struct {
+	LotID     uuid.UUID `json:"lot_id"`
+	LotNumber string    `json:"lot_number"`
+	ExpiresAt *string   `json:"expires_at,omitempty"`
+	Quantity  int       `json:"quantity"`
+}
+
+// LotsFilter narrows the lot list.
+type LotsFilter struct {
+	ItemID       *uuid.UUID
+	LocationID   *uuid.UUID
+	LotNumber    string
+	ExpiresBy    *string // YYYY-MM-DD: lots expiring on or before the day
+	IncludeEmpty bool    // also lots with nothing remaining
+	Limit        int
+	Offset       int
+}
+
+// ExpiringLot is a lot in the expiry report.
+type ExpiringLot struct {
+	*Lot
+	DaysLeft int `json:"days_left"` // negative once expired
+}
+
+// LotTraceLine is a movement of a lot; issues for a booking carry the vehicle.
+type LotTraceLine struct {
+	LotID         uuid.UUID  `json:"lot_id"`
+	LocationID    uuid.UUID  `json:"location_id"`
+	MovementID    uuid.UUID  `json:"movement_id"`
+	Type          string     `json:"type"`
+	QuantityDelta int        `json:"quantity_delta"`
+	Reference     *string    `json:"reference,omitempty"`
+	BookingID     *uuid.UUID `json:"booking_id,omitempty"`
+	VehicleID     *uuid.UUID `json:"vehicle_id,omitempty"`
+	VIN           *string    `json:"vin,omitempty"`
+	LicensePlate  *string    `json:"license_plate,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// LotTrace is where the stock of a lot number went, at every location, for a recall.
+type LotTrace struct {
+	ItemID     uuid.UUID       `json:"item_id"`
+	LotNumber  string          `json:"lot_number"`
+	Lots       []*Lot          `json:"lots"`
+	Movements  []*LotTraceLine `json:"movements"`
+	VehicleIDs []uuid.UUID     `json:"vehicle_ids"` // vehicles that received parts from the lot
+}
+
+// lotInput is the lot a movement uses when the caller chose one: the number and expiry of
+// incoming stock, the lot stock is taken from, or the lots a transfer took it from.
+type lotInput struct {
+	number    string
+	expiresAt *string
+	lotID     *uuid.UUID
+	from      []*MovementLot
+}
+
+// Fitment says an item fits a catalog component on a platform, a generation or a model; the
+// years and engine code narrow it further.
+type Fitment struct {
+	ID                uuid.UUID  `json:"id"`
+	ItemID            uuid.UUID  `json:"item_id"`
+	ComponentCode     string     `json:"component_code"` // also covers the codes below it, e.g. brakes.pad covers brakes.pad.front
+	VehiclePlatformID *uuid.UUID `json:"vehicle_platform_id,omitempty"`
+	GenerationID      *uuid.UUID `json:"generation_id,omitempty"`
+	ModelID           *string    `json:"model_id,omitempty"`
+	YearFrom          *int       `json:"year_from,omitempty"`
+	YearTo            *int       `json:"year_to,omitempty"`
+	EngineCode        *string    `json:"engine_code,omitempty"`
+	Notes             string     `json:"notes"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// AddFitmentRequest is the request body for adding a fitment to an item; exactly one of the
+// platform, generation and model is set.
+type AddFitmentRequest struct {
+	ComponentCode     string     `json:"component_code" binding:"required"`
+	VehiclePlatformID *uuid.UUID `json:"vehicle_platform_id,omitempty"`
+	GenerationID      *uuid.UUID `json:"generation_id,omitempty"`
+	ModelID           *string    `json:"model_id,omitempty"`
+	YearFrom          *int       `json:"year_from,omitempty"`
+	YearTo            *int       `json:"year_to,omitempty"`
+	EngineCode        *string    `json:"engine_code,omitempty"`
+	Notes             string     `json:"notes"`
+}
+
+// FitVehicle is what fitments are matched against: a vehicle with its place in the catalog.
+type FitVehicle struct {
+	ID                uuid.UUID  `json:"id"`
+	VIN               *string    `json:"vin,omitempty"`
+	LicensePlate      *string    `json:"license_plate,omitempty"`
+	Year              *int       `json:"year,omitempty"`
+	EngineCode        *string    `json:"engine_code,omitempty"` // the vehicle's own, else its platform's
+	VehiclePlatformID *uuid.UUID `json:"vehicle_platform_id,omitempty"`
+	GenerationID      *uuid.UUID `json:"generation_id,omitempty"`
+	ModelID           *string    `json:"model_id,omitempty"`
+}
+
+// FittingItem is an item in stock that fits a vehicle.
+type FittingItem struct {
+	Item        *Item         `json:"item"`
+	Fitments    []*Fitment    `json:"fitments"` // why it fits
+	PartNumbers []*PartNumber `json:"part_numbers"`
+	Available   int           `json:"available"`
+	Stock       []*Stock      `json:"stock"` // locations with stock available
+}
+
+// VehicleParts is the answer to "which parts in stock fit this vehicle".
+type VehicleParts struct {
+	Vehicle       *FitVehicle    `json:"vehicle"`
+	ComponentCode string         `json:"component_code"`
+	Items         []*FittingItem `json:"items"`
 }
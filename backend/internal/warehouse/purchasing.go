@@ -0,0 +1,512 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultCurrency is used for supplier prices given without a currency.
+const defaultCurrency = "KZT"
+
+// poReference is how a purchase order is referred to on stock movements and documents.
+func poReference(number int64) string {
+	return fmt.Sprintf("PO-%06d", number)
+}
+
+// Suppliers
+
+func (s *Service) CreateSupplier(ctx context.Context, req *CreateSupplierRequest) (*Supplier, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.LeadTimeDays < 0 {
+		return nil, fmt.Errorf("lead_time_days must not be negative")
+	}
+	sup := &Supplier{
+		ID:           uuid.New(),
+		Name:         name,
+		ContactName:  req.ContactName,
+		Phone:        req.Phone,
+		Email:        req.Email,
+		LeadTimeDays: req.LeadTimeDays,
+		Notes:        req.Notes,
+		IsActive:     true,
+	}
+	if err := s.repo.CreateSupplier(ctx, sup); err != nil {
+		return nil, err
+	}
+	return sup, nil
+}
+
+func (s *Service) GetSupplier(ctx context.Context, id uuid.UUID) (*Supplier, error) {
+	return s.repo.GetSupplierByID(ctx, id)
+}
+
+func (s *Service) ListSuppliers(ctx context.Context, activeOnly bool) ([]*Supplier, error) {
+	return s.repo.ListSuppliers(ctx, activeOnly)
+}
+
+func (s *Service) UpdateSupplier(ctx context.Context, id uuid.UUID, req *UpdateSupplierRequest) (*Supplier, error) {
+	sup, err := s.repo.GetSupplierByID(ctx, id)
+	if err != nil || sup == nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		sup.Name = name
+	}
+	if req.ContactName != nil {
+		sup.ContactName = req.ContactName
+	}
+	if req.Phone != nil {
+		sup.Phone = req.Phone
+	}
+	if req.Email != nil {
+		sup.Email = req.Email
+	}
+	if req.LeadTimeDays != nil {
+		if *req.LeadTimeDays < 0 {
+			return nil, fmt.Errorf("lead_time_days must not be negative")
+		}
+		sup.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.Notes != nil {
+		sup.Notes = req.Notes
+	}
+	if req.IsActive != nil {
+		sup.IsActive = *req.IsActive
+	}
+	if err := s.repo.UpdateSupplier(ctx, sup); err != nil {
+		return nil, err
+	}
+	return sup, nil
+}
+
+// SetSupplierItem saves the SKU, price and minimum order of an item at a supplier.
+func (s *Service) SetSupplierItem(ctx context.Context, supplierID, itemID uuid.UUID, req *SupplierItemRequest) (*SupplierItem, error) {
+	sup, err := s.repo.GetSupplierByID(ctx, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	if sup == nil {
+		return nil, fmt.Errorf("supplier not found")
+	}
+	item, err := s.repo.GetItemByID(ctx, itemID)
+	if err != nil || item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	if req.Price < 0 {
+		return nil, fmt.Errorf("price must not be negative")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	if len(currency) != 3 {
+		return nil, fmt.Errorf("currency must be a 3-letter code")
+	}
+	minOrder := req.MinOrderQuantity
+	if minOrder <= 0 {
+		minOrder = 1
+	}
+	si := &SupplierItem{
+		SupplierID:       supplierID,
+		ItemID:           itemID,
+		SupplierSKU:      req.SupplierSKU,
+		Price:            req.Price,
+		Currency:         currency,
+		MinOrderQuantity: minOrder,
+		IsPreferred:      req.IsPreferred,
+	}
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.repo.UpsertSupplierItem(ctx, tx, si)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return si, nil
+}
+
+func (s *Service) DeleteSupplierItem(ctx context.Context, supplierID, itemID uuid.UUID) (bool, error) {
+	return s.repo.DeleteSupplierItem(ctx, supplierID, itemID)
+}
+
+// ListSupplierItems returns what a supplier sells.
+func (s *Service) ListSupplierItems(ctx context.Context, supplierID uuid.UUID) ([]*SupplierItem, error) {
+	return s.repo.ListSupplierItems(ctx, &supplierID, nil)
+}
+
+// ListItemSuppliers returns who sells an item, the preferred supplier first.
+func (s *Service) ListItemSuppliers(ctx context.Context, itemID uuid.UUID) ([]*SupplierItem, error) {
+	return s.repo.ListSupplierItems(ctx, nil, &itemID)
+}
+
+// Purchase orders
+
+// CreatePurchaseOrder creates a draft order; line prices default to the supplier's prices.
+func (s *Service) CreatePurchaseOrder(ctx context.Context, userID uuid.UUID, req *CreatePurchaseOrderRequest) (*PurchaseOrder, error) {
+	sup, err := s.repo.GetSupplierByID(ctx, req.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+	if sup == nil || !sup.IsActive {
+		return nil, fmt.Errorf("supplier not found")
+	}
+	loc, err := s.repo.GetLocationByID(ctx, req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil || !loc.IsActive {
+		return nil, fmt.Errorf("location not found")
+	}
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("at least one line is required")
+	}
+	terms, err := s.repo.ListSupplierItems(ctx, &sup.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	bySupplier := make(map[uuid.UUID]*SupplierItem, len(terms))
+	for _, t := range terms {
+		bySupplier[t.ItemID] = t
+	}
+
+	po := &PurchaseOrder{
+		ID:         uuid.New(),
+		SupplierID: sup.ID,
+		LocationID: loc.ID,
+		Status:     POStatusDraft,
+		Notes:      req.Notes,
+		CreatedBy:  &userID,
+		Lines:      make([]*PurchaseOrderLine, 0, len(req.Lines)),
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, rl := range req.Lines {
+		if seen[rl.ItemID] {
+			return nil, fmt.Errorf("item %s is listed twice", rl.ItemID)
+		}
+		seen[rl.ItemID] = true
+		item, err := s.repo.GetItemByID(ctx, rl.ItemID)
+		if err != nil || item == nil {
+			return nil, fmt.Errorf("item %s not found", rl.ItemID)
+		}
+		if rl.Quantity <= 0 {
+			return nil, fmt.Errorf("%s: quantity must be positive", item.SKU)
+		}
+		line := &PurchaseOrderLine{ID: uuid.New(), OrderID: po.ID, ItemID: item.ID, Quantity: rl.Quantity}
+		if t := bySupplier[item.ID]; t != nil {
+			if rl.Quantity < t.MinOrderQuantity {
+				return nil, fmt.Errorf("%s: the supplier sells at least %d", item.SKU, t.MinOrderQuantity)
+			}
+			if po.Currency != "" && po.Currency != t.Currency {
+				return nil, fmt.Errorf("supplier prices of the order use different currencies")
+			}
+			po.Currency = t.Currency
+			line.UnitPrice = t.Price
+		}
+		if rl.UnitPrice != nil {
+			if *rl.UnitPrice < 0 {
+				return nil, fmt.Errorf("%s: unit_price must not be negative", item.SKU)
+			}
+			line.UnitPrice = *rl.UnitPrice
+		}
+		po.Lines = append(po.Lines, line)
+	}
+	if po.Currency == "" {
+		po.Currency = defaultCurrency
+	}
+
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.CreatePurchaseOrder(ctx, tx, po); err != nil {
+			return err
+		}
+		return s.repo.CreatePurchaseOrderLines(ctx, tx, po.Lines)
+	})
+	if err != nil {
+		return nil, err
+	}
+	po.setTotal()
+	return po, nil
+}
+
+// setTotal sums the ordered lines.
+func (po *PurchaseOrder) setTotal() {
+	po.Total = 0
+	for _, l := range po.Lines {
+		po.Total += float64(l.Quantity) * l.UnitPrice
+	}
+}
+
+// withLines loads the lines and totals of the given orders.
+func (s *Service) withLines(ctx context.Context, orders ...*PurchaseOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(orders))
+	for i, po := range orders {
+		ids[i] = po.ID
+	}
+	lines, err := s.repo.ListPurchaseOrderLines(ctx, s.repo.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, po := range orders {
+		po.Lines = lines[po.ID]
+		if po.Lines == nil {
+			po.Lines = []*PurchaseOrderLine{}
+		}
+		po.setTotal()
+	}
+	return nil
+}
+
+// GetPurchaseOrder returns an order delivered to a location the user may see, nil otherwise.
+func (s *Service) GetPurchaseOrder(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID) (*PurchaseOrder, error) {
+	po, err := s.repo.GetPurchaseOrderByID(ctx, id)
+	if err != nil || po == nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(po.LocationID) {
+		return nil, nil
+	}
+	if err := s.withLines(ctx, po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// ListPurchaseOrders returns orders delivered to the locations the user may see.
+func (s *Service) ListPurchaseOrders(ctx context.Context, userID uuid.UUID, role string, filter PurchaseOrdersFilter) ([]*PurchaseOrder, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListPurchaseOrders(ctx, filter, scope.ids())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withLines(ctx, list...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SendPurchaseOrder marks a draft as sent to the supplier; delivery is expected after the
+// supplier's lead time.
+func (s *Service) SendPurchaseOrder(ctx context.Context, id uuid.UUID) (*PurchaseOrder, error) {
+	return s.updatePurchaseOrder(ctx, id, func(po *PurchaseOrder, sup *Supplier) error {
+		if po.Status != POStatusDraft {
+			return fmt.Errorf("only draft orders can be sent")
+		}
+		if !sup.IsActive {
+			return fmt.Errorf("supplier is inactive")
+		}
+		now := time.Now()
+		expected := now.AddDate(0, 0, sup.LeadTimeDays).Format("2006-01-02")
+		po.Status = POStatusSent
+		po.SentAt = &now
+		po.ExpectedAt = &expected
+		return nil
+	})
+}
+
+// CancelPurchaseOrder cancels an order nothing has been received against.
+func (s *Service) CancelPurchaseOrder(ctx context.Context, id uuid.UUID) (*PurchaseOrder, error) {
+	return s.updatePurchaseOrder(ctx, id, func(po *PurchaseOrder, sup *Supplier) error {
+		if po.Status != POStatusDraft && po.Status != POStatusSent {
+			return fmt.Errorf("cannot cancel a %s order", po.Status)
+		}
+		po.Status = POStatusCancelled
+		return nil
+	})
+}
+
+// updatePurchaseOrder applies fn to a locked order and saves it; it returns nil if the order
+// does not exist.
+func (s *Service) updatePurchaseOrder(ctx context.Context, id uuid.UUID, fn func(po *PurchaseOrder, sup *Supplier) error) (*PurchaseOrder, error) {
+	var po *PurchaseOrder
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		locked, err := s.repo.LockPurchaseOrder(ctx, tx, id)
+		if err != nil || locked == nil {
+			return err
+		}
+		sup, err := s.repo.GetSupplierByID(ctx, locked.SupplierID)
+		if err != nil {
+			return err
+		}
+		if sup == nil {
+			return fmt.Errorf("supplier not found")
+		}
+		if err := fn(locked, sup); err != nil {
+			return err
+		}
+		if err := s.repo.UpdatePurchaseOrder(ctx, tx, locked); err != nil {
+			return err
+		}
+		po = locked
+		return nil
+	})
+	if err != nil || po == nil {
+		return nil, err
+	}
+	if err := s.withLines(ctx, po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// receivedStatus is the status of a sent order after a delivery.
+func receivedStatus(lines []*PurchaseOrderLine) string {
+	for _, l := range lines {
+		if l.ReceivedQuantity < l.Quantity {
+			return POStatusPartiallyReceived
+		}
+	}
+	return POStatusReceived
+}
+
+// ReceivePurchaseOrder books a delivery: the received quantities go into stock at the order's
+// location as "in" movements referencing the order. A retried delivery with the same
+// idempotency key is booked once; the order is returned with replayed set.
+func (s *Service) ReceivePurchaseOrder(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, req *ReceivePurchaseOrderRequest) (po *PurchaseOrder, replayed bool, err error) {
+	key, err := idempotencyKey(req.IdempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if key != nil {
+		if po, err = s.replayReceipt(ctx, userID, role, id, *key); po != nil || err != nil {
+			return po, po != nil, err
+		}
+	}
+	current, err := s.repo.GetPurchaseOrderByID(ctx, id)
+	if err != nil || current == nil {
+		return nil, false, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, false, err
+	}
+	if !scope.allows(current.LocationID) {
+		return nil, false, ErrLocationAccess
+	}
+	if len(req.Lines) == 0 {
+		return nil, false, fmt.Errorf("at least one line is required")
+	}
+	received := map[uuid.UUID]int{}
+	lots := make([]*lotInput, len(req.Lines))
+	for i, rl := range req.Lines {
+		if rl.Quantity <= 0 {
+			return nil, false, fmt.Errorf("received quantity must be positive")
+		}
+		received[rl.ItemID] += rl.Quantity
+		if lots[i], err = newLotInput(rl.LotNumber, rl.ExpiresAt, nil); err != nil {
+			return nil, false, err
+		}
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		locked, err := s.repo.LockPurchaseOrder(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return fmt.Errorf("purchase order not found")
+		}
+		if locked.Status != POStatusSent && locked.Status != POStatusPartiallyReceived {
+			return fmt.Errorf("only sent orders can be received")
+		}
+		lines, err := s.repo.ListPurchaseOrderLines(ctx, tx, []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		byItem := make(map[uuid.UUID]*PurchaseOrderLine, len(lines[id]))
+		for _, l := range lines[id] {
+			byItem[l.ItemID] = l
+		}
+		ref := poReference(locked.Number)
+		for itemID, qty := range received {
+			l := byItem[itemID]
+			if l == nil {
+				return fmt.Errorf("item %s is not on the order", itemID)
+			}
+			if l.ReceivedQuantity+qty > l.Quantity {
+				return fmt.Errorf("item %s: %d of %d already received, cannot receive %d more",
+					itemID, l.ReceivedQuantity, l.Quantity, qty)
+			}
+			l.ReceivedQuantity += qty
+			if err := s.repo.UpdateReceivedQuantity(ctx, tx, l); err != nil {
+				return err
+			}
+		}
+		// Each delivered line is its own movement so it can go into its own lot.
+		for i, rl := range req.Lines {
+			stock, err := s.addStock(ctx, tx, rl.ItemID, locked.LocationID, rl.Quantity)
+			if err != nil {
+				return err
+			}
+			m := &Movement{
+				ID:            uuid.New(),
+				ItemID:        rl.ItemID,
+				LocationID:    locked.LocationID,
+				QuantityDelta: rl.Quantity,
+				Type:          MovementTypeIn,
+				Reference:     &ref,
+				lot:           lots[i],
+			}
+			if err := s.post(ctx, tx, m, stock, &byItem[rl.ItemID].UnitPrice); err != nil {
+				return fmt.Errorf("item %s: %w", rl.ItemID, err)
+			}
+		}
+		if err := s.repo.CreateReceipt(ctx, tx, id, key, userID); err != nil {
+			return err
+		}
+		locked.Status = receivedStatus(lines[id])
+		if locked.Status == POStatusReceived {
+			now := time.Now()
+			locked.ReceivedAt = &now
+		}
+		if err := s.repo.UpdatePurchaseOrder(ctx, tx, locked); err != nil {
+			return err
+		}
+		locked.Lines = lines[id]
+		locked.setTotal()
+		po = locked
+		return nil
+	})
+	if errors.Is(err, errDuplicateKey) && key != nil {
+		// A concurrent delivery with the same key won; ours was rolled back.
+		po, err = s.replayReceipt(ctx, userID, role, id, *key)
+		return po, po != nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return po, false, nil
+}
+
+// replayReceipt returns the order if a delivery with key was already booked against it, nil
+// if the key is new.
+func (s *Service) replayReceipt(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, key string) (*PurchaseOrder, error) {
+	orderID, err := s.repo.GetReceiptOrder(ctx, key)
+	if err != nil || orderID == uuid.Nil {
+		return nil, err
+	}
+	if orderID != id {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return s.GetPurchaseOrder(ctx, userID, role, id)
+}
@@ -0,0 +1,42 @@
+package warehouse
+
+import "testing"
+
+func TestCrossedMinimum(t *testing.T) {
+	cases := []struct {
+		name               string
+		before, after, min int
+		want               bool
+	}{
+		{"falls below", 5, 4, 5, true},
+		{"already below", 4, 3, 5, false},
+		{"stays above", 10, 6, 5, false},
+		{"restocked", 3, 8, 5, false},
+		{"no minimum", 1, 0, 0, false},
+	}
+	for _, c := range cases {
+		if got := crossedMinimum(c.before, c.after, c.min); got != c.want {
+			t.Errorf("%s: crossedMinimum(%d, %d, %d) = %v, want %v", c.name, c.before, c.after, c.min, got, c.want)
+		}
+	}
+}
+
+func TestReceivedStatus(t *testing.T) {
+	partial := []*PurchaseOrderLine{
+		{Quantity: 10, ReceivedQuantity: 10},
+		{Quantity: 4, ReceivedQuantity: 1},
+	}
+	if got := receivedStatus(partial); got != POStatusPartiallyReceived {
+		t.Errorf("partial delivery: got %q", got)
+	}
+	partial[1].ReceivedQuantity = 4
+	if got := receivedStatus(partial); got != POStatusReceived {
+		t.Errorf("full delivery: got %q", got)
+	}
+}
+
+func TestPOReference(t *testing.T) {
+	if got := poReference(42); got != "PO-000042" {
+		t.Errorf("poReference(42) = %q", got)
+	}
+}
@@ -3,12 +3,30 @@ package warehouse
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"alem-auto/internal/database"
 )
 
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// errDuplicateKey is returned when a movement with the same idempotency key already exists.
+var errDuplicateKey = errors.New("duplicate idempotency key")
+
+// movementKeyIndex is the unique index on the idempotency key of movements.
+const movementKeyIndex = "idx_warehouse_movements_idempotency_key"
+
+// errMissingReference is returned when a row refers to a catalog entry that does not exist.
+var errMissingReference = errors.New("referenced row does not exist")
+
 type Repository struct {
 	db *database.DB
 }
@@ -17,13 +35,33 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithTx runs fn inside a database transaction.
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.WithTx(ctx, fn)
+}
+
+const itemColumns = `id, sku, name, description, unit, category, min_quantity, track_lots, shelf_life_days,
+	created_at, updated_at`
+
+func scanItem(row rowScanner) (*Item, error) {
+	item := &Item{}
+	err := row.Scan(&item.ID, &item.SKU, &item.Name, &item.Description, &item.Unit, &item.Category, &item.MinQuantity,
+		&item.TrackLots, &item.ShelfLifeDays, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
 func (r *Repository) CreateItem(ctx context.Context, item *Item) error {
 	query := `
-		INSERT INTO warehouse_items (id, sku, name, description, unit, min_quantity, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO warehouse_items (id, sku, name, description, unit, category, min_quantity, track_lots, shelf_life_days,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		item.ID, item.SKU, item.Name, item.Description, item.Unit, item.MinQuantity,
+		item.ID, item.SKU, item.Name, item.Description, item.Unit, item.Category, item.MinQuantity,
+		item.TrackLots, item.ShelfLifeDays,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create item: %w", err)
@@ -32,11 +70,7 @@ func (r *Repository) CreateItem(ctx context.Context, item *Item) error {
 }
 
 func (r *Repository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error) {
-	query := `SELECT id, sku, name, description, unit, min_quantity, created_at, updated_at FROM warehouse_items WHERE id = $1`
-	item := &Item{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&item.ID, &item.SKU, &item.Name, &item.Description, &item.Unit, &item.MinQuantity, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(r.db.QueryRowContext(ctx, `SELECT `+itemColumns+` FROM warehouse_items WHERE id = $1`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -47,11 +81,7 @@ func (r *Repository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, erro
 }
 
 func (r *Repository) GetItemBySKU(ctx context.Context, sku string) (*Item, error) {
-	query := `SELECT id, sku, name, description, unit, min_quantity, created_at, updated_at FROM warehouse_items WHERE sku = $1`
-	item := &Item{}
-	err := r.db.QueryRowContext(ctx, query, sku).Scan(
-		&item.ID, &item.SKU, &item.Name, &item.Description, &item.Unit, &item.MinQuantity, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(r.db.QueryRowContext(ctx, `SELECT `+itemColumns+` FROM warehouse_items WHERE sku = $1`, sku))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -61,20 +91,54 @@ func (r *Repository) GetItemBySKU(ctx context.Context, sku string) (*Item, error
 	return item, nil
 }
 
-func (r *Repository) ListItems(ctx context.Context, limit, offset int) ([]*Item, error) {
-	if limit <= 0 {
-		limit = 50
+// ListItems returns items by SKU, or by relevance to the search query. The query matches
+// substrings and, through trigram similarity, misspelt words of the SKU, name and description,
+// as well as part numbers and barcodes.
+func (r *Repository) ListItems(ctx context.Context, f ItemsFilter) ([]*Item, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	query := `SELECT id, sku, name, description, unit, min_quantity, created_at, updated_at FROM warehouse_items ORDER BY sku LIMIT $1 OFFSET $2`
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	var where []string
+	if f.Category != "" {
+		where = append(where, `i.category = `+arg(f.Category))
+	}
+	order := `i.sku`
+	if f.Query != "" {
+		q := arg(f.Query) + `::text`
+		like := arg("%"+likeEscaper.Replace(f.Query)+"%") + `::text`
+		pn := arg(normalizePartNumber(f.Query)) + `::text`
+		code := arg(strings.ToUpper(f.Query)) + `::text`
+		where = append(where, `(i.sku ILIKE `+like+` OR i.name ILIKE `+like+` OR i.description ILIKE `+like+`
+			OR i.sku % `+q+` OR `+q+` <% i.name OR `+q+` <% i.description
+			OR EXISTS (SELECT 1 FROM warehouse_item_part_numbers p WHERE p.item_id = i.id AND `+pn+` <> ''
+				AND (p.normalized LIKE '%' || `+pn+` || '%' OR p.normalized % `+pn+`))
+			OR EXISTS (SELECT 1 FROM warehouse_item_barcodes b WHERE b.item_id = i.id AND b.code = `+code+`))`)
+		order = `GREATEST(
+				similarity(i.sku, ` + q + `),
+				word_similarity(` + q + `, i.name),
+				word_similarity(` + q + `, i.description) * 0.8,
+				COALESCE((SELECT MAX(similarity(p.normalized, ` + pn + `)) FROM warehouse_item_part_numbers p WHERE p.item_id = i.id), 0),
+				CASE WHEN EXISTS (SELECT 1 FROM warehouse_item_barcodes b WHERE b.item_id = i.id AND b.code = ` + code + `) THEN 1 ELSE 0 END
+			) DESC, i.sku`
+	}
+	query := `SELECT ` + itemColumns + ` FROM warehouse_items i`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY ` + order + ` LIMIT ` + arg(f.Limit) + ` OFFSET ` + arg(f.Offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list items: %w", err)
 	}
 	defer rows.Close()
-	var list []*Item
+	list := []*Item{}
 	for rows.Next() {
-		item := &Item{}
-		err := rows.Scan(&item.ID, &item.SKU, &item.Name, &item.Description, &item.Unit, &item.MinQuantity, &item.CreatedAt, &item.UpdatedAt)
+		item, err := scanItem(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scan item: %w", err)
 		}
@@ -83,18 +147,88 @@ func (r *Repository) ListItems(ctx context.Context, limit, offset int) ([]*Item,
 	return list, rows.Err()
 }
 
-func (r *Repository) UpdateItem(ctx context.Context, item *Item) error {
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repository) UpdateItem(ctx context.Context, q database.Querier, item *Item) error {
 	query := `
-		UPDATE warehouse_items SET name = $2, description = $3, unit = $4, min_quantity = $5, updated_at = NOW()
+		UPDATE warehouse_items SET name = $2, description = $3, unit = $4, category = $5, min_quantity = $6,
+			track_lots = $7, shelf_life_days = $8, updated_at = NOW()
 		WHERE id = $1
 	`
-	_, err := r.db.ExecContext(ctx, query, item.ID, item.Name, item.Description, item.Unit, item.MinQuantity)
+	_, err := q.ExecContext(ctx, query, item.ID, item.Name, item.Description, item.Unit, item.Category, item.MinQuantity,
+		item.TrackLots, item.ShelfLifeDays)
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
 	return nil
 }
 
+// ListItemsBySKU returns the items with the given SKUs by SKU.
+func (r *Repository) ListItemsBySKU(ctx context.Context, skus []string) (map[string]*Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM warehouse_items WHERE sku = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(skus))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+	items := make(map[string]*Item, len(skus))
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan item: %w", err)
+		}
+		items[item.SKU] = item
+	}
+	return items, rows.Err()
+}
+
+// UpsertItem creates an item or, if its SKU exists, updates it; item.ID is set to the saved row.
+func (r *Repository) UpsertItem(ctx context.Context, q database.Querier, item *Item) error {
+	query := `
+		INSERT INTO warehouse_items (id, sku, name, description, unit, category, min_quantity, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (sku) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description,
+			unit = EXCLUDED.unit, category = EXCLUDED.category, min_quantity = EXCLUDED.min_quantity, updated_at = NOW()
+		RETURNING id
+	`
+	err := q.QueryRowContext(ctx, query, item.ID, item.SKU, item.Name, item.Description, item.Unit, item.Category,
+		item.MinQuantity).Scan(&item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save item %s: %w", item.SKU, err)
+	}
+	return nil
+}
+
+// ListItemBalances returns all items with their stock, summed over all locations or taken
+// at one.
+func (r *Repository) ListItemBalances(ctx context.Context, locationID *uuid.UUID) ([]*ItemBalance, error) {
+	query := `
+		SELECT i.id, i.sku, i.name, i.description, i.unit, i.category, i.min_quantity, i.created_at, i.updated_at,
+			COALESCE(SUM(s.quantity), 0), COALESCE(SUM(s.reserved), 0), COALESCE(SUM(s.value), 0)
+		FROM warehouse_items i
+		LEFT JOIN warehouse_stock s ON s.item_id = i.id AND ($1::uuid IS NULL OR s.location_id = $1)
+		GROUP BY i.id
+		ORDER BY i.sku
+	`
+	rows, err := r.db.QueryContext(ctx, query, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list item balances: %w", err)
+	}
+	defer rows.Close()
+	var list []*ItemBalance
+	for rows.Next() {
+		b := &ItemBalance{Item: &Item{}}
+		err := rows.Scan(&b.Item.ID, &b.Item.SKU, &b.Item.Name, &b.Item.Description, &b.Item.Unit, &b.Item.Category,
+			&b.Item.MinQuantity, &b.Item.CreatedAt, &b.Item.UpdatedAt, &b.Quantity, &b.Reserved, &b.Value)
+		if err != nil {
+			return nil, fmt.Errorf("scan item balance: %w", err)
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
 func (r *Repository) DeleteItem(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, "DELETE FROM warehouse_items WHERE id = $1", id)
 	if err != nil {
@@ -103,10 +237,21 @@ func (r *Repository) DeleteItem(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (r *Repository) GetStockByItemID(ctx context.Context, itemID uuid.UUID) (*Stock, error) {
-	query := `SELECT id, item_id, quantity, updated_at FROM warehouse_stock WHERE item_id = $1`
+const stockColumns = `id, item_id, location_id, quantity, reserved, value, updated_at`
+
+func scanStock(row rowScanner) (*Stock, error) {
 	s := &Stock{}
-	err := r.db.QueryRowContext(ctx, query, itemID).Scan(&s.ID, &s.ItemID, &s.Quantity, &s.UpdatedAt)
+	if err := row.Scan(&s.ID, &s.ItemID, &s.LocationID, &s.Quantity, &s.Reserved, &s.Value, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.Available = s.Quantity - s.Reserved
+	return s, nil
+}
+
+// GetStock returns the stock of an item at a location, nil if it was never stocked there.
+func (r *Repository) GetStock(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID) (*Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM warehouse_stock WHERE item_id = $1 AND location_id = $2`
+	s, err := scanStock(q.QueryRowContext(ctx, query, itemID, locationID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -116,66 +261,1879 @@ func (r *Repository) GetStockByItemID(ctx context.Context, itemID uuid.UUID) (*S
 	return s, nil
 }
 
-func (r *Repository) CreateStock(ctx context.Context, s *Stock) error {
-	query := `INSERT INTO warehouse_stock (id, item_id, quantity, updated_at) VALUES ($1, $2, $3, NOW())`
-	_, err := r.db.ExecContext(ctx, query, s.ID, s.ItemID, s.Quantity)
+// ListItemStock returns the stock rows of an item; locationIDs limits them to the given
+// locations unless nil.
+func (r *Repository) ListItemStock(ctx context.Context, itemID uuid.UUID, locationIDs []uuid.UUID) ([]*Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM warehouse_stock WHERE item_id = $1`
+	args := []interface{}{itemID}
+	if locationIDs != nil {
+		query += ` AND location_id = ANY($2::uuid[])`
+		args = append(args, uuidArray(locationIDs))
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY location_id`, args...)
 	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	defer rows.Close()
+	list := []*Stock{}
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock: %w", err)
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// EnsureStock creates the zero stock row of an item at a location if it is missing.
+func (r *Repository) EnsureStock(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID) error {
+	query := `
+		INSERT INTO warehouse_stock (id, item_id, location_id, quantity, updated_at) VALUES ($1, $2, $3, 0, NOW())
+		ON CONFLICT (item_id, location_id) DO NOTHING
+	`
+	if _, err := q.ExecContext(ctx, query, uuid.New(), itemID, locationID); err != nil {
 		return fmt.Errorf("failed to create stock: %w", err)
 	}
 	return nil
 }
 
-func (r *Repository) UpdateStockQuantity(ctx context.Context, itemID uuid.UUID, quantity int) error {
-	query := `UPDATE warehouse_stock SET quantity = $2, updated_at = NOW() WHERE item_id = $1`
-	_, err := r.db.ExecContext(ctx, query, itemID, quantity)
+// LockStock returns the stock of an item at a location locked for update, nil if there is none.
+func (r *Repository) LockStock(ctx context.Context, tx *sql.Tx, itemID, locationID uuid.UUID) (*Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM warehouse_stock WHERE item_id = $1 AND location_id = $2 FOR UPDATE`
+	s, err := scanStock(tx.QueryRowContext(ctx, query, itemID, locationID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to update stock: %w", err)
+		return nil, fmt.Errorf("failed to lock stock: %w", err)
 	}
-	return nil
+	return s, nil
+}
+
+// ListLocationStock returns the stock rows of the given items at a location by item.
+func (r *Repository) ListLocationStock(ctx context.Context, locationID uuid.UUID, itemIDs []uuid.UUID) (map[uuid.UUID]*Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM warehouse_stock WHERE location_id = $1 AND item_id = ANY($2::uuid[])`
+	rows, err := r.db.QueryContext(ctx, query, locationID, uuidArray(itemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	defer rows.Close()
+	stock := make(map[uuid.UUID]*Stock, len(itemIDs))
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock: %w", err)
+		}
+		stock[s.ItemID] = s
+	}
+	return stock, rows.Err()
+}
+
+// AddStockQuantity changes the stock of an item at a location by delta in a single statement,
+// so concurrent changes cannot overwrite each other. It returns nil if the stock would go
+// below what is reserved.
+func (r *Repository) AddStockQuantity(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID, delta int) (*Stock, error) {
+	query := `
+		UPDATE warehouse_stock SET quantity = quantity + $3, updated_at = NOW()
+		WHERE item_id = $1 AND location_id = $2 AND quantity + $3 >= reserved
+		RETURNING ` + stockColumns
+	s, err := scanStock(q.QueryRowContext(ctx, query, itemID, locationID, delta))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update stock: %w", err)
+	}
+	return s, nil
+}
+
+// AddReserved changes the reserved stock of an item at a location by delta; it returns nil
+// if more than the available stock would be reserved.
+func (r *Repository) AddReserved(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID, delta int) (*Stock, error) {
+	query := `
+		UPDATE warehouse_stock SET reserved = reserved + $3, updated_at = NOW()
+		WHERE item_id = $1 AND location_id = $2 AND reserved + $3 >= 0 AND quantity - reserved >= $3
+		RETURNING ` + stockColumns
+	s, err := scanStock(q.QueryRowContext(ctx, query, itemID, locationID, delta))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reserved stock: %w", err)
+	}
+	return s, nil
+}
+
+// ConsumeReserved issues reserved stock: quantity and reserved both go down by qty. It returns
+// nil if less than qty is reserved.
+func (r *Repository) ConsumeReserved(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID, qty int) (*Stock, error) {
+	query := `
+		UPDATE warehouse_stock SET quantity = quantity - $3, reserved = reserved - $3, updated_at = NOW()
+		WHERE item_id = $1 AND location_id = $2 AND reserved >= $3
+		RETURNING ` + stockColumns
+	s, err := scanStock(q.QueryRowContext(ctx, query, itemID, locationID, qty))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue reserved stock: %w", err)
+	}
+	return s, nil
 }
 
-func (r *Repository) CreateMovement(ctx context.Context, m *Movement) error {
-	query := `INSERT INTO warehouse_movements (id, item_id, quantity_delta, type, reference, created_at) VALUES ($1, $2, $3, $4, $5, NOW())`
-	var ref interface{}
-	if m.Reference != nil {
-		ref = *m.Reference
+func (r *Repository) CreateMovement(ctx context.Context, q database.Querier, m *Movement) error {
+	query := `
+		INSERT INTO warehouse_movements (id, item_id, location_id, quantity_delta, type, reference, transfer_id,
+			idempotency_key, quantity_after, unit_cost, total_cost, sale_price, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		RETURNING created_at
+	`
+	err := q.QueryRowContext(ctx, query, m.ID, m.ItemID, m.LocationID, m.QuantityDelta, m.Type, m.Reference, m.TransferID,
+		m.IdempotencyKey, m.QuantityAfter, m.UnitCost, m.TotalCost, m.SalePrice).Scan(&m.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == movementKeyIndex {
+		return errDuplicateKey
 	}
-	_, err := r.db.ExecContext(ctx, query, m.ID, m.ItemID, m.QuantityDelta, m.Type, ref)
 	if err != nil {
 		return fmt.Errorf("failed to create movement: %w", err)
 	}
 	return nil
 }
 
-func (r *Repository) ListMovements(ctx context.Context, itemID *uuid.UUID, limit, offset int) ([]*Movement, error) {
-	if limit <= 0 {
-		limit = 50
+const movementColumns = `id, item_id, location_id, quantity_delta, type, reference, transfer_id, idempotency_key,
+	quantity_after, unit_cost, total_cost, sale_price, created_at`
+
+func scanMovement(row rowScanner) (*Movement, error) {
+	m := &Movement{}
+	err := row.Scan(&m.ID, &m.ItemID, &m.LocationID, &m.QuantityDelta, &m.Type, &m.Reference, &m.TransferID,
+		&m.IdempotencyKey, &m.QuantityAfter, &m.UnitCost, &m.TotalCost, &m.SalePrice, &m.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
-	query := `SELECT id, item_id, quantity_delta, type, reference, created_at FROM warehouse_movements`
-	args := []interface{}{limit, offset}
-	if itemID != nil {
-		query = `SELECT id, item_id, quantity_delta, type, reference, created_at FROM warehouse_movements WHERE item_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-		args = []interface{}{*itemID, limit, offset}
-	} else {
-		query += ` ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	return m, nil
+}
+
+// GetMovementByKey returns the movement recorded for an idempotency key, nil if there is none.
+func (r *Repository) GetMovementByKey(ctx context.Context, q database.Querier, key string) (*Movement, error) {
+	m, err := scanMovement(q.QueryRowContext(ctx, `SELECT `+movementColumns+` FROM warehouse_movements WHERE idempotency_key = $1`, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movement: %w", err)
+	}
+	return m, nil
+}
+
+// ListTransferLegs returns both movements of a transfer, the outgoing one first.
+func (r *Repository) ListTransferLegs(ctx context.Context, transferID uuid.UUID) ([]*Movement, error) {
+	return r.listMovements(ctx, `WHERE transfer_id = $1 ORDER BY quantity_delta`, transferID)
+}
+
+// ListMovements returns movements, newest first; locationIDs limits them to the given
+// locations unless nil.
+func (r *Repository) ListMovements(ctx context.Context, f MovementsFilter, locationIDs []uuid.UUID) ([]*Movement, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	var where []string
+	if f.ItemID != nil {
+		args = append(args, *f.ItemID)
+		where = append(where, fmt.Sprintf("item_id = $%d", len(args)))
+	}
+	if f.LocationID != nil {
+		args = append(args, *f.LocationID)
+		where = append(where, fmt.Sprintf("location_id = $%d", len(args)))
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		where = append(where, fmt.Sprintf("location_id = ANY($%d::uuid[])", len(args)))
+	}
+	query := ""
+	if len(where) > 0 {
+		query = "WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	return r.listMovements(ctx, query, args...)
+}
+
+func (r *Repository) listMovements(ctx context.Context, where string, args ...interface{}) ([]*Movement, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+movementColumns+` FROM warehouse_movements `+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list movements: %w", err)
 	}
 	defer rows.Close()
 	var list []*Movement
 	for rows.Next() {
-		m := &Movement{}
-		var ref sql.NullString
-		err := rows.Scan(&m.ID, &m.ItemID, &m.QuantityDelta, &m.Type, &ref, &m.CreatedAt)
+		m, err := scanMovement(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scan movement: %w", err)
 		}
-		if ref.Valid {
-			m.Reference = &ref.String
-		}
 		list = append(list, m)
 	}
 	return list, rows.Err()
 }
+
+// Locations
+
+const locationColumns = `id, code, name, service_center_id, is_active, created_at, updated_at`
+
+func scanLocation(row rowScanner) (*Location, error) {
+	l := &Location{}
+	if err := row.Scan(&l.ID, &l.Code, &l.Name, &l.ServiceCenterID, &l.IsActive, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (r *Repository) CreateLocation(ctx context.Context, l *Location) error {
+	query := `
+		INSERT INTO warehouse_locations (id, code, name, service_center_id, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, l.ID, l.Code, l.Name, l.ServiceCenterID, l.IsActive).Scan(&l.CreatedAt, &l.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("location with code %s already exists", l.Code)
+	}
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return fmt.Errorf("service center not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create location: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetLocationByID(ctx context.Context, id uuid.UUID) (*Location, error) {
+	return r.getLocation(ctx, `id = $1`, id)
+}
+
+func (r *Repository) GetLocationByCode(ctx context.Context, code string) (*Location, error) {
+	return r.getLocation(ctx, `code = $1`, code)
+}
+
+func (r *Repository) getLocation(ctx context.Context, where string, arg interface{}) (*Location, error) {
+	l, err := scanLocation(r.db.QueryRowContext(ctx, `SELECT `+locationColumns+` FROM warehouse_locations WHERE `+where, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+	return l, nil
+}
+
+// ListLocations returns locations by code; ids limits them to the given ones unless nil.
+func (r *Repository) ListLocations(ctx context.Context, ids []uuid.UUID) ([]*Location, error) {
+	query := `SELECT ` + locationColumns + ` FROM warehouse_locations`
+	var args []interface{}
+	if ids != nil {
+		query += ` WHERE id = ANY($1::uuid[])`
+		args = append(args, uuidArray(ids))
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY code`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locations: %w", err)
+	}
+	defer rows.Close()
+	list := []*Location{}
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan location: %w", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) UpdateLocation(ctx context.Context, l *Location) error {
+	query := `
+		UPDATE warehouse_locations SET name = $2, service_center_id = $3, is_active = $4
+		WHERE id = $1 RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, l.ID, l.Name, l.ServiceCenterID, l.IsActive).Scan(&l.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return fmt.Errorf("service center not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update location: %w", err)
+	}
+	return nil
+}
+
+// ListUserLocationIDs returns the locations of the service centers the user works at.
+func (r *Repository) ListUserLocationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id FROM warehouse_locations l
+		JOIN service_center_users scu ON scu.service_center_id = l.service_center_id
+		WHERE scu.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user locations: %w", err)
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan location id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func uuidArray(ids []uuid.UUID) interface{} {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+	return pq.Array(strIDs)
+}
+
+// ListLowStock returns stock below the item minimum at each location; locationIDs limits the
+// report to the given locations unless nil. Items never stocked anywhere count as empty in the
+// main warehouse.
+func (r *Repository) ListLowStock(ctx context.Context, locationID *uuid.UUID, locationIDs []uuid.UUID) ([]*LowStock, error) {
+	query := `
+		SELECT i.id, i.sku, i.name, i.unit, l.id, l.code, COALESCE(s.quantity, 0), i.min_quantity,
+			COALESCE((
+				SELECT SUM(pol.quantity - pol.received_quantity) FROM purchase_order_lines pol
+				JOIN purchase_orders po ON po.id = pol.order_id
+				WHERE pol.item_id = i.id AND po.location_id = l.id AND po.status IN ($1, $2)
+			), 0),
+			(SELECT si.supplier_id FROM supplier_items si WHERE si.item_id = i.id AND si.is_preferred)
+		FROM warehouse_items i
+		CROSS JOIN warehouse_locations l
+		LEFT JOIN warehouse_stock s ON s.item_id = i.id AND s.location_id = l.id
+		WHERE i.min_quantity > 0 AND l.is_active AND COALESCE(s.quantity, 0) < i.min_quantity
+			AND (s.id IS NOT NULL OR (l.code = $3 AND NOT EXISTS (SELECT 1 FROM warehouse_stock x WHERE x.item_id = i.id)))
+	`
+	args := []interface{}{POStatusSent, POStatusPartiallyReceived, MainLocationCode}
+	if locationID != nil {
+		args = append(args, *locationID)
+		query += fmt.Sprintf(` AND l.id = $%d`, len(args))
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		query += fmt.Sprintf(` AND l.id = ANY($%d::uuid[])`, len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY l.code, i.sku`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list low stock: %w", err)
+	}
+	defer rows.Close()
+	list := []*LowStock{}
+	for rows.Next() {
+		ls := &LowStock{}
+		err := rows.Scan(&ls.ItemID, &ls.SKU, &ls.Name, &ls.Unit, &ls.LocationID, &ls.LocationCode, &ls.Quantity,
+			&ls.MinQuantity, &ls.OnOrder, &ls.PreferredSupplierID)
+		if err != nil {
+			return nil, fmt.Errorf("scan low stock: %w", err)
+		}
+		list = append(list, ls)
+	}
+	return list, rows.Err()
+}
+
+// ListStockKeepers returns the users told about low stock at a location: platform admins and
+// the staff of the location's service center.
+func (r *Repository) ListStockKeepers(ctx context.Context, locationID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM users WHERE role = 'admin'
+		UNION
+		SELECT scu.user_id FROM service_center_users scu
+		JOIN warehouse_locations l ON l.service_center_id = scu.service_center_id
+		WHERE l.id = $1
+	`, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock keepers: %w", err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Suppliers
+
+const supplierColumns = `id, name, contact_name, phone, email, lead_time_days, notes, is_active, created_at, updated_at`
+
+func scanSupplier(row rowScanner) (*Supplier, error) {
+	s := &Supplier{}
+	err := row.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.LeadTimeDays, &s.Notes, &s.IsActive,
+		&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *Repository) CreateSupplier(ctx context.Context, s *Supplier) error {
+	query := `
+		INSERT INTO suppliers (id, name, contact_name, phone, email, lead_time_days, notes, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, s.ID, s.Name, s.ContactName, s.Phone, s.Email, s.LeadTimeDays, s.Notes, s.IsActive).
+		Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create supplier: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetSupplierByID(ctx context.Context, id uuid.UUID) (*Supplier, error) {
+	s, err := scanSupplier(r.db.QueryRowContext(ctx, `SELECT `+supplierColumns+` FROM suppliers WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+	return s, nil
+}
+
+func (r *Repository) ListSuppliers(ctx context.Context, activeOnly bool) ([]*Supplier, error) {
+	query := `SELECT ` + supplierColumns + ` FROM suppliers`
+	if activeOnly {
+		query += ` WHERE is_active`
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppliers: %w", err)
+	}
+	defer rows.Close()
+	list := []*Supplier{}
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan supplier: %w", err)
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) UpdateSupplier(ctx context.Context, s *Supplier) error {
+	query := `
+		UPDATE suppliers SET name = $2, contact_name = $3, phone = $4, email = $5, lead_time_days = $6, notes = $7, is_active = $8
+		WHERE id = $1 RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, s.ID, s.Name, s.ContactName, s.Phone, s.Email, s.LeadTimeDays, s.Notes, s.IsActive).
+		Scan(&s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return nil
+}
+
+const supplierItemColumns = `supplier_id, item_id, supplier_sku, price, currency, min_order_quantity, is_preferred, created_at, updated_at`
+
+func scanSupplierItem(row rowScanner) (*SupplierItem, error) {
+	si := &SupplierItem{}
+	err := row.Scan(&si.SupplierID, &si.ItemID, &si.SupplierSKU, &si.Price, &si.Currency, &si.MinOrderQuantity,
+		&si.IsPreferred, &si.CreatedAt, &si.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return si, nil
+}
+
+// UpsertSupplierItem saves a supplier's terms for an item. Marking it preferred clears the
+// flag on the item's other suppliers.
+func (r *Repository) UpsertSupplierItem(ctx context.Context, q database.Querier, si *SupplierItem) error {
+	if si.IsPreferred {
+		_, err := q.ExecContext(ctx, `
+			UPDATE supplier_items SET is_preferred = FALSE WHERE item_id = $1 AND supplier_id <> $2 AND is_preferred
+		`, si.ItemID, si.SupplierID)
+		if err != nil {
+			return fmt.Errorf("failed to update preferred supplier: %w", err)
+		}
+	}
+	query := `
+		INSERT INTO supplier_items (supplier_id, item_id, supplier_sku, price, currency, min_order_quantity, is_preferred,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (supplier_id, item_id) DO UPDATE SET supplier_sku = EXCLUDED.supplier_sku, price = EXCLUDED.price,
+			currency = EXCLUDED.currency, min_order_quantity = EXCLUDED.min_order_quantity, is_preferred = EXCLUDED.is_preferred
+		RETURNING created_at, updated_at
+	`
+	err := q.QueryRowContext(ctx, query, si.SupplierID, si.ItemID, si.SupplierSKU, si.Price, si.Currency,
+		si.MinOrderQuantity, si.IsPreferred).Scan(&si.CreatedAt, &si.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save supplier item: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteSupplierItem(ctx context.Context, supplierID, itemID uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM supplier_items WHERE supplier_id = $1 AND item_id = $2`, supplierID, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete supplier item: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListSupplierItems returns the items of a supplier, or the suppliers of an item.
+func (r *Repository) ListSupplierItems(ctx context.Context, supplierID, itemID *uuid.UUID) ([]*SupplierItem, error) {
+	query := `SELECT ` + supplierItemColumns + ` FROM supplier_items`
+	var args []interface{}
+	var where []string
+	if supplierID != nil {
+		args = append(args, *supplierID)
+		where = append(where, fmt.Sprintf("supplier_id = $%d", len(args)))
+	}
+	if itemID != nil {
+		args = append(args, *itemID)
+		where = append(where, fmt.Sprintf("item_id = $%d", len(args)))
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY is_preferred DESC, price`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list supplier items: %w", err)
+	}
+	defer rows.Close()
+	list := []*SupplierItem{}
+	for rows.Next() {
+		si, err := scanSupplierItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan supplier item: %w", err)
+		}
+		list = append(list, si)
+	}
+	return list, rows.Err()
+}
+
+// Purchase orders
+
+const purchaseOrderColumns = `id, number, supplier_id, location_id, status, currency, notes, created_by, sent_at,
+	TO_CHAR(expected_at, 'YYYY-MM-DD'), received_at, created_at, updated_at`
+
+func scanPurchaseOrder(row rowScanner) (*PurchaseOrder, error) {
+	po := &PurchaseOrder{}
+	err := row.Scan(&po.ID, &po.Number, &po.SupplierID, &po.LocationID, &po.Status, &po.Currency, &po.Notes, &po.CreatedBy,
+		&po.SentAt, &po.ExpectedAt, &po.ReceivedAt, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func (r *Repository) CreatePurchaseOrder(ctx context.Context, q database.Querier, po *PurchaseOrder) error {
+	query := `
+		INSERT INTO purchase_orders (id, supplier_id, location_id, status, currency, notes, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING number, created_at, updated_at
+	`
+	err := q.QueryRowContext(ctx, query, po.ID, po.SupplierID, po.LocationID, po.Status, po.Currency, po.Notes, po.CreatedBy).
+		Scan(&po.Number, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase order: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CreatePurchaseOrderLines(ctx context.Context, q database.Querier, lines []*PurchaseOrderLine) error {
+	for _, l := range lines {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO purchase_order_lines (id, order_id, item_id, quantity, received_quantity, unit_price)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, l.ID, l.OrderID, l.ItemID, l.Quantity, l.ReceivedQuantity, l.UnitPrice)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase order line: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *Repository) GetPurchaseOrderByID(ctx context.Context, id uuid.UUID) (*PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(r.db.QueryRowContext(ctx, `SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+	return po, nil
+}
+
+// LockPurchaseOrder reads a purchase order with a row lock for the duration of tx.
+func (r *Repository) LockPurchaseOrder(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(tx.QueryRowContext(ctx, `SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock purchase order: %w", err)
+	}
+	return po, nil
+}
+
+// ListPurchaseOrders returns purchase orders, newest first; locationIDs limits them to orders
+// delivered to the given locations unless nil.
+func (r *Repository) ListPurchaseOrders(ctx context.Context, f PurchaseOrdersFilter, locationIDs []uuid.UUID) ([]*PurchaseOrder, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	var where []string
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.SupplierID != nil {
+		args = append(args, *f.SupplierID)
+		where = append(where, fmt.Sprintf("supplier_id = $%d", len(args)))
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		where = append(where, fmt.Sprintf("location_id = ANY($%d::uuid[])", len(args)))
+	}
+	query := `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase orders: %w", err)
+	}
+	defer rows.Close()
+	list := []*PurchaseOrder{}
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan purchase order: %w", err)
+		}
+		list = append(list, po)
+	}
+	return list, rows.Err()
+}
+
+// UpdatePurchaseOrder saves the status and dates of a purchase order.
+func (r *Repository) UpdatePurchaseOrder(ctx context.Context, q database.Querier, po *PurchaseOrder) error {
+	query := `
+		UPDATE purchase_orders SET status = $2, sent_at = $3, expected_at = $4, received_at = $5
+		WHERE id = $1 RETURNING updated_at
+	`
+	if err := q.QueryRowContext(ctx, query, po.ID, po.Status, po.SentAt, po.ExpectedAt, po.ReceivedAt).Scan(&po.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update purchase order: %w", err)
+	}
+	return nil
+}
+
+// ListPurchaseOrderLines returns the lines of the given orders.
+func (r *Repository) ListPurchaseOrderLines(ctx context.Context, q database.Querier, orderIDs []uuid.UUID) (map[uuid.UUID][]*PurchaseOrderLine, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT pol.id, pol.order_id, pol.item_id, pol.quantity, pol.received_quantity, pol.unit_price
+		FROM purchase_order_lines pol JOIN warehouse_items i ON i.id = pol.item_id
+		WHERE pol.order_id = ANY($1::uuid[])
+		ORDER BY pol.order_id, i.sku
+	`, uuidArray(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase order lines: %w", err)
+	}
+	defer rows.Close()
+	lines := map[uuid.UUID][]*PurchaseOrderLine{}
+	for rows.Next() {
+		l := &PurchaseOrderLine{}
+		if err := rows.Scan(&l.ID, &l.OrderID, &l.ItemID, &l.Quantity, &l.ReceivedQuantity, &l.UnitPrice); err != nil {
+			return nil, fmt.Errorf("scan purchase order line: %w", err)
+		}
+		lines[l.OrderID] = append(lines[l.OrderID], l)
+	}
+	return lines, rows.Err()
+}
+
+func (r *Repository) UpdateReceivedQuantity(ctx context.Context, q database.Querier, l *PurchaseOrderLine) error {
+	_, err := q.ExecContext(ctx, `UPDATE purchase_order_lines SET received_quantity = $2 WHERE id = $1`, l.ID, l.ReceivedQuantity)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order line: %w", err)
+	}
+	return nil
+}
+
+// CreateReceipt records a delivery; it returns errDuplicateKey if the key was already used.
+func (r *Repository) CreateReceipt(ctx context.Context, q database.Querier, orderID uuid.UUID, key *string, receivedBy uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO purchase_order_receipts (id, order_id, idempotency_key, received_by, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, uuid.New(), orderID, key, receivedBy)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errDuplicateKey
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record receipt: %w", err)
+	}
+	return nil
+}
+
+// GetReceiptOrder returns the order a receipt key was used for, uuid.Nil if the key is new.
+func (r *Repository) GetReceiptOrder(ctx context.Context, key string) (uuid.UUID, error) {
+	var orderID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT order_id FROM purchase_order_receipts WHERE idempotency_key = $1`, key).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	return orderID, nil
+}
+
+// Costing
+
+// AddStockValue changes the value of the stock of an item at a location by delta.
+func (r *Repository) AddStockValue(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID, delta float64) (float64, error) {
+	var value float64
+	err := q.QueryRowContext(ctx, `
+		UPDATE warehouse_stock SET value = value + $3, updated_at = NOW()
+		WHERE item_id = $1 AND location_id = $2
+		RETURNING value
+	`, itemID, locationID, delta).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update stock value: %w", err)
+	}
+	return value, nil
+}
+
+const costLayerColumns = `id, item_id, location_id, movement_id, unit_cost, quantity, remaining, created_at`
+
+func scanCostLayer(row rowScanner) (*CostLayer, error) {
+	l := &CostLayer{}
+	err := row.Scan(&l.ID, &l.ItemID, &l.LocationID, &l.MovementID, &l.UnitCost, &l.Quantity, &l.Remaining, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (r *Repository) CreateCostLayer(ctx context.Context, q database.Querier, l *CostLayer) error {
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO warehouse_cost_layers (id, item_id, location_id, movement_id, unit_cost, quantity, remaining, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING created_at
+	`, l.ID, l.ItemID, l.LocationID, l.MovementID, l.UnitCost, l.Quantity, l.Remaining).Scan(&l.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create cost layer: %w", err)
+	}
+	return nil
+}
+
+// ListOpenCostLayers locks the layers of an item at a location that still have stock, oldest first.
+func (r *Repository) ListOpenCostLayers(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID) ([]*CostLayer, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+costLayerColumns+` FROM warehouse_cost_layers
+		WHERE item_id = $1 AND location_id = $2 AND remaining > 0
+		ORDER BY created_at, seq
+		FOR UPDATE
+	`, itemID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cost layers: %w", err)
+	}
+	defer rows.Close()
+	var list []*CostLayer
+	for rows.Next() {
+		l, err := scanCostLayer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan cost layer: %w", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) UpdateCostLayerRemaining(ctx context.Context, q database.Querier, l *CostLayer) error {
+	_, err := q.ExecContext(ctx, `UPDATE warehouse_cost_layers SET remaining = $2 WHERE id = $1`, l.ID, l.Remaining)
+	if err != nil {
+		return fmt.Errorf("failed to update cost layer: %w", err)
+	}
+	return nil
+}
+
+// LastUnitCost returns the cost of the latest receipt of an item, preferring the given
+// location; nil if the item was never received.
+func (r *Repository) LastUnitCost(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID) (*float64, error) {
+	var cost float64
+	err := q.QueryRowContext(ctx, `
+		SELECT unit_cost FROM warehouse_cost_layers WHERE item_id = $1
+		ORDER BY (location_id = $2) DESC, created_at DESC
+		LIMIT 1
+	`, itemID, locationID).Scan(&cost)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last unit cost: %w", err)
+	}
+	return &cost, nil
+}
+
+// ListValuation sums the movements up to (excluding) before per item and location;
+// locationIDs limits them to the given locations unless nil.
+func (r *Repository) ListValuation(ctx context.Context, before time.Time, locationIDs []uuid.UUID) ([]*ValuationLine, error) {
+	query := `
+		SELECT m.item_id, i.sku, i.name, m.location_id, SUM(m.quantity_delta), COALESCE(SUM(m.total_cost), 0)
+		FROM warehouse_movements m
+		JOIN warehouse_items i ON i.id = m.item_id
+		WHERE m.created_at < $1`
+	args := []interface{}{before}
+	if locationIDs != nil {
+		query += ` AND m.location_id = ANY($2::uuid[])`
+		args = append(args, uuidArray(locationIDs))
+	}
+	query += `
+		GROUP BY m.item_id, i.sku, i.name, m.location_id
+		HAVING SUM(m.quantity_delta) <> 0 OR COALESCE(SUM(m.total_cost), 0) <> 0
+		ORDER BY i.sku, m.location_id`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list valuation: %w", err)
+	}
+	defer rows.Close()
+	list := []*ValuationLine{}
+	for rows.Next() {
+		v := &ValuationLine{}
+		if err := rows.Scan(&v.ItemID, &v.SKU, &v.Name, &v.LocationID, &v.Quantity, &v.Value); err != nil {
+			return nil, fmt.Errorf("scan valuation: %w", err)
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// ListMargins sums revenue and cost of sold parts per reference.
+func (r *Repository) ListMargins(ctx context.Context, f MarginFilter) ([]*MarginLine, error) {
+	args := []interface{}{}
+	where := []string{"quantity_delta < 0", "sale_price IS NOT NULL"}
+	if f.From != nil {
+		args = append(args, *f.From)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, f.To.AddDate(0, 0, 1))
+		where = append(where, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if f.Reference != nil {
+		args = append(args, *f.Reference)
+		where = append(where, fmt.Sprintf("reference = $%d", len(args)))
+	}
+	if f.LocationID != nil {
+		args = append(args, *f.LocationID)
+		where = append(where, fmt.Sprintf("location_id = $%d", len(args)))
+	}
+	query := `
+		SELECT COALESCE(reference, ''), SUM(-quantity_delta), SUM(-quantity_delta * sale_price), COALESCE(SUM(-total_cost), 0)
+		FROM warehouse_movements
+		WHERE ` + strings.Join(where, " AND ") + `
+		GROUP BY COALESCE(reference, '')
+		ORDER BY 1`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list margins: %w", err)
+	}
+	defer rows.Close()
+	list := []*MarginLine{}
+	for rows.Next() {
+		m := &MarginLine{}
+		if err := rows.Scan(&m.Reference, &m.Quantity, &m.Revenue, &m.Cost); err != nil {
+			return nil, fmt.Errorf("scan margin: %w", err)
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Reservations
+
+const reservationColumns = `id, item_id, location_id, quantity, reference_type, reference_id, status, expires_at,
+	movement_id, created_by, created_at, updated_at`
+
+func scanReservation(row rowScanner) (*Reservation, error) {
+	res := &Reservation{}
+	err := row.Scan(&res.ID, &res.ItemID, &res.LocationID, &res.Quantity, &res.ReferenceType, &res.ReferenceID,
+		&res.Status, &res.ExpiresAt, &res.MovementID, &res.CreatedBy, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Repository) CreateReservation(ctx context.Context, q database.Querier, res *Reservation) error {
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO warehouse_reservations (id, item_id, location_id, quantity, reference_type, reference_id, status,
+			expires_at, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`, res.ID, res.ItemID, res.LocationID, res.Quantity, res.ReferenceType, res.ReferenceID, res.Status,
+		res.ExpiresAt, res.CreatedBy).Scan(&res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+	return nil
+}
+
+// LockReservation returns a reservation locked for update, nil if it does not exist.
+func (r *Repository) LockReservation(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Reservation, error) {
+	res, err := scanReservation(tx.QueryRowContext(ctx, `SELECT `+reservationColumns+` FROM warehouse_reservations WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock reservation: %w", err)
+	}
+	return res, nil
+}
+
+// UpdateReservation saves the status and movement of a reservation.
+func (r *Repository) UpdateReservation(ctx context.Context, q database.Querier, res *Reservation) error {
+	err := q.QueryRowContext(ctx, `
+		UPDATE warehouse_reservations SET status = $2, movement_id = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, res.ID, res.Status, res.MovementID).Scan(&res.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update reservation: %w", err)
+	}
+	return nil
+}
+
+// ListReservations returns reservations, newest first; locationIDs limits them to the given
+// locations unless nil.
+func (r *Repository) ListReservations(ctx context.Context, f ReservationsFilter, locationIDs []uuid.UUID) ([]*Reservation, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	var where []string
+	if f.ItemID != nil {
+		args = append(args, *f.ItemID)
+		where = append(where, fmt.Sprintf("item_id = $%d", len(args)))
+	}
+	if f.ReferenceType != "" {
+		args = append(args, f.ReferenceType)
+		where = append(where, fmt.Sprintf("reference_type = $%d", len(args)))
+	}
+	if f.ReferenceID != "" {
+		args = append(args, f.ReferenceID)
+		where = append(where, fmt.Sprintf("reference_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		where = append(where, fmt.Sprintf("location_id = ANY($%d::uuid[])", len(args)))
+	}
+	query := `SELECT ` + reservationColumns + ` FROM warehouse_reservations`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	defer rows.Close()
+	list := []*Reservation{}
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan reservation: %w", err)
+		}
+		list = append(list, res)
+	}
+	return list, rows.Err()
+}
+
+// ListActiveReservationIDs returns the active reservations of a reference.
+func (r *Repository) ListActiveReservationIDs(ctx context.Context, refType, refID string) ([]uuid.UUID, error) {
+	return r.listReservationIDs(ctx, `
+		SELECT id FROM warehouse_reservations
+		WHERE reference_type = $1 AND reference_id = $2 AND status = 'active'
+		ORDER BY created_at
+	`, refType, refID)
+}
+
+// expiredReservation is an active reservation past its expiry. BookingCompleted is set when
+// it holds parts for a booking that was completed, so the parts were used.
+type expiredReservation struct {
+	ID               uuid.UUID
+	BookingCompleted bool
+}
+
+// ListExpiredReservations returns active reservations past their expiry, oldest first.
+func (r *Repository) ListExpiredReservations(ctx context.Context, now time.Time, limit int) ([]expiredReservation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.id, COALESCE(b.status = 'completed', FALSE)
+		FROM warehouse_reservations r
+		LEFT JOIN bookings b ON r.reference_type = $3 AND b.id::text = r.reference_id
+		WHERE r.status = 'active' AND r.expires_at <= $1
+		ORDER BY r.expires_at
+		LIMIT $2
+	`, now, limit, ReferenceBooking)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	defer rows.Close()
+	var list []expiredReservation
+	for rows.Next() {
+		var e expiredReservation
+		if err := rows.Scan(&e.ID, &e.BookingCompleted); err != nil {
+			return nil, fmt.Errorf("scan reservation id: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) listReservationIDs(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan reservation id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Stocktakes
+
+const stocktakeColumns = `st.id, st.number, st.location_id, st.category, st.status, st.notes, st.started_by, st.posted_by,
+	st.posted_at,
+	(SELECT COUNT(*) FROM warehouse_stocktake_lines l WHERE l.stocktake_id = st.id),
+	(SELECT COUNT(l.counted_quantity) FROM warehouse_stocktake_lines l WHERE l.stocktake_id = st.id),
+	(SELECT COUNT(*) FROM warehouse_stocktake_lines l WHERE l.stocktake_id = st.id AND l.counted_quantity <> l.expected_quantity),
+	st.created_at, st.updated_at`
+
+func scanStocktake(row rowScanner) (*Stocktake, error) {
+	st := &Stocktake{}
+	err := row.Scan(&st.ID, &st.Number, &st.LocationID, &st.Category, &st.Status, &st.Notes, &st.StartedBy, &st.PostedBy,
+		&st.PostedAt, &st.Items, &st.Counted, &st.Variances, &st.CreatedAt, &st.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// LockLocationForStocktake locks a location row for the duration of tx, so stocktakes of one
+// location are started one after another.
+func (r *Repository) LockLocationForStocktake(ctx context.Context, tx *sql.Tx, locationID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM warehouse_locations WHERE id = $1 FOR UPDATE`, locationID).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to lock location: %w", err)
+	}
+	return nil
+}
+
+// ListOpenStocktakeCategories returns the categories of the open stocktakes at a location; nil
+// stands for a stocktake of the whole location.
+func (r *Repository) ListOpenStocktakeCategories(ctx context.Context, q database.Querier, locationID uuid.UUID) ([]*string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT category FROM warehouse_stocktakes WHERE location_id = $1 AND status = $2
+	`, locationID, StocktakeOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open stocktakes: %w", err)
+	}
+	defer rows.Close()
+	var list []*string
+	for rows.Next() {
+		var category *string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("scan stocktake: %w", err)
+		}
+		list = append(list, category)
+	}
+	return list, rows.Err()
+}
+
+// CreateStocktake saves a new stocktake; it returns errDuplicateKey if the same items are
+// already being counted.
+func (r *Repository) CreateStocktake(ctx context.Context, q database.Querier, st *Stocktake) error {
+	query := `
+		INSERT INTO warehouse_stocktakes (id, location_id, category, status, notes, started_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING number, created_at, updated_at
+	`
+	err := q.QueryRowContext(ctx, query, st.ID, st.LocationID, st.Category, st.Status, st.Notes, st.StartedBy).
+		Scan(&st.Number, &st.CreatedAt, &st.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errDuplicateKey
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create stocktake: %w", err)
+	}
+	return nil
+}
+
+// CreateStocktakeLines freezes the expected quantities of a stocktake: one line per item of its
+// category with the item's current stock at its location. It returns the number of lines.
+func (r *Repository) CreateStocktakeLines(ctx context.Context, q database.Querier, st *Stocktake) (int, error) {
+	res, err := q.ExecContext(ctx, `
+		INSERT INTO warehouse_stocktake_lines (id, stocktake_id, item_id, expected_quantity)
+		SELECT uuid_generate_v4(), $1, i.id, COALESCE(s.quantity, 0)
+		FROM warehouse_items i
+		LEFT JOIN warehouse_stock s ON s.item_id = i.id AND s.location_id = $2
+		WHERE $3::text IS NULL OR i.category = $3
+	`, st.ID, st.LocationID, st.Category)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create stocktake lines: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create stocktake lines: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *Repository) GetStocktakeByID(ctx context.Context, id uuid.UUID) (*Stocktake, error) {
+	st, err := scanStocktake(r.db.QueryRowContext(ctx, `SELECT `+stocktakeColumns+` FROM warehouse_stocktakes st WHERE st.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stocktake: %w", err)
+	}
+	return st, nil
+}
+
+// LockStocktake reads a stocktake with a row lock for the duration of tx.
+func (r *Repository) LockStocktake(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Stocktake, error) {
+	st, err := scanStocktake(tx.QueryRowContext(ctx, `SELECT `+stocktakeColumns+` FROM warehouse_stocktakes st WHERE st.id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stocktake: %w", err)
+	}
+	return st, nil
+}
+
+// ListStocktakes returns stocktakes, newest first; locationIDs limits them to the given
+// locations unless nil.
+func (r *Repository) ListStocktakes(ctx context.Context, f StocktakesFilter, locationIDs []uuid.UUID) ([]*Stocktake, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	var where []string
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("st.status = $%d", len(args)))
+	}
+	if f.LocationID != nil {
+		args = append(args, *f.LocationID)
+		where = append(where, fmt.Sprintf("st.location_id = $%d", len(args)))
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		where = append(where, fmt.Sprintf("st.location_id = ANY($%d::uuid[])", len(args)))
+	}
+	query := `SELECT ` + stocktakeColumns + ` FROM warehouse_stocktakes st`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY st.created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocktakes: %w", err)
+	}
+	defer rows.Close()
+	list := []*Stocktake{}
+	for rows.Next() {
+		st, err := scanStocktake(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stocktake: %w", err)
+		}
+		list = append(list, st)
+	}
+	return list, rows.Err()
+}
+
+// UpdateStocktake saves the status of a stocktake and who posted it.
+func (r *Repository) UpdateStocktake(ctx context.Context, q database.Querier, st *Stocktake) error {
+	query := `
+		UPDATE warehouse_stocktakes SET status = $2, posted_by = $3, posted_at = $4
+		WHERE id = $1 RETURNING updated_at
+	`
+	if err := q.QueryRowContext(ctx, query, st.ID, st.Status, st.PostedBy, st.PostedAt).Scan(&st.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update stocktake: %w", err)
+	}
+	return nil
+}
+
+const stocktakeLineColumns = `l.id, l.stocktake_id, l.item_id, i.sku, i.name, l.expected_quantity, l.counted_quantity, l.movement_id`
+
+func scanStocktakeLine(row rowScanner) (*StocktakeLine, error) {
+	l := &StocktakeLine{}
+	err := row.Scan(&l.ID, &l.StocktakeID, &l.ItemID, &l.SKU, &l.Name, &l.ExpectedQuantity, &l.CountedQuantity, &l.MovementID)
+	if err != nil {
+		return nil, err
+	}
+	if l.CountedQuantity != nil {
+		v := *l.CountedQuantity - l.ExpectedQuantity
+		l.Variance = &v
+	}
+	return l, nil
+}
+
+// ListStocktakeLines returns the lines of a stocktake by SKU.
+func (r *Repository) ListStocktakeLines(ctx context.Context, q database.Querier, stocktakeID uuid.UUID) ([]*StocktakeLine, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+stocktakeLineColumns+`
+		FROM warehouse_stocktake_lines l JOIN warehouse_items i ON i.id = l.item_id
+		WHERE l.stocktake_id = $1
+		ORDER BY i.sku
+	`, stocktakeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocktake lines: %w", err)
+	}
+	defer rows.Close()
+	list := []*StocktakeLine{}
+	for rows.Next() {
+		l, err := scanStocktakeLine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stocktake line: %w", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// LockStocktakeLine reads the line of an item with a row lock for the duration of tx, nil if
+// the item is not part of the stocktake.
+func (r *Repository) LockStocktakeLine(ctx context.Context, tx *sql.Tx, stocktakeID, itemID uuid.UUID) (*StocktakeLine, error) {
+	l, err := scanStocktakeLine(tx.QueryRowContext(ctx, `
+		SELECT `+stocktakeLineColumns+`
+		FROM warehouse_stocktake_lines l JOIN warehouse_items i ON i.id = l.item_id
+		WHERE l.stocktake_id = $1 AND l.item_id = $2
+		FOR UPDATE OF l
+	`, stocktakeID, itemID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stocktake line: %w", err)
+	}
+	return l, nil
+}
+
+// UpdateStocktakeLine saves the counted quantity and posted movement of a line.
+func (r *Repository) UpdateStocktakeLine(ctx context.Context, q database.Querier, l *StocktakeLine) error {
+	_, err := q.ExecContext(ctx, `UPDATE warehouse_stocktake_lines SET counted_quantity = $2, movement_id = $3 WHERE id = $1`,
+		l.ID, l.CountedQuantity, l.MovementID)
+	if err != nil {
+		return fmt.Errorf("failed to update stocktake line: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CreateStocktakeCount(ctx context.Context, q database.Querier, c *StocktakeCount) error {
+	query := `
+		INSERT INTO warehouse_stocktake_counts (id, stocktake_id, item_id, mode, quantity, counted_quantity, code, counted_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING created_at
+	`
+	err := q.QueryRowContext(ctx, query, c.ID, c.StocktakeID, c.ItemID, c.Mode, c.Quantity, c.CountedQuantity, c.Code, c.CountedBy).
+		Scan(&c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stocktake count: %w", err)
+	}
+	return nil
+}
+
+// ListStocktakeCounts returns the count entries of a stocktake in the order they were made,
+// optionally of one item.
+func (r *Repository) ListStocktakeCounts(ctx context.Context, stocktakeID uuid.UUID, itemID *uuid.UUID) ([]*StocktakeCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, stocktake_id, item_id, mode, quantity, counted_quantity, code, counted_by, created_at
+		FROM warehouse_stocktake_counts
+		WHERE stocktake_id = $1 AND ($2::uuid IS NULL OR item_id = $2)
+		ORDER BY created_at, id
+	`, stocktakeID, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocktake counts: %w", err)
+	}
+	defer rows.Close()
+	list := []*StocktakeCount{}
+	for rows.Next() {
+		c := &StocktakeCount{}
+		err := rows.Scan(&c.ID, &c.StocktakeID, &c.ItemID, &c.Mode, &c.Quantity, &c.CountedQuantity, &c.Code, &c.CountedBy, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan stocktake count: %w", err)
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// Barcodes and part numbers
+
+const barcodeColumns = `id, item_id, code, type, created_at`
+
+func scanBarcode(row rowScanner) (*Barcode, error) {
+	b := &Barcode{}
+	if err := row.Scan(&b.ID, &b.ItemID, &b.Code, &b.Type, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// CreateBarcode saves a barcode; it returns errDuplicateKey if the code is taken.
+func (r *Repository) CreateBarcode(ctx context.Context, b *Barcode) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO warehouse_item_barcodes (id, item_id, code, type, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING created_at
+	`, b.ID, b.ItemID, b.Code, b.Type).Scan(&b.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errDuplicateKey
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create barcode: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetBarcodeByCode(ctx context.Context, code string) (*Barcode, error) {
+	b, err := scanBarcode(r.db.QueryRowContext(ctx, `SELECT `+barcodeColumns+` FROM warehouse_item_barcodes WHERE code = $1`, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get barcode: %w", err)
+	}
+	return b, nil
+}
+
+func (r *Repository) ListBarcodes(ctx context.Context, itemID uuid.UUID) ([]*Barcode, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+barcodeColumns+` FROM warehouse_item_barcodes WHERE item_id = $1 ORDER BY created_at`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list barcodes: %w", err)
+	}
+	defer rows.Close()
+	list := []*Barcode{}
+	for rows.Next() {
+		b, err := scanBarcode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan barcode: %w", err)
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// DeleteBarcode removes a barcode of an item and reports whether it existed.
+func (r *Repository) DeleteBarcode(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM warehouse_item_barcodes WHERE id = $1 AND item_id = $2`, id, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete barcode: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete barcode: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetItemByBarcode returns the item one of the codes belongs to, nil if none does.
+func (r *Repository) GetItemByBarcode(ctx context.Context, codes []string) (*Item, error) {
+	item, err := scanItem(r.db.QueryRowContext(ctx, `
+		SELECT `+itemColumns+` FROM warehouse_items i
+		WHERE i.id = (SELECT item_id FROM warehouse_item_barcodes WHERE code = ANY($1) LIMIT 1)
+	`, pq.Array(codes)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item by barcode: %w", err)
+	}
+	return item, nil
+}
+
+const partNumberColumns = `id, item_id, kind, brand, number, created_at`
+
+func scanPartNumber(row rowScanner) (*PartNumber, error) {
+	pn := &PartNumber{}
+	if err := row.Scan(&pn.ID, &pn.ItemID, &pn.Kind, &pn.Brand, &pn.Number, &pn.CreatedAt); err != nil {
+		return nil, err
+	}
+	return pn, nil
+}
+
+// CreatePartNumber saves a part number under its normalized form; if the item already has it
+// under the same kind, the saved one is returned.
+func (r *Repository) CreatePartNumber(ctx context.Context, pn *PartNumber, normalized string) (*PartNumber, error) {
+	saved, err := scanPartNumber(r.db.QueryRowContext(ctx, `
+		INSERT INTO warehouse_item_part_numbers (id, item_id, kind, brand, number, normalized, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (item_id, kind, normalized) DO UPDATE SET normalized = EXCLUDED.normalized
+		RETURNING `+partNumberColumns+`
+	`, pn.ID, pn.ItemID, pn.Kind, pn.Brand, pn.Number, normalized))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create part number: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *Repository) ListPartNumbers(ctx context.Context, itemID uuid.UUID) ([]*PartNumber, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+partNumberColumns+` FROM warehouse_item_part_numbers
+		WHERE item_id = $1 ORDER BY kind DESC, brand NULLS FIRST, number
+	`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list part numbers: %w", err)
+	}
+	defer rows.Close()
+	list := []*PartNumber{}
+	for rows.Next() {
+		pn, err := scanPartNumber(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan part number: %w", err)
+		}
+		list = append(list, pn)
+	}
+	return list, rows.Err()
+}
+
+// DeletePartNumber removes a part number of an item and reports whether it existed.
+func (r *Repository) DeletePartNumber(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM warehouse_item_part_numbers WHERE id = $1 AND item_id = $2`, id, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete part number: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete part number: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetItemLotSettings returns whether an item is lot-tracked and its shelf life, as seen by q.
+func (r *Repository) GetItemLotSettings(ctx context.Context, q database.Querier, itemID uuid.UUID) (bool, *int, error) {
+	var track bool
+	var shelfLife *int
+	err := q.QueryRowContext(ctx, `SELECT track_lots, shelf_life_days FROM warehouse_items WHERE id = $1`, itemID).
+		Scan(&track, &shelfLife)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get item lot settings: %w", err)
+	}
+	return track, shelfLife, nil
+}
+
+const lotColumns = `l.id, l.item_id, i.sku, i.name, l.location_id, wl.code, l.lot_number,
+	TO_CHAR(l.expires_at, 'YYYY-MM-DD'), l.quantity, l.remaining, l.created_at`
+
+const lotTables = `warehouse_lots l
+	JOIN warehouse_items i ON i.id = l.item_id
+	JOIN warehouse_locations wl ON wl.id = l.location_id`
+
+func scanLot(row rowScanner) (*Lot, error) {
+	l := &Lot{}
+	err := row.Scan(&l.ID, &l.ItemID, &l.SKU, &l.Name, &l.LocationID, &l.LocationCode, &l.LotNumber,
+		&l.ExpiresAt, &l.Quantity, &l.Remaining, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// AddToLot puts qty into a lot of an item at a location, creating the lot if there is none with
+// that number. A lot keeps the expiry it was created with unless it had none.
+func (r *Repository) AddToLot(ctx context.Context, q database.Querier, itemID, locationID uuid.UUID, number string, expiresAt *string, qty int) (*Lot, error) {
+	var id uuid.UUID
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO warehouse_lots (id, item_id, location_id, lot_number, expires_at, quantity, remaining, created_at)
+		VALUES ($1, $2, $3, $4, $5::date, $6, $6, NOW())
+		ON CONFLICT (item_id, location_id, lot_number) DO UPDATE SET
+			quantity = warehouse_lots.quantity + EXCLUDED.quantity,
+			remaining = warehouse_lots.remaining + EXCLUDED.remaining,
+			expires_at = COALESCE(warehouse_lots.expires_at, EXCLUDED.expires_at)
+		RETURNING id
+	`, uuid.New(), itemID, locationID, number, expiresAt, qty).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save lot: %w", err)
+	}
+	return r.getLot(ctx, q, id)
+}
+
+func (r *Repository) getLot(ctx context.Context, q database.Querier, id uuid.UUID) (*Lot, error) {
+	l, err := scanLot(q.QueryRowContext(ctx, `SELECT `+lotColumns+` FROM `+lotTables+` WHERE l.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lot: %w", err)
+	}
+	return l, nil
+}
+
+// LockLot reads a lot with a row lock for the duration of tx.
+func (r *Repository) LockLot(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Lot, error) {
+	l, err := scanLot(tx.QueryRowContext(ctx, `SELECT `+lotColumns+` FROM `+lotTables+` WHERE l.id = $1 FOR UPDATE OF l`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock lot: %w", err)
+	}
+	return l, nil
+}
+
+// ListOpenLots returns the lots of an item at a location that have stock left, locked, in the
+// order they are picked: by expiry with undated lots last, or oldest first.
+func (r *Repository) ListOpenLots(ctx context.Context, tx *sql.Tx, itemID, locationID uuid.UUID, byExpiry bool) ([]*Lot, error) {
+	order := `l.created_at, l.id`
+	if byExpiry {
+		order = `l.expires_at NULLS LAST, l.created_at, l.id`
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+lotColumns+` FROM `+lotTables+`
+		WHERE l.item_id = $1 AND l.location_id = $2 AND l.remaining > 0
+		ORDER BY `+order+`
+		FOR UPDATE OF l
+	`, itemID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lots: %w", err)
+	}
+	defer rows.Close()
+	var list []*Lot
+	for rows.Next() {
+		l, err := scanLot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan lot: %w", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (r *Repository) UpdateLotRemaining(ctx context.Context, q database.Querier, l *Lot) error {
+	_, err := q.ExecContext(ctx, `UPDATE warehouse_lots SET remaining = $2 WHERE id = $1`, l.ID, l.Remaining)
+	if err != nil {
+		return fmt.Errorf("failed to update lot: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CreateLotMovement(ctx context.Context, q database.Querier, lotID, movementID uuid.UUID, delta int) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO warehouse_lot_movements (id, lot_id, movement_id, quantity_delta, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, uuid.New(), lotID, movementID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to create lot movement: %w", err)
+	}
+	return nil
+}
+
+// CreateOpeningLots puts the stock of an item that is in no lot into an opening lot at each
+// location, so lot tracking starts from the stock on hand.
+func (r *Repository) CreateOpeningLots(ctx context.Context, q database.Querier, itemID uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO warehouse_lots (id, item_id, location_id, lot_number, quantity, remaining, created_at)
+		SELECT uuid_generate_v4(), s.item_id, s.location_id, $2, s.quantity - COALESCE(l.remaining, 0),
+			s.quantity - COALESCE(l.remaining, 0), NOW()
+		FROM warehouse_stock s
+		LEFT JOIN (
+			SELECT location_id, SUM(remaining) AS remaining FROM warehouse_lots
+			WHERE item_id = $1 GROUP BY location_id
+		) l ON l.location_id = s.location_id
+		WHERE s.item_id = $1 AND s.quantity > COALESCE(l.remaining, 0)
+		ON CONFLICT (item_id, location_id, lot_number) DO UPDATE SET
+			quantity = warehouse_lots.quantity + EXCLUDED.quantity,
+			remaining = warehouse_lots.remaining + EXCLUDED.remaining
+	`, itemID, LotOpening)
+	if err != nil {
+		return fmt.Errorf("failed to create opening lots: %w", err)
+	}
+	return nil
+}
+
+// ListLots returns lots by expiry, then location. Without IncludeEmpty only lots with stock
+// left are listed.
+func (r *Repository) ListLots(ctx context.Context, f LotsFilter, locationIDs []uuid.UUID) ([]*Lot, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var args []interface{}
+	var where []string
+	if f.ItemID != nil {
+		args = append(args, *f.ItemID)
+		where = append(where, fmt.Sprintf("l.item_id = $%d", len(args)))
+	}
+	if f.LocationID != nil {
+		args = append(args, *f.LocationID)
+		where = append(where, fmt.Sprintf("l.location_id = $%d", len(args)))
+	}
+	if f.LotNumber != "" {
+		args = append(args, f.LotNumber)
+		where = append(where, fmt.Sprintf("l.lot_number = $%d", len(args)))
+	}
+	if f.ExpiresBy != nil {
+		args = append(args, *f.ExpiresBy)
+		where = append(where, fmt.Sprintf("l.expires_at <= $%d::date", len(args)))
+	}
+	if !f.IncludeEmpty {
+		where = append(where, "l.remaining > 0")
+	}
+	if locationIDs != nil {
+		args = append(args, uuidArray(locationIDs))
+		where = append(where, fmt.Sprintf("l.location_id = ANY($%d::uuid[])", len(args)))
+	}
+	query := `SELECT ` + lotColumns + ` FROM ` + lotTables
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY l.expires_at NULLS LAST, wl.code, l.created_at LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lots: %w", err)
+	}
+	defer rows.Close()
+	list := []*Lot{}
+	for rows.Next() {
+		l, err := scanLot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan lot: %w", err)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// ListLotTrace returns the movements of the lots, oldest first. Issues whose reference is a
+// booking carry the booking's vehicle.
+func (r *Repository) ListLotTrace(ctx context.Context, lotIDs []uuid.UUID) ([]*LotTraceLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT lm.lot_id, m.location_id, m.id, m.type, lm.quantity_delta, m.reference,
+			b.id, v.id, v.vin, v.license_plate, m.created_at
+		FROM warehouse_lot_movements lm
+		JOIN warehouse_movements m ON m.id = lm.movement_id
+		LEFT JOIN bookings b ON lm.quantity_delta < 0 AND b.id::text = m.reference
+		LEFT JOIN vehicles v ON v.id = b.vehicle_id
+		WHERE lm.lot_id = ANY($1::uuid[])
+		ORDER BY m.created_at, lm.quantity_delta
+	`, uuidArray(lotIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace lots: %w", err)
+	}
+	defer rows.Close()
+	list := []*LotTraceLine{}
+	for rows.Next() {
+		t := &LotTraceLine{}
+		err := rows.Scan(&t.LotID, &t.LocationID, &t.MovementID, &t.Type, &t.QuantityDelta, &t.Reference,
+			&t.BookingID, &t.VehicleID, &t.VIN, &t.LicensePlate, &t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan lot movement: %w", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// ListItemsByID returns the items with the given ids by id.
+func (r *Repository) ListItemsByID(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*Item, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+itemColumns+` FROM warehouse_items WHERE id = ANY($1::uuid[])`, uuidArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+	items := make(map[uuid.UUID]*Item, len(ids))
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan item: %w", err)
+		}
+		items[item.ID] = item
+	}
+	return items, rows.Err()
+}
+
+// ListAvailableStock returns, per item, the locations where some of it is available.
+func (r *Repository) ListAvailableStock(ctx context.Context, itemIDs []uuid.UUID, locationIDs []uuid.UUID) (map[uuid.UUID][]*Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM warehouse_stock WHERE item_id = ANY($1::uuid[]) AND quantity > reserved`
+	args := []interface{}{uuidArray(itemIDs)}
+	if locationIDs != nil {
+		query += ` AND location_id = ANY($2::uuid[])`
+		args = append(args, uuidArray(locationIDs))
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY item_id, quantity - reserved DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock: %w", err)
+	}
+	defer rows.Close()
+	stock := make(map[uuid.UUID][]*Stock, len(itemIDs))
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock: %w", err)
+		}
+		stock[s.ItemID] = append(stock[s.ItemID], s)
+	}
+	return stock, rows.Err()
+}
+
+const fitmentColumns = `id, item_id, component_code, vehicle_platform_id, generation_id, model_id, year_from, year_to,
+	engine_code, notes, created_at`
+
+func scanFitment(row rowScanner) (*Fitment, error) {
+	f := &Fitment{}
+	err := row.Scan(&f.ID, &f.ItemID, &f.ComponentCode, &f.VehiclePlatformID, &f.GenerationID, &f.ModelID,
+		&f.YearFrom, &f.YearTo, &f.EngineCode, &f.Notes, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *Repository) CreateFitment(ctx context.Context, f *Fitment) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO warehouse_item_fitments (id, item_id, component_code, vehicle_platform_id, generation_id, model_id,
+			year_from, year_to, engine_code, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING created_at
+	`, f.ID, f.ItemID, f.ComponentCode, f.VehiclePlatformID, f.GenerationID, f.ModelID,
+		f.YearFrom, f.YearTo, f.EngineCode, f.Notes).Scan(&f.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return errDuplicateKey
+		case "23503":
+			return errMissingReference
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create fitment: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListFitments(ctx context.Context, itemID uuid.UUID) ([]*Fitment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+fitmentColumns+` FROM warehouse_item_fitments
+		WHERE item_id = $1 ORDER BY component_code, created_at
+	`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fitments: %w", err)
+	}
+	defer rows.Close()
+	list := []*Fitment{}
+	for rows.Next() {
+		f, err := scanFitment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan fitment: %w", err)
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// DeleteFitment removes a fitment of an item and reports whether it existed.
+func (r *Repository) DeleteFitment(ctx context.Context, itemID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM warehouse_item_fitments WHERE id = $1 AND item_id = $2`, id, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete fitment: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete fitment: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetFitVehicle returns a vehicle with its platform, generation and model, nil if there is none.
+func (r *Repository) GetFitVehicle(ctx context.Context, id uuid.UUID) (*FitVehicle, error) {
+	v := &FitVehicle{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT v.id, v.vin, v.license_plate, v.year, COALESCE(NULLIF(v.engine_code, ''), vp.engine_code),
+			v.vehicle_platform_id, vp.generation_id, g.model_id
+		FROM vehicles v
+		LEFT JOIN vehicle_platforms vp ON vp.id = v.vehicle_platform_id
+		LEFT JOIN generations g ON g.id = vp.generation_id
+		WHERE v.id = $1
+	`, id).Scan(&v.ID, &v.VIN, &v.LicensePlate, &v.Year, &v.EngineCode, &v.VehiclePlatformID, &v.GenerationID, &v.ModelID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicle: %w", err)
+	}
+	return v, nil
+}
+
+// GetComponentCode returns the code of a catalog component, "" if there is none.
+func (r *Repository) GetComponentCode(ctx context.Context, id uuid.UUID) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `SELECT code FROM components WHERE id = $1`, id).Scan(&code)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get component: %w", err)
+	}
+	return code, nil
+}
+
+// ListVehicleFitments returns the fitments on the vehicle's platform, generation or model for
+// the component code, for the codes above it and for those below it.
+func (r *Repository) ListVehicleFitments(ctx context.Context, v *FitVehicle, code string) ([]*Fitment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+fitmentColumns+` FROM warehouse_item_fitments
+		WHERE (vehicle_platform_id = $1 OR generation_id = $2 OR model_id = $3)
+			AND (component_code = $4
+				OR LEFT($4, LENGTH(component_code) + 1) = component_code || '.'
+				OR LEFT(component_code, LENGTH($4) + 1) = $4 || '.')
+		ORDER BY component_code, created_at
+	`, v.VehiclePlatformID, v.GenerationID, v.ModelID, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fitments: %w", err)
+	}
+	defer rows.Close()
+	var list []*Fitment
+	for rows.Next() {
+		f, err := scanFitment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan fitment: %w", err)
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,240 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrReservationClosed is returned when a released or expired reservation is completed.
+var ErrReservationClosed = errors.New("reservation is no longer active")
+
+const (
+	defaultReservationTTL = 72 * time.Hour
+	expireBatch           = 100
+)
+
+// Reserve holds stock of an item for a booking or work order until it expires. Only available
+// stock can be reserved.
+func (s *Service) Reserve(ctx context.Context, userID uuid.UUID, role string, req *CreateReservationRequest) (*Reservation, error) {
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	switch req.ReferenceType {
+	case ReferenceBooking, ReferenceWorkOrder:
+	default:
+		return nil, fmt.Errorf("reference_type must be %s or %s", ReferenceBooking, ReferenceWorkOrder)
+	}
+	refID := strings.TrimSpace(req.ReferenceID)
+	if refID == "" || len(refID) > 100 {
+		return nil, fmt.Errorf("reference_id is required and must not exceed 100 characters")
+	}
+	if req.ReferenceType == ReferenceBooking {
+		bookingID, err := uuid.Parse(refID)
+		if err != nil {
+			return nil, fmt.Errorf("reference_id must be a booking id")
+		}
+		refID = bookingID.String()
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.reservationTTL())
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("expires_at must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	item, err := s.repo.GetItemByID(ctx, req.ItemID)
+	if err != nil || item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	loc, err := s.usableLocation(ctx, userID, role, req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	res := &Reservation{
+		ID:            uuid.New(),
+		ItemID:        item.ID,
+		LocationID:    loc.ID,
+		Quantity:      req.Quantity,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   refID,
+		Status:        ReservationActive,
+		ExpiresAt:     expiresAt,
+		CreatedBy:     &userID,
+	}
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.EnsureStock(ctx, tx, item.ID, loc.ID); err != nil {
+			return err
+		}
+		updated, err := s.repo.AddReserved(ctx, tx, item.ID, loc.ID, req.Quantity)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			current, err := s.repo.GetStock(ctx, tx, item.ID, loc.ID)
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %d available, requested %d", ErrInsufficientStock, current.Available, req.Quantity)
+		}
+		return s.repo.CreateReservation(ctx, tx, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) reservationTTL() time.Duration {
+	if s.cfg.ReservationTTL > 0 {
+		return s.cfg.ReservationTTL
+	}
+	return defaultReservationTTL
+}
+
+// ListReservations returns reservations at the locations the user may see.
+func (s *Service) ListReservations(ctx context.Context, userID uuid.UUID, role string, filter ReservationsFilter) ([]*Reservation, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListReservations(ctx, filter, scope.ids())
+}
+
+// CompleteReservation turns a reservation into an "out" movement referencing the job.
+// Completing a consumed reservation again returns it unchanged.
+func (s *Service) CompleteReservation(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, req *CompleteReservationRequest) (*Reservation, error) {
+	if req.SalePrice != nil && *req.SalePrice < 0 {
+		return nil, fmt.Errorf("sale_price must not be negative")
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.closeReservation(ctx, id, &scope, ReservationConsumed, req.SalePrice)
+}
+
+// ReleaseReservation returns reserved stock to the available stock when the job is cancelled.
+func (s *Service) ReleaseReservation(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID) (*Reservation, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.closeReservation(ctx, id, &scope, ReservationReleased, nil)
+}
+
+// closeReservation moves an active reservation to status in one transaction: consumed issues
+// the stock, released and expired give it back. A nil scope means the system acts. It returns
+// nil if the reservation does not exist or is outside the scope.
+func (s *Service) closeReservation(ctx context.Context, id uuid.UUID, scope *locationScope, status string, salePrice *float64) (*Reservation, error) {
+	var res *Reservation
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		locked, err := s.repo.LockReservation(ctx, tx, id)
+		if err != nil || locked == nil {
+			return err
+		}
+		if scope != nil && !scope.allows(locked.LocationID) {
+			return nil
+		}
+		res = locked
+		if locked.Status == status {
+			return nil
+		}
+		if locked.Status != ReservationActive {
+			return fmt.Errorf("%w: it is %s", ErrReservationClosed, locked.Status)
+		}
+		if status != ReservationConsumed {
+			stock, err := s.repo.AddReserved(ctx, tx, locked.ItemID, locked.LocationID, -locked.Quantity)
+			if err != nil {
+				return err
+			}
+			if stock == nil {
+				return fmt.Errorf("reserved stock of reservation %s is missing", locked.ID)
+			}
+			locked.Status = status
+			return s.repo.UpdateReservation(ctx, tx, locked)
+		}
+		stock, err := s.repo.ConsumeReserved(ctx, tx, locked.ItemID, locked.LocationID, locked.Quantity)
+		if err != nil {
+			return err
+		}
+		if stock == nil {
+			return fmt.Errorf("reserved stock of reservation %s is missing", locked.ID)
+		}
+		ref := locked.ReferenceID
+		m := &Movement{
+			ID:            uuid.New(),
+			ItemID:        locked.ItemID,
+			LocationID:    locked.LocationID,
+			QuantityDelta: -locked.Quantity,
+			Type:          MovementTypeOut,
+			Reference:     &ref,
+			SalePrice:     salePrice,
+		}
+		if err := s.post(ctx, tx, m, stock, nil); err != nil {
+			return err
+		}
+		locked.Status = ReservationConsumed
+		locked.MovementID = &m.ID
+		return s.repo.UpdateReservation(ctx, tx, locked)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// BookingClosed consumes the parts reserved for a completed booking and releases those of a
+// cancelled or missed one. Reservations already closed are left as they are, so it can be
+// repeated.
+func (s *Service) BookingClosed(ctx context.Context, bookingID uuid.UUID, completed bool) error {
+	ids, err := s.repo.ListActiveReservationIDs(ctx, ReferenceBooking, bookingID.String())
+	if err != nil {
+		return err
+	}
+	status := ReservationReleased
+	if completed {
+		status = ReservationConsumed
+	}
+	for _, id := range ids {
+		if _, err := s.closeReservation(ctx, id, nil, status, nil); err != nil && !errors.Is(err, ErrReservationClosed) {
+			return fmt.Errorf("reservation %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// ExpireReservations releases active reservations past their expiry and returns how many
+// expired. Reservations of completed bookings whose parts were not settled when the booking
+// closed are consumed instead, since the parts were used.
+func (s *Service) ExpireReservations(ctx context.Context) (int, error) {
+	list, err := s.repo.ListExpiredReservations(ctx, time.Now(), expireBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range list {
+		status := ReservationExpired
+		if e.BookingCompleted {
+			status = ReservationConsumed
+		}
+		res, err := s.closeReservation(ctx, e.ID, nil, status, nil)
+		if errors.Is(err, ErrReservationClosed) {
+			// Completed or released since the list was read.
+			continue
+		}
+		if err != nil {
+			return n, fmt.Errorf("reservation %s: %w", e.ID, err)
+		}
+		if res != nil && status == ReservationExpired {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,31 @@
+package warehouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReserveRejectsInvalidRequests(t *testing.T) {
+	s := &Service{}
+	past := time.Now().Add(-time.Hour)
+	cases := []struct {
+		name string
+		req  CreateReservationRequest
+	}{
+		{"zero quantity", CreateReservationRequest{Quantity: 0, ReferenceType: ReferenceWorkOrder, ReferenceID: "WO-1"}},
+		{"unknown reference type", CreateReservationRequest{Quantity: 1, ReferenceType: "invoice", ReferenceID: "1"}},
+		{"blank reference", CreateReservationRequest{Quantity: 1, ReferenceType: ReferenceWorkOrder, ReferenceID: "  "}},
+		{"booking reference is not an id", CreateReservationRequest{Quantity: 1, ReferenceType: ReferenceBooking, ReferenceID: "B-1"}},
+		{"expiry in the past", CreateReservationRequest{Quantity: 1, ReferenceType: ReferenceWorkOrder, ReferenceID: "WO-1", ExpiresAt: &past}},
+	}
+	for _, c := range cases {
+		req := c.req
+		req.ItemID = uuid.New()
+		if _, err := s.Reserve(context.Background(), uuid.New(), "admin", &req); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
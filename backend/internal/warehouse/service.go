@@ -2,17 +2,39 @@ package warehouse
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
+	"alem-auto/config"
+	"alem-auto/internal/notify"
 )
 
+var (
+	// ErrInsufficientStock is returned when an issue would take more than the available stock.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrIdempotencyKeyReused is returned when a key is repeated with a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// maxIdempotencyKeyLen matches warehouse_movements.idempotency_key.
+const maxIdempotencyKeyLen = 255
+
+// maxCategoryLen matches warehouse_items.category.
+const maxCategoryLen = 100
+
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	cfg      config.WarehouseConfig
+	notifier notify.Channel
+	posted   sync.Map // *sql.Tx of withTx -> *[]*Movement posted in it
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, cfg config.WarehouseConfig) *Service {
+	return &Service{repo: repo, cfg: cfg}
 }
 
 func (s *Service) CreateItem(ctx context.Context, req *CreateItemRequest) (*Item, error) {
@@ -27,22 +49,28 @@ func (s *Service) CreateItem(ctx context.Context, req *CreateItemRequest) (*Item
 	if req.MinQuantity < 0 {
 		req.MinQuantity = 0
 	}
+	req.Category = strings.TrimSpace(req.Category)
+	if len([]rune(req.Category)) > maxCategoryLen {
+		return nil, fmt.Errorf("category must not exceed %d characters", maxCategoryLen)
+	}
+	if req.ShelfLifeDays != nil && *req.ShelfLifeDays <= 0 {
+		return nil, fmt.Errorf("shelf_life_days must be positive")
+	}
 	item := &Item{
-		ID:          uuid.New(),
-		SKU:         req.SKU,
-		Name:        req.Name,
-		Description: req.Description,
-		Unit:        unit,
-		MinQuantity: req.MinQuantity,
+		ID:            uuid.New(),
+		SKU:           req.SKU,
+		Name:          req.Name,
+		Description:   req.Description,
+		Unit:          unit,
+		Category:      req.Category,
+		MinQuantity:   req.MinQuantity,
+		TrackLots:     req.TrackLots,
+		ShelfLifeDays: req.ShelfLifeDays,
 	}
+	// Stock rows are created per location with the first movement
 	if err := s.repo.CreateItem(ctx, item); err != nil {
 		return nil, err
 	}
-	// Create initial stock row with 0
-	stock := &Stock{ID: uuid.New(), ItemID: item.ID, Quantity: 0}
-	if err := s.repo.CreateStock(ctx, stock); err != nil {
-		return nil, err
-	}
 	return item, nil
 }
 
@@ -50,8 +78,13 @@ func (s *Service) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error)
 	return s.repo.GetItemByID(ctx, id)
 }
 
-func (s *Service) ListItems(ctx context.Context, limit, offset int) ([]*Item, error) {
-	return s.repo.ListItems(ctx, limit, offset)
+// ListItems returns items by SKU, or the best matches of a search query first.
+func (s *Service) ListItems(ctx context.Context, filter ItemsFilter) ([]*Item, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if len([]rune(filter.Query)) > maxSearchLen {
+		return nil, fmt.Errorf("search query must not exceed %d characters", maxSearchLen)
+	}
+	return s.repo.ListItems(ctx, filter)
 }
 
 func (s *Service) UpdateItem(ctx context.Context, id uuid.UUID, req *UpdateItemRequest) (*Item, error) {
@@ -68,13 +101,47 @@ func (s *Service) UpdateItem(ctx context.Context, id uuid.UUID, req *UpdateItemR
 	if req.Unit != nil {
 		item.Unit = *req.Unit
 	}
+	if req.Category != nil {
+		category := strings.TrimSpace(*req.Category)
+		if len([]rune(category)) > maxCategoryLen {
+			return nil, fmt.Errorf("category must not exceed %d characters", maxCategoryLen)
+		}
+		item.Category = category
+	}
 	if req.MinQuantity != nil {
 		if *req.MinQuantity < 0 {
 			*req.MinQuantity = 0
 		}
 		item.MinQuantity = *req.MinQuantity
 	}
-	if err := s.repo.UpdateItem(ctx, item); err != nil {
+	if req.ShelfLifeDays != nil {
+		switch {
+		case *req.ShelfLifeDays < 0:
+			return nil, fmt.Errorf("shelf_life_days must not be negative")
+		case *req.ShelfLifeDays == 0:
+			item.ShelfLifeDays = nil
+		default:
+			item.ShelfLifeDays = req.ShelfLifeDays
+		}
+	}
+	startTracking := req.TrackLots != nil && *req.TrackLots && !item.TrackLots
+	if req.TrackLots != nil {
+		item.TrackLots = *req.TrackLots
+	}
+	if !startTracking {
+		if err := s.repo.UpdateItem(ctx, s.repo.db, item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+	// Stock on hand goes into opening lots so issues can be picked from lots right away.
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.UpdateItem(ctx, tx, item); err != nil {
+			return err
+		}
+		return s.repo.CreateOpeningLots(ctx, tx, item.ID)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return item, nil
@@ -88,52 +155,156 @@ func (s *Service) DeleteItem(ctx context.Context, id uuid.UUID) error {
 	return s.repo.DeleteItem(ctx, id)
 }
 
-func (s *Service) GetStock(ctx context.Context, itemID uuid.UUID) (*Stock, error) {
-	return s.repo.GetStockByItemID(ctx, itemID)
+// GetStock returns the on-hand, reserved and available stock of an item at the locations the
+// user may see.
+func (s *Service) GetStock(ctx context.Context, userID uuid.UUID, role string, itemID uuid.UUID) (*ItemStock, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListItemStock(ctx, itemID, scope.ids())
+	if err != nil {
+		return nil, err
+	}
+	res := &ItemStock{ItemID: itemID, Locations: list}
+	for _, st := range list {
+		res.Quantity += st.Quantity
+		res.Reserved += st.Reserved
+		res.Available += st.Available
+	}
+	return res, nil
 }
 
-func (s *Service) AdjustStock(ctx context.Context, itemID uuid.UUID, req *AdjustStockRequest) (*Stock, error) {
+// AdjustStock changes the stock of an item at a location and records the movement in one
+// transaction. If the request carries an idempotency key that was already applied, nothing is
+// changed and the current stock is returned with replayed set.
+func (s *Service) AdjustStock(ctx context.Context, userID uuid.UUID, role string, itemID uuid.UUID, req *AdjustStockRequest) (stock *Stock, replayed bool, err error) {
 	item, err := s.repo.GetItemByID(ctx, itemID)
 	if err != nil || item == nil {
-		return nil, fmt.Errorf("item not found")
+		return nil, false, fmt.Errorf("item not found")
 	}
 	switch req.Type {
 	case MovementTypeIn, MovementTypeOut, MovementTypeAdjust:
 		// ok
 	default:
-		return nil, fmt.Errorf("invalid movement type")
+		return nil, false, fmt.Errorf("invalid movement type")
+	}
+	if req.UnitCost != nil && (*req.UnitCost < 0 || req.QuantityDelta <= 0) {
+		return nil, false, fmt.Errorf("unit_cost must not be negative and is only for incoming stock")
 	}
-	stock, err := s.repo.GetStockByItemID(ctx, itemID)
+	if req.SalePrice != nil && (*req.SalePrice < 0 || req.QuantityDelta >= 0) {
+		return nil, false, fmt.Errorf("sale_price must not be negative and is only for outgoing stock")
+	}
+	if (req.LotNumber != nil || req.ExpiresAt != nil) && req.QuantityDelta <= 0 {
+		return nil, false, fmt.Errorf("lot_number and expires_at are only for incoming stock")
+	}
+	if req.LotID != nil && req.QuantityDelta >= 0 {
+		return nil, false, fmt.Errorf("lot_id is only for outgoing stock")
+	}
+	lot, err := newLotInput(req.LotNumber, req.ExpiresAt, req.LotID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	if stock == nil {
-		stock = &Stock{ID: uuid.New(), ItemID: itemID, Quantity: 0}
-		if err := s.repo.CreateStock(ctx, stock); err != nil {
-			return nil, err
+	key, err := idempotencyKey(req.IdempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	loc, err := s.usableLocation(ctx, userID, role, req.LocationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if key != nil {
+		if stock, err = s.replay(ctx, itemID, loc.ID, req, *key); stock != nil || err != nil {
+			return stock, stock != nil, err
+		}
+	}
+
+	m := &Movement{
+		ID:             uuid.New(),
+		ItemID:         itemID,
+		LocationID:     loc.ID,
+		QuantityDelta:  req.QuantityDelta,
+		Type:           req.Type,
+		Reference:      req.Reference,
+		SalePrice:      req.SalePrice,
+		IdempotencyKey: key,
+		lot:            lot,
+	}
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		updated, err := s.addStock(ctx, tx, itemID, loc.ID, req.QuantityDelta)
+		if err != nil {
+			return err
 		}
+		if err := s.post(ctx, tx, m, updated, req.UnitCost); err != nil {
+			return err
+		}
+		stock = updated
+		return nil
+	})
+	if errors.Is(err, errDuplicateKey) && key != nil {
+		// A concurrent request with the same key won; ours was rolled back.
+		stock, err = s.replay(ctx, itemID, loc.ID, req, *key)
+		return stock, stock != nil, err
 	}
-	newQty := stock.Quantity + req.QuantityDelta
-	if newQty < 0 {
-		return nil, fmt.Errorf("insufficient stock: have %d, requested change %d", stock.Quantity, req.QuantityDelta)
+	if err != nil {
+		return nil, false, err
 	}
-	if err := s.repo.UpdateStockQuantity(ctx, itemID, newQty); err != nil {
+	return stock, false, nil
+}
+
+// addStock changes the stock of an item at a location by delta inside tx.
+func (s *Service) addStock(ctx context.Context, tx *sql.Tx, itemID, locationID uuid.UUID, delta int) (*Stock, error) {
+	if err := s.repo.EnsureStock(ctx, tx, itemID, locationID); err != nil {
 		return nil, err
 	}
-	m := &Movement{
-		ID:            uuid.New(),
-		ItemID:        itemID,
-		QuantityDelta: req.QuantityDelta,
-		Type:          req.Type,
-		Reference:     req.Reference,
+	updated, err := s.repo.AddStockQuantity(ctx, tx, itemID, locationID, delta)
+	if err != nil || updated != nil {
+		return updated, err
 	}
-	if err := s.repo.CreateMovement(ctx, m); err != nil {
+	current, err := s.repo.GetStock(ctx, tx, itemID, locationID)
+	if err != nil {
 		return nil, err
 	}
-	stock.Quantity = newQty
-	return stock, nil
+	available := 0
+	if current != nil {
+		available = current.Available
+	}
+	return nil, fmt.Errorf("%w: %d available, requested change %d", ErrInsufficientStock, available, delta)
+}
+
+// idempotencyKey trims a client key; an empty key means the request is not deduplicated.
+func idempotencyKey(v *string) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	k := strings.TrimSpace(*v)
+	if len(k) > maxIdempotencyKeyLen {
+		return nil, fmt.Errorf("idempotency key must not exceed %d characters", maxIdempotencyKeyLen)
+	}
+	if k == "" {
+		return nil, nil
+	}
+	return &k, nil
 }
 
-func (s *Service) ListMovements(ctx context.Context, itemID *uuid.UUID, limit, offset int) ([]*Movement, error) {
-	return s.repo.ListMovements(ctx, itemID, limit, offset)
+// replay returns the current stock if a movement with key was already recorded for the same
+// request, nil if the key is new.
+func (s *Service) replay(ctx context.Context, itemID, locationID uuid.UUID, req *AdjustStockRequest, key string) (*Stock, error) {
+	m, err := s.repo.GetMovementByKey(ctx, s.repo.db, key)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	if m.ItemID != itemID || m.LocationID != locationID || m.QuantityDelta != req.QuantityDelta || m.Type != req.Type {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return s.repo.GetStock(ctx, s.repo.db, itemID, locationID)
+}
+
+// ListMovements returns movements at the locations the user may see.
+func (s *Service) ListMovements(ctx context.Context, userID uuid.UUID, role string, filter MovementsFilter) ([]*Movement, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListMovements(ctx, filter, scope.ids())
 }
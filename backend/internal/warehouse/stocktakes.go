@@ -0,0 +1,362 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrStocktakeClosed is returned when a posted or cancelled stocktake is changed.
+	ErrStocktakeClosed = errors.New("stocktake is no longer open")
+	// ErrStocktakeInProgress is returned when the items are already being counted.
+	ErrStocktakeInProgress = errors.New("an open stocktake already covers these items")
+)
+
+// stocktakeReference is how a stocktake is shown to people and referenced by its adjustments.
+func stocktakeReference(number int64) string {
+	return fmt.Sprintf("ST-%06d", number)
+}
+
+// stocktakesOverlap reports whether two stocktakes of one location count the same items: a
+// stocktake without a category covers the whole location.
+func stocktakesOverlap(a, b *string) bool {
+	return a == nil || b == nil || *a == *b
+}
+
+// applyCount returns the count of an item after an entry; counted is nil if the item was not
+// counted yet.
+func applyCount(counted *int, mode string, qty int) (int, error) {
+	if mode == CountSet {
+		if qty < 0 {
+			return 0, fmt.Errorf("quantity must not be negative")
+		}
+		return qty, nil
+	}
+	if qty == 0 {
+		return 0, fmt.Errorf("quantity must not be zero")
+	}
+	current := 0
+	if counted != nil {
+		current = *counted
+	}
+	if current+qty < 0 {
+		return 0, fmt.Errorf("only %d counted, cannot take away %d", current, -qty)
+	}
+	return current + qty, nil
+}
+
+// StartStocktake opens a count of a location, or of one category at it, freezing the expected
+// quantity of every item it covers.
+func (s *Service) StartStocktake(ctx context.Context, userID uuid.UUID, role string, req *StartStocktakeRequest) (*Stocktake, error) {
+	loc, err := s.usableLocation(ctx, userID, role, req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	var category *string
+	if req.Category != nil {
+		if c := strings.TrimSpace(*req.Category); c != "" {
+			if len([]rune(c)) > maxCategoryLen {
+				return nil, fmt.Errorf("category must not exceed %d characters", maxCategoryLen)
+			}
+			category = &c
+		}
+	}
+	st := &Stocktake{
+		ID:         uuid.New(),
+		LocationID: loc.ID,
+		Category:   category,
+		Status:     StocktakeOpen,
+		Notes:      req.Notes,
+		StartedBy:  &userID,
+	}
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.LockLocationForStocktake(ctx, tx, loc.ID); err != nil {
+			return err
+		}
+		open, err := s.repo.ListOpenStocktakeCategories(ctx, tx, loc.ID)
+		if err != nil {
+			return err
+		}
+		for _, other := range open {
+			if stocktakesOverlap(category, other) {
+				return ErrStocktakeInProgress
+			}
+		}
+		if err := s.repo.CreateStocktake(ctx, tx, st); err != nil {
+			return err
+		}
+		n, err := s.repo.CreateStocktakeLines(ctx, tx, st)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("there are no items to count")
+		}
+		return nil
+	})
+	if errors.Is(err, errDuplicateKey) {
+		return nil, ErrStocktakeInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetStocktake(ctx, userID, role, st.ID, false)
+}
+
+// GetStocktake returns a stocktake with its lines, or only those whose count differs from the
+// expected quantity; nil if it does not exist or the user may not see its location.
+func (s *Service) GetStocktake(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, variancesOnly bool) (*Stocktake, error) {
+	st, err := s.visibleStocktake(ctx, userID, role, id)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	lines, err := s.repo.ListStocktakeLines(ctx, s.repo.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if variancesOnly {
+		filtered := lines[:0]
+		for _, l := range lines {
+			if l.Variance != nil && *l.Variance != 0 {
+				filtered = append(filtered, l)
+			}
+		}
+		lines = filtered
+	}
+	st.Lines = lines
+	return st, nil
+}
+
+func (s *Service) visibleStocktake(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID) (*Stocktake, error) {
+	st, err := s.repo.GetStocktakeByID(ctx, id)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(st.LocationID) {
+		return nil, nil
+	}
+	return st, nil
+}
+
+// ListStocktakes returns stocktakes at the locations the user may see, without their lines.
+func (s *Service) ListStocktakes(ctx context.Context, userID uuid.UUID, role string, filter StocktakesFilter) ([]*Stocktake, error) {
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListStocktakes(ctx, filter, scope.ids())
+}
+
+// ListStocktakeCounts returns who counted what in a stocktake, optionally for one item; nil if
+// the stocktake is not visible to the user.
+func (s *Service) ListStocktakeCounts(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, itemID *uuid.UUID) ([]*StocktakeCount, error) {
+	st, err := s.visibleStocktake(ctx, userID, role, id)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	return s.repo.ListStocktakeCounts(ctx, id, itemID)
+}
+
+// RecordCount records a count of an item, given by ID or by a scanned code, and returns its
+// line. Every entry is kept with the user who made it.
+func (s *Service) RecordCount(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID, req *StocktakeCountRequest) (*StocktakeLine, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = CountSet
+	}
+	var qty int
+	switch {
+	case mode == CountSet && req.Quantity == nil:
+		return nil, fmt.Errorf("quantity is required to set a count")
+	case mode == CountAdd && req.Quantity == nil:
+		qty = 1
+	case mode == CountSet || mode == CountAdd:
+		qty = *req.Quantity
+	default:
+		return nil, fmt.Errorf("mode must be %s or %s", CountSet, CountAdd)
+	}
+	var code *string
+	var item *Item
+	var err error
+	switch {
+	case req.ItemID != nil:
+		item, err = s.repo.GetItemByID(ctx, *req.ItemID)
+	case req.Code != nil && strings.TrimSpace(*req.Code) != "":
+		c := strings.TrimSpace(*req.Code)
+		code = &c
+		item, _, err = s.findItemByCode(ctx, c)
+	default:
+		return nil, fmt.Errorf("item_id or code is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item not found")
+	}
+	current, err := s.repo.GetStocktakeByID(ctx, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(current.LocationID) {
+		return nil, ErrLocationAccess
+	}
+
+	var line *StocktakeLine
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		st, err := s.repo.LockStocktake(ctx, tx, id)
+		if err != nil || st == nil {
+			return err
+		}
+		if st.Status != StocktakeOpen {
+			return fmt.Errorf("%w: it is %s", ErrStocktakeClosed, st.Status)
+		}
+		l, err := s.repo.LockStocktakeLine(ctx, tx, id, item.ID)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			return fmt.Errorf("item %s is not part of this stocktake", item.SKU)
+		}
+		counted, err := applyCount(l.CountedQuantity, mode, qty)
+		if err != nil {
+			return err
+		}
+		l.CountedQuantity = &counted
+		variance := counted - l.ExpectedQuantity
+		l.Variance = &variance
+		if err := s.repo.UpdateStocktakeLine(ctx, tx, l); err != nil {
+			return err
+		}
+		line = l
+		return s.repo.CreateStocktakeCount(ctx, tx, &StocktakeCount{
+			ID:              uuid.New(),
+			StocktakeID:     id,
+			ItemID:          item.ID,
+			Mode:            mode,
+			Quantity:        qty,
+			CountedQuantity: counted,
+			Code:            code,
+			CountedBy:       &userID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// PostStocktake books the variance of every counted line as an adjust movement referencing the
+// stocktake; lines never counted are left as they are. Variances apply to the current stock, so
+// movements made during the count are kept. Posting a posted stocktake again returns it
+// unchanged.
+func (s *Service) PostStocktake(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID) (*Stocktake, error) {
+	current, err := s.repo.GetStocktakeByID(ctx, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(current.LocationID) {
+		return nil, ErrLocationAccess
+	}
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		st, err := s.repo.LockStocktake(ctx, tx, id)
+		if err != nil || st == nil {
+			return err
+		}
+		switch st.Status {
+		case StocktakePosted:
+			return nil
+		case StocktakeCancelled:
+			return fmt.Errorf("%w: it is %s", ErrStocktakeClosed, st.Status)
+		}
+		lines, err := s.repo.ListStocktakeLines(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		ref := stocktakeReference(st.Number)
+		for _, l := range lines {
+			if l.Variance == nil || *l.Variance == 0 {
+				continue
+			}
+			stock, err := s.addStock(ctx, tx, l.ItemID, st.LocationID, *l.Variance)
+			if err != nil {
+				return fmt.Errorf("item %s: %w", l.SKU, err)
+			}
+			m := &Movement{
+				ID:            uuid.New(),
+				ItemID:        l.ItemID,
+				LocationID:    st.LocationID,
+				QuantityDelta: *l.Variance,
+				Type:          MovementTypeAdjust,
+				Reference:     &ref,
+			}
+			if err := s.post(ctx, tx, m, stock, nil); err != nil {
+				return err
+			}
+			l.MovementID = &m.ID
+			if err := s.repo.UpdateStocktakeLine(ctx, tx, l); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		st.Status = StocktakePosted
+		st.PostedBy = &userID
+		st.PostedAt = &now
+		return s.repo.UpdateStocktake(ctx, tx, st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetStocktake(ctx, userID, role, id, false)
+}
+
+// CancelStocktake closes an open stocktake without changing stock.
+func (s *Service) CancelStocktake(ctx context.Context, userID uuid.UUID, role string, id uuid.UUID) (*Stocktake, error) {
+	current, err := s.repo.GetStocktakeByID(ctx, id)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	scope, err := s.scope(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(current.LocationID) {
+		return nil, ErrLocationAccess
+	}
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		st, err := s.repo.LockStocktake(ctx, tx, id)
+		if err != nil || st == nil {
+			return err
+		}
+		switch st.Status {
+		case StocktakeCancelled:
+			return nil
+		case StocktakePosted:
+			return fmt.Errorf("%w: it is %s", ErrStocktakeClosed, st.Status)
+		}
+		st.Status = StocktakeCancelled
+		return s.repo.UpdateStocktake(ctx, tx, st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetStocktake(ctx, userID, role, id, false)
+}
@@ -0,0 +1,58 @@
+package warehouse
+
+import "testing"
+
+func TestApplyCount(t *testing.T) {
+	five := 5
+	cases := []struct {
+		name    string
+		counted *int
+		mode    string
+		qty     int
+		want    int
+		wantErr bool
+	}{
+		{"set first count", nil, CountSet, 7, 7, false},
+		{"set replaces", &five, CountSet, 2, 2, false},
+		{"set to zero", &five, CountSet, 0, 0, false},
+		{"set negative", &five, CountSet, -1, 0, true},
+		{"first scan", nil, CountAdd, 1, 1, false},
+		{"scan adds", &five, CountAdd, 3, 8, false},
+		{"undo a scan", &five, CountAdd, -1, 4, false},
+		{"undo below zero", &five, CountAdd, -6, 0, true},
+		{"undo uncounted", nil, CountAdd, -1, 0, true},
+		{"add nothing", &five, CountAdd, 0, 0, true},
+	}
+	for _, c := range cases {
+		got, err := applyCount(c.counted, c.mode, c.qty)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("%s: applyCount = %d, %v", c.name, got, err)
+		}
+	}
+}
+
+func TestStocktakeReference(t *testing.T) {
+	if got := stocktakeReference(7); got != "ST-000007" {
+		t.Errorf("stocktakeReference(7) = %q", got)
+	}
+}
+
+func TestStocktakesOverlap(t *testing.T) {
+	oils, filters := "oils", "filters"
+	cases := []struct {
+		name string
+		a, b *string
+		want bool
+	}{
+		{"both whole location", nil, nil, true},
+		{"whole location while a category is open", nil, &oils, true},
+		{"category while the whole location is open", &oils, nil, true},
+		{"same category", &oils, &oils, true},
+		{"other category", &oils, &filters, false},
+	}
+	for _, c := range cases {
+		if got := stocktakesOverlap(c.a, c.b); got != c.want {
+			t.Errorf("%s: stocktakesOverlap = %v", c.name, got)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_fines_due_at;
+ALTER TABLE fines DROP COLUMN IF EXISTS paid_amount;
+ALTER TABLE fines DROP COLUMN IF EXISTS discount_percent;
+ALTER TABLE fines DROP COLUMN IF EXISTS discount_until;
+ALTER TABLE fines DROP COLUMN IF EXISTS due_at;
+
+-- Postgres cannot drop an enum value, so recreate the type without 'overdue'
+UPDATE fines SET status = 'pending' WHERE status = 'overdue';
+ALTER TABLE fines ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE fine_status RENAME TO fine_status_old;
+CREATE TYPE fine_status AS ENUM ('pending', 'paid', 'disputed');
+ALTER TABLE fines ALTER COLUMN status TYPE fine_status USING status::text::fine_status;
+ALTER TABLE fines ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE fine_status_old;
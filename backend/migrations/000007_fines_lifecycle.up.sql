@@ -0,0 +1,12 @@
+-- Fines lifecycle: due dates, reduced-payment window, overdue status
+ALTER TYPE fine_status ADD VALUE IF NOT EXISTS 'overdue';
+
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS due_at DATE;
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS discount_until DATE;
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS discount_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent < 100);
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS paid_amount DECIMAL(12, 2) CHECK (paid_amount >= 0);
+
+-- Backfill existing rows with the default statutory terms
+UPDATE fines SET due_at = issued_at + 30 WHERE due_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_fines_due_at ON fines(due_at);
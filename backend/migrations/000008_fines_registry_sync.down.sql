@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS fines_sync_runs;
+DROP INDEX IF EXISTS idx_fines_user_source_external_id;
+ALTER TABLE fines DROP COLUMN IF EXISTS synced_at;
+ALTER TABLE fines DROP COLUMN IF EXISTS external_id;
+ALTER TABLE fines DROP COLUMN IF EXISTS source;
+ALTER TABLE users DROP COLUMN IF EXISTS iin;
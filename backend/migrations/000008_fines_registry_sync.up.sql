@@ -0,0 +1,24 @@
+-- Fines registry sync: IIN on users, external IDs on fines, sync run history
+ALTER TABLE users ADD COLUMN IF NOT EXISTS iin VARCHAR(12);
+
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'manual';
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fines_user_source_external_id ON fines(user_id, source, external_id) WHERE external_id IS NOT NULL;
+
+CREATE TABLE fines_sync_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running', -- running, success, failed
+    fetched INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    matched INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_fines_sync_runs_user_id ON fines_sync_runs(user_id, started_at DESC);
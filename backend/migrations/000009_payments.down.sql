@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_payment_intents_updated_at ON payment_intents;
+DROP TABLE IF EXISTS payment_webhook_events;
+DROP TABLE IF EXISTS payment_refunds;
+DROP TABLE IF EXISTS payment_intents;
+DROP TYPE IF EXISTS payment_status;
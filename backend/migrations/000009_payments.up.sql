@@ -0,0 +1,53 @@
+-- Payments: intents tied to a payable entity, provider webhooks and refunds
+CREATE TYPE payment_status AS ENUM ('created', 'pending', 'succeeded', 'failed', 'cancelled', 'partially_refunded', 'refunded');
+
+CREATE TABLE payment_intents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payable_type VARCHAR(20) NOT NULL, -- fine, booking, order
+    payable_id UUID NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(10) NOT NULL DEFAULT 'KZT',
+    description TEXT,
+    provider VARCHAR(50) NOT NULL,
+    provider_payment_id VARCHAR(255),
+    checkout_url TEXT,
+    status payment_status NOT NULL DEFAULT 'created',
+    refunded_amount DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
+    failure_reason TEXT,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_intents_user_id ON payment_intents(user_id);
+CREATE INDEX idx_payment_intents_payable ON payment_intents(payable_type, payable_id);
+CREATE UNIQUE INDEX idx_payment_intents_provider_payment_id ON payment_intents(provider, provider_payment_id) WHERE provider_payment_id IS NOT NULL;
+
+CREATE TABLE payment_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_intent_id UUID NOT NULL REFERENCES payment_intents(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    provider_refund_id VARCHAR(255),
+    created_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_refunds_intent_id ON payment_refunds(payment_intent_id);
+
+-- Webhook events are stored before processing; (provider, event_id) makes redelivery idempotent
+CREATE TABLE payment_webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payment_intent_id UUID REFERENCES payment_intents(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(provider, event_id)
+);
+
+CREATE TRIGGER update_payment_intents_updated_at BEFORE UPDATE ON payment_intents
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS update_fine_disputes_updated_at ON fine_disputes;
+DROP TABLE IF EXISTS fine_dispute_events;
+DROP TABLE IF EXISTS fine_disputes;
+DROP TYPE IF EXISTS fine_dispute_status;
+
+DELETE FROM asset_links WHERE link_type = 'fine';
+ALTER TYPE asset_link_type RENAME TO asset_link_type_old;
+CREATE TYPE asset_link_type AS ENUM ('vehicle', 'inspection', 'component', 'component_observation');
+ALTER TABLE asset_links ALTER COLUMN link_type TYPE asset_link_type USING link_type::text::asset_link_type;
+DROP TYPE asset_link_type_old;
+
+UPDATE fines SET status = 'pending' WHERE status = 'cancelled';
+ALTER TABLE fines ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE fine_status RENAME TO fine_status_old;
+CREATE TYPE fine_status AS ENUM ('pending', 'paid', 'disputed', 'overdue');
+ALTER TABLE fines ALTER COLUMN status TYPE fine_status USING status::text::fine_status;
+ALTER TABLE fines ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE fine_status_old;
@@ -0,0 +1,39 @@
+-- Fine disputes: reason, text, evidence (asset_links with link_type 'fine') and status history
+ALTER TYPE fine_status ADD VALUE IF NOT EXISTS 'cancelled';
+ALTER TYPE asset_link_type ADD VALUE IF NOT EXISTS 'fine';
+
+CREATE TYPE fine_dispute_status AS ENUM ('submitted', 'under_review', 'accepted', 'rejected');
+
+CREATE TABLE fine_disputes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    fine_id UUID NOT NULL REFERENCES fines(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(50) NOT NULL,
+    text TEXT NOT NULL,
+    status fine_dispute_status NOT NULL DEFAULT 'submitted',
+    resolution_comment TEXT,
+    submitted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_fine_disputes_fine_id ON fine_disputes(fine_id);
+CREATE INDEX idx_fine_disputes_status ON fine_disputes(status);
+-- Only one open dispute per fine
+CREATE UNIQUE INDEX idx_fine_disputes_open ON fine_disputes(fine_id) WHERE status IN ('submitted', 'under_review');
+
+CREATE TABLE fine_dispute_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dispute_id UUID NOT NULL REFERENCES fine_disputes(id) ON DELETE CASCADE,
+    status fine_dispute_status NOT NULL,
+    comment TEXT,
+    actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_fine_dispute_events_dispute_id ON fine_dispute_events(dispute_id, created_at);
+
+CREATE TRIGGER update_fine_disputes_updated_at BEFORE UPDATE ON fine_disputes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
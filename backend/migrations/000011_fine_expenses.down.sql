@@ -0,0 +1,5 @@
+ALTER TABLE fines DROP COLUMN IF EXISTS expense_record_id;
+
+DELETE FROM service_records WHERE fine_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_service_records_fine_id;
+ALTER TABLE service_records DROP COLUMN IF EXISTS fine_id;
@@ -0,0 +1,20 @@
+-- Paid fines as expense entries: service_records.fine_id <-> fines.expense_record_id
+ALTER TABLE service_records ADD COLUMN IF NOT EXISTS fine_id UUID REFERENCES fines(id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_records_fine_id ON service_records(fine_id) WHERE fine_id IS NOT NULL;
+
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS expense_record_id UUID REFERENCES service_records(id) ON DELETE SET NULL;
+
+-- Backfill expense entries for fines that are already paid
+INSERT INTO service_records (id, user_id, vehicle_id, date, category, amount, description, created_at, fine_id)
+SELECT uuid_generate_v4(), f.user_id, f.vehicle_id,
+       TO_CHAR(COALESCE(f.paid_at, f.updated_at), 'YYYY-MM-DD'), 'fine',
+       COALESCE(f.paid_amount, f.amount),
+       CASE WHEN f.article IS NOT NULL THEN 'Штраф по ст. ' || f.article || ': ' || f.description ELSE f.description END,
+       NOW(), f.id
+FROM fines f
+WHERE f.status = 'paid'
+  AND NOT EXISTS (SELECT 1 FROM service_records sr WHERE sr.fine_id = f.id);
+
+UPDATE fines f SET expense_record_id = sr.id
+FROM service_records sr
+WHERE sr.fine_id = f.id AND f.expense_record_id IS NULL;
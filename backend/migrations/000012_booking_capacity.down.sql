@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_bookings_center_time;
+ALTER TABLE bookings DROP COLUMN IF EXISTS ends_at;
+
+DROP TABLE IF EXISTS service_center_services;
+DROP TABLE IF EXISTS service_center_holidays;
+DROP TABLE IF EXISTS service_center_hours;
+
+ALTER TABLE service_centers DROP COLUMN IF EXISTS default_duration_minutes;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS slot_minutes;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS bays;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,52 @@
+-- Service center working hours, holidays, capacity and service durations for bookings
+ALTER TABLE service_centers ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Almaty';
+ALTER TABLE service_centers ADD COLUMN IF NOT EXISTS bays INTEGER NOT NULL DEFAULT 1 CHECK (bays > 0);
+ALTER TABLE service_centers ADD COLUMN IF NOT EXISTS slot_minutes INTEGER NOT NULL DEFAULT 30 CHECK (slot_minutes > 0);
+ALTER TABLE service_centers ADD COLUMN IF NOT EXISTS default_duration_minutes INTEGER NOT NULL DEFAULT 60 CHECK (default_duration_minutes > 0);
+
+-- Weekly schedule; a weekday without a row is a day off. weekday: 0 = Sunday ... 6 = Saturday
+CREATE TABLE service_center_hours (
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    PRIMARY KEY (service_center_id, weekday),
+    CHECK (closes_at > opens_at)
+);
+
+-- Existing centers start with Mon-Sat 09:00-18:00
+INSERT INTO service_center_hours (service_center_id, weekday, opens_at, closes_at)
+SELECT sc.id, d, '09:00', '18:00' FROM service_centers sc, generate_series(1, 6) AS d
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE service_center_holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (service_center_id, date)
+);
+
+-- Services offered by a center with their duration
+CREATE TABLE service_center_services (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_service_center_services_center_id ON service_center_services(service_center_id);
+
+CREATE TRIGGER update_service_center_services_updated_at BEFORE UPDATE ON service_center_services
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Bookings occupy [scheduled_at, ends_at)
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITH TIME ZONE;
+UPDATE bookings SET ends_at = scheduled_at + INTERVAL '60 minutes' WHERE ends_at IS NULL;
+ALTER TABLE bookings ALTER COLUMN ends_at SET NOT NULL;
+
+CREATE INDEX idx_bookings_center_time ON bookings(service_center_id, scheduled_at, ends_at);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS booking_events_no_update ON booking_events;
+DROP FUNCTION IF EXISTS booking_events_append_only();
+DROP TABLE IF EXISTS booking_events;
+
+UPDATE bookings SET status = 'scheduled' WHERE status IN ('confirmed', 'checked_in');
+ALTER TABLE bookings ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE booking_status RENAME TO booking_status_old;
+CREATE TYPE booking_status AS ENUM ('scheduled', 'completed', 'cancelled', 'no_show');
+ALTER TABLE bookings ALTER COLUMN status TYPE booking_status USING status::text::booking_status;
+ALTER TABLE bookings ALTER COLUMN status SET DEFAULT 'scheduled';
+DROP TYPE booking_status_old;
@@ -0,0 +1,33 @@
+-- Booking lifecycle: confirmation and check-in by the service center, history of changes
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'confirmed' AFTER 'scheduled';
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'checked_in' AFTER 'confirmed';
+
+-- Append-only: rows are only inserted; they go away with the booking
+CREATE TABLE booking_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    old_scheduled_at TIMESTAMP WITH TIME ZONE,
+    new_scheduled_at TIMESTAMP WITH TIME ZONE,
+    actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_role VARCHAR(20) NOT NULL, -- owner, center, system
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_booking_events_booking_id ON booking_events(booking_id, created_at);
+
+CREATE OR REPLACE FUNCTION booking_events_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'booking_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER booking_events_no_update BEFORE UPDATE ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION booking_events_append_only();
+
+-- Existing bookings start their history with the current state
+INSERT INTO booking_events (booking_id, from_status, to_status, new_scheduled_at, actor_user_id, actor_role, created_at)
+SELECT id, NULL, status::text, scheduled_at, user_id, 'owner', created_at FROM bookings;
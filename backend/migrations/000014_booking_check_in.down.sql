@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_bookings_mechanic;
+DROP INDEX IF EXISTS idx_bookings_inspection_id;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS inspection_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS mechanic_user_id;
@@ -0,0 +1,6 @@
+-- Check-in at the service center: the assigned mechanic and the inspection opened for the visit
+ALTER TABLE bookings ADD COLUMN mechanic_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN inspection_id UUID REFERENCES inspections(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_bookings_inspection_id ON bookings(inspection_id) WHERE inspection_id IS NOT NULL;
+CREATE INDEX idx_bookings_mechanic ON bookings(mechanic_user_id, scheduled_at) WHERE mechanic_user_id IS NOT NULL;
@@ -0,0 +1,16 @@
+ALTER TABLE bookings DROP COLUMN IF EXISTS service_record_id;
+
+DROP INDEX IF EXISTS idx_service_records_booking_id;
+ALTER TABLE service_records DROP COLUMN IF EXISTS booking_id;
+
+DROP TABLE IF EXISTS booking_services;
+
+DROP INDEX IF EXISTS idx_service_center_services_category;
+ALTER TABLE service_center_services DROP CONSTRAINT IF EXISTS service_center_services_price_range;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS component_codes;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS platform_ids;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS currency;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS price_max;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS price_min;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS description;
+ALTER TABLE service_center_services DROP COLUMN IF EXISTS category;
@@ -0,0 +1,35 @@
+-- Price list of a service center: category, price range and the vehicles/components a service applies to
+ALTER TABLE service_center_services ADD COLUMN category VARCHAR(50);
+ALTER TABLE service_center_services ADD COLUMN description TEXT;
+ALTER TABLE service_center_services ADD COLUMN price_min NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (price_min >= 0);
+ALTER TABLE service_center_services ADD COLUMN price_max NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE service_center_services ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'KZT';
+ALTER TABLE service_center_services ADD COLUMN platform_ids UUID[] NOT NULL DEFAULT '{}'; -- empty: any platform
+ALTER TABLE service_center_services ADD COLUMN component_codes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE service_center_services ADD CONSTRAINT service_center_services_price_range CHECK (price_max >= price_min);
+
+CREATE INDEX idx_service_center_services_category ON service_center_services(service_center_id, category);
+
+-- Services selected for a booking with the price quoted at booking time and the price actually charged
+CREATE TABLE booking_services (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    center_service_id UUID REFERENCES service_center_services(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(50),
+    duration_minutes INTEGER NOT NULL,
+    quoted_price_min NUMERIC(12, 2) NOT NULL,
+    quoted_price_max NUMERIC(12, 2) NOT NULL,
+    actual_price NUMERIC(12, 2) CHECK (actual_price >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_booking_services_booking_id ON booking_services(booking_id, position);
+
+-- Completed bookings as service book entries: service_records.booking_id <-> bookings.service_record_id
+ALTER TABLE service_records ADD COLUMN IF NOT EXISTS booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_records_booking_id ON service_records(booking_id) WHERE booking_id IS NOT NULL;
+
+ALTER TABLE bookings ADD COLUMN service_record_id UUID REFERENCES service_records(id) ON DELETE SET NULL;
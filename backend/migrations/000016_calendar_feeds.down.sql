@@ -0,0 +1,3 @@
+ALTER TABLE bookings DROP COLUMN IF EXISTS sequence;
+
+DROP TABLE IF EXISTS calendar_feeds;
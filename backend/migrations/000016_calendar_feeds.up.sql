@@ -0,0 +1,15 @@
+-- Secret-token iCalendar feeds of bookings, per user or per service center
+CREATE TABLE calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    token VARCHAR(64) NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_center_id UUID REFERENCES service_centers(id) ON DELETE CASCADE, -- NULL: the user's own bookings
+    last_accessed_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_calendar_feeds_user_id ON calendar_feeds(user_id);
+
+-- iCalendar SEQUENCE: grows with every change so calendar apps pick up reschedules and cancellations
+ALTER TABLE bookings ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+ALTER TABLE service_centers DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS rating_avg;
+
+DROP TABLE IF EXISTS review_flags;
+DROP TRIGGER IF EXISTS update_reviews_updated_at ON reviews;
+DROP TABLE IF EXISTS reviews;
+
+DELETE FROM asset_links WHERE link_type = 'review';
+ALTER TYPE asset_link_type RENAME TO asset_link_type_old;
+CREATE TYPE asset_link_type AS ENUM ('vehicle', 'inspection', 'component', 'component_observation', 'fine');
+ALTER TABLE asset_links ALTER COLUMN link_type TYPE asset_link_type USING link_type::text::asset_link_type;
+DROP TYPE asset_link_type_old;
@@ -0,0 +1,44 @@
+-- Reviews of service centers by owners, for a completed booking or an inspection of their vehicle
+ALTER TYPE asset_link_type ADD VALUE IF NOT EXISTS 'review';
+
+CREATE TABLE reviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    booking_id UUID UNIQUE REFERENCES bookings(id) ON DELETE SET NULL,
+    inspection_id UUID UNIQUE REFERENCES inspections(id) ON DELETE SET NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'published', -- published, hidden
+    flags_count INTEGER NOT NULL DEFAULT 0,
+    moderation_reason TEXT,
+    moderated_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    moderated_at TIMESTAMP WITH TIME ZONE,
+    reply_text TEXT,
+    reply_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    replied_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_reviews_center ON reviews(service_center_id, status, created_at DESC);
+CREATE INDEX idx_reviews_user_id ON reviews(user_id);
+CREATE INDEX idx_reviews_flagged ON reviews(flags_count DESC) WHERE flags_count > 0;
+
+CREATE TRIGGER update_reviews_updated_at BEFORE UPDATE ON reviews
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- One flag per user and review
+CREATE TABLE review_flags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(50) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (review_id, user_id)
+);
+
+-- Aggregated rating of published reviews, kept up to date by the application
+ALTER TABLE service_centers ADD COLUMN rating_avg NUMERIC(3, 2);
+ALTER TABLE service_centers ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
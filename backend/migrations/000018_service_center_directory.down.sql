@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_service_centers_make_ids;
+DROP INDEX IF EXISTS idx_service_centers_city;
+DROP INDEX IF EXISTS idx_service_centers_location;
+
+ALTER TABLE service_centers DROP CONSTRAINT IF EXISTS service_centers_coordinates;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS make_ids;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS phones;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS longitude;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS latitude;
+ALTER TABLE service_centers DROP COLUMN IF EXISTS city;
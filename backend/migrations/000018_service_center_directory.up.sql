@@ -0,0 +1,12 @@
+-- Public directory of service centers: location, contacts and make specialization
+ALTER TABLE service_centers ADD COLUMN city VARCHAR(100);
+ALTER TABLE service_centers ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90);
+ALTER TABLE service_centers ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+ALTER TABLE service_centers ADD COLUMN phones TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE service_centers ADD COLUMN make_ids UUID[] NOT NULL DEFAULT '{}'; -- catalog makes the center specializes in; empty: multi-brand
+ALTER TABLE service_centers ADD CONSTRAINT service_centers_coordinates CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- Nearby search narrows by a bounding box on these columns before computing the exact distance
+CREATE INDEX idx_service_centers_location ON service_centers(latitude, longitude) WHERE latitude IS NOT NULL;
+CREATE INDEX idx_service_centers_city ON service_centers(LOWER(city));
+CREATE INDEX idx_service_centers_make_ids ON service_centers USING GIN (make_ids);
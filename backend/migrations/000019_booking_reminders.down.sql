@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bookings_open_start;
+DROP TABLE IF EXISTS booking_reminders;
@@ -0,0 +1,12 @@
+-- Reminders sent to owners before their bookings. A rescheduled booking gets its reminders again.
+CREATE TABLE booking_reminders (
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    lead_minutes INTEGER NOT NULL CHECK (lead_minutes > 0),
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL, -- start of the booking the reminder was for
+    channel VARCHAR(20) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (booking_id, lead_minutes, scheduled_at)
+);
+
+-- Reminder and no-show jobs scan upcoming and overdue bookings that are still open
+CREATE INDEX idx_bookings_open_start ON bookings(scheduled_at) WHERE status IN ('scheduled', 'confirmed');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS waitlist_offers;
+DROP TRIGGER IF EXISTS update_booking_waitlist_updated_at ON booking_waitlist;
+DROP TABLE IF EXISTS booking_waitlist;
@@ -0,0 +1,40 @@
+-- Waitlist for fully booked centers: users wait for a date range, freed slots are offered in queue order
+CREATE TABLE booking_waitlist (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    vehicle_id UUID NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    service_ids UUID[] NOT NULL DEFAULT '{}',
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting', -- waiting, offered, booked, cancelled, expired
+    notes TEXT,
+    queued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(), -- position in the queue; moves back after a missed offer
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (date_to >= date_from)
+);
+
+CREATE INDEX idx_booking_waitlist_queue ON booking_waitlist(service_center_id, queued_at) WHERE status = 'waiting';
+CREATE INDEX idx_booking_waitlist_user_id ON booking_waitlist(user_id, created_at);
+
+CREATE TRIGGER update_booking_waitlist_updated_at BEFORE UPDATE ON booking_waitlist
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- A freed slot held for a waitlisted user until the offer expires; pending offers count as busy time
+CREATE TABLE waitlist_offers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entry_id UUID NOT NULL REFERENCES booking_waitlist(id) ON DELETE CASCADE,
+    service_center_id UUID NOT NULL REFERENCES service_centers(id) ON DELETE CASCADE,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, accepted, declined, expired
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    CHECK (ends_at > scheduled_at)
+);
+
+CREATE UNIQUE INDEX idx_waitlist_offers_one_pending ON waitlist_offers(entry_id) WHERE status = 'pending';
+CREATE INDEX idx_waitlist_offers_holds ON waitlist_offers(service_center_id, scheduled_at) WHERE status = 'pending';
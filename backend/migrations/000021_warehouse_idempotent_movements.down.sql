@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_warehouse_movements_idempotency_key;
+ALTER TABLE warehouse_movements DROP COLUMN IF EXISTS quantity_after;
+ALTER TABLE warehouse_movements DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,6 @@
+-- A retried stock request carries the same idempotency key and must not move stock twice.
+ALTER TABLE warehouse_movements ADD COLUMN idempotency_key VARCHAR(255);
+ALTER TABLE warehouse_movements ADD COLUMN quantity_after INTEGER; -- stock level right after the movement
+
+CREATE UNIQUE INDEX idx_warehouse_movements_idempotency_key ON warehouse_movements(idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_warehouse_movements_transfer_id;
+DROP INDEX IF EXISTS idx_warehouse_movements_location_id;
+ALTER TABLE warehouse_movements DROP COLUMN IF EXISTS transfer_id;
+-- Transfers only moved stock between locations; without locations they net out to nothing
+DELETE FROM warehouse_movements WHERE type = 'transfer';
+ALTER TABLE warehouse_movements DROP COLUMN IF EXISTS location_id;
+
+-- Fold per-location stock back into one row per item
+DROP INDEX IF EXISTS idx_warehouse_stock_location_id;
+ALTER TABLE warehouse_stock DROP CONSTRAINT IF EXISTS warehouse_stock_item_location_key;
+CREATE TEMP TABLE warehouse_stock_totals AS
+    SELECT item_id, MIN(id::text)::uuid AS keep_id, SUM(quantity)::int AS total FROM warehouse_stock GROUP BY item_id;
+DELETE FROM warehouse_stock s USING warehouse_stock_totals t WHERE s.item_id = t.item_id AND s.id <> t.keep_id;
+UPDATE warehouse_stock s SET quantity = t.total FROM warehouse_stock_totals t WHERE s.id = t.keep_id;
+DROP TABLE warehouse_stock_totals;
+ALTER TABLE warehouse_stock DROP COLUMN IF EXISTS location_id;
+ALTER TABLE warehouse_stock ADD CONSTRAINT warehouse_stock_item_id_key UNIQUE (item_id);
+
+DROP TRIGGER IF EXISTS update_warehouse_locations_updated_at ON warehouse_locations;
+DROP TABLE IF EXISTS warehouse_locations;
+-- The 'transfer' value stays in warehouse_movement_type: enum values cannot be dropped
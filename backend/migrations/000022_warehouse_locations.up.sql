@@ -0,0 +1,35 @@
+-- Warehouse locations: the platform's main warehouse and the storerooms of service centers
+ALTER TYPE warehouse_movement_type ADD VALUE IF NOT EXISTS 'transfer';
+
+CREATE TABLE warehouse_locations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    service_center_id UUID REFERENCES service_centers(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_locations_service_center_id ON warehouse_locations(service_center_id);
+
+CREATE TRIGGER update_warehouse_locations_updated_at BEFORE UPDATE ON warehouse_locations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Existing stock and movements belong to the main warehouse
+INSERT INTO warehouse_locations (code, name) VALUES ('main', 'Main warehouse');
+
+ALTER TABLE warehouse_stock ADD COLUMN location_id UUID REFERENCES warehouse_locations(id) ON DELETE CASCADE;
+UPDATE warehouse_stock SET location_id = (SELECT id FROM warehouse_locations WHERE code = 'main');
+ALTER TABLE warehouse_stock ALTER COLUMN location_id SET NOT NULL;
+ALTER TABLE warehouse_stock DROP CONSTRAINT warehouse_stock_item_id_key;
+ALTER TABLE warehouse_stock ADD CONSTRAINT warehouse_stock_item_location_key UNIQUE (item_id, location_id);
+CREATE INDEX idx_warehouse_stock_location_id ON warehouse_stock(location_id);
+
+ALTER TABLE warehouse_movements ADD COLUMN location_id UUID REFERENCES warehouse_locations(id) ON DELETE CASCADE;
+UPDATE warehouse_movements SET location_id = (SELECT id FROM warehouse_locations WHERE code = 'main');
+ALTER TABLE warehouse_movements ALTER COLUMN location_id SET NOT NULL;
+-- Both legs of a transfer share transfer_id
+ALTER TABLE warehouse_movements ADD COLUMN transfer_id UUID;
+CREATE INDEX idx_warehouse_movements_location_id ON warehouse_movements(location_id);
+CREATE INDEX idx_warehouse_movements_transfer_id ON warehouse_movements(transfer_id) WHERE transfer_id IS NOT NULL;
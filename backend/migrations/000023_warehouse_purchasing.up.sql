@@ -0,0 +1,82 @@
+-- Suppliers of parts with their lead times
+CREATE TABLE suppliers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    contact_name VARCHAR(255),
+    phone VARCHAR(50),
+    email VARCHAR(255),
+    lead_time_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0), -- from sending an order to delivery
+    notes TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_suppliers_updated_at BEFORE UPDATE ON suppliers
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- What a supplier sells: its own SKU and price for our item
+CREATE TABLE supplier_items (
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    supplier_sku VARCHAR(100),
+    price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+    min_order_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_order_quantity > 0),
+    is_preferred BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (supplier_id, item_id)
+);
+
+CREATE INDEX idx_supplier_items_item_id ON supplier_items(item_id);
+-- At most one preferred supplier per item
+CREATE UNIQUE INDEX idx_supplier_items_preferred ON supplier_items(item_id) WHERE is_preferred;
+
+CREATE TRIGGER update_supplier_items_updated_at BEFORE UPDATE ON supplier_items
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Purchase orders are delivered to one location; number is shown to people as PO-<number>
+CREATE TABLE purchase_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    number BIGSERIAL UNIQUE NOT NULL,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id),
+    location_id UUID NOT NULL REFERENCES warehouse_locations(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft', -- draft, sent, partially_received, received, cancelled
+    currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    expected_at DATE, -- sent date plus the supplier's lead time
+    received_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_purchase_orders_status ON purchase_orders(status, created_at);
+CREATE INDEX idx_purchase_orders_supplier_id ON purchase_orders(supplier_id);
+
+CREATE TRIGGER update_purchase_orders_updated_at BEFORE UPDATE ON purchase_orders
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE purchase_order_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES warehouse_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    unit_price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (unit_price >= 0),
+    UNIQUE (order_id, item_id),
+    CHECK (received_quantity <= quantity)
+);
+
+-- One row per delivery; a retried receipt with the same key is not booked twice
+CREATE TABLE purchase_order_receipts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) UNIQUE,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_purchase_order_receipts_order_id ON purchase_order_receipts(order_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS warehouse_cost_layers;
+DROP INDEX IF EXISTS idx_warehouse_movements_created_at;
+ALTER TABLE warehouse_stock DROP COLUMN IF EXISTS value;
+ALTER TABLE warehouse_movements
+    DROP COLUMN IF EXISTS sale_price,
+    DROP COLUMN IF EXISTS total_cost,
+    DROP COLUMN IF EXISTS unit_cost;
@@ -0,0 +1,28 @@
+-- Cost of goods: unit cost of incoming stock, cost of outgoing stock and the sale price of issued parts
+ALTER TABLE warehouse_movements
+    ADD COLUMN unit_cost NUMERIC(14, 4) CHECK (unit_cost >= 0), -- per unit: purchase cost for receipts, cost of goods for issues
+    ADD COLUMN total_cost NUMERIC(14, 2),                        -- change of the stock value, negative for issues
+    ADD COLUMN sale_price NUMERIC(14, 2) CHECK (sale_price >= 0); -- per unit, when a part is sold on a work order
+
+-- Value of the stock on hand at the location
+ALTER TABLE warehouse_stock ADD COLUMN value NUMERIC(14, 2) NOT NULL DEFAULT 0;
+
+-- FIFO layers: what is left of each receipt at a location, consumed oldest first
+CREATE TABLE warehouse_cost_layers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    location_id UUID NOT NULL REFERENCES warehouse_locations(id),
+    -- NULL for the opening balance; deferred because the layer is written before its movement
+    movement_id UUID REFERENCES warehouse_movements(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+    unit_cost NUMERIC(14, 4) NOT NULL CHECK (unit_cost >= 0),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    remaining INTEGER NOT NULL CHECK (remaining >= 0 AND remaining <= quantity),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_cost_layers_open ON warehouse_cost_layers(item_id, location_id, created_at) WHERE remaining > 0;
+CREATE INDEX idx_warehouse_movements_created_at ON warehouse_movements(created_at);
+
+-- Stock received before costing has no known cost: it opens at zero
+INSERT INTO warehouse_cost_layers (item_id, location_id, unit_cost, quantity, remaining)
+SELECT item_id, location_id, 0, quantity, quantity FROM warehouse_stock WHERE quantity > 0;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_warehouse_reservations_updated_at ON warehouse_reservations;
+DROP TABLE IF EXISTS warehouse_reservations;
+ALTER TABLE warehouse_stock
+    DROP CONSTRAINT IF EXISTS warehouse_stock_reserved_check,
+    DROP COLUMN IF EXISTS reserved;
@@ -0,0 +1,26 @@
+-- Stock held for scheduled jobs; only quantity - reserved can be issued freely
+ALTER TABLE warehouse_stock
+    ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0,
+    ADD CONSTRAINT warehouse_stock_reserved_check CHECK (reserved >= 0 AND reserved <= quantity);
+
+CREATE TABLE warehouse_reservations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    location_id UUID NOT NULL REFERENCES warehouse_locations(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    reference_type VARCHAR(20) NOT NULL, -- booking, work_order
+    reference_id VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, consumed, released, expired
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    movement_id UUID REFERENCES warehouse_movements(id) ON DELETE SET NULL, -- the issue a consumed reservation became
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_reservations_reference ON warehouse_reservations(reference_type, reference_id);
+CREATE INDEX idx_warehouse_reservations_item_id ON warehouse_reservations(item_id, location_id);
+CREATE INDEX idx_warehouse_reservations_expires_at ON warehouse_reservations(expires_at) WHERE status = 'active';
+
+CREATE TRIGGER update_warehouse_reservations_updated_at BEFORE UPDATE ON warehouse_reservations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS warehouse_stocktake_counts;
+DROP TABLE IF EXISTS warehouse_stocktake_lines;
+DROP TRIGGER IF EXISTS update_warehouse_stocktakes_updated_at ON warehouse_stocktakes;
+DROP TABLE IF EXISTS warehouse_stocktakes;
+DROP INDEX IF EXISTS idx_warehouse_items_category;
+ALTER TABLE warehouse_items DROP COLUMN IF EXISTS category;
@@ -0,0 +1,53 @@
+-- Items are grouped by category so a stocktake can cover part of a location
+ALTER TABLE warehouse_items ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_warehouse_items_category ON warehouse_items(category);
+
+-- Physical counts of a location, or of one category at it; number is shown to people as ST-<number>
+CREATE TABLE warehouse_stocktakes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    number BIGSERIAL UNIQUE NOT NULL,
+    location_id UUID NOT NULL REFERENCES warehouse_locations(id),
+    category VARCHAR(100), -- NULL counts every item
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, posted, cancelled
+    notes TEXT,
+    started_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    posted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    posted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_stocktakes_status ON warehouse_stocktakes(status, created_at);
+-- One open count at a time for the same items
+CREATE UNIQUE INDEX idx_warehouse_stocktakes_open ON warehouse_stocktakes(location_id, COALESCE(category, ''))
+    WHERE status = 'open';
+
+CREATE TRIGGER update_warehouse_stocktakes_updated_at BEFORE UPDATE ON warehouse_stocktakes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Expected quantity is frozen when the count starts; counted stays NULL until the item is counted
+CREATE TABLE warehouse_stocktake_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stocktake_id UUID NOT NULL REFERENCES warehouse_stocktakes(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    expected_quantity INTEGER NOT NULL,
+    counted_quantity INTEGER CHECK (counted_quantity >= 0),
+    movement_id UUID REFERENCES warehouse_movements(id) ON DELETE SET NULL, -- the adjustment posted for the variance
+    UNIQUE (stocktake_id, item_id)
+);
+
+-- Every count entry, so it is known who counted what
+CREATE TABLE warehouse_stocktake_counts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stocktake_id UUID NOT NULL REFERENCES warehouse_stocktakes(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    mode VARCHAR(10) NOT NULL, -- set, add
+    quantity INTEGER NOT NULL,
+    counted_quantity INTEGER NOT NULL, -- the item's count after this entry
+    code VARCHAR(100), -- the scanned code, if the item was scanned
+    counted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_stocktake_counts_stocktake_id ON warehouse_stocktake_counts(stocktake_id, created_at);
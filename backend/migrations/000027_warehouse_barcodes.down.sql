@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS warehouse_item_part_numbers;
+DROP TABLE IF EXISTS warehouse_item_barcodes;
+DROP INDEX IF EXISTS idx_warehouse_items_description_trgm;
+DROP INDEX IF EXISTS idx_warehouse_items_name_trgm;
+DROP INDEX IF EXISTS idx_warehouse_items_sku_trgm;
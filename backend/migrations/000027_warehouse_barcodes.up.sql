@@ -0,0 +1,32 @@
+-- Trigram matching for typo-tolerant item search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_warehouse_items_sku_trgm ON warehouse_items USING gin (sku gin_trgm_ops);
+CREATE INDEX idx_warehouse_items_name_trgm ON warehouse_items USING gin (name gin_trgm_ops);
+CREATE INDEX idx_warehouse_items_description_trgm ON warehouse_items USING gin (description gin_trgm_ops);
+
+-- Codes printed on packaging; UPC-A is kept as EAN-13 with a leading zero
+CREATE TABLE warehouse_item_barcodes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    type VARCHAR(20) NOT NULL, -- ean13, ean8, upc_a, internal
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_item_barcodes_item_id ON warehouse_item_barcodes(item_id);
+
+-- OEM and aftermarket numbers an item is sold under; normalized keeps only letters and digits
+-- in upper case so "04465-02220" finds "0446502220"
+CREATE TABLE warehouse_item_part_numbers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'oem', -- oem, aftermarket
+    brand VARCHAR(100),
+    number VARCHAR(100) NOT NULL,
+    normalized VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (item_id, kind, normalized)
+);
+
+CREATE INDEX idx_warehouse_item_part_numbers_normalized ON warehouse_item_part_numbers USING gin (normalized gin_trgm_ops);
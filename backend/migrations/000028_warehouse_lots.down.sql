@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS warehouse_lot_movements;
+DROP TABLE IF EXISTS warehouse_lots;
+ALTER TABLE warehouse_items
+    DROP COLUMN IF EXISTS shelf_life_days,
+    DROP COLUMN IF EXISTS track_lots;
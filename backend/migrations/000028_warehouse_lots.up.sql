@@ -0,0 +1,34 @@
+-- Lot tracking is enabled per item; shelf life gives the expiry of lots received without one
+ALTER TABLE warehouse_items
+    ADD COLUMN track_lots BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN shelf_life_days INTEGER CHECK (shelf_life_days > 0);
+
+-- A batch of an item at a location; a lot moved to another location keeps its number and expiry
+CREATE TABLE warehouse_lots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    location_id UUID NOT NULL REFERENCES warehouse_locations(id),
+    lot_number VARCHAR(100) NOT NULL,
+    expires_at DATE,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0), -- received in total
+    remaining INTEGER NOT NULL CHECK (remaining >= 0 AND remaining <= quantity),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (item_id, location_id, lot_number)
+);
+
+CREATE INDEX idx_warehouse_lots_open ON warehouse_lots(item_id, location_id, expires_at) WHERE remaining > 0;
+CREATE INDEX idx_warehouse_lots_expires_at ON warehouse_lots(expires_at) WHERE remaining > 0;
+CREATE INDEX idx_warehouse_lots_lot_number ON warehouse_lots(lot_number);
+
+-- Which movements took stock into and out of each lot, for recalls
+CREATE TABLE warehouse_lot_movements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    lot_id UUID NOT NULL REFERENCES warehouse_lots(id) ON DELETE CASCADE,
+    -- deferred because the row is written before its movement
+    movement_id UUID NOT NULL REFERENCES warehouse_movements(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+    quantity_delta INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_warehouse_lot_movements_lot_id ON warehouse_lot_movements(lot_id);
+CREATE INDEX idx_warehouse_lot_movements_movement_id ON warehouse_lot_movements(movement_id);
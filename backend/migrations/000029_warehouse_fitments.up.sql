@@ -0,0 +1,24 @@
+-- Which vehicles an item fits: a catalog component code on a platform, a whole generation or a
+-- whole model, optionally narrowed to model years and an engine code
+CREATE TABLE warehouse_item_fitments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id UUID NOT NULL REFERENCES warehouse_items(id) ON DELETE CASCADE,
+    component_code VARCHAR(255) NOT NULL, -- components.code, e.g. brakes.pad.front; covers the codes below it
+    vehicle_platform_id UUID REFERENCES vehicle_platforms(id) ON DELETE CASCADE,
+    generation_id UUID REFERENCES generations(id) ON DELETE CASCADE,
+    model_id VARCHAR(100) REFERENCES models(id) ON DELETE CASCADE,
+    year_from INTEGER,
+    year_to INTEGER,
+    engine_code VARCHAR(100), -- stored upper case
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (num_nonnulls(vehicle_platform_id, generation_id, model_id) = 1),
+    CHECK (year_from IS NULL OR year_to IS NULL OR year_from <= year_to)
+);
+
+CREATE UNIQUE INDEX idx_warehouse_item_fitments_unique ON warehouse_item_fitments(
+    item_id, component_code, COALESCE(vehicle_platform_id::text, generation_id::text, model_id),
+    COALESCE(year_from, 0), COALESCE(year_to, 0), COALESCE(engine_code, ''));
+CREATE INDEX idx_warehouse_item_fitments_platform ON warehouse_item_fitments(vehicle_platform_id, component_code);
+CREATE INDEX idx_warehouse_item_fitments_generation ON warehouse_item_fitments(generation_id, component_code);
+CREATE INDEX idx_warehouse_item_fitments_model ON warehouse_item_fitments(model_id, component_code);
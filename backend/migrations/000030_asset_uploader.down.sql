@@ -0,0 +1,6 @@
+UPDATE asset_links al SET link_id = d.fine_id
+FROM fine_disputes d
+WHERE al.link_type = 'fine' AND al.link_id = d.id;
+
+DROP INDEX IF EXISTS idx_assets_user_id;
+ALTER TABLE assets DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,10 @@
+-- Who uploaded an asset, so only the uploader can attach it as evidence or to a review
+ALTER TABLE assets ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_assets_user_id ON assets(user_id);
+
+-- Dispute evidence is linked to the dispute, not the fine, so a later dispute starts empty
+UPDATE asset_links al SET link_id = d.id
+FROM (
+    SELECT DISTINCT ON (fine_id) id, fine_id FROM fine_disputes ORDER BY fine_id, submitted_at DESC
+) d
+WHERE al.link_type = 'fine' AND al.link_id = d.fine_id;
@@ -0,0 +1,3 @@
+DELETE FROM payment_refunds WHERE status <> 'succeeded';
+ALTER TABLE payment_refunds DROP COLUMN IF EXISTS failure_reason;
+ALTER TABLE payment_refunds DROP COLUMN IF EXISTS status;
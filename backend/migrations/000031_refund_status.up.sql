@@ -0,0 +1,5 @@
+-- A refund is recorded as pending before the provider is called and finalized afterwards,
+-- so the provider is never called inside a database transaction
+ALTER TABLE payment_refunds ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'succeeded'
+    CHECK (status IN ('pending', 'succeeded', 'failed'));
+ALTER TABLE payment_refunds ADD COLUMN failure_reason TEXT;
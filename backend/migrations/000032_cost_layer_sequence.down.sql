@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_warehouse_cost_layers_open;
+ALTER TABLE warehouse_cost_layers DROP COLUMN IF EXISTS seq;
+CREATE INDEX idx_warehouse_cost_layers_open ON warehouse_cost_layers(item_id, location_id, created_at) WHERE remaining > 0;
@@ -0,0 +1,12 @@
+-- Layers written in one transaction share created_at; seq keeps FIFO order among them
+ALTER TABLE warehouse_cost_layers ADD COLUMN seq BIGSERIAL;
+
+-- Existing layers are numbered in the order they have been consumed so far
+UPDATE warehouse_cost_layers l SET seq = o.n
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS n FROM warehouse_cost_layers) o
+WHERE l.id = o.id;
+SELECT setval(pg_get_serial_sequence('warehouse_cost_layers', 'seq'), COALESCE(MAX(seq), 0) + 1, false)
+FROM warehouse_cost_layers;
+
+DROP INDEX IF EXISTS idx_warehouse_cost_layers_open;
+CREATE INDEX idx_warehouse_cost_layers_open ON warehouse_cost_layers(item_id, location_id, created_at, seq) WHERE remaining > 0;
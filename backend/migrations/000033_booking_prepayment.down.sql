@@ -0,0 +1,2 @@
+ALTER TABLE bookings DROP COLUMN IF EXISTS paid_at;
+ALTER TABLE bookings DROP COLUMN IF EXISTS paid_amount;
@@ -0,0 +1,3 @@
+-- A booking with a fixed price can be prepaid; set by payment webhooks, cleared by a refund
+ALTER TABLE bookings ADD COLUMN paid_amount DECIMAL(12, 2);
+ALTER TABLE bookings ADD COLUMN paid_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,5 @@
+DELETE FROM asset_links WHERE link_type = 'fine_dispute';
+ALTER TYPE asset_link_type RENAME TO asset_link_type_old;
+CREATE TYPE asset_link_type AS ENUM ('vehicle', 'inspection', 'component', 'component_observation', 'fine', 'review');
+ALTER TABLE asset_links ALTER COLUMN link_type TYPE asset_link_type USING link_type::text::asset_link_type;
+DROP TYPE asset_link_type_old;
@@ -0,0 +1,3 @@
+-- Dispute evidence gets its own link type, so 'fine' links only ever hold fine IDs.
+-- The new value cannot be used in this transaction: links are moved by the next migration.
+ALTER TYPE asset_link_type ADD VALUE IF NOT EXISTS 'fine_dispute';
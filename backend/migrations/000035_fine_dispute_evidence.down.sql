@@ -0,0 +1 @@
+UPDATE asset_links SET link_type = 'fine' WHERE link_type = 'fine_dispute';
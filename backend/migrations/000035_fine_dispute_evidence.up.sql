@@ -0,0 +1,4 @@
+-- Evidence links hold dispute IDs since 000030; give them the dispute link type
+UPDATE asset_links al SET link_type = 'fine_dispute'
+FROM fine_disputes d
+WHERE al.link_type = 'fine' AND al.link_id = d.id;